@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// Usage of command.
+const usage = "usage: migrate [flags] up | down N | version | force V | status"
+
+func main() {
+	err := run(os.Stdout)
+	if err != nil {
+		log.Fatalf("Migrate error: %s", err)
+	}
+}
+
+// run execute migration command from arguments against database from configuration.
+func run(out io.Writer) (err error) {
+	cfg, err := config.Init()
+	if err != nil {
+		return fmt.Errorf("can not init config: %w", err)
+	}
+	if cfg.DatabaseDSN == "" {
+		return errors.New("database dsn is not set")
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	m, err := repository.NewMigrate(cfg.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("can not init migrations: %w", err)
+	}
+	defer func() {
+		sourceErr, dbErr := m.Close()
+		if closeErr := errors.Join(sourceErr, dbErr); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("can not close migrations: %w", closeErr))
+		}
+	}()
+
+	var value int
+	switch args[0] {
+	case "up":
+		err = m.Up()
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("can not migrate up: %w", err)
+		}
+		return printVersion(out, m)
+	case "down":
+		value, err = argument(args)
+		if err != nil {
+			return err
+		}
+		if value <= 0 {
+			return errors.New("amount of migrations to revert must be positive")
+		}
+		err = m.Steps(-value)
+		if err != nil {
+			return fmt.Errorf("can not migrate down: %w", err)
+		}
+		return printVersion(out, m)
+	case "version":
+		return printVersion(out, m)
+	case "force":
+		value, err = argument(args)
+		if err != nil {
+			return err
+		}
+		err = m.Force(value)
+		if err != nil {
+			return fmt.Errorf("can not force version: %w", err)
+		}
+		return printVersion(out, m)
+	case "status":
+		return printStatus(out, m, cfg.DatabaseDSN)
+	default:
+		return fmt.Errorf("unknown command %s, %s", args[0], usage)
+	}
+}
+
+// argument parse numeric argument of command.
+func argument(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("command %s requires one argument, %s", args[0], usage)
+	}
+	value, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, fmt.Errorf("can not parse argument of command %s: %w", args[0], err)
+	}
+	return value, nil
+}
+
+// printVersion print current version of database.
+func printVersion(out io.Writer, m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			_, err = fmt.Fprintln(out, "no migrations applied")
+			return err //nolint:wrapcheck // error of output
+		}
+		return fmt.Errorf("can not get version: %w", err)
+	}
+
+	if dirty {
+		_, err = fmt.Fprintf(out, "version %d (dirty)\n", version)
+		return err //nolint:wrapcheck // error of output
+	}
+	_, err = fmt.Fprintf(out, "version %d\n", version)
+	return err //nolint:wrapcheck // error of output
+}
+
+// printStatus print all migrations with their state.
+func printStatus(out io.Writer, m *migrate.Migrate, connString string) error {
+	driver, err := repository.NewMigrationsSource(connString)
+	if err != nil {
+		return fmt.Errorf("can not init migrations source: %w", err)
+	}
+
+	migrations, err := repository.Migrations(m, driver)
+	if err != nil {
+		return errors.Join(fmt.Errorf("can not get migrations: %w", err), driver.Close())
+	}
+
+	for _, migration := range migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		_, err = fmt.Fprintf(out, "%05d %s %s\n", migration.Version, migration.Identifier, state)
+		if err != nil {
+			return errors.Join(fmt.Errorf("can not print status: %w", err), driver.Close())
+		}
+	}
+
+	err = driver.Close()
+	if err != nil {
+		return fmt.Errorf("can not close migrations source: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dsn := repository.SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db")
+
+	tests := []struct {
+		name   string
+		want   string
+		err    string
+		args   []string
+		envDSN bool
+	}{
+		{
+			name: "no database dsn",
+			args: []string{"version"},
+			err:  "database dsn is not set",
+		},
+		{
+			name: "no command",
+			args: []string{},
+			err:  usage,
+		},
+		{
+			name: "unknown command",
+			args: []string{"sideways"},
+			err:  "unknown command sideways",
+		},
+		{
+			name: "version of empty database",
+			args: []string{"version"},
+			want: "no migrations applied\n",
+		},
+		{
+			name: "up",
+			args: []string{"up"},
+			want: "version 10\n",
+		},
+		{
+			name:   "up with dsn from environment",
+			args:   []string{"up"},
+			want:   "version 10\n",
+			envDSN: true,
+		},
+		{
+			name: "down without argument",
+			args: []string{"down"},
+			err:  "command down requires one argument",
+		},
+		{
+			name: "down with invalid argument",
+			args: []string{"down", "0"},
+			err:  "must be positive",
+		},
+		{
+			name: "down",
+			args: []string{"down", "8"},
+			want: "version 1\n",
+		},
+		{
+			name: "status",
+			args: []string{"status"},
+			want: "00001 init applied\n00002 add_user_id pending\n" +
+				"00003 add_deleted pending\n00004 add_created_at pending\n00006 add_api_keys pending\n" +
+				"00007 add_refresh_tokens pending\n00008 add_users pending\n00009 add_disabled pending\n" +
+				"00010 add_audit_log pending\n",
+		},
+		{
+			name: "force",
+			args: []string{"force", "2"},
+			want: "version 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Args = []string{"migrate"}
+			switch {
+			case tt.envDSN:
+				err := os.Setenv("DATABASE_DSN", dsn)
+				assert.NoError(t, err)
+			case tt.err != "database dsn is not set":
+				os.Args = append(os.Args, "-d", dsn)
+			}
+			os.Args = append(os.Args, tt.args...)
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			out := &bytes.Buffer{}
+			err := run(out)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/RexArseny/url_shortener/internal/app/admin"
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/logger"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+)
+
+// Usage of command.
+const usage = "usage: shortener-admin [flags] export [-format jsonl|csv] [-output path] | " +
+	"import [-format jsonl|csv] [-input path] [-strategy skip|overwrite|fail] [-batch-size N] [-dry-run]"
+
+// Path of standard input or output.
+const stdPath = "-"
+
+func main() {
+	err := run(context.Background(), os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("Admin error: %s", err)
+	}
+}
+
+// run execute command from arguments against repository from configuration.
+// Data is read from in and written to out, progress is reported to progress.
+func run(ctx context.Context, in io.Reader, out io.Writer, progress io.Writer) (err error) {
+	cfg, err := config.Init()
+	if err != nil {
+		return fmt.Errorf("can not init config: %w", err)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	if args[0] != "export" && args[0] != "import" {
+		return fmt.Errorf("unknown command %s, %s", args[0], usage)
+	}
+
+	mainLogger, err := logger.InitLogger()
+	if err != nil {
+		return fmt.Errorf("can not init logger: %w", err)
+	}
+
+	repo, closeRepository, err := repository.NewRepository(ctx, mainLogger.Named("repository"), cfg)
+	if err != nil {
+		return fmt.Errorf("can not init repository: %w", err)
+	}
+	if closeRepository != nil {
+		defer func() {
+			closeErr := closeRepository()
+			if closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("can not close repository: %w", closeErr))
+			}
+		}()
+	}
+
+	dumper, ok := repo.(repository.Dumper)
+	if !ok {
+		return errors.New("repository does not support export and import")
+	}
+
+	if args[0] == "export" {
+		return export(ctx, dumper, args[1:], out, progress)
+	}
+	return importLinks(ctx, dumper, args[1:], in, out, progress)
+}
+
+// export write all links of repository into output from arguments.
+func export(ctx context.Context, dumper repository.Dumper, args []string, out io.Writer, progress io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(progress)
+	format := flags.String("format", admin.FormatJSONL, "format of output: jsonl or csv")
+	output := flags.String("output", stdPath, "path of output file, - for standard output")
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("can not parse arguments of export: %w", err)
+	}
+
+	w := out
+	var file *os.File
+	if *output != stdPath {
+		file, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("can not create output file: %w", err)
+		}
+		w = file
+	}
+
+	count, err := admin.Export(ctx, dumper, w, *format, progress)
+	if file != nil {
+		closeErr := file.Close()
+		if closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("can not close output file: %w", closeErr))
+		}
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // errors are wrapped inside export
+	}
+
+	_, err = fmt.Fprintf(progress, "exported %d links\n", count)
+	return err //nolint:wrapcheck // error of output
+}
+
+// importLinks read links from input from arguments and store them in repository.
+func importLinks(
+	ctx context.Context,
+	dumper repository.Dumper,
+	args []string,
+	in io.Reader,
+	out io.Writer,
+	progress io.Writer,
+) error {
+	var cfg admin.ImportConfig
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(progress)
+	flags.StringVar(&cfg.Format, "format", admin.FormatJSONL, "format of input: jsonl or csv")
+	input := flags.String("input", stdPath, "path of input file, - for standard input")
+	flags.StringVar(&cfg.Strategy, "strategy", admin.DefaultStrategy,
+		"strategy for links which conflict with existing ones: skip, overwrite or fail")
+	flags.IntVar(&cfg.BatchSize, "batch-size", admin.DefaultBatchSize, "amount of links stored at once")
+	flags.BoolVar(&cfg.DryRun, "dry-run", false, "report changes without storing them")
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("can not parse arguments of import: %w", err)
+	}
+
+	r := in
+	if *input != stdPath {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("can not open input file: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		r = file
+	}
+
+	report, err := admin.Import(ctx, dumper, r, cfg, progress)
+	if err != nil {
+		return err //nolint:wrapcheck // errors are wrapped inside import
+	}
+
+	var dryRun string
+	if cfg.DryRun {
+		dryRun = " (dry run, nothing is stored)"
+	}
+	_, err = fmt.Fprintf(out, "read %d records: %d created, %d overwritten, %d skipped, %d unchanged%s\n",
+		report.Read, report.Created, report.Overwritten, report.Skipped, report.Unchanged, dryRun)
+	return err //nolint:wrapcheck // error of output
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// runArgs run command with provided arguments and standard input.
+func runArgs(t *testing.T, in string, args ...string) (string, string, error) {
+	t.Helper()
+	os.Clearenv()
+	os.Args = append([]string{"shortener-admin"}, args...)
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	out := &bytes.Buffer{}
+	progress := &bytes.Buffer{}
+	err := run(context.Background(), strings.NewReader(in), out, progress)
+	return out.String(), progress.String(), err
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "shorturls.txt")
+	dsn := repository.SQLiteScheme + filepath.Join(dir, "shorturls.db")
+	exportPath := filepath.Join(dir, "export.csv")
+
+	links, err := repository.NewLinksWithFile(zap.NewNop(), filePath, repository.FileConfig{})
+	assert.NoError(t, err)
+	userID := uuid.New()
+	_, err = links.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = links.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+	assert.NoError(t, err)
+	err = links.DeleteURLs(context.Background(), []string{"def456"}, userID)
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+
+	_, _, err = runArgs(t, "", "-f", filePath)
+	assert.ErrorContains(t, err, usage)
+
+	_, _, err = runArgs(t, "", "-f", filePath, "vacuum")
+	assert.ErrorContains(t, err, "unknown command vacuum")
+
+	_, progress, err := runArgs(t, "", "-f", filePath, "export", "-format", "csv", "-output", exportPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "exported 2 links\n", progress)
+
+	out, _, err := runArgs(t, "", "-d", dsn, "import", "-format", "csv", "-input", exportPath, "-dry-run")
+	assert.NoError(t, err)
+	assert.Equal(t, "read 2 records: 2 created, 0 overwritten, 0 skipped, 0 unchanged "+
+		"(dry run, nothing is stored)\n", out)
+
+	out, _, err = runArgs(t, "", "-d", dsn, "import", "-format", "csv", "-input", exportPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "read 2 records: 2 created, 0 overwritten, 0 skipped, 0 unchanged\n", out)
+
+	fileExport, _, err := runArgs(t, "", "-f", filePath, "export")
+	assert.NoError(t, err)
+	sqliteExport, _, err := runArgs(t, "", "-d", dsn, "export")
+	assert.NoError(t, err)
+	assert.Equal(t, fileExport, sqliteExport)
+	assert.Contains(t, sqliteExport, `"deleted":true`)
+
+	changed := strings.Replace(fileExport, "https://example.com", "https://changed.com", 1)
+	_, _, err = runArgs(t, changed, "-d", dsn, "import", "-strategy", "fail")
+	assert.ErrorContains(t, err, "imported link conflicts with existing one")
+
+	out, _, err = runArgs(t, changed, "-d", dsn, "import", "-strategy", "overwrite")
+	assert.NoError(t, err)
+	assert.Equal(t, "read 2 records: 0 created, 1 overwritten, 0 skipped, 1 unchanged\n", out)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/gojek/heimdall/v7/httpclient"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestCreateShortLink(t *testing.T) {
@@ -78,12 +79,12 @@ func TestCreateShortLink(t *testing.T) {
 			var urlRepository repository.Repository
 			switch {
 			case cfg.DatabaseDSN != "":
-				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN)
+				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN, repository.DBConfig{})
 				assert.NoError(t, err)
 				defer dbRepository.Close()
 				urlRepository = dbRepository
 			case cfg.FileStoragePath != "":
-				linksWithFile, err := repository.NewLinksWithFile(cfg.FileStoragePath)
+				linksWithFile, err := repository.NewLinksWithFile(zap.NewNop(), cfg.FileStoragePath, repository.FileConfig{})
 				assert.NoError(t, err)
 				defer func() {
 					err = linksWithFile.Close()
@@ -99,6 +100,7 @@ func TestCreateShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				urlRepository,
+				usecases.DeleterConfig{},
 			)
 			conntroller := controllers.NewController(testLogger.Named("controller"), interactor, nil)
 			middleware, err := middlewares.NewMiddleware(
@@ -107,7 +109,7 @@ func TestCreateShortLink(t *testing.T) {
 				testLogger.Named("middleware"),
 			)
 			assert.NoError(t, err)
-			router, err := routers.NewRouter(&cfg, conntroller, middleware)
+			router, err := routers.NewRouter(&cfg, conntroller, middleware, nil, nil)
 			assert.NoError(t, err)
 
 			server := httptest.NewServer(router)
@@ -199,12 +201,12 @@ func TestCreateShortLinkJSON(t *testing.T) {
 			var urlRepository repository.Repository
 			switch {
 			case cfg.DatabaseDSN != "":
-				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN)
+				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN, repository.DBConfig{})
 				assert.NoError(t, err)
 				defer dbRepository.Close()
 				urlRepository = dbRepository
 			case cfg.FileStoragePath != "":
-				linksWithFile, err := repository.NewLinksWithFile(cfg.FileStoragePath)
+				linksWithFile, err := repository.NewLinksWithFile(zap.NewNop(), cfg.FileStoragePath, repository.FileConfig{})
 				assert.NoError(t, err)
 				defer func() {
 					err = linksWithFile.Close()
@@ -220,6 +222,7 @@ func TestCreateShortLinkJSON(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				urlRepository,
+				usecases.DeleterConfig{},
 			)
 			conntroller := controllers.NewController(testLogger.Named("controller"), interactor, nil)
 			middleware, err := middlewares.NewMiddleware(
@@ -228,7 +231,7 @@ func TestCreateShortLinkJSON(t *testing.T) {
 				testLogger.Named("middleware"),
 			)
 			assert.NoError(t, err)
-			router, err := routers.NewRouter(&cfg, conntroller, middleware)
+			router, err := routers.NewRouter(&cfg, conntroller, middleware, nil, nil)
 			assert.NoError(t, err)
 
 			server := httptest.NewServer(router)
@@ -321,12 +324,12 @@ func TestGetShortLink(t *testing.T) {
 			var urlRepository repository.Repository
 			switch {
 			case cfg.DatabaseDSN != "":
-				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN)
+				dbRepository, err := repository.NewDBRepository(ctx, testLogger.Named("repository"), cfg.DatabaseDSN, repository.DBConfig{})
 				assert.NoError(t, err)
 				defer dbRepository.Close()
 				urlRepository = dbRepository
 			case cfg.FileStoragePath != "":
-				linksWithFile, err := repository.NewLinksWithFile(cfg.FileStoragePath)
+				linksWithFile, err := repository.NewLinksWithFile(zap.NewNop(), cfg.FileStoragePath, repository.FileConfig{})
 				assert.NoError(t, err)
 				defer func() {
 					err = linksWithFile.Close()
@@ -342,6 +345,7 @@ func TestGetShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				urlRepository,
+				usecases.DeleterConfig{},
 			)
 			conntroller := controllers.NewController(testLogger.Named("controller"), interactor, nil)
 			middleware, err := middlewares.NewMiddleware(
@@ -350,7 +354,7 @@ func TestGetShortLink(t *testing.T) {
 				testLogger.Named("middleware"),
 			)
 			assert.NoError(t, err)
-			router, err := routers.NewRouter(&cfg, conntroller, middleware)
+			router, err := routers.NewRouter(&cfg, conntroller, middleware, nil, nil)
 			assert.NoError(t, err)
 
 			server := httptest.NewServer(router)
@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+)
+
+// Strategies of import for links which conflict with existing ones.
+const (
+	// StrategySkip keep existing link.
+	StrategySkip = "skip"
+	// StrategyOverwrite replace existing link with imported one.
+	StrategyOverwrite = "overwrite"
+	// StrategyFail stop import.
+	StrategyFail = "fail"
+)
+
+// Default values of ImportConfig.
+const (
+	DefaultBatchSize = 500
+	DefaultStrategy  = StrategySkip
+)
+
+// Amount of exported records between progress reports.
+const exportProgressInterval = 1000
+
+// ErrConflict is an error of imported link which conflicts with existing one.
+var ErrConflict = errors.New("imported link conflicts with existing one")
+
+// ImportConfig is a set of parameters of import.
+// Zero values are replaced with default ones.
+type ImportConfig struct {
+	Format    string
+	Strategy  string
+	BatchSize int
+	DryRun    bool
+}
+
+// ImportReport is a summary of import.
+type ImportReport struct {
+	Read        int
+	Created     int
+	Overwritten int
+	Skipped     int
+	Unchanged   int
+}
+
+// links is a state of links which are affected by batch of import.
+type links struct {
+	byShortURL    map[string]repository.Record
+	byOriginalURL map[string]string
+}
+
+// Export write all links of repository in provided format and report progress.
+// Return amount of exported links.
+func Export(
+	ctx context.Context,
+	dumper repository.Dumper,
+	w io.Writer,
+	format string,
+	progress io.Writer,
+) (int, error) {
+	writer, err := newRecordWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = dumper.Export(ctx, func(record repository.Record) error {
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+		count++
+		if count%exportProgressInterval == 0 {
+			_, err = fmt.Fprintf(progress, "exported %d links\n", count)
+			if err != nil {
+				return fmt.Errorf("can not report progress: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("can not export links: %w", err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// Import read links in provided format and store them in repository by batches.
+// Import is idempotent: links which are equal to existing ones regardless of creation time
+// are left unchanged. Links which have the same short URL or original URL as existing ones
+// are resolved according to strategy. Batches which were stored before failure remain stored.
+// In dry run mode nothing is stored and report shows what would be done.
+func Import(
+	ctx context.Context,
+	dumper repository.Dumper,
+	r io.Reader,
+	cfg ImportConfig,
+	progress io.Writer,
+) (ImportReport, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = DefaultStrategy
+	case StrategySkip, StrategyOverwrite, StrategyFail:
+	default:
+		return ImportReport{}, fmt.Errorf("unknown strategy: %s", cfg.Strategy)
+	}
+
+	reader, err := newRecordReader(r, cfg.Format)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	batch := make([]repository.Record, 0, cfg.BatchSize)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("can not read record %d: %w", report.Read+1, err)
+		}
+		report.Read++
+
+		err = validate(record)
+		if err != nil {
+			return report, fmt.Errorf("can not import record %d: %w", report.Read, err)
+		}
+
+		batch = append(batch, record)
+		if len(batch) < cfg.BatchSize {
+			continue
+		}
+		err = importBatch(ctx, dumper, batch, cfg, &report, progress)
+		if err != nil {
+			return report, err
+		}
+		batch = batch[:0]
+	}
+
+	if len(batch) != 0 {
+		err = importBatch(ctx, dumper, batch, cfg, &report, progress)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// importBatch resolve conflicts of batch with existing links, store accepted links and report progress.
+func importBatch(
+	ctx context.Context,
+	dumper repository.Dumper,
+	batch []repository.Record,
+	cfg ImportConfig,
+	report *ImportReport,
+	progress io.Writer,
+) error {
+	existing, err := dumper.Lookup(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("can not lookup existing links: %w", err)
+	}
+	state := newLinks(existing)
+
+	accepted := make([]repository.Record, 0, len(batch))
+	var created, overwritten, skipped, unchanged int
+	for i := range batch {
+		current, shortURLExists := state.byShortURL[batch[i].ShortURL]
+		_, originalURLExists := state.byOriginalURL[batch[i].OriginalURL]
+		switch {
+		case !shortURLExists && !originalURLExists:
+			created++
+		case shortURLExists && equal(current, batch[i]):
+			unchanged++
+			continue
+		case cfg.Strategy == StrategySkip:
+			skipped++
+			continue
+		case cfg.Strategy == StrategyFail:
+			return fmt.Errorf("%w: short url %s, original url %s",
+				ErrConflict, batch[i].ShortURL, batch[i].OriginalURL)
+		default:
+			overwritten++
+		}
+
+		state.replace(batch[i])
+		accepted = append(accepted, batch[i])
+	}
+
+	if !cfg.DryRun && len(accepted) != 0 {
+		err = dumper.Import(ctx, accepted)
+		if err != nil {
+			return fmt.Errorf("can not import links: %w", err)
+		}
+	}
+
+	report.Created += created
+	report.Overwritten += overwritten
+	report.Skipped += skipped
+	report.Unchanged += unchanged
+
+	_, err = fmt.Fprintf(progress, "processed %d records: %d created, %d overwritten, %d skipped, %d unchanged\n",
+		report.Read, report.Created, report.Overwritten, report.Skipped, report.Unchanged)
+	if err != nil {
+		return fmt.Errorf("can not report progress: %w", err)
+	}
+	return nil
+}
+
+// validate check that record can be imported.
+func validate(record repository.Record) error {
+	if record.ShortURL == "" {
+		return repository.ErrInvalidShortURL
+	}
+	_, err := url.ParseRequestURI(record.OriginalURL)
+	if err != nil {
+		return repository.ErrInvalidURL
+	}
+	return nil
+}
+
+// equal check if records describe the same link regardless of creation time,
+// because precision of creation time depends on repository.
+func equal(a repository.Record, b repository.Record) bool {
+	return a.ShortURL == b.ShortURL &&
+		a.OriginalURL == b.OriginalURL &&
+		a.UserID == b.UserID &&
+		a.Deleted == b.Deleted
+}
+
+// newLinks create state of links from existing ones.
+func newLinks(records []repository.Record) *links {
+	state := &links{
+		byShortURL:    make(map[string]repository.Record, len(records)),
+		byOriginalURL: make(map[string]string, len(records)),
+	}
+	for i := range records {
+		state.replace(records[i])
+	}
+	return state
+}
+
+// replace store record instead of links with the same short URL or original URL
+// the same way as repository does.
+func (l *links) replace(record repository.Record) {
+	if shortURL, ok := l.byOriginalURL[record.OriginalURL]; ok {
+		delete(l.byShortURL, shortURL)
+	}
+	if current, ok := l.byShortURL[record.ShortURL]; ok {
+		delete(l.byOriginalURL, current.OriginalURL)
+	}
+	l.byShortURL[record.ShortURL] = record
+	l.byOriginalURL[record.OriginalURL] = record.ShortURL
+}
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// export return all links of repository.
+func export(t *testing.T, dumper repository.Dumper) []repository.Record {
+	t.Helper()
+	var records []repository.Record
+	err := dumper.Export(context.Background(), func(record repository.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	assert.NoError(t, err)
+	return records
+}
+
+func TestExportImport(t *testing.T) {
+	userID := uuid.New()
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 6, time.UTC)
+	records := []repository.Record{
+		{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID},
+		{
+			CreatedAt:   createdAt.Add(time.Second),
+			ShortURL:    "def456",
+			OriginalURL: "https://another.com?a=1,b",
+			Deleted:     true,
+		},
+		{ShortURL: "ghi789", OriginalURL: "https://third.com", UserID: userID},
+	}
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			source := repository.NewLinks()
+			err := source.Import(context.Background(), records)
+			assert.NoError(t, err)
+
+			data := &bytes.Buffer{}
+			count, err := Export(context.Background(), source, data, format, io.Discard)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+
+			target := repository.NewShardedLinks()
+			progress := &bytes.Buffer{}
+			report, err := Import(context.Background(), target, bytes.NewReader(data.Bytes()), ImportConfig{
+				Format:    format,
+				BatchSize: 2,
+			}, progress)
+			assert.NoError(t, err)
+			assert.Equal(t, ImportReport{Read: 3, Created: 3}, report)
+			assert.Equal(t, "processed 2 records: 2 created, 0 overwritten, 0 skipped, 0 unchanged\n"+
+				"processed 3 records: 3 created, 0 overwritten, 0 skipped, 0 unchanged\n", progress.String())
+			assert.Equal(t, export(t, source), export(t, target))
+
+			report, err = Import(context.Background(), target, bytes.NewReader(data.Bytes()), ImportConfig{
+				Format:   format,
+				Strategy: StrategyFail,
+			}, io.Discard)
+			assert.NoError(t, err)
+			assert.Equal(t, ImportReport{Read: 3, Unchanged: 3}, report)
+		})
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	userID := uuid.New()
+	input := `{"short_url":"abc123","original_url":"https://changed.com","user_id":"` + userID.String() + `"}
+{"short_url":"def456","original_url":"https://another.com","user_id":"` + userID.String() + `"}
+{"short_url":"ghi789","original_url":"https://third.com","user_id":"` + userID.String() + `"}
+`
+
+	tests := []struct {
+		name     string
+		strategy string
+		err      error
+		report   ImportReport
+		dryRun   bool
+		changed  bool
+	}{
+		{
+			name:     "skip",
+			strategy: StrategySkip,
+			report:   ImportReport{Read: 3, Created: 1, Skipped: 1, Unchanged: 1},
+		},
+		{
+			name:     "overwrite",
+			strategy: StrategyOverwrite,
+			report:   ImportReport{Read: 3, Created: 1, Overwritten: 1, Unchanged: 1},
+			changed:  true,
+		},
+		{
+			name:     "overwrite in dry run",
+			strategy: StrategyOverwrite,
+			report:   ImportReport{Read: 3, Created: 1, Overwritten: 1, Unchanged: 1},
+			dryRun:   true,
+		},
+		{
+			name:     "fail",
+			strategy: StrategyFail,
+			err:      ErrConflict,
+			report:   ImportReport{Read: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := repository.NewLinks()
+			_, err := links.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+			assert.NoError(t, err)
+			_, err = links.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+			assert.NoError(t, err)
+
+			report, err := Import(context.Background(), links, strings.NewReader(input), ImportConfig{
+				Format:   FormatJSONL,
+				Strategy: tt.strategy,
+				DryRun:   tt.dryRun,
+			}, io.Discard)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.report, report)
+
+			originalURL, err := links.GetOriginalURL(context.Background(), "abc123")
+			assert.NoError(t, err)
+			if tt.changed {
+				assert.Equal(t, "https://changed.com", *originalURL)
+			} else {
+				assert.Equal(t, "https://example.com", *originalURL)
+			}
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		cfg   ImportConfig
+		err   string
+	}{
+		{
+			name: "unknown format",
+			cfg:  ImportConfig{Format: "xml"},
+			err:  "unknown format: xml",
+		},
+		{
+			name: "unknown strategy",
+			cfg:  ImportConfig{Format: FormatJSONL, Strategy: "merge"},
+			err:  "unknown strategy: merge",
+		},
+		{
+			name:  "invalid original url",
+			input: `{"short_url":"abc123","original_url":"invalid"}`,
+			cfg:   ImportConfig{Format: FormatJSONL},
+			err:   "can not import record 1: provided string is not valid url",
+		},
+		{
+			name:  "invalid json",
+			input: `{"short_url":"abc123"`,
+			cfg:   ImportConfig{Format: FormatJSONL},
+			err:   "can not read record 1",
+		},
+		{
+			name:  "unexpected csv header",
+			input: "a,b,c,d,e\n",
+			cfg:   ImportConfig{Format: FormatCSV},
+			err:   "unexpected header",
+		},
+		{
+			name:  "invalid csv user id",
+			input: "short_url,original_url,user_id,deleted,created_at\nabc123,https://example.com,user,false,\n",
+			cfg:   ImportConfig{Format: FormatCSV},
+			err:   "can not parse user id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := repository.NewLinks()
+			_, err := Import(context.Background(), links, strings.NewReader(tt.input), tt.cfg, io.Discard)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+)
+
+// Formats of exported data.
+const (
+	// FormatJSONL is a format with one JSON object per line.
+	FormatJSONL = "jsonl"
+	// FormatCSV is a format of comma separated values with header.
+	FormatCSV = "csv"
+)
+
+// Columns of CSV format.
+var csvHeader = []string{"short_url", "original_url", "user_id", "deleted", "created_at"}
+
+// recordWriter is an interface of encoders of records.
+type recordWriter interface {
+	Write(record repository.Record) error
+	Flush() error
+}
+
+// recordReader is an interface of decoders of records.
+// Read return io.EOF when there are no more records.
+type recordReader interface {
+	Read() (repository.Record, error)
+}
+
+// jsonlWriter encode records as JSON lines.
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+// jsonlReader decode records from JSON lines.
+type jsonlReader struct {
+	decoder *json.Decoder
+}
+
+// csvWriter encode records as CSV.
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+// csvReader decode records from CSV.
+type csvReader struct {
+	reader *csv.Reader
+	header bool
+}
+
+// newRecordWriter create encoder of records of provided format.
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// newRecordReader create decoder of records of provided format.
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case FormatJSONL:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		return &jsonlReader{decoder: decoder}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = len(csvHeader)
+		return &csvReader{reader: reader}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// Write encode record as JSON line.
+func (w *jsonlWriter) Write(record repository.Record) error {
+	err := w.encoder.Encode(record)
+	if err != nil {
+		return fmt.Errorf("can not encode record: %w", err)
+	}
+	return nil
+}
+
+// Flush do nothing because JSON lines are written immediately.
+func (w *jsonlWriter) Flush() error {
+	return nil
+}
+
+// Read decode next JSON line.
+func (r *jsonlReader) Read() (repository.Record, error) {
+	var record repository.Record
+	err := r.decoder.Decode(&record)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return repository.Record{}, io.EOF
+		}
+		return repository.Record{}, fmt.Errorf("can not decode record: %w", err)
+	}
+	return record, nil
+}
+
+// Write encode record as CSV row and write header before the first one.
+func (w *csvWriter) Write(record repository.Record) error {
+	if !w.header {
+		err := w.writer.Write(csvHeader)
+		if err != nil {
+			return fmt.Errorf("can not write header: %w", err)
+		}
+		w.header = true
+	}
+
+	var createdAt string
+	if !record.CreatedAt.IsZero() {
+		createdAt = record.CreatedAt.Format(time.RFC3339Nano)
+	}
+	err := w.writer.Write([]string{
+		record.ShortURL,
+		record.OriginalURL,
+		record.UserID.String(),
+		strconv.FormatBool(record.Deleted),
+		createdAt,
+	})
+	if err != nil {
+		return fmt.Errorf("can not write record: %w", err)
+	}
+	return nil
+}
+
+// Flush write buffered rows and header if there were no rows.
+func (w *csvWriter) Flush() error {
+	if !w.header {
+		err := w.writer.Write(csvHeader)
+		if err != nil {
+			return fmt.Errorf("can not write header: %w", err)
+		}
+		w.header = true
+	}
+	w.writer.Flush()
+	err := w.writer.Error()
+	if err != nil {
+		return fmt.Errorf("can not flush records: %w", err)
+	}
+	return nil
+}
+
+// Read decode next CSV row and check header before the first one.
+func (r *csvReader) Read() (repository.Record, error) {
+	if !r.header {
+		header, err := r.reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return repository.Record{}, io.EOF
+			}
+			return repository.Record{}, fmt.Errorf("can not read header: %w", err)
+		}
+		if !slices.Equal(header, csvHeader) {
+			return repository.Record{}, fmt.Errorf("unexpected header: %v", header)
+		}
+		r.header = true
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return repository.Record{}, io.EOF
+		}
+		return repository.Record{}, fmt.Errorf("can not read record: %w", err)
+	}
+
+	record := repository.Record{
+		ShortURL:    row[0],
+		OriginalURL: row[1],
+	}
+	record.UserID, err = uuid.Parse(row[2])
+	if err != nil {
+		return repository.Record{}, fmt.Errorf("can not parse user id: %w", err)
+	}
+	record.Deleted, err = strconv.ParseBool(row[3])
+	if err != nil {
+		return repository.Record{}, fmt.Errorf("can not parse deleted flag: %w", err)
+	}
+	if row[4] != "" {
+		record.CreatedAt, err = time.Parse(time.RFC3339Nano, row[4])
+		if err != nil {
+			return repository.Record{}, fmt.Errorf("can not parse creation time: %w", err)
+		}
+	}
+	return record, nil
+}
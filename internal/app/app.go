@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"expvar"
 	"fmt"
 	"io/fs"
 	"log"
@@ -11,17 +12,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/RexArseny/url_shortener/internal/app/controllers"
+	"github.com/RexArseny/url_shortener/internal/app/idempotency"
+	"github.com/RexArseny/url_shortener/internal/app/lifecycle"
 	"github.com/RexArseny/url_shortener/internal/app/logger"
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
 	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/oidc"
+	"github.com/RexArseny/url_shortener/internal/app/ratelimit"
 	"github.com/RexArseny/url_shortener/internal/app/repository"
 	"github.com/RexArseny/url_shortener/internal/app/routers"
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -58,21 +66,19 @@ func NewServer() error {
 		return fmt.Errorf("can not init config: %w", err)
 	}
 
-	urlRepository, repositoryClose, err := repository.NewRepository(
-		ctx,
-		mainLogger.Named("repository"),
-		cfg.FileStoragePath,
-		cfg.DatabaseDSN,
-	)
+	manager := lifecycle.NewManager(mainLogger.Named("lifecycle"), cfg.ShutdownTimeout.Duration)
+
+	urlRepository, repositoryClose, err := repository.NewRepository(ctx, mainLogger.Named("repository"), cfg)
 	if err != nil {
 		return fmt.Errorf("can not init repository: %w", err)
 	}
 	defer func() {
-		if repositoryClose != nil {
-			err = repositoryClose()
-			if err != nil {
-				mainLogger.Error("Can not close repository", zap.Error(err))
-			}
+		if manager.Draining() || repositoryClose == nil {
+			return
+		}
+		err := repositoryClose()
+		if err != nil {
+			mainLogger.Error("Can not close repository", zap.Error(err))
 		}
 	}()
 
@@ -84,32 +90,128 @@ func NewServer() error {
 		}
 	}
 
-	interactor := usecases.NewInteractor(ctx, mainLogger.Named("interactor"), cfg.BasicPath, urlRepository)
-	controller := controllers.NewController(mainLogger.Named("controller"), interactor, trustedSubnet)
-	middleware, err := middlewares.NewMiddleware(
-		cfg.PublicKeyPath,
-		cfg.PrivateKeyPath,
-		mainLogger.Named("middleware"),
+	interactor := usecases.NewInteractor(
+		ctx,
+		mainLogger.Named("interactor"),
+		cfg.BasicPath,
+		urlRepository,
+		usecases.DeleterConfig{
+			FlushInterval: cfg.DeleteFlushInterval.Duration,
+			BatchSize:     cfg.DeleteBatchSize,
+			Concurrency:   cfg.DeleteConcurrency,
+		},
 	)
+	controller := controllers.NewController(mainLogger.Named("controller"), interactor, trustedSubnet)
+	var middleware *middlewares.Middleware
+	if cfg.JWTKeysDir != "" {
+		middleware, err = middlewares.NewMiddlewareWithKeysDir(cfg.JWTKeysDir, mainLogger.Named("middleware"))
+	} else {
+		middleware, err = middlewares.NewMiddleware(
+			cfg.PublicKeyPath,
+			cfg.PrivateKeyPath,
+			mainLogger.Named("middleware"),
+		)
+	}
 	if err != nil {
 		return fmt.Errorf("can not init middleware: %w", err)
 	}
-	router, err := routers.NewRouter(cfg, controller, middleware)
+	sameSite, err := middlewares.ParseSameSite(cfg.CookieSameSite)
+	if err != nil {
+		return fmt.Errorf("can not parse cookie samesite: %w", err)
+	}
+	err = middleware.UseCookies(middlewares.CookieConfig{
+		Domain:   cfg.CookieDomain,
+		Path:     cfg.CookiePath,
+		MaxAge:   cfg.CookieMaxAge.Duration,
+		SameSite: sameSite,
+		Secure:   cfg.EnableHTTPS || cfg.CookieSecure,
+		HTTPOnly: cfg.CookieHTTPOnly,
+	})
+	if err != nil {
+		return fmt.Errorf("can not use cookies: %w", err)
+	}
+	middleware.TrustOrigins(cfg.CSRFTrustedOrigins)
+	err = middleware.TrustProxies(cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("can not use trusted proxies: %w", err)
+	}
+	go reloadKeys(ctx, mainLogger, middleware)
+	middleware.UseAPIKeys(&interactor)
+	admins, err := parseUserIDs(cfg.AdminUsers)
+	if err != nil {
+		return fmt.Errorf("can not parse admin users: %w", err)
+	}
+	middleware.UseAdmins(admins)
+	if cfg.RefreshTokenTTL.Duration > 0 {
+		middleware.UseRefreshTokens(&interactor, cfg.RefreshTokenTTL.Duration)
+	}
+	if cfg.OIDCIssuer != "" {
+		provider, err := oidc.NewProvider(ctx, oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("can not init oidc provider: %w", err)
+		}
+		middleware.UseOIDC(provider, &interactor)
+	}
+	idempotencyStore, err := idempotency.NewStore(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("can not init idempotency store: %w", err)
+	}
+	defer func() {
+		if manager.Draining() || idempotencyStore == nil {
+			return
+		}
+		err := idempotencyStore.Close()
+		if err != nil {
+			mainLogger.Error("Can not close idempotency store", zap.Error(err))
+		}
+	}()
+	interceptors := []grpc.UnaryServerInterceptor{middleware.GRPCClientIP, middleware.GRPCLogger, middleware.GRPCAuth}
+	limiter, err := ratelimit.NewLimiter(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("can not init rate limiter: %w", err)
+	}
+	defer func() {
+		if manager.Draining() || limiter == nil {
+			return
+		}
+		err := limiter.Close()
+		if err != nil {
+			mainLogger.Error("Can not close rate limiter", zap.Error(err))
+		}
+	}()
+	var rateLimitMiddleware *middlewares.RateLimit
+	if limiter != nil {
+		rateLimitMiddleware = middlewares.NewRateLimit(mainLogger.Named("ratelimit"), limiter, ratelimit.Limits(cfg))
+		interceptors = append(interceptors, rateLimitMiddleware.GRPCInterceptor)
+	}
+	var idempotencyMiddleware *middlewares.Idempotency
+	if idempotencyStore != nil {
+		idempotencyMiddleware = middlewares.NewIdempotency(
+			mainLogger.Named("idempotency"),
+			idempotencyStore,
+			cfg.IdempotencyTTL.Duration,
+		)
+		interceptors = append(interceptors, idempotencyMiddleware.GRPCInterceptor)
+	}
+	router, err := routers.NewRouter(cfg, controller, middleware, idempotencyMiddleware, rateLimitMiddleware)
 	if err != nil {
 		return fmt.Errorf("can not init router: %w", err)
 	}
 
 	pprof.Register(router)
+	router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
 	server := &http.Server{
 		Addr:    cfg.ServerAddress,
 		Handler: router,
 	}
 
-	grpcServerOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
-		middleware.GRPCLogger,
-		middleware.GRPCAuth,
-	)}
+	grpcServerOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(interceptors...)}
 
 	if cfg.EnableHTTPS && cfg.CertificatePath != "" && cfg.CertificateKeyPath != "" {
 		certBytes, err := os.ReadFile(cfg.CertificatePath)
@@ -142,47 +244,126 @@ func NewServer() error {
 
 	grpcController := controllers.NewGRPCController(mainLogger.Named("grpccontroller"), interactor, trustedSubnet)
 
+	grpcAdminController := controllers.NewGRPCAdminController(mainLogger.Named("grpcadmincontroller"), interactor)
+
 	grpcServer := grpc.NewServer(grpcServerOpts...)
 
 	pb.RegisterURLShortenerServer(grpcServer, &grpcController)
+	pb.RegisterAdminServer(grpcServer, &grpcAdminController)
+
+	manager.Add("http server", server.Shutdown)
+	manager.Add("grpc server", func(ctx context.Context) error {
+		return stopGRPCServer(ctx, grpcServer)
+	})
+	manager.Add("interactor", interactor.Shutdown)
+	manager.Add("idempotency store", func(context.Context) error {
+		if idempotencyStore == nil {
+			return nil
+		}
+		return idempotencyStore.Close()
+	})
+	manager.Add("rate limiter", func(context.Context) error {
+		if limiter == nil {
+			return nil
+		}
+		return limiter.Close()
+	})
+	manager.Add("repository", func(context.Context) error {
+		if repositoryClose == nil {
+			return nil
+		}
+		return repositoryClose()
+	})
 
 	fmt.Printf("Build version: %s\n", buildVersion)
 	fmt.Printf("Build date: %s\n", buildDate)
 	fmt.Printf("Build commit: %s\n", buildCommit)
 
-	go func() {
-		<-ctx.Done()
-		err = server.Shutdown(ctx)
-		if err != nil {
-			mainLogger.Error("Can not shutdown server", zap.Error(err))
-		}
-		grpcServer.GracefulStop()
-	}()
+	serveErrors := make(chan error, 2)
 
 	go func() {
 		err := grpcServer.Serve(listener)
 		if err != nil {
-			mainLogger.Error("Can not serve grpc", zap.Error(err))
+			serveErrors <- fmt.Errorf("can not serve grpc: %w", err)
 		}
 	}()
 
-	if cfg.EnableHTTPS {
-		err = server.ListenAndServeTLS("", "")
+	go func() {
+		var err error
+		if cfg.EnableHTTPS {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return fmt.Errorf("can not listen and serve: %w", err)
+			serveErrors <- fmt.Errorf("can not listen and serve: %w", err)
 		}
+	}()
 
-		fmt.Println("Server shutdown gracefully")
-
-		return nil
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-serveErrors:
 	}
 
-	err = server.ListenAndServe()
-	if err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("can not listen and serve: %w", err)
+	err = manager.Shutdown()
+	if err != nil {
+		return errors.Join(serveErr, fmt.Errorf("can not shutdown gracefully: %w", err))
+	}
+	if serveErr != nil {
+		return serveErr
 	}
 
 	fmt.Println("Server shutdown gracefully")
 
 	return nil
 }
+
+// parseUserIDs parse ids of users from config.
+func parseUserIDs(items []string) ([]uuid.UUID, error) {
+	userIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		userID, err := uuid.Parse(strings.TrimSpace(item))
+		if err != nil {
+			return nil, fmt.Errorf("can not parse user id %q: %w", item, err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// reloadKeys reload JWT keys of middleware on SIGHUP until context is done.
+func reloadKeys(ctx context.Context, logger *zap.Logger, middleware *middlewares.Middleware) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			err := middleware.ReloadKeys()
+			if err != nil {
+				logger.Error("Can not reload keys", zap.Error(err))
+			}
+		}
+	}
+}
+
+// stopGRPCServer stop gRPC server gracefully and stop it forcibly if context is done.
+func stopGRPCServer(ctx context.Context, grpcServer *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		grpcServer.Stop()
+		return fmt.Errorf("can not stop grpc server gracefully: %w", ctx.Err())
+	}
+}
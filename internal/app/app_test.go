@@ -1,17 +1,30 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
+	"flag"
 	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestNewServer(t *testing.T) {
@@ -49,6 +62,8 @@ func TestNewServer(t *testing.T) {
 		t.Setenv("CERTIFICATE_PATH", "cert.pem")
 		t.Setenv("CERTIFICATE_KEY_PATH", "key.pem")
 		t.Setenv("TRUSTED_SUBNET", "127.0.0.0/24")
+		t.Setenv("FILE_STORAGE_PATH", filepath.Join(t.TempDir(), "shorturls.txt"))
+		t.Setenv("AUDIT_STORAGE_PATH", filepath.Join(t.TempDir(), "audit.jsonl"))
 
 		defer func() {
 			err = os.Remove("cert.pem")
@@ -67,3 +82,97 @@ func TestNewServer(t *testing.T) {
 		t.SkipNow()
 	})
 }
+
+//nolint:reassign // reassign for tests
+func TestNewServerShutdown(t *testing.T) {
+	t.Run("queued deletions are not lost on SIGTERM", func(t *testing.T) {
+		file, err := os.CreateTemp(t.TempDir(), "shutdown")
+		assert.NoError(t, err)
+		err = file.Close()
+		assert.NoError(t, err)
+		defer func() {
+			err = os.Remove(file.Name())
+			assert.NoError(t, err)
+		}()
+
+		oldArgs := os.Args
+		oldCommandLine := flag.CommandLine
+		defer func() {
+			os.Args = oldArgs
+			flag.CommandLine = oldCommandLine
+		}()
+		os.Args = []string{"cmd"}
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+		t.Setenv("SERVER_ADDRESS", "localhost:8181")
+		t.Setenv("BASE_URL", "http://localhost:8181")
+		t.Setenv("GRPC_SERVER_ADDRESS", "localhost:9191")
+		t.Setenv("FILE_STORAGE_PATH", file.Name())
+		t.Setenv("AUDIT_STORAGE_PATH", filepath.Join(t.TempDir(), "audit.jsonl"))
+		t.Setenv("PUBLIC_KEY_PATH", "../../public.pem")
+		t.Setenv("PRIVATE_KEY_PATH", "../../private.pem")
+		t.Setenv("ENABLE_HTTPS", "false")
+
+		serverErr := make(chan error)
+		go func() {
+			serverErr <- NewServer()
+		}()
+
+		jar, err := cookiejar.New(nil)
+		assert.NoError(t, err)
+		client := &http.Client{Jar: jar}
+
+		assert.Eventually(t, func() bool {
+			resp, err := client.Get("http://localhost:8181/ping")
+			if err != nil {
+				return false
+			}
+			return resp.Body.Close() == nil && resp.StatusCode == http.StatusOK
+		}, time.Second*5, time.Millisecond*10)
+
+		request, err := json.Marshal(models.ShortenRequest{URL: "https://ya.ru"})
+		assert.NoError(t, err)
+		resp, err := client.Post("http://localhost:8181/api/shorten", "application/json", bytes.NewReader(request))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var response models.ShortenResponse
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		assert.NoError(t, err)
+		err = resp.Body.Close()
+		assert.NoError(t, err)
+
+		shortURL, err := url.ParseRequestURI(response.Result)
+		assert.NoError(t, err)
+
+		request, err = json.Marshal([]string{path.Base(shortURL.Path)})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodDelete, "http://localhost:8181/api/user/urls", bytes.NewReader(request))
+		assert.NoError(t, err)
+		resp, err = client.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		err = resp.Body.Close()
+		assert.NoError(t, err)
+
+		err = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		assert.NoError(t, err)
+
+		select {
+		case err = <-serverErr:
+			assert.NoError(t, err)
+		case <-time.After(time.Second * 10):
+			t.Fatal("server was not stopped")
+		}
+
+		linksWithFile, err := repository.NewLinksWithFile(zap.NewNop(), file.Name(), repository.FileConfig{})
+		assert.NoError(t, err)
+		defer func() {
+			err = linksWithFile.Close()
+			assert.NoError(t, err)
+		}()
+
+		originalURL, err := linksWithFile.GetOriginalURL(context.Background(), path.Base(shortURL.Path))
+		assert.ErrorIs(t, err, repository.ErrURLIsDeleted)
+		assert.Nil(t, originalURL)
+	})
+}
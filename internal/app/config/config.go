@@ -6,37 +6,142 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
+	"time"
 
 	env "github.com/caarlos0/env/v11"
 )
 
 // Default Config values.
 const (
-	DefaultServerAddress      = "localhost:8080"
-	DefaultBasicPath          = "http://localhost:8080"
-	DefaultFileStoragePath    = "shorturls.txt"
-	DefaultPublicKeyPath      = "public.pem"
-	DefaultPrivateKeyPath     = "private.pem"
-	DefaultEnableHTTPS        = false
-	DefaultCertificatePath    = "cert.pem"
-	DefaultCertificateKeyPath = "key.pem"
-	DefaultGRPCServerAddress  = "localhost:9000"
+	DefaultServerAddress        = "localhost:8080"
+	DefaultBasicPath            = "http://localhost:8080"
+	DefaultFileStoragePath      = "shorturls.txt"
+	DefaultAuditStoragePath     = "audit.jsonl"
+	DefaultPublicKeyPath        = "public.pem"
+	DefaultPrivateKeyPath       = "private.pem"
+	DefaultEnableHTTPS          = false
+	DefaultCertificatePath      = "cert.pem"
+	DefaultCertificateKeyPath   = "key.pem"
+	DefaultGRPCServerAddress    = "localhost:9000"
+	DefaultShutdownTimeout      = 10 * time.Second
+	DefaultDeleteBatchSize      = 100
+	DefaultDeleteConcurrency    = 4
+	DefaultDeleteFlushInterval  = 100 * time.Millisecond
+	DefaultFileFsyncPolicy      = "interval"
+	DefaultFileFsyncInterval    = time.Second
+	DefaultFileCompactInterval  = time.Minute
+	DefaultFileRecovery         = false
+	DefaultAutoMigrate          = true
+	DefaultReplicaCheckInterval = 5 * time.Second
+	DefaultDBRetryAttempts      = 3
+	DefaultDBRetryBaseDelay     = 50 * time.Millisecond
+	DefaultDBRetryMaxDelay      = time.Second
+	DefaultBreakerThreshold     = 5
+	DefaultBreakerOpenTimeout   = 10 * time.Second
+	DefaultBreakerCacheSize     = 10000
+	DefaultIdempotencyTTL       = 24 * time.Hour
+	DefaultRefreshTokenTTL      = 30 * 24 * time.Hour
+	DefaultCookiePath           = "/"
+	DefaultCookieSameSite       = "lax"
+	DefaultCookieMaxAge         = 15 * time.Minute
+	DefaultCookieSecure         = false
+	DefaultCookieHTTPOnly       = true
 )
 
 // Config is a set of service configurable variables.
 type Config struct {
-	ServerAddress      string `env:"SERVER_ADDRESS" json:"server_address"`
-	BasicPath          string `env:"BASE_URL" json:"basic_url"`
-	FileStoragePath    string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	DatabaseDSN        string `env:"DATABASE_DSN" json:"database_dsn"`
-	PublicKeyPath      string `env:"PUBLIC_KEY_PATH" json:"public_key_path"`
-	PrivateKeyPath     string `env:"PRIVATE_KEY_PATH" json:"private_key_path"`
-	Config             string `env:"CONFIG" json:"config"`
-	TrustedSubnet      string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
-	GRPCServerAddress  string `env:"GRPC_SERVER_ADDRESS" json:"grpc_server_address"`
-	CertificatePath    string `env:"CERTIFICATE_PATH" json:"certificate_path"`
-	CertificateKeyPath string `env:"CERTIFICATE_KEY_PATH" json:"certificate_key_path"`
-	EnableHTTPS        bool   `env:"ENABLE_HTTPS" json:"enable_https"`
+	ServerAddress          string   `env:"SERVER_ADDRESS" json:"server_address"`
+	BasicPath              string   `env:"BASE_URL" json:"basic_url"`
+	FileStoragePath        string   `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
+	AuditStoragePath       string   `env:"AUDIT_STORAGE_PATH" json:"audit_storage_path"`
+	DatabaseDSN            string   `env:"DATABASE_DSN" json:"database_dsn"`
+	BoltStoragePath        string   `env:"BOLT_STORAGE_PATH" json:"bolt_storage_path"`
+	IdempotencyPath        string   `env:"IDEMPOTENCY_STORAGE_PATH" json:"idempotency_storage_path"`
+	PublicKeyPath          string   `env:"PUBLIC_KEY_PATH" json:"public_key_path"`
+	PrivateKeyPath         string   `env:"PRIVATE_KEY_PATH" json:"private_key_path"`
+	JWTKeysDir             string   `env:"JWT_KEYS_DIR" json:"jwt_keys_dir"`
+	Config                 string   `env:"CONFIG" json:"config"`
+	TrustedSubnet          string   `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	GRPCServerAddress      string   `env:"GRPC_SERVER_ADDRESS" json:"grpc_server_address"`
+	CertificatePath        string   `env:"CERTIFICATE_PATH" json:"certificate_path"`
+	CertificateKeyPath     string   `env:"CERTIFICATE_KEY_PATH" json:"certificate_key_path"`
+	FileFsyncPolicy        string   `env:"FILE_FSYNC_POLICY" json:"file_fsync_policy"`
+	CookieDomain           string   `env:"COOKIE_DOMAIN" json:"cookie_domain"`
+	CookiePath             string   `env:"COOKIE_PATH" json:"cookie_path"`
+	CookieSameSite         string   `env:"COOKIE_SAMESITE" json:"cookie_samesite"`
+	OIDCIssuer             string   `env:"OIDC_ISSUER" json:"oidc_issuer"`
+	OIDCClientID           string   `env:"OIDC_CLIENT_ID" json:"oidc_client_id"`
+	OIDCClientSecret       string   `env:"OIDC_CLIENT_SECRET" json:"oidc_client_secret"`
+	OIDCRedirectURL        string   `env:"OIDC_REDIRECT_URL" json:"oidc_redirect_url"`
+	ShutdownTimeout        Duration `env:"SHUTDOWN_TIMEOUT" json:"shutdown_timeout"`
+	DeleteFlushInterval    Duration `env:"DELETE_FLUSH_INTERVAL" json:"delete_flush_interval"`
+	FileFsyncInterval      Duration `env:"FILE_FSYNC_INTERVAL" json:"file_fsync_interval"`
+	FileCompactInterval    Duration `env:"FILE_COMPACT_INTERVAL" json:"file_compact_interval"`
+	ReplicaCheckInterval   Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL" json:"database_replica_check_interval"`
+	ReadYourWritesWindow   Duration `env:"READ_YOUR_WRITES_WINDOW" json:"read_your_writes_window"`
+	DBRetryBaseDelay       Duration `env:"DATABASE_RETRY_BASE_DELAY" json:"database_retry_base_delay"`
+	DBRetryMaxDelay        Duration `env:"DATABASE_RETRY_MAX_DELAY" json:"database_retry_max_delay"`
+	BreakerOpenTimeout     Duration `env:"CIRCUIT_BREAKER_OPEN_TIMEOUT" json:"circuit_breaker_open_timeout"`
+	IdempotencyTTL         Duration `env:"IDEMPOTENCY_TTL" json:"idempotency_ttl"`
+	RefreshTokenTTL        Duration `env:"REFRESH_TOKEN_TTL" json:"refresh_token_ttl"`
+	CookieMaxAge           Duration `env:"COOKIE_MAX_AGE" json:"cookie_max_age"`
+	DatabaseReplicaDSNs    []string `env:"DATABASE_REPLICA_DSNS" json:"database_replica_dsns"`
+	CSRFTrustedOrigins     []string `env:"CSRF_TRUSTED_ORIGINS" json:"csrf_trusted_origins"`
+	AdminUsers             []string `env:"ADMIN_USERS" json:"admin_users"`
+	TrustedProxies         []string `env:"TRUSTED_PROXIES" json:"trusted_proxies"`
+	RateLimitCreate        float64  `env:"RATE_LIMIT_CREATE" json:"rate_limit_create"`
+	RateLimitBatch         float64  `env:"RATE_LIMIT_BATCH" json:"rate_limit_batch"`
+	RateLimitRedirect      float64  `env:"RATE_LIMIT_REDIRECT" json:"rate_limit_redirect"`
+	RateLimitAdmin         float64  `env:"RATE_LIMIT_ADMIN" json:"rate_limit_admin"`
+	DeleteBatchSize        int      `env:"DELETE_BATCH_SIZE" json:"delete_batch_size"`
+	DBRetryAttempts        int      `env:"DATABASE_RETRY_ATTEMPTS" json:"database_retry_attempts"`
+	DeleteConcurrency      int      `env:"DELETE_CONCURRENCY" json:"delete_concurrency"`
+	BreakerThreshold       int      `env:"CIRCUIT_BREAKER_THRESHOLD" json:"circuit_breaker_threshold"`
+	BreakerCacheSize       int      `env:"CIRCUIT_BREAKER_CACHE_SIZE" json:"circuit_breaker_cache_size"`
+	RateLimitCreateBurst   int      `env:"RATE_LIMIT_CREATE_BURST" json:"rate_limit_create_burst"`
+	RateLimitBatchBurst    int      `env:"RATE_LIMIT_BATCH_BURST" json:"rate_limit_batch_burst"`
+	RateLimitRedirectBurst int      `env:"RATE_LIMIT_REDIRECT_BURST" json:"rate_limit_redirect_burst"`
+	RateLimitAdminBurst    int      `env:"RATE_LIMIT_ADMIN_BURST" json:"rate_limit_admin_burst"`
+	EnableHTTPS            bool     `env:"ENABLE_HTTPS" json:"enable_https"`
+	FileRecovery           bool     `env:"FILE_RECOVERY" json:"file_recovery"`
+	AutoMigrate            bool     `env:"AUTO_MIGRATE" json:"auto_migrate"`
+	CookieSecure           bool     `env:"COOKIE_SECURE" json:"cookie_secure"`
+	CookieHTTPOnly         bool     `env:"COOKIE_HTTP_ONLY" json:"cookie_http_only"`
+}
+
+// Duration is a time.Duration which is represented as a string like "10s" in config file.
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON marshal Duration into string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.String())
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal duration: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON unmarshal Duration from string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return fmt.Errorf("can not unmarshal duration: %w", err)
+	}
+	return d.UnmarshalText([]byte(value))
+}
+
+// UnmarshalText parse Duration from text.
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("can not parse duration: %w", err)
+	}
+	d.Duration = duration
+	return nil
 }
 
 // Init parse values for Config from environment and flags.
@@ -46,16 +151,190 @@ func Init() (*Config, error) {
 	flag.StringVar(&cfg.ServerAddress, "a", DefaultServerAddress, "server address")
 	flag.StringVar(&cfg.BasicPath, "b", DefaultBasicPath, "basic path")
 	flag.StringVar(&cfg.FileStoragePath, "f", DefaultFileStoragePath, "file storage path")
+	flag.StringVar(
+		&cfg.AuditStoragePath,
+		"audit-storage-path",
+		DefaultAuditStoragePath,
+		"audit log path of file storage, audit log is kept in memory if it is empty",
+	)
 	flag.StringVar(&cfg.DatabaseDSN, "d", "", "database dsn")
+	flag.Func("replica-dsns", "comma separated database replica dsns", func(value string) error {
+		cfg.DatabaseReplicaDSNs = strings.Split(value, ",")
+		return nil
+	})
+	flag.DurationVar(
+		&cfg.ReplicaCheckInterval.Duration,
+		"replica-check-interval",
+		DefaultReplicaCheckInterval,
+		"database replica health check interval",
+	)
+	flag.DurationVar(
+		&cfg.ReadYourWritesWindow.Duration,
+		"read-your-writes-window",
+		0,
+		"window after write of user during which its reads are served by primary database",
+	)
+	flag.IntVar(&cfg.DBRetryAttempts, "db-retry-attempts", DefaultDBRetryAttempts, "database operation attempts")
+	flag.DurationVar(
+		&cfg.DBRetryBaseDelay.Duration,
+		"db-retry-base-delay",
+		DefaultDBRetryBaseDelay,
+		"base delay between database operation attempts",
+	)
+	flag.DurationVar(
+		&cfg.DBRetryMaxDelay.Duration,
+		"db-retry-max-delay",
+		DefaultDBRetryMaxDelay,
+		"max delay between database operation attempts",
+	)
+	flag.IntVar(
+		&cfg.BreakerThreshold,
+		"breaker-threshold",
+		DefaultBreakerThreshold,
+		"consecutive database failures which open circuit breaker, zero disables it",
+	)
+	flag.DurationVar(
+		&cfg.BreakerOpenTimeout.Duration,
+		"breaker-open-timeout",
+		DefaultBreakerOpenTimeout,
+		"time during which open circuit breaker rejects operations",
+	)
+	flag.IntVar(
+		&cfg.BreakerCacheSize,
+		"breaker-cache-size",
+		DefaultBreakerCacheSize,
+		"amount of redirects which are served from cache while circuit breaker is open",
+	)
+	flag.StringVar(&cfg.BoltStoragePath, "bolt-storage-path", "", "bolt storage path")
+	flag.DurationVar(
+		&cfg.IdempotencyTTL.Duration,
+		"idempotency-ttl",
+		DefaultIdempotencyTTL,
+		"time during which responses of requests with idempotency key are replayed, zero disables it",
+	)
+	flag.StringVar(
+		&cfg.IdempotencyPath,
+		"idempotency-storage-path",
+		"",
+		"idempotency keys file storage path, keys are kept in memory if it is empty",
+	)
+	flag.DurationVar(
+		&cfg.RefreshTokenTTL.Duration,
+		"refresh-token-ttl",
+		DefaultRefreshTokenTTL,
+		"lifetime of refresh tokens, zero disables them",
+	)
 	flag.StringVar(&cfg.PublicKeyPath, "p", DefaultPublicKeyPath, "public key path")
 	flag.StringVar(&cfg.PrivateKeyPath, "k", DefaultPrivateKeyPath, "private key path")
+	flag.StringVar(
+		&cfg.JWTKeysDir,
+		"jwt-keys-dir",
+		"",
+		"directory of jwt keys which are reloaded on SIGHUP, public and private key paths are used if it is empty",
+	)
 	flag.BoolVar(&cfg.EnableHTTPS, "s", DefaultEnableHTTPS, "enable https")
+	flag.StringVar(&cfg.CookieDomain, "cookie-domain", "", "domain of auth cookies")
+	flag.StringVar(&cfg.CookiePath, "cookie-path", DefaultCookiePath, "path of auth cookie")
+	flag.StringVar(
+		&cfg.CookieSameSite,
+		"cookie-samesite",
+		DefaultCookieSameSite,
+		"samesite of auth cookies: lax, strict, none or empty",
+	)
+	flag.DurationVar(
+		&cfg.CookieMaxAge.Duration,
+		"cookie-max-age",
+		DefaultCookieMaxAge,
+		"lifetime of auth cookie, zero makes session cookie",
+	)
+	flag.BoolVar(
+		&cfg.CookieSecure,
+		"cookie-secure",
+		DefaultCookieSecure,
+		"send auth cookies only over https, they are always secure if https is enabled",
+	)
+	flag.BoolVar(&cfg.CookieHTTPOnly, "cookie-http-only", DefaultCookieHTTPOnly, "hide auth cookie from javascript")
+	flag.Func(
+		"csrf-trusted-origins",
+		"comma separated origins which are allowed to send mutating requests with auth cookie",
+		func(value string) error {
+			cfg.CSRFTrustedOrigins = strings.Split(value, ",")
+			return nil
+		},
+	)
+	flag.Func(
+		"admin-users",
+		"comma separated ids of users which are allowed to use admin api",
+		func(value string) error {
+			cfg.AdminUsers = strings.Split(value, ",")
+			return nil
+		},
+	)
+	flag.Func(
+		"trusted-proxies",
+		"comma separated cidrs of proxies whose X-Forwarded-For and X-Real-IP headers are trusted",
+		func(value string) error {
+			cfg.TrustedProxies = strings.Split(value, ",")
+			return nil
+		},
+	)
+	for _, limit := range []struct {
+		rate  *float64
+		burst *int
+		class string
+	}{
+		{rate: &cfg.RateLimitCreate, burst: &cfg.RateLimitCreateBurst, class: "create"},
+		{rate: &cfg.RateLimitBatch, burst: &cfg.RateLimitBatchBurst, class: "batch"},
+		{rate: &cfg.RateLimitRedirect, burst: &cfg.RateLimitRedirectBurst, class: "redirect"},
+		{rate: &cfg.RateLimitAdmin, burst: &cfg.RateLimitAdminBurst, class: "admin"},
+	} {
+		flag.Float64Var(
+			limit.rate,
+			"rate-limit-"+limit.class,
+			0,
+			"requests per second of "+limit.class+" routes for each user, api key or ip, zero disables limit",
+		)
+		flag.IntVar(
+			limit.burst,
+			"rate-limit-"+limit.class+"-burst",
+			0,
+			"burst of requests of "+limit.class+" routes, rate rounded up is used if it is zero",
+		)
+	}
+	flag.StringVar(&cfg.OIDCIssuer, "oidc-issuer", "", "issuer of openid connect provider, login is disabled if it is empty")
+	flag.StringVar(&cfg.OIDCClientID, "oidc-client-id", "", "client id of openid connect provider")
+	flag.StringVar(&cfg.OIDCClientSecret, "oidc-client-secret", "", "client secret of openid connect provider")
+	flag.StringVar(
+		&cfg.OIDCRedirectURL,
+		"oidc-redirect-url",
+		"",
+		"url of openid connect callback like https://example.com/api/auth/oidc/callback",
+	)
 	flag.StringVar(&cfg.CertificatePath, "cert", DefaultCertificatePath, "certificate path")
 	flag.StringVar(&cfg.CertificateKeyPath, "key", DefaultCertificateKeyPath, "certificate key path")
 	flag.StringVar(&cfg.Config, "c", "", "config")
 	flag.StringVar(&cfg.Config, "config", "", "config")
 	flag.StringVar(&cfg.TrustedSubnet, "t", "", "trusted subnet")
 	flag.StringVar(&cfg.GRPCServerAddress, "grpc", DefaultGRPCServerAddress, "grpc server address")
+	flag.DurationVar(&cfg.ShutdownTimeout.Duration, "shutdown-timeout", DefaultShutdownTimeout, "shutdown timeout")
+	flag.IntVar(&cfg.DeleteBatchSize, "delete-batch-size", DefaultDeleteBatchSize, "urls deletion batch size")
+	flag.IntVar(&cfg.DeleteConcurrency, "delete-concurrency", DefaultDeleteConcurrency, "urls deletion concurrency")
+	flag.DurationVar(
+		&cfg.DeleteFlushInterval.Duration,
+		"delete-flush-interval",
+		DefaultDeleteFlushInterval,
+		"urls deletion flush interval",
+	)
+	flag.StringVar(&cfg.FileFsyncPolicy, "file-fsync-policy", DefaultFileFsyncPolicy, "file fsync policy: always, interval or never")
+	flag.DurationVar(&cfg.FileFsyncInterval.Duration, "file-fsync-interval", DefaultFileFsyncInterval, "file fsync interval")
+	flag.DurationVar(
+		&cfg.FileCompactInterval.Duration,
+		"file-compact-interval",
+		DefaultFileCompactInterval,
+		"file compaction interval",
+	)
+	flag.BoolVar(&cfg.FileRecovery, "file-recovery", DefaultFileRecovery, "skip corrupt records of file on load")
+	flag.BoolVar(&cfg.AutoMigrate, "auto-migrate", DefaultAutoMigrate, "apply database migrations on startup")
 
 	flag.Parse()
 
@@ -69,7 +348,7 @@ func Init() (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("can not read config file: %w", err)
 		}
-		var configFileData Config
+		configFileData := Config{AutoMigrate: DefaultAutoMigrate, CookieHTTPOnly: DefaultCookieHTTPOnly}
 		err = json.Unmarshal(configFile, &configFileData)
 		if err != nil {
 			return nil, fmt.Errorf("can not unmarshal config file: %w", err)
@@ -84,18 +363,128 @@ func Init() (*Config, error) {
 		if cfg.FileStoragePath == DefaultFileStoragePath {
 			cfg.FileStoragePath = configFileData.FileStoragePath
 		}
+		if cfg.AuditStoragePath == DefaultAuditStoragePath && configFileData.AuditStoragePath != "" {
+			cfg.AuditStoragePath = configFileData.AuditStoragePath
+		}
 		if cfg.DatabaseDSN == "" {
 			cfg.DatabaseDSN = configFileData.DatabaseDSN
 		}
+		if len(cfg.DatabaseReplicaDSNs) == 0 {
+			cfg.DatabaseReplicaDSNs = configFileData.DatabaseReplicaDSNs
+		}
+		if cfg.ReplicaCheckInterval.Duration == DefaultReplicaCheckInterval &&
+			configFileData.ReplicaCheckInterval.Duration != 0 {
+			cfg.ReplicaCheckInterval = configFileData.ReplicaCheckInterval
+		}
+		if cfg.ReadYourWritesWindow.Duration == 0 {
+			cfg.ReadYourWritesWindow = configFileData.ReadYourWritesWindow
+		}
+		if cfg.DBRetryAttempts == DefaultDBRetryAttempts && configFileData.DBRetryAttempts != 0 {
+			cfg.DBRetryAttempts = configFileData.DBRetryAttempts
+		}
+		if cfg.DBRetryBaseDelay.Duration == DefaultDBRetryBaseDelay && configFileData.DBRetryBaseDelay.Duration != 0 {
+			cfg.DBRetryBaseDelay = configFileData.DBRetryBaseDelay
+		}
+		if cfg.DBRetryMaxDelay.Duration == DefaultDBRetryMaxDelay && configFileData.DBRetryMaxDelay.Duration != 0 {
+			cfg.DBRetryMaxDelay = configFileData.DBRetryMaxDelay
+		}
+		if cfg.BreakerThreshold == DefaultBreakerThreshold && configFileData.BreakerThreshold != 0 {
+			cfg.BreakerThreshold = configFileData.BreakerThreshold
+		}
+		if cfg.BreakerOpenTimeout.Duration == DefaultBreakerOpenTimeout &&
+			configFileData.BreakerOpenTimeout.Duration != 0 {
+			cfg.BreakerOpenTimeout = configFileData.BreakerOpenTimeout
+		}
+		if cfg.BreakerCacheSize == DefaultBreakerCacheSize && configFileData.BreakerCacheSize != 0 {
+			cfg.BreakerCacheSize = configFileData.BreakerCacheSize
+		}
+		if cfg.BoltStoragePath == "" {
+			cfg.BoltStoragePath = configFileData.BoltStoragePath
+		}
+		if cfg.IdempotencyTTL.Duration == DefaultIdempotencyTTL && configFileData.IdempotencyTTL.Duration != 0 {
+			cfg.IdempotencyTTL = configFileData.IdempotencyTTL
+		}
+		if cfg.IdempotencyPath == "" {
+			cfg.IdempotencyPath = configFileData.IdempotencyPath
+		}
+		if cfg.RefreshTokenTTL.Duration == DefaultRefreshTokenTTL && configFileData.RefreshTokenTTL.Duration != 0 {
+			cfg.RefreshTokenTTL = configFileData.RefreshTokenTTL
+		}
 		if cfg.PublicKeyPath == DefaultPublicKeyPath {
 			cfg.PublicKeyPath = configFileData.PublicKeyPath
 		}
 		if cfg.PrivateKeyPath == DefaultPrivateKeyPath {
 			cfg.PrivateKeyPath = configFileData.PrivateKeyPath
 		}
+		if cfg.JWTKeysDir == "" {
+			cfg.JWTKeysDir = configFileData.JWTKeysDir
+		}
 		if !cfg.EnableHTTPS {
 			cfg.EnableHTTPS = configFileData.EnableHTTPS
 		}
+		if cfg.CookieDomain == "" {
+			cfg.CookieDomain = configFileData.CookieDomain
+		}
+		if cfg.CookiePath == DefaultCookiePath && configFileData.CookiePath != "" {
+			cfg.CookiePath = configFileData.CookiePath
+		}
+		if cfg.CookieSameSite == DefaultCookieSameSite && configFileData.CookieSameSite != "" {
+			cfg.CookieSameSite = configFileData.CookieSameSite
+		}
+		if cfg.CookieMaxAge.Duration == DefaultCookieMaxAge && configFileData.CookieMaxAge.Duration != 0 {
+			cfg.CookieMaxAge = configFileData.CookieMaxAge
+		}
+		if !cfg.CookieSecure {
+			cfg.CookieSecure = configFileData.CookieSecure
+		}
+		if cfg.CookieHTTPOnly == DefaultCookieHTTPOnly {
+			cfg.CookieHTTPOnly = configFileData.CookieHTTPOnly
+		}
+		if len(cfg.CSRFTrustedOrigins) == 0 {
+			cfg.CSRFTrustedOrigins = configFileData.CSRFTrustedOrigins
+		}
+		if len(cfg.AdminUsers) == 0 {
+			cfg.AdminUsers = configFileData.AdminUsers
+		}
+		if len(cfg.TrustedProxies) == 0 {
+			cfg.TrustedProxies = configFileData.TrustedProxies
+		}
+		if cfg.RateLimitCreate == 0 {
+			cfg.RateLimitCreate = configFileData.RateLimitCreate
+		}
+		if cfg.RateLimitCreateBurst == 0 {
+			cfg.RateLimitCreateBurst = configFileData.RateLimitCreateBurst
+		}
+		if cfg.RateLimitBatch == 0 {
+			cfg.RateLimitBatch = configFileData.RateLimitBatch
+		}
+		if cfg.RateLimitBatchBurst == 0 {
+			cfg.RateLimitBatchBurst = configFileData.RateLimitBatchBurst
+		}
+		if cfg.RateLimitRedirect == 0 {
+			cfg.RateLimitRedirect = configFileData.RateLimitRedirect
+		}
+		if cfg.RateLimitRedirectBurst == 0 {
+			cfg.RateLimitRedirectBurst = configFileData.RateLimitRedirectBurst
+		}
+		if cfg.RateLimitAdmin == 0 {
+			cfg.RateLimitAdmin = configFileData.RateLimitAdmin
+		}
+		if cfg.RateLimitAdminBurst == 0 {
+			cfg.RateLimitAdminBurst = configFileData.RateLimitAdminBurst
+		}
+		if cfg.OIDCIssuer == "" {
+			cfg.OIDCIssuer = configFileData.OIDCIssuer
+		}
+		if cfg.OIDCClientID == "" {
+			cfg.OIDCClientID = configFileData.OIDCClientID
+		}
+		if cfg.OIDCClientSecret == "" {
+			cfg.OIDCClientSecret = configFileData.OIDCClientSecret
+		}
+		if cfg.OIDCRedirectURL == "" {
+			cfg.OIDCRedirectURL = configFileData.OIDCRedirectURL
+		}
 		if cfg.CertificatePath == DefaultCertificatePath {
 			cfg.CertificatePath = configFileData.CertificatePath
 		}
@@ -108,6 +497,36 @@ func Init() (*Config, error) {
 		if cfg.GRPCServerAddress == DefaultGRPCServerAddress {
 			cfg.GRPCServerAddress = configFileData.GRPCServerAddress
 		}
+		if cfg.ShutdownTimeout.Duration == DefaultShutdownTimeout && configFileData.ShutdownTimeout.Duration != 0 {
+			cfg.ShutdownTimeout = configFileData.ShutdownTimeout
+		}
+		if cfg.DeleteBatchSize == DefaultDeleteBatchSize && configFileData.DeleteBatchSize != 0 {
+			cfg.DeleteBatchSize = configFileData.DeleteBatchSize
+		}
+		if cfg.DeleteConcurrency == DefaultDeleteConcurrency && configFileData.DeleteConcurrency != 0 {
+			cfg.DeleteConcurrency = configFileData.DeleteConcurrency
+		}
+		if cfg.DeleteFlushInterval.Duration == DefaultDeleteFlushInterval &&
+			configFileData.DeleteFlushInterval.Duration != 0 {
+			cfg.DeleteFlushInterval = configFileData.DeleteFlushInterval
+		}
+		if cfg.FileFsyncPolicy == DefaultFileFsyncPolicy && configFileData.FileFsyncPolicy != "" {
+			cfg.FileFsyncPolicy = configFileData.FileFsyncPolicy
+		}
+		if cfg.FileFsyncInterval.Duration == DefaultFileFsyncInterval &&
+			configFileData.FileFsyncInterval.Duration != 0 {
+			cfg.FileFsyncInterval = configFileData.FileFsyncInterval
+		}
+		if cfg.FileCompactInterval.Duration == DefaultFileCompactInterval &&
+			configFileData.FileCompactInterval.Duration != 0 {
+			cfg.FileCompactInterval = configFileData.FileCompactInterval
+		}
+		if !cfg.FileRecovery {
+			cfg.FileRecovery = configFileData.FileRecovery
+		}
+		if cfg.AutoMigrate == DefaultAutoMigrate {
+			cfg.AutoMigrate = configFileData.AutoMigrate
+		}
 	}
 
 	if cfg.BasicPath[len(cfg.BasicPath)-1] == '/' {
@@ -121,5 +540,12 @@ func Init() (*Config, error) {
 		}
 	}
 
+	for _, proxy := range cfg.TrustedProxies {
+		_, _, err = net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("can not parse cidr of trusted proxy: %w", err)
+		}
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Query parameters of admin API.
+const (
+	AdminQuery  = "query"
+	AdminUserID = "user_id"
+	AdminLimit  = "limit"
+	AdminOffset = "offset"
+	AdminLinkID = "link_id"
+	AdminFrom   = "from"
+	AdminTo     = "to"
+)
+
+// SearchLinks return links of all users which match query, user and page.
+// It has to be used after RequireAdmin.
+func (c *Controller) SearchLinks(ctx *gin.Context) {
+	var userID uuid.UUID
+	if value := ctx.Query(AdminUserID); value != "" {
+		var err error
+		userID, err = uuid.Parse(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+	}
+	c.searchLinks(ctx, userID)
+}
+
+// GetLinksOfUser return links of user including deleted and disabled ones.
+// It has to be used after RequireAdmin.
+func (c *Controller) GetLinksOfUser(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param(ID))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+	c.searchLinks(ctx, userID)
+}
+
+// searchLinks return links which match query and page of request and user.
+func (c *Controller) searchLinks(ctx *gin.Context, userID uuid.UUID) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+	limit, offset, ok := adminPage(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	result, err := c.interactor.SearchLinks(ctx, token.UserID, repository.LinkFilter{
+		Query:  ctx.Query(AdminQuery),
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.adminError(ctx, "Can not search links", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DisableLink disable link of any user so it is not redirected any more.
+// It has to be used after RequireAdmin.
+func (c *Controller) DisableLink(ctx *gin.Context) {
+	c.setLinkDisabled(ctx, true)
+}
+
+// EnableLink reenable link which is disabled by administrator.
+// It has to be used after RequireAdmin.
+func (c *Controller) EnableLink(ctx *gin.Context) {
+	c.setLinkDisabled(ctx, false)
+}
+
+// setLinkDisabled disable or reenable link of request.
+func (c *Controller) setLinkDisabled(ctx *gin.Context, disabled bool) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	result, err := c.interactor.SetLinkDisabled(ctx, token.UserID, ctx.Param(ID), disabled)
+	if err != nil {
+		c.adminError(ctx, "Can not set link disabled", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetUsers return users which have links with amount of their links.
+// It has to be used after RequireAdmin.
+func (c *Controller) GetUsers(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+	limit, offset, ok := adminPage(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	result, err := c.interactor.GetUsers(ctx, token.UserID, limit, offset)
+	if err != nil {
+		c.adminError(ctx, "Can not get users", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// TransferLinks move links of any users to another user.
+// It has to be used after RequireAdmin.
+func (c *Controller) TransferLinks(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	var request models.TransferRequest
+	err = json.Unmarshal(data, &request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	transferred, err := c.interactor.TransferLinks(ctx, token.UserID, request.URLs, request.UserID)
+	if err != nil {
+		c.adminError(ctx, "Can not transfer links", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.TransferResponse{Transferred: transferred})
+}
+
+// GetAudit return events of audit log which match link, user, time range and limit of request.
+// Times are in RFC 3339 format. It has to be used after RequireAdmin.
+func (c *Controller) GetAudit(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+	filter, ok := auditFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	result, err := c.interactor.GetAudit(ctx, token.UserID, filter)
+	if err != nil {
+		c.adminError(ctx, "Can not get audit events", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ExportAudit write events of audit log which match filter of request as JSON lines.
+// Without limit all matching events are exported. It has to be used after RequireAdmin.
+func (c *Controller) ExportAudit(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+	filter, ok := auditFilter(ctx)
+	if !ok || filter.Limit < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Status(http.StatusOK)
+	err := c.interactor.ExportAudit(ctx, token.UserID, filter, ctx.Writer)
+	if err != nil {
+		c.logger.Error("Can not export audit events", zap.Error(err))
+	}
+}
+
+// adminError write response of failed admin action.
+func (c *Controller) adminError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrInvalidPage) || errors.Is(err, usecases.ErrInvalidTransfer):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+	case retryAfter(ctx, err):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+	default:
+		c.logger.Error(message, zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+	}
+}
+
+// adminPage return limit and offset of request, they are zero if they are not presented.
+func adminPage(ctx *gin.Context) (int, int, bool) {
+	var limit, offset int
+	var err error
+	if value := ctx.Query(AdminLimit); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	if value := ctx.Query(AdminOffset); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return limit, offset, true
+}
+
+// auditFilter return filter of audit events of request.
+func auditFilter(ctx *gin.Context) (repository.AuditFilter, bool) {
+	filter := repository.AuditFilter{
+		ShortURL: ctx.Query(AdminLinkID),
+	}
+	var err error
+	if value := ctx.Query(AdminUserID); value != "" {
+		filter.UserID, err = uuid.Parse(value)
+		if err != nil {
+			return repository.AuditFilter{}, false
+		}
+	}
+	if value := ctx.Query(AdminFrom); value != "" {
+		filter.From, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return repository.AuditFilter{}, false
+		}
+	}
+	if value := ctx.Query(AdminTo); value != "" {
+		filter.To, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return repository.AuditFilter{}, false
+		}
+	}
+	if value := ctx.Query(AdminLimit); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil {
+			return repository.AuditFilter{}, false
+		}
+	}
+	return filter, true
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	interactor := usecases.NewInteractor(
+		ctx,
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	controller := NewController(zap.NewNop(), interactor, nil)
+
+	admin := uuid.New()
+	owner := uuid.New()
+	receiver := uuid.New()
+	link, err := interactor.CreateShortLink(ctx, "https://ya.ru", owner)
+	assert.NoError(t, err)
+	id := path.Base(*link)
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set(middlewares.Authorization, &middlewares.JWT{UserID: admin})
+		ctx.Set(middlewares.AuthorizationNew, false)
+	})
+	router.GET("/:id", controller.GetShortLink)
+	router.GET("/api/admin/links", controller.SearchLinks)
+	router.POST("/api/admin/links/transfer", controller.TransferLinks)
+	router.POST("/api/admin/links/:id/disable", controller.DisableLink)
+	router.POST("/api/admin/links/:id/enable", controller.EnableLink)
+	router.GET("/api/admin/users", controller.GetUsers)
+	router.GET("/api/admin/users/:id/links", controller.GetLinksOfUser)
+	router.GET("/api/admin/audit", controller.GetAudit)
+	router.GET("/api/admin/audit/export", controller.ExportAudit)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/admin/links?query=ya.ru&limit=10", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var links []models.AdminLink
+	err = json.Unmarshal(w.Body.Bytes(), &links)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, id, links[0].ID)
+	assert.Equal(t, owner, links[0].UserID)
+
+	w = serve(http.MethodGet, "/api/admin/links?limit=abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodGet, "/api/admin/links?limit=100000", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodGet, "/api/admin/links?user_id=abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodPost, "/api/admin/links/"+id+"/disable", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"disabled":true`)
+	w = serve(http.MethodGet, "/"+id, "")
+	assert.Equal(t, http.StatusGone, w.Code)
+	w = serve(http.MethodPost, "/api/admin/links/unknown/disable", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(http.MethodPost, "/api/admin/links/"+id+"/enable", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(http.MethodGet, "/"+id, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	w = serve(http.MethodPost, "/api/admin/links/transfer", `{"urls":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodPost, "/api/admin/links/transfer", `{"urls":["`+id+`"],"user_id":"`+receiver.String()+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"transferred":1}`, w.Body.String())
+
+	w = serve(http.MethodGet, "/api/admin/users/"+receiver.String()+"/links", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &links)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	w = serve(http.MethodGet, "/api/admin/users/"+owner.String()+"/links", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = serve(http.MethodGet, "/api/admin/users", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var users []models.AdminUser
+	err = json.Unmarshal(w.Body.Bytes(), &users)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AdminUser{{UserID: receiver, URLs: 1}}, users)
+
+	w = serve(http.MethodGet, "/api/admin/audit?link_id="+id+"&user_id="+admin.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var events []models.AuditEvent
+	err = json.Unmarshal(w.Body.Bytes(), &events)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, usecases.AuditDisable, events[0].Action)
+		assert.Equal(t, admin, events[0].ActorID)
+		assert.Equal(t, owner, events[0].UserID)
+		assert.Equal(t, usecases.AuditTransfer, events[2].Action)
+	}
+	w = serve(http.MethodGet, "/api/admin/audit?to=2000-01-01T00:00:00Z", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	w = serve(http.MethodGet, "/api/admin/audit?from=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodGet, "/api/admin/audit?limit=100000", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodGet, "/api/admin/audit/export?link_id="+id, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 4)
+	w = serve(http.MethodGet, "/api/admin/audit/export?limit=-1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateAPIKey create new API key of user if JWT is presented.
+// Key is returned only in this response. Admin scope is granted only to users with admin role.
+func (c *Controller) CreateAPIKey(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	var request models.APIKeyRequest
+	if len(data) != 0 {
+		err = json.Unmarshal(data, &request)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+	}
+
+	if slices.Contains(request.Scopes, models.ScopeAdmin) && !token.HasRole(models.RoleAdmin) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+
+	result, err := c.interactor.CreateAPIKey(ctx, token.UserID, request.Name, request.Scopes)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidScope) || errors.Is(err, usecases.ErrInvalidAPIKeyName) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
+		c.logger.Error("Can not create api key", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// GetAPIKeys return all API keys of user if such exist and JWT is presented.
+func (c *Controller) GetAPIKeys(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusNoContent, gin.H{"error": http.StatusText(http.StatusNoContent)})
+		return
+	}
+
+	result, err := c.interactor.GetAPIKeys(ctx, token.UserID)
+	if err != nil {
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
+		c.logger.Error("Can not get api keys of user", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	if len(result) == 0 {
+		ctx.JSON(http.StatusNoContent, gin.H{"error": http.StatusText(http.StatusNoContent)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RevokeAPIKey revoke API key of user if it exists and JWT is presented.
+func (c *Controller) RevokeAPIKey(ctx *gin.Context) {
+	token, ok := existingToken(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	err := c.interactor.RevokeAPIKey(ctx, ctx.Param(ID), token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+			return
+		}
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
+		c.logger.Error("Can not revoke api key", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// existingToken return JWT of request if it is not generated by this request.
+func existingToken(ctx *gin.Context) (*middlewares.JWT, bool) {
+	if ctx.GetBool(middlewares.AuthorizationNew) {
+		return nil, false
+	}
+	token, ok := ctx.Value(middlewares.Authorization).(*middlewares.JWT)
+	return token, ok
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeys(t *testing.T) {
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	controller := NewController(zap.NewNop(), interactor, nil)
+
+	userID := uuid.New()
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		token := &middlewares.JWT{UserID: userID}
+		if ctx.GetHeader("X-Admin") != "" {
+			token.Roles = []string{models.RoleAdmin}
+		}
+		ctx.Set(middlewares.Authorization, token)
+		ctx.Set(middlewares.AuthorizationNew, ctx.GetHeader("X-New") != "")
+	})
+	router.POST("/api/user/api-keys", controller.CreateAPIKey)
+	router.GET("/api/user/api-keys", controller.GetAPIKeys)
+	router.DELETE("/api/user/api-keys/:id", controller.RevokeAPIKey)
+
+	serve := func(method string, path string, body string, newToken bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		if newToken {
+			request.Header.Set("X-New", "true")
+		}
+		router.ServeHTTP(w, request)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/user/api-keys", "", false)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serve(http.MethodPost, "/api/user/api-keys", `{"name":"ci","scopes":["create"]}`, true)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve(http.MethodPost, "/api/user/api-keys", `{"name":"ci","scopes":["unknown"]}`, false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(http.MethodPost, "/api/user/api-keys", `{"name":"ci","scopes":["admin"]}`, false)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serve(http.MethodPost, "/api/user/api-keys", `{"name":`, false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodPost, "/api/user/api-keys", `{"name":"ci","scopes":["create"]}`, false)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.APIKeyResponse
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, "ci", created.Name)
+	assert.Equal(t, []string{models.ScopeCreate}, created.Scopes)
+
+	w = serve(http.MethodDelete, "/api/user/api-keys/"+uuid.NewString(), "", false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(http.MethodDelete, "/api/user/api-keys/"+created.ID, "", false)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serve(http.MethodGet, "/api/user/api-keys", "", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var keys []models.APIKeyResponse
+	err = json.Unmarshal(w.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key)
+	assert.NotNil(t, keys[0].RevokedAt)
+
+	w = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/user/api-keys", strings.NewReader(`{"scopes":["admin"]}`))
+	request.Header.Set("X-Admin", "true")
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Content types of bulk shortening.
+const (
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// Parameters of bulk shortening.
+const (
+	bulkChunkSize    = 1000
+	bulkMaxLineBytes = 1 << 20
+)
+
+// Errors of bulk upload.
+var (
+	// errInvalidRow is an error of row which can not be decoded.
+	// Rows after it are still processed.
+	errInvalidRow = errors.New("invalid row")
+	// errUnsupportedContentType is an error of upload of unknown content type.
+	errUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// Statuses of rows of bulk shortening by statuses of items of batch.
+var bulkStatuses = map[string]string{
+	models.BatchStatusCreated:  models.BulkStatusCreated,
+	models.BatchStatusExisting: models.BulkStatusConflict,
+	models.BatchStatusInvalid:  models.BulkStatusInvalid,
+	models.BatchStatusError:    models.BulkStatusError,
+}
+
+// bulkReader is an interface of decoders of rows of bulk shortening.
+// Read return error wrapping errInvalidRow for row which can not be decoded
+// and io.EOF when there are no more rows.
+type bulkReader interface {
+	Read() (models.ShortenBatchRequest, error)
+}
+
+// csvBulkReader decode rows from CSV with header.
+type csvBulkReader struct {
+	reader        *csv.Reader
+	originalURL   int
+	correlationID int
+}
+
+// ndjsonBulkReader decode rows from JSON lines.
+type ndjsonBulkReader struct {
+	scanner *bufio.Scanner
+}
+
+// bulkRow is a decoded row of bulk shortening.
+type bulkRow struct {
+	err     error
+	request models.ShortenBatchRequest
+	number  int
+}
+
+// CreateShortLinkBulk create new short URLs from original URLs of CSV or NDJSON upload.
+// Upload is read and processed by chunks, result of every row is streamed as JSON line,
+// so invalid rows and conflicts do not fail the whole upload.
+// Generate new JWT and put it in cookie if it is not presented.
+func (c *Controller) CreateShortLinkBulk(ctx *gin.Context) {
+	tokenValue, ok := ctx.Get(middlewares.Authorization)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+	token, ok := tokenValue.(*middlewares.JWT)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	reader, err := newBulkReader(ctx.Request.Body, ctx.GetHeader("Content-Type"))
+	if err != nil {
+		if errors.Is(err, errUnsupportedContentType) {
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": http.StatusText(http.StatusUnsupportedMediaType)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		return
+	}
+
+	ctx.Writer.Header().Set("Content-Type", contentTypeNDJSON)
+	ctx.Status(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Writer)
+
+	chunk := make([]bulkRow, 0, bulkChunkSize)
+	var number int
+	for {
+		request, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		number++
+		if err != nil && !errors.Is(err, errInvalidRow) {
+			c.logger.Error("Can not read bulk upload", zap.Error(err))
+			chunk = append(chunk, bulkRow{err: errors.New("can not read row"), number: number})
+			break
+		}
+		chunk = append(chunk, bulkRow{err: err, request: request, number: number})
+		if len(chunk) < bulkChunkSize {
+			continue
+		}
+		if !c.writeBulkChunk(ctx, encoder, chunk, token) {
+			return
+		}
+		chunk = chunk[:0]
+	}
+
+	if len(chunk) != 0 {
+		c.writeBulkChunk(ctx, encoder, chunk, token)
+	}
+}
+
+// writeBulkChunk create short URLs for valid rows of chunk and write result of every row.
+// Return false if client is gone or results can not be written.
+func (c *Controller) writeBulkChunk(
+	ctx *gin.Context,
+	encoder *json.Encoder,
+	chunk []bulkRow,
+	token *middlewares.JWT,
+) bool {
+	if ctx.Request.Context().Err() != nil {
+		return false
+	}
+
+	results := make([]models.ShortenBulkResult, len(chunk))
+	batch := make([]models.ShortenBatchRequest, 0, len(chunk))
+	indexes := make([]int, 0, len(chunk))
+	for j := range chunk {
+		results[j] = models.ShortenBulkResult{
+			CorrelationID: chunk[j].request.CorrelationID,
+			Row:           chunk[j].number,
+		}
+		if chunk[j].err != nil {
+			results[j].Status = models.BulkStatusInvalid
+			results[j].Error = chunk[j].err.Error()
+			continue
+		}
+		batch = append(batch, chunk[j].request)
+		indexes = append(indexes, j)
+	}
+
+	if len(batch) != 0 {
+		response, err := c.interactor.CreateShortLinks(ctx, batch, token.UserID, true)
+		if err != nil {
+			message := http.StatusText(http.StatusInternalServerError)
+			var unavailable *repository.UnavailableError
+			if errors.As(err, &unavailable) {
+				message = http.StatusText(http.StatusServiceUnavailable)
+			} else {
+				c.logger.Error("Can not create short links from bulk upload", zap.Error(err))
+			}
+			for _, j := range indexes {
+				results[j].Status = models.BulkStatusError
+				results[j].Error = message
+			}
+		}
+		for k := range response {
+			j := indexes[k]
+			results[j].ShortURL = response[k].ShortURL
+			results[j].Status = bulkStatuses[response[k].Status]
+			results[j].Error = response[k].Error
+		}
+	}
+
+	for j := range results {
+		err := encoder.Encode(results[j])
+		if err != nil {
+			c.logger.Error("Can not write bulk result", zap.Error(err))
+			return false
+		}
+	}
+	ctx.Writer.Flush()
+	return true
+}
+
+// newBulkReader create decoder of rows of provided content type.
+// Header of CSV is read immediately and has to contain original_url column.
+func newBulkReader(r io.Reader, contentType string) (bulkReader, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedContentType
+	}
+
+	switch mediaType {
+	case contentTypeCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can not read header: %w", err)
+		}
+		originalURL := slices.Index(header, "original_url")
+		if originalURL == -1 {
+			return nil, errors.New("header does not contain original_url")
+		}
+		return &csvBulkReader{
+			reader:        reader,
+			originalURL:   originalURL,
+			correlationID: slices.Index(header, "correlation_id"),
+		}, nil
+	case contentTypeNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, bulkMaxLineBytes)
+		return &ndjsonBulkReader{scanner: scanner}, nil
+	default:
+		return nil, errUnsupportedContentType
+	}
+}
+
+// Read decode next CSV row.
+func (r *csvBulkReader) Read() (models.ShortenBatchRequest, error) {
+	row, err := r.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return models.ShortenBatchRequest{}, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return models.ShortenBatchRequest{}, fmt.Errorf("%w: %w", errInvalidRow, parseErr.Err)
+		}
+		return models.ShortenBatchRequest{}, fmt.Errorf("can not read row: %w", err)
+	}
+
+	var request models.ShortenBatchRequest
+	if r.correlationID != -1 && r.correlationID < len(row) {
+		request.CorrelationID = row[r.correlationID]
+	}
+	if r.originalURL >= len(row) {
+		return request, fmt.Errorf("%w: original_url is missing", errInvalidRow)
+	}
+	request.OriginalURL = row[r.originalURL]
+	return request, nil
+}
+
+// Read decode next non-empty JSON line.
+func (r *ndjsonBulkReader) Read() (models.ShortenBatchRequest, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var request models.ShortenBatchRequest
+		err := json.Unmarshal(line, &request)
+		if err != nil {
+			return models.ShortenBatchRequest{}, fmt.Errorf("%w: %w", errInvalidRow, err)
+		}
+		return request, nil
+	}
+
+	err := r.scanner.Err()
+	if err != nil {
+		return models.ShortenBatchRequest{}, fmt.Errorf("can not read line: %w", err)
+	}
+	return models.ShortenBatchRequest{}, io.EOF
+}
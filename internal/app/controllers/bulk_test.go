@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/logger"
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateShortLinkBulk(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+	middleware, err := middlewares.NewMiddleware(
+		"../../../public.pem",
+		"../../../private.pem",
+		testLogger.Named("middleware"),
+	)
+	assert.NoError(t, err)
+
+	var manyRows strings.Builder
+	manyRows.WriteString("original_url\n")
+	for i := range bulkChunkSize + 1 {
+		fmt.Fprintf(&manyRows, "https://example.com/%d\n", i)
+	}
+
+	type want struct {
+		statuses    []string
+		contenType  string
+		stastusCode int
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		request     string
+		want        want
+	}{
+		{
+			name:        "csv",
+			contentType: "text/csv; charset=utf-8",
+			request: "original_url,correlation_id\r\n" +
+				"https://ya.ru,1\r\n" +
+				"abc,2\r\n" +
+				"https://ya.ru,3\r\n" +
+				"https://google.com\r\n" +
+				"https://exists.com,4\r\n" +
+				"\"https://broken.com,5\r\n",
+			want: want{
+				stastusCode: http.StatusOK,
+				contenType:  contentTypeNDJSON,
+				statuses: []string{
+					models.BulkStatusCreated,
+					models.BulkStatusInvalid,
+					models.BulkStatusConflict,
+					models.BulkStatusCreated,
+					models.BulkStatusConflict,
+					models.BulkStatusInvalid,
+				},
+			},
+		},
+		{
+			name:        "ndjson",
+			contentType: contentTypeNDJSON,
+			request: `{"correlation_id":"1","original_url":"https://ya.ru"}` + "\n" +
+				"\n" +
+				`{"correlation_id":"2","original_url":` + "\n" +
+				`{"correlation_id":"3","original_url":"https://exists.com"}` + "\n" +
+				`{"correlation_id":"4","original_url":"abc"}`,
+			want: want{
+				stastusCode: http.StatusOK,
+				contenType:  contentTypeNDJSON,
+				statuses: []string{
+					models.BulkStatusCreated,
+					models.BulkStatusInvalid,
+					models.BulkStatusConflict,
+					models.BulkStatusInvalid,
+				},
+			},
+		},
+		{
+			name:        "several chunks",
+			contentType: contentTypeCSV,
+			request:     manyRows.String(),
+			want: want{
+				stastusCode: http.StatusOK,
+				contenType:  contentTypeNDJSON,
+				statuses:    slices.Repeat([]string{models.BulkStatusCreated}, bulkChunkSize+1),
+			},
+		},
+		{
+			name:        "empty upload",
+			contentType: contentTypeNDJSON,
+			want: want{
+				stastusCode: http.StatusOK,
+				contenType:  contentTypeNDJSON,
+			},
+		},
+		{
+			name:        "csv without original url",
+			contentType: contentTypeCSV,
+			request:     "url\nhttps://ya.ru\n",
+			want: want{
+				stastusCode: http.StatusBadRequest,
+				contenType:  "application/json; charset=utf-8",
+			},
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "application/json",
+			request:     `[{"correlation_id":"1","original_url":"https://ya.ru"}]`,
+			want: want{
+				stastusCode: http.StatusUnsupportedMediaType,
+				contenType:  "application/json; charset=utf-8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := repository.NewLinks()
+			_, err := links.SetLink(context.Background(), "https://exists.com", []string{"exists"}, uuid.New())
+			assert.NoError(t, err)
+			interactor := usecases.NewInteractor(
+				context.Background(),
+				testLogger.Named("interactor"),
+				config.DefaultBasicPath,
+				links,
+				usecases.DeleterConfig{},
+			)
+			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/shorten/bulk", strings.NewReader(tt.request))
+			ctx.Request.Header.Set("Content-Type", tt.contentType)
+
+			middleware.Auth()(ctx)
+
+			conntroller.CreateShortLinkBulk(ctx)
+
+			result := w.Result()
+			defer func() {
+				err := result.Body.Close()
+				assert.NoError(t, err)
+			}()
+
+			assert.Equal(t, tt.want.stastusCode, result.StatusCode)
+			assert.Equal(t, tt.want.contenType, result.Header.Get("Content-Type"))
+			if tt.want.stastusCode != http.StatusOK {
+				return
+			}
+
+			var results []models.ShortenBulkResult
+			scanner := bufio.NewScanner(result.Body)
+			for scanner.Scan() {
+				var row models.ShortenBulkResult
+				err := json.Unmarshal(scanner.Bytes(), &row)
+				assert.NoError(t, err)
+				results = append(results, row)
+			}
+			assert.NoError(t, scanner.Err())
+
+			assert.Len(t, results, len(tt.want.statuses))
+			for i := range results {
+				assert.Equal(t, i+1, results[i].Row)
+				assert.Equal(t, tt.want.statuses[i], results[i].Status)
+				switch results[i].Status {
+				case models.BulkStatusCreated, models.BulkStatusConflict:
+					assert.Contains(t, results[i].ShortURL, config.DefaultBasicPath)
+					assert.Empty(t, results[i].Error)
+				default:
+					assert.Empty(t, results[i].ShortURL)
+					assert.NotEmpty(t, results[i].Error)
+				}
+			}
+		})
+	}
+}
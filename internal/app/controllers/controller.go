@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
 	"github.com/RexArseny/url_shortener/internal/app/models"
@@ -18,6 +20,12 @@ import (
 // ID is name of URL parameter.
 const ID = "id"
 
+// Query parameter and its value which turn on per item mode of batch shortening.
+const (
+	BatchMode        = "mode"
+	BatchModePerItem = "per_item"
+)
+
 // Controller is responsible for managing the network interactions of the service.
 type Controller struct {
 	logger        *zap.Logger
@@ -70,6 +78,10 @@ func (c *Controller) CreateShortLink(ctx *gin.Context) {
 			ctx.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 			return
 		}
+		if retryAfter(ctx, err) {
+			ctx.String(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
 		c.logger.Error("Can not create short link", zap.Error(err))
 		ctx.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
@@ -125,6 +137,10 @@ func (c *Controller) CreateShortLinkJSON(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
 			return
 		}
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
 		c.logger.Error("Can not create short link from json", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 		return
@@ -143,6 +159,8 @@ func (c *Controller) CreateShortLinkJSON(ctx *gin.Context) {
 
 // CreateShortLinkJSONBatch create new short URLs from original URLs.
 // Input and output are in JSON format.
+// In per item mode every item carries its own status, response code is 200
+// if all items have short URLs and 207 otherwise.
 // Generate new JWT and put it in cookie if it is not presented.
 func (c *Controller) CreateShortLinkJSONBatch(ctx *gin.Context) {
 	tokenValue, ok := ctx.Get(middlewares.Authorization)
@@ -169,7 +187,8 @@ func (c *Controller) CreateShortLinkJSONBatch(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.interactor.CreateShortLinks(ctx, request, token.UserID)
+	perItem := ctx.Query(BatchMode) == BatchModePerItem
+	result, err := c.interactor.CreateShortLinks(ctx, request, token.UserID, perItem)
 	if err != nil {
 		if errors.Is(err, repository.ErrOriginalURLUniqueViolation) && result != nil {
 			ctx.JSON(http.StatusConflict, result)
@@ -179,11 +198,27 @@ func (c *Controller) CreateShortLinkJSONBatch(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
 			return
 		}
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
 		c.logger.Error("Can not create short links", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
 
+	if perItem {
+		status := http.StatusOK
+		for i := range result {
+			if result[i].ShortURL == "" {
+				status = http.StatusMultiStatus
+				break
+			}
+		}
+		ctx.JSON(status, result)
+		return
+	}
+
 	ctx.JSON(http.StatusCreated, result)
 }
 
@@ -193,10 +228,14 @@ func (c *Controller) GetShortLink(ctx *gin.Context) {
 
 	result, err := c.interactor.GetShortLink(ctx, data)
 	if err != nil {
-		if errors.Is(err, repository.ErrURLIsDeleted) {
+		if errors.Is(err, repository.ErrURLIsDeleted) || errors.Is(err, repository.ErrURLIsDisabled) {
 			ctx.String(http.StatusGone, http.StatusText(http.StatusGone))
 			return
 		}
+		if retryAfter(ctx, err) {
+			ctx.String(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
 		ctx.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
@@ -222,6 +261,18 @@ func (c *Controller) PingDB(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"status": http.StatusText(http.StatusOK)})
 }
 
+// Ready return readiness of service with state of its components.
+func (c *Controller) Ready(ctx *gin.Context) {
+	readiness, err := c.interactor.Ready(ctx)
+	if err != nil {
+		c.logger.Error("Service is not ready", zap.Error(err))
+		ctx.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, readiness)
+}
+
 // GetShortLinksOfUser return all short and original URLs of user if such exist and JWT is presented.
 func (c *Controller) GetShortLinksOfUser(ctx *gin.Context) {
 	newToken := ctx.GetBool(middlewares.AuthorizationNew)
@@ -242,6 +293,10 @@ func (c *Controller) GetShortLinksOfUser(ctx *gin.Context) {
 
 	result, err := c.interactor.GetShortLinksOfUser(ctx, token.UserID)
 	if err != nil {
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
 		c.logger.Error("Can not get short links of user", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 		return
@@ -288,6 +343,10 @@ func (c *Controller) DeleteURLs(ctx *gin.Context) {
 
 	err = c.interactor.DeleteURLs(ctx, request, token.UserID)
 	if err != nil {
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
 		c.logger.Error("Can not delete urls", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 		return
@@ -297,14 +356,20 @@ func (c *Controller) DeleteURLs(ctx *gin.Context) {
 }
 
 // Stats return statistic of shortened urls and users in service.
+// Address of client is resolved by ClientIP middleware and has to belong to trusted subnet.
 func (c *Controller) Stats(ctx *gin.Context) {
-	if c.trustedSubnet == nil || !c.trustedSubnet.Contains(net.ParseIP(ctx.GetHeader("X-Real-IP"))) {
+	ip := net.ParseIP(middlewares.ClientIPFromContext(ctx.Request.Context()))
+	if c.trustedSubnet == nil || ip == nil || !c.trustedSubnet.Contains(ip) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
 		return
 	}
 
 	stats, err := c.interactor.Stats(ctx)
 	if err != nil {
+		if retryAfter(ctx, err) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
 		c.logger.Error("Can not get stats", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 		return
@@ -312,3 +377,14 @@ func (c *Controller) Stats(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, stats)
 }
+
+// retryAfter set Retry-After header if error is caused by temporary unavailable storage.
+// Return true if header is set.
+func retryAfter(ctx *gin.Context, err error) bool {
+	var unavailable *repository.UnavailableError
+	if !errors.As(err, &unavailable) {
+		return false
+	}
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
+	return true
+}
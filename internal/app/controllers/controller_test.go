@@ -4,6 +4,7 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -22,7 +23,9 @@ import (
 	"github.com/RexArseny/url_shortener/internal/app/repository"
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestCreateShortLink(t *testing.T) {
@@ -121,9 +124,9 @@ func TestCreateShortLink(t *testing.T) {
 			cfg := config.Config{
 				BasicPath: config.DefaultBasicPath,
 			}
-			file, err := os.CreateTemp("./", "*.test")
+			file, err := os.CreateTemp(t.TempDir(), "*.test")
 			assert.NoError(t, err)
-			urlRepository, err := repository.NewLinksWithFile(file.Name())
+			urlRepository, err := repository.NewLinksWithFile(zap.NewNop(), file.Name(), repository.FileConfig{})
 			assert.NoError(t, err)
 
 			defer func() {
@@ -140,6 +143,7 @@ func TestCreateShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				urlRepository,
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -283,6 +287,7 @@ func TestCreateShortLinkJSON(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -334,6 +339,7 @@ func TestCreateShortLinkJSONBatch(t *testing.T) {
 	tests := []struct {
 		name    string
 		request string
+		query   string
 		auth    gin.HandlerFunc
 		want    want
 	}{
@@ -377,6 +383,45 @@ func TestCreateShortLinkJSONBatch(t *testing.T) {
 				response:    map[string]interface{}{"short_url": "http"},
 			},
 		},
+		{
+			name:    "existing url",
+			request: `[{"correlation_id":"1","original_url":"https://exists.com"}]`,
+			auth:    auth,
+			want: want{
+				stastusCode: http.StatusConflict,
+				contenType:  "application/json; charset=utf-8",
+				response:    map[string]interface{}{"short_url": "http://localhost:8080/exists"},
+			},
+		},
+		{
+			name: "per item valid urls",
+			request: `[{"correlation_id":"1","original_url":"https://ya.ru"},` +
+				`{"correlation_id":"2","original_url":"https://exists.com"}]`,
+			query: "?" + BatchMode + "=" + BatchModePerItem,
+			auth:  auth,
+			want: want{
+				stastusCode: http.StatusOK,
+				contenType:  "application/json; charset=utf-8",
+				response: map[string]interface{}{
+					"created":  `"status":"created"`,
+					"existing": `"status":"existing"`,
+				},
+			},
+		},
+		{
+			name:    "per item invalid url",
+			request: `[{"correlation_id":"1","original_url":"https://ya.ru"},{"correlation_id":"2","original_url":"abc"}]`,
+			query:   "?" + BatchMode + "=" + BatchModePerItem,
+			auth:    auth,
+			want: want{
+				stastusCode: http.StatusMultiStatus,
+				contenType:  "application/json; charset=utf-8",
+				response: map[string]interface{}{
+					"created": `"status":"created"`,
+					"invalid": `"status":"invalid","error":"provided string is not valid url"`,
+				},
+			},
+		},
 		{
 			name:    "no auth",
 			request: `[{"correlation_id":"1","original_url":"https://ya.ru"}]`,
@@ -404,17 +449,21 @@ func TestCreateShortLinkJSONBatch(t *testing.T) {
 			cfg := config.Config{
 				BasicPath: config.DefaultBasicPath,
 			}
+			links := repository.NewLinks()
+			_, err := links.SetLink(context.Background(), "https://exists.com", []string{"exists"}, uuid.New())
+			assert.NoError(t, err)
 			interactor := usecases.NewInteractor(
 				context.Background(),
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
-				repository.NewLinks(),
+				links,
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
 			w := httptest.NewRecorder()
 			ctx, _ := gin.CreateTestContext(w)
-			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(tt.request))
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/shorten/batch"+tt.query, strings.NewReader(tt.request))
 
 			tt.auth(ctx)
 
@@ -498,6 +547,7 @@ func TestGetShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -583,6 +633,7 @@ func TestPingDB(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -611,6 +662,35 @@ func TestPingDB(t *testing.T) {
 	}
 }
 
+func TestReady(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		testLogger.Named("interactor"),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	conntroller := NewController(testLogger.Named("controller"), interactor, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ready", http.NoBody)
+
+	conntroller.Ready(ctx)
+
+	result := w.Result()
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+
+	var readiness models.Readiness
+	err = json.NewDecoder(result.Body).Decode(&readiness)
+	assert.NoError(t, err)
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReadinessReady, readiness.Status)
+}
+
 func TestGetShortLinksOfUser(t *testing.T) {
 	testLogger, err := logger.InitLogger()
 	assert.NoError(t, err)
@@ -676,6 +756,7 @@ func TestGetShortLinksOfUser(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -808,9 +889,9 @@ func TestDeleteURLs(t *testing.T) {
 			var urlRepository repository.Repository
 			var file *os.File
 			if tt.file {
-				file, err = os.CreateTemp("./", "*.test")
+				file, err = os.CreateTemp(t.TempDir(), "*.test")
 				assert.NoError(t, err)
-				urlRepository, err = repository.NewLinksWithFile(file.Name())
+				urlRepository, err = repository.NewLinksWithFile(zap.NewNop(), file.Name(), repository.FileConfig{})
 				assert.NoError(t, err)
 			} else {
 				urlRepository = repository.NewLinks()
@@ -836,6 +917,7 @@ func TestDeleteURLs(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				urlRepository,
+				usecases.DeleterConfig{},
 			)
 			conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -933,6 +1015,7 @@ func TestStats(t *testing.T) {
 	tests := []struct {
 		name    string
 		request string
+		realIP  string
 		want    int
 	}{
 		{
@@ -945,6 +1028,12 @@ func TestStats(t *testing.T) {
 			request: "128.0.0.1",
 			want:    http.StatusForbidden,
 		},
+		{
+			name:    "forged header",
+			request: "128.0.0.1",
+			realIP:  "127.0.0.1",
+			want:    http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
@@ -960,6 +1049,7 @@ func TestStats(t *testing.T) {
 				testLogger.Named("interactor"),
 				cfg.BasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR(cfg.TrustedSubnet)
 			assert.NoError(t, err)
@@ -968,7 +1058,10 @@ func TestStats(t *testing.T) {
 			w := httptest.NewRecorder()
 			ctx, _ := gin.CreateTestContext(w)
 			ctx.Request = httptest.NewRequest(http.MethodGet, "/api/internal/stats", http.NoBody)
-			ctx.Request.Header.Add("X-Real-IP", tt.request)
+			ctx.Request.RemoteAddr = tt.request + ":1234"
+			if tt.realIP != "" {
+				ctx.Request.Header.Add("X-Real-IP", tt.realIP)
+			}
 
 			middleware, err := middlewares.NewMiddleware(
 				"../../../public.pem",
@@ -976,6 +1069,7 @@ func TestStats(t *testing.T) {
 				testLogger.Named("middleware"),
 			)
 			assert.NoError(t, err)
+			middleware.ClientIP()(ctx)
 			auth := middleware.Auth()
 			auth(ctx)
 
@@ -990,3 +1084,116 @@ func TestStats(t *testing.T) {
 		})
 	}
 }
+
+// downRepository is a repository which fails while it is down.
+type downRepository struct {
+	*repository.Links
+	down bool
+}
+
+// GetOriginalURL return error if repository is down.
+func (d *downRepository) GetOriginalURL(ctx context.Context, shortLink string) (*string, error) {
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	return d.Links.GetOriginalURL(ctx, shortLink)
+}
+
+// SetLink return error if repository is down.
+func (d *downRepository) SetLink(
+	ctx context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	return d.Links.SetLink(ctx, originalURL, shortURLs, userID)
+}
+
+func TestStorageUnavailable(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+	repo := &downRepository{Links: repository.NewLinks()}
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		testLogger.Named("interactor"),
+		config.DefaultBasicPath,
+		repository.NewBreaker(testLogger.Named("breaker"), repo, repository.BreakerConfig{
+			Threshold:   1,
+			OpenTimeout: time.Minute,
+		}),
+		usecases.DeleterConfig{},
+	)
+	conntroller := NewController(testLogger.Named("controller"), interactor, nil)
+	middleware, err := middlewares.NewMiddleware(
+		"../../../public.pem",
+		"../../../private.pem",
+		testLogger.Named("middleware"),
+	)
+	assert.NoError(t, err)
+
+	createShortLink := func() *http.Response {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://ya.ru"))
+		middleware.Auth()(ctx)
+		conntroller.CreateShortLink(ctx)
+		return w.Result()
+	}
+	getShortLink := func(id string) *http.Response {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/:"+ID, http.NoBody)
+		ctx.Params = []gin.Param{{Key: ID, Value: id}}
+		conntroller.GetShortLink(ctx)
+		return w.Result()
+	}
+
+	result := createShortLink()
+	resultBody, err := io.ReadAll(result.Body)
+	assert.NoError(t, err)
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, result.StatusCode)
+	parsedURL, err := url.ParseRequestURI(string(resultBody))
+	assert.NoError(t, err)
+
+	repo.down = true
+
+	result = createShortLink()
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
+
+	result = createShortLink()
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.Equal(t, "60", result.Header.Get("Retry-After"))
+
+	result = getShortLink(path.Base(parsedURL.Path))
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, result.StatusCode)
+	assert.Equal(t, "https://ya.ru", result.Header.Get("Location"))
+
+	result = getShortLink("unknown")
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.NotEmpty(t, result.Header.Get("Retry-After"))
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["abc123"]`))
+	ctx.Set(middlewares.Authorization, &middlewares.JWT{UserID: uuid.New()})
+	ctx.Set(middlewares.AuthorizationNew, false)
+	conntroller.DeleteURLs(ctx)
+	result = w.Result()
+	err = result.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.NotEmpty(t, result.Header.Get("Retry-After"))
+}
@@ -30,6 +30,7 @@ func ExampleController_CreateShortLink() {
 		testLogger.Named("interactor"),
 		cfg.BasicPath,
 		repository.NewLinks(),
+		usecases.DeleterConfig{},
 	)
 	conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -76,6 +77,7 @@ func ExampleController_CreateShortLinkJSON() {
 		testLogger.Named("interactor"),
 		cfg.BasicPath,
 		repository.NewLinks(),
+		usecases.DeleterConfig{},
 	)
 	conntroller := NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -0,0 +1,188 @@
+//nolint:wrapcheck // errors of grpc
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	pbModel "github.com/RexArseny/url_shortener/internal/app/models/proto/model"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCAdminController is responsible for managing the network interactions of admin service with gRPC.
+// Its methods have to be called after GRPCAuth which checks that user is an administrator.
+type GRPCAdminController struct {
+	pb.UnimplementedAdminServer
+	logger     *zap.Logger
+	interactor usecases.Interactor
+}
+
+// NewGRPCAdminController create new GRPCAdminController.
+func NewGRPCAdminController(logger *zap.Logger, interactor usecases.Interactor) GRPCAdminController {
+	return GRPCAdminController{
+		logger:     logger,
+		interactor: interactor,
+	}
+}
+
+// SearchLinks return links of all users which match query, user and page.
+func (c *GRPCAdminController) SearchLinks(
+	ctx context.Context,
+	in *pbModel.SearchLinksRequest,
+) (*pbModel.SearchLinksResponse, error) {
+	actor, ok := grpcUserID(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+	}
+	var userID uuid.UUID
+	if in.GetUserId() != "" {
+		var err error
+		userID, err = uuid.Parse(in.GetUserId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+	}
+
+	result, err := c.interactor.SearchLinks(ctx, actor, repository.LinkFilter{
+		Query:  in.GetQuery(),
+		UserID: userID,
+		Limit:  int(in.GetLimit()),
+		Offset: int(in.GetOffset()),
+	})
+	if err != nil {
+		return nil, c.adminError(ctx, "Can not search links", err)
+	}
+
+	links := make([]*pbModel.AdminLink, 0, len(result))
+	for i := range result {
+		links = append(links, pbModel.AdminLink_builder{
+			Id:          &result[i].ID,
+			ShortUrl:    &result[i].ShortURL,
+			OriginalUrl: &result[i].OriginalURL,
+			UserId:      ptr(result[i].UserID.String()),
+			CreatedAt:   ptr(result[i].CreatedAt.Format(time.RFC3339Nano)),
+			Deleted:     &result[i].Deleted,
+			Disabled:    &result[i].Disabled,
+		}.Build())
+	}
+	return pbModel.SearchLinksResponse_builder{
+		Links: links,
+	}.Build(), nil
+}
+
+// SetLinkDisabled disable or reenable link of any user.
+func (c *GRPCAdminController) SetLinkDisabled(
+	ctx context.Context,
+	in *pbModel.SetLinkDisabledRequest,
+) (*pbModel.SetLinkDisabledResponse, error) {
+	actor, ok := grpcUserID(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+	}
+
+	_, err := c.interactor.SetLinkDisabled(ctx, actor, in.GetId().GetId(), in.GetDisabled())
+	if err != nil {
+		return nil, c.adminError(ctx, "Can not set link disabled", err)
+	}
+
+	return pbModel.SetLinkDisabledResponse_builder{}.Build(), nil
+}
+
+// GetUsers return users which have links with amount of their links.
+func (c *GRPCAdminController) GetUsers(
+	ctx context.Context,
+	in *pbModel.GetUsersRequest,
+) (*pbModel.GetUsersResponse, error) {
+	actor, ok := grpcUserID(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+	}
+
+	result, err := c.interactor.GetUsers(ctx, actor, int(in.GetLimit()), int(in.GetOffset()))
+	if err != nil {
+		return nil, c.adminError(ctx, "Can not get users", err)
+	}
+
+	users := make([]*pbModel.AdminUser, 0, len(result))
+	for i := range result {
+		users = append(users, pbModel.AdminUser_builder{
+			UserId: ptr(result[i].UserID.String()),
+			Urls:   ptr(int64(result[i].URLs)),
+		}.Build())
+	}
+	return pbModel.GetUsersResponse_builder{
+		Users: users,
+	}.Build(), nil
+}
+
+// TransferLinks move links of any users to another user.
+func (c *GRPCAdminController) TransferLinks(
+	ctx context.Context,
+	in *pbModel.TransferLinksRequest,
+) (*pbModel.TransferLinksResponse, error) {
+	actor, ok := grpcUserID(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+	}
+	to, err := uuid.Parse(in.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	}
+	ids := make([]string, 0, len(in.GetIds()))
+	for _, id := range in.GetIds() {
+		ids = append(ids, id.GetId())
+	}
+
+	transferred, err := c.interactor.TransferLinks(ctx, actor, ids, to)
+	if err != nil {
+		return nil, c.adminError(ctx, "Can not transfer links", err)
+	}
+
+	return pbModel.TransferLinksResponse_builder{
+		Transferred: ptr(int32(transferred)), //nolint:gosec // amount of links of request
+	}.Build(), nil
+}
+
+// adminError return status of failed admin action.
+func (c *GRPCAdminController) adminError(ctx context.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, usecases.ErrInvalidPage) || errors.Is(err, usecases.ErrInvalidTransfer):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, codes.NotFound.String())
+	case errors.Is(err, repository.ErrUnavailable):
+		return unavailable(ctx, c.logger, err)
+	default:
+		c.logger.Error(message, zap.Error(err))
+		return status.Error(codes.Internal, codes.Internal.String())
+	}
+}
+
+// grpcUserID return user of gRPC request which is authenticated by GRPCAuth.
+func grpcUserID(ctx context.Context) (uuid.UUID, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	for _, item := range md.Get(middlewares.UserID) {
+		userID, err := uuid.Parse(item)
+		if err == nil && userID != uuid.Nil {
+			return userID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// ptr return pointer to value.
+func ptr[T any](value T) *T {
+	return &value
+}
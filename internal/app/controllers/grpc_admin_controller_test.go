@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	pbModel "github.com/RexArseny/url_shortener/internal/app/models/proto/model"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAdminController(t *testing.T) {
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	controller := NewGRPCAdminController(zap.NewNop(), interactor)
+	urlController := NewGRPCController(zap.NewNop(), interactor, nil)
+
+	owner := uuid.New()
+	receiver := uuid.NewString()
+	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", owner)
+	assert.NoError(t, err)
+	id := path.Base(*link)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(middlewares.UserID, uuid.NewString()))
+
+	_, err = controller.SearchLinks(context.Background(), pbModel.SearchLinksRequest_builder{}.Build())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	query := "ya.ru"
+	links, err := controller.SearchLinks(ctx, pbModel.SearchLinksRequest_builder{Query: &query}.Build())
+	assert.NoError(t, err)
+	assert.Len(t, links.GetLinks(), 1)
+	assert.Equal(t, id, links.GetLinks()[0].GetId())
+	assert.Equal(t, owner.String(), links.GetLinks()[0].GetUserId())
+
+	limit := int32(-1)
+	_, err = controller.SearchLinks(ctx, pbModel.SearchLinksRequest_builder{Limit: &limit}.Build())
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	disabled := true
+	_, err = controller.SetLinkDisabled(ctx, pbModel.SetLinkDisabledRequest_builder{
+		Id:       pbModel.ID_builder{Id: &id}.Build(),
+		Disabled: &disabled,
+	}.Build())
+	assert.NoError(t, err)
+	_, err = urlController.GetShortLink(ctx, pbModel.GetShortLinkRequest_builder{
+		Id: pbModel.ID_builder{Id: &id}.Build(),
+	}.Build())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	unknown := "unknown"
+	_, err = controller.SetLinkDisabled(ctx, pbModel.SetLinkDisabledRequest_builder{
+		Id:       pbModel.ID_builder{Id: &unknown}.Build(),
+		Disabled: &disabled,
+	}.Build())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	invalid := "abc"
+	_, err = controller.TransferLinks(ctx, pbModel.TransferLinksRequest_builder{
+		Ids:    []*pbModel.ID{pbModel.ID_builder{Id: &id}.Build()},
+		UserId: &invalid,
+	}.Build())
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	transferred, err := controller.TransferLinks(ctx, pbModel.TransferLinksRequest_builder{
+		Ids:    []*pbModel.ID{pbModel.ID_builder{Id: &id}.Build()},
+		UserId: &receiver,
+	}.Build())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), transferred.GetTransferred())
+
+	users, err := controller.GetUsers(ctx, pbModel.GetUsersRequest_builder{}.Build())
+	assert.NoError(t, err)
+	assert.Len(t, users.GetUsers(), 1)
+	assert.Equal(t, receiver, users.GetUsers()[0].GetUserId())
+	assert.Equal(t, int64(1), users.GetUsers()[0].GetUrls())
+}
@@ -4,7 +4,9 @@ package controllers
 import (
 	"context"
 	"errors"
+	"math"
 	"net"
+	"strconv"
 
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
 	"github.com/RexArseny/url_shortener/internal/app/models"
@@ -14,6 +16,7 @@ import (
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -67,6 +70,9 @@ func (c *GRPCController) CreateShortLink(
 		if errors.Is(err, repository.ErrInvalidURL) {
 			return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
 		}
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not create short link", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
@@ -108,6 +114,9 @@ func (c *GRPCController) CreateShortLinkJSON(
 		if errors.Is(err, repository.ErrInvalidURL) {
 			return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
 		}
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not create short link from json", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
@@ -123,6 +132,7 @@ func (c *GRPCController) CreateShortLinkJSON(
 }
 
 // CreateShortLinkJSONBatch create new short URLs from original URLs.
+// In per item mode every item of response carries its own status and error.
 // Generate new JWT if it is not presented.
 func (c *GRPCController) CreateShortLinkJSONBatch(
 	ctx context.Context,
@@ -152,20 +162,30 @@ func (c *GRPCController) CreateShortLinkJSONBatch(
 			OriginalURL:   requests[i].GetOriginalUrl(),
 		})
 	}
-	result, err := c.interactor.CreateShortLinks(ctx, request, userID)
+	result, err := c.interactor.CreateShortLinks(ctx, request, userID, in.GetPerItem())
 	if err != nil {
 		if errors.Is(err, repository.ErrInvalidURL) {
 			return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
 		}
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not create short links", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
 	response := make([]*pbModel.BatchResponse, 0, len(result))
 	for i := range result {
-		response = append(response, pbModel.BatchResponse_builder{
+		item := pbModel.BatchResponse_builder{
 			CorrelationId: &result[i].CorrelationID,
 			ShortUrl:      &result[i].ShortURL,
-		}.Build())
+		}
+		if in.GetPerItem() {
+			item.Status = &result[i].Status
+			if result[i].Error != "" {
+				item.Error = &result[i].Error
+			}
+		}
+		response = append(response, item.Build())
 	}
 	return pbModel.CreateShortLinkJSONBatchResponse_builder{
 		Responses: response,
@@ -182,6 +202,12 @@ func (c *GRPCController) GetShortLink(
 		if errors.Is(err, repository.ErrURLIsDeleted) {
 			return nil, status.Errorf(codes.NotFound, "url is deleted")
 		}
+		if errors.Is(err, repository.ErrURLIsDisabled) {
+			return nil, status.Errorf(codes.NotFound, "url is disabled")
+		}
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		return nil, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
 	}
 	if result == nil || *result == "" {
@@ -242,6 +268,9 @@ func (c *GRPCController) GetShortLinksOfUser(
 	}
 	result, err := c.interactor.GetShortLinksOfUser(ctx, userID)
 	if err != nil {
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not get short links of user", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
@@ -293,6 +322,9 @@ func (c *GRPCController) DeleteURLs(
 	}
 	err := c.interactor.DeleteURLs(ctx, ids, userID)
 	if err != nil {
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not delete urls", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
@@ -305,26 +337,20 @@ func (c *GRPCController) DeleteURLs(
 }
 
 // Stats return statistic of shortened urls and users in service.
+// Address of client is resolved by GRPCClientIP interceptor and has to belong to trusted subnet.
 func (c *GRPCController) Stats(
 	ctx context.Context,
 	in *pbModel.StatsRequest,
 ) (*pbModel.StatsResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
-	}
-	var ip net.IP
-	for _, item := range md.Get("X-Real-IP") {
-		ip = net.ParseIP(item)
-		if ip.String() != "" {
-			break
-		}
-	}
-	if ip.String() == "" || c.trustedSubnet == nil || !c.trustedSubnet.Contains(ip) {
+	ip := net.ParseIP(middlewares.ClientIPFromContext(ctx))
+	if c.trustedSubnet == nil || ip == nil || !c.trustedSubnet.Contains(ip) {
 		return nil, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
 	}
 	stats, err := c.interactor.Stats(ctx)
 	if err != nil {
+		if errors.Is(err, repository.ErrUnavailable) {
+			return nil, unavailable(ctx, c.logger, err)
+		}
 		c.logger.Error("Can not get stats", zap.Error(err))
 		return nil, status.Error(codes.Internal, codes.Internal.String())
 	}
@@ -339,3 +365,16 @@ func (c *GRPCController) Stats(
 		}.Build(),
 	}.Build(), nil
 }
+
+// unavailable return Unavailable status and pass retry-after header to client if it is known.
+func unavailable(ctx context.Context, logger *zap.Logger, err error) error {
+	var unavailable *repository.UnavailableError
+	if errors.As(err, &unavailable) {
+		retryAfter := strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds())))
+		err = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+		if err != nil {
+			logger.Debug("Can not set retry-after header", zap.Error(err))
+		}
+	}
+	return status.Error(codes.Unavailable, codes.Unavailable.String())
+}
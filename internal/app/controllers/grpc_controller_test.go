@@ -11,6 +11,7 @@ import (
 	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/RexArseny/url_shortener/internal/app/logger"
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
 	pbModel "github.com/RexArseny/url_shortener/internal/app/models/proto/model"
 	"github.com/RexArseny/url_shortener/internal/app/repository"
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
@@ -18,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -108,6 +110,7 @@ func TestGRPCControllerCreateShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -211,6 +214,7 @@ func TestGRPCControllerCreateShortLinkJSON(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -232,11 +236,13 @@ func TestGRPCControllerCreateShortLinkJSONBatch(t *testing.T) {
 	originalURL := "https://ya.ru"
 	originalURLInvalid := "abc"
 	correlationID := "1"
+	perItem := true
 	type request struct {
 		in  *pbModel.CreateShortLinkJSONBatchRequest
 		ctx context.Context
 	}
 	type want struct {
+		statuses []string
 		err      bool
 		response bool
 	}
@@ -299,6 +305,30 @@ func TestGRPCControllerCreateShortLinkJSONBatch(t *testing.T) {
 				response: false,
 			},
 		},
+		{
+			name: "invalid url per item",
+			request: request{
+				in: pbModel.CreateShortLinkJSONBatchRequest_builder{
+					Requests: []*pbModel.BatchRequest{
+						pbModel.BatchRequest_builder{
+							CorrelationId: &correlationID,
+							OriginalUrl:   &originalURL,
+						}.Build(),
+						pbModel.BatchRequest_builder{
+							CorrelationId: &correlationID,
+							OriginalUrl:   &originalURLInvalid,
+						}.Build(),
+					},
+					PerItem: &perItem,
+				}.Build(),
+				ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(middlewares.UserID, testUserID.String())),
+			},
+			want: want{
+				err:      false,
+				response: true,
+				statuses: []string{models.BatchStatusCreated, models.BatchStatusInvalid},
+			},
+		},
 		{
 			name: "invalid metadata",
 			request: request{
@@ -327,6 +357,7 @@ func TestGRPCControllerCreateShortLinkJSONBatch(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -341,6 +372,10 @@ func TestGRPCControllerCreateShortLinkJSONBatch(t *testing.T) {
 				assert.NotEmpty(t, resp.GetResponses()[0].GetShortUrl())
 				assert.NotEmpty(t, resp.GetResponses()[0].GetCorrelationId())
 			}
+			for i, status := range tt.want.statuses {
+				assert.Equal(t, status, resp.GetResponses()[i].GetStatus())
+				assert.Equal(t, status != models.BatchStatusCreated, resp.GetResponses()[i].HasError())
+			}
 		})
 	}
 }
@@ -403,6 +438,7 @@ func TestGRPCControllerGetShortLink(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -467,6 +503,7 @@ func TestGRPCControllerPingDB(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -529,6 +566,7 @@ func TestGRPCControllerGetShortLinksOfUser(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -606,6 +644,7 @@ func TestGRPCControllerDeleteURLs(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
@@ -662,18 +701,30 @@ func TestGRPCControllerStats(t *testing.T) {
 		{
 			name: "valid request",
 			request: request{
-				ctx: metadata.NewIncomingContext(
+				ctx: peer.NewContext(metadata.NewIncomingContext(
 					context.Background(),
-					metadata.Pairs(middlewares.UserID, testUserID.String(), "X-Real-IP", "127.0.0.1")),
+					metadata.Pairs(middlewares.UserID, testUserID.String())),
+					&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}}),
 			},
 			err: nil,
 		},
 		{
 			name: "invalid request",
 			request: request{
-				ctx: metadata.NewIncomingContext(
+				ctx: peer.NewContext(metadata.NewIncomingContext(
+					context.Background(),
+					metadata.Pairs(middlewares.UserID, testUserID.String())),
+					&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("128.0.0.1"), Port: 1234}}),
+			},
+			err: status.Error(codes.PermissionDenied, codes.PermissionDenied.String()),
+		},
+		{
+			name: "forged header",
+			request: request{
+				ctx: peer.NewContext(metadata.NewIncomingContext(
 					context.Background(),
-					metadata.Pairs(middlewares.UserID, testUserID.String(), "X-Real-IP", "128.0.0.1")),
+					metadata.Pairs(middlewares.UserID, testUserID.String(), "X-Real-IP", "127.0.0.1")),
+					&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("128.0.0.1"), Port: 1234}}),
 			},
 			err: status.Error(codes.PermissionDenied, codes.PermissionDenied.String()),
 		},
@@ -694,28 +745,77 @@ func TestGRPCControllerStats(t *testing.T) {
 				testLogger.Named("interactor"),
 				config.DefaultBasicPath,
 				repository.NewLinks(),
+				usecases.DeleterConfig{},
 			)
 			_, trustedSubnet, err := net.ParseCIDR("127.0.0.0/24")
 			assert.NoError(t, err)
 			conntroller := NewGRPCController(testLogger.Named("controller"), interactor, trustedSubnet)
-			resp1, err := conntroller.Stats(tt.request.ctx, &pbModel.StatsRequest{})
+			var middleware middlewares.Middleware
+			ctx := tt.request.ctx
+			_, err = middleware.GRPCClientIP(tt.request.ctx, nil, nil,
+				func(resolved context.Context, _ interface{}) (interface{}, error) {
+					ctx = resolved
+					return new(interface{}), nil
+				})
+			assert.NoError(t, err)
+			resp1, err := conntroller.Stats(ctx, &pbModel.StatsRequest{})
 			if tt.err != nil {
 				assert.Equal(t, tt.err.Error(), err.Error())
 				assert.Empty(t, resp1)
 				return
 			}
 			assert.NoError(t, err)
-			data, err := conntroller.CreateShortLink(tt.request.ctx, pbModel.CreateShortLinkRequest_builder{
+			data, err := conntroller.CreateShortLink(ctx, pbModel.CreateShortLinkRequest_builder{
 				OriginalUrl: pbModel.OriginalURL_builder{
 					OriginalUrl: &originalURL,
 				}.Build(),
 			}.Build())
 			assert.NoError(t, err)
 			assert.NotEmpty(t, data.GetShortUrl())
-			resp2, err := conntroller.Stats(tt.request.ctx, &pbModel.StatsRequest{})
+			resp2, err := conntroller.Stats(ctx, &pbModel.StatsRequest{})
 			assert.NoError(t, err)
 			assert.NotEmpty(t, resp2.GetUrls())
 			assert.NotEmpty(t, resp2.GetUsers())
 		})
 	}
 }
+
+func TestGRPCControllerStorageUnavailable(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+	repo := &downRepository{Links: repository.NewLinks(), down: true}
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		testLogger.Named("interactor"),
+		config.DefaultBasicPath,
+		repository.NewBreaker(testLogger.Named("breaker"), repo, repository.BreakerConfig{Threshold: 1}),
+		usecases.DeleterConfig{},
+	)
+	conntroller := NewGRPCController(testLogger.Named("controller"), interactor, nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(middlewares.UserID, uuid.NewString()))
+	originalURL := "https://ya.ru"
+	request := pbModel.CreateShortLinkRequest_builder{
+		OriginalUrl: pbModel.OriginalURL_builder{
+			OriginalUrl: &originalURL,
+		}.Build(),
+	}.Build()
+
+	_, err = conntroller.CreateShortLink(ctx, request)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = conntroller.CreateShortLink(ctx, request)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	id := "unknown"
+	_, err = conntroller.GetShortLink(ctx, pbModel.GetShortLinkRequest_builder{
+		Id: pbModel.ID_builder{
+			Id: &id,
+		}.Build(),
+	}.Build())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = conntroller.DeleteURLs(ctx, pbModel.DeleteURLsRequest_builder{
+		Ids: []*pbModel.ID{pbModel.ID_builder{Id: &id}.Build()},
+	}.Build())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
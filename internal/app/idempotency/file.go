@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Mode to operate with file with idempotency keys.
+const fileMode = 0o600
+
+// FileStore is a store of idempotency keys in memory with completed records persisted in file.
+// File is an append-only log of completed records which is rewritten without expired records on open.
+// Reservations are not persisted, so requests which are in progress on restart can be repeated.
+type FileStore struct {
+	*MemoryStore
+	file    *os.File
+	encoder *json.Encoder
+	m       *sync.Mutex
+}
+
+// NewFileStore create new FileStore and load live records from file.
+func NewFileStore(path string) (*FileStore, error) {
+	records, err := loadRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return nil, fmt.Errorf("can not create temporary file: %w", err)
+	}
+	encoder := json.NewEncoder(tmp)
+	for _, record := range records {
+		err = encoder.Encode(record)
+		if err != nil {
+			_ = tmp.Close()
+			return nil, fmt.Errorf("can not write record: %w", err)
+		}
+	}
+	err = tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can not close temporary file: %w", err)
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return nil, fmt.Errorf("can not replace file: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_APPEND, fileMode)
+	if err != nil {
+		return nil, fmt.Errorf("can not open file: %w", err)
+	}
+
+	memory := NewMemoryStore()
+	for _, record := range records {
+		memory.records[record.Key] = record
+	}
+
+	return &FileStore{
+		MemoryStore: memory,
+		file:        file,
+		encoder:     json.NewEncoder(file),
+		m:           &sync.Mutex{},
+	}, nil
+}
+
+// loadRecords read live records from file.
+// Later records of key replace earlier ones.
+func loadRecords(path string) (map[string]Record, error) {
+	records := make(map[string]Record)
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return records, nil
+		}
+		return nil, fmt.Errorf("can not open file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	now := time.Now()
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var record Record
+		err = decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not decode record: %w", err)
+		}
+		if record.Response == nil || !record.ExpiresAt.After(now) {
+			delete(records, record.Key)
+			continue
+		}
+		records[record.Key] = record
+	}
+	return records, nil
+}
+
+// Complete store response of request with reserved key and append record into file.
+func (f *FileStore) Complete(_ context.Context, key string, response Response) error {
+	record, err := f.complete(key, response)
+	if err != nil {
+		return err
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	err = f.encoder.Encode(record)
+	if err != nil {
+		return fmt.Errorf("can not write record: %w", err)
+	}
+	return nil
+}
+
+// Close close file.
+func (f *FileStore) Close() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	err := f.file.Close()
+	if err != nil {
+		return fmt.Errorf("can not close file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+)
+
+// Names of headers of idempotent requests.
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotency-Replayed"
+)
+
+// purgeInterval is a minimal interval between removals of expired records.
+const purgeInterval = time.Minute
+
+// ErrRecordNotFound is used in case if record of key does not exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// Response is a stored response of request.
+type Response struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+	Status      int    `json:"status"`
+}
+
+// Record is a state of idempotency key.
+// Response is nil while the first request with key is in progress.
+type Record struct {
+	ExpiresAt   time.Time `json:"expires_at"`
+	Response    *Response `json:"response"`
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// Store is an interface of storages of idempotency keys.
+type Store interface {
+	// Begin reserve key for request with fingerprint until expiresAt.
+	// Return true if key is reserved and live record of key if it already exists.
+	Begin(ctx context.Context, key string, fingerprint string, expiresAt time.Time) (Record, bool, error)
+	// Complete store response of request with reserved key.
+	Complete(ctx context.Context, key string, response Response) error
+	// Release remove reservation of key, so request can be repeated.
+	Release(ctx context.Context, key string) error
+	Close() error
+}
+
+// Fingerprint return hash of request which identifies its payload.
+func Fingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// NewStore creates new store of type which depends on configuration.
+// Return nil store if idempotency keys are disabled.
+func NewStore(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch {
+	case cfg.IdempotencyTTL.Duration <= 0:
+		return nil, nil //nolint:nilnil // idempotency keys are disabled
+	case cfg.DatabaseDSN != "" && !strings.HasPrefix(cfg.DatabaseDSN, repository.SQLiteScheme):
+		store, err := NewPostgresStore(ctx, cfg.DatabaseDSN)
+		if err != nil {
+			return nil, fmt.Errorf("can not init postgres store: %w", err)
+		}
+		return store, nil
+	case cfg.IdempotencyPath != "":
+		store, err := NewFileStore(cfg.IdempotencyPath)
+		if err != nil {
+			return nil, fmt.Errorf("can not init file store: %w", err)
+		}
+		return store, nil
+	default:
+		return NewMemoryStore(), nil
+	}
+}
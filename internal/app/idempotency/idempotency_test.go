@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			t.Helper()
+			return NewMemoryStore()
+		},
+		"file": func(t *testing.T) Store {
+			t.Helper()
+			store, err := NewFileStore(filepath.Join(t.TempDir(), "idempotency.jsonl"))
+			assert.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer func() {
+				err := store.Close()
+				assert.NoError(t, err)
+			}()
+			ctx := context.Background()
+			expiresAt := time.Now().Add(time.Hour)
+			response := Response{ContentType: "text/plain", Body: []byte("abc"), Status: 201}
+
+			_, reserved, err := store.Begin(ctx, "key", "fingerprint", expiresAt)
+			assert.NoError(t, err)
+			assert.True(t, reserved)
+
+			record, reserved, err := store.Begin(ctx, "key", "fingerprint", expiresAt)
+			assert.NoError(t, err)
+			assert.False(t, reserved)
+			assert.Equal(t, "fingerprint", record.Fingerprint)
+			assert.Nil(t, record.Response)
+
+			err = store.Release(ctx, "key")
+			assert.NoError(t, err)
+			_, reserved, err = store.Begin(ctx, "key", "fingerprint", expiresAt)
+			assert.NoError(t, err)
+			assert.True(t, reserved)
+
+			err = store.Complete(ctx, "key", response)
+			assert.NoError(t, err)
+			err = store.Release(ctx, "key")
+			assert.NoError(t, err)
+			record, reserved, err = store.Begin(ctx, "key", "another", expiresAt)
+			assert.NoError(t, err)
+			assert.False(t, reserved)
+			assert.Equal(t, "fingerprint", record.Fingerprint)
+			assert.Equal(t, &response, record.Response)
+
+			_, reserved, err = store.Begin(ctx, "expired", "fingerprint", time.Now().Add(-time.Second))
+			assert.NoError(t, err)
+			assert.True(t, reserved)
+			_, reserved, err = store.Begin(ctx, "expired", "fingerprint", expiresAt)
+			assert.NoError(t, err)
+			assert.True(t, reserved)
+
+			err = store.Complete(ctx, "unknown", response)
+			assert.ErrorIs(t, err, ErrRecordNotFound)
+		})
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency.jsonl")
+	ctx := context.Background()
+	response := Response{ContentType: "application/json", Body: []byte(`{"result":"abc"}`), Status: 201}
+
+	store, err := NewFileStore(path)
+	assert.NoError(t, err)
+	for _, key := range []string{"completed", "pending", "expired"} {
+		expiresAt := time.Now().Add(time.Hour)
+		if key == "expired" {
+			expiresAt = time.Now().Add(-time.Second)
+		}
+		_, reserved, err := store.Begin(ctx, key, "fingerprint", expiresAt)
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+		if key != "pending" {
+			err = store.Complete(ctx, key, response)
+			assert.NoError(t, err)
+		}
+	}
+	err = store.Close()
+	assert.NoError(t, err)
+
+	store, err = NewFileStore(path)
+	assert.NoError(t, err)
+	defer func() {
+		err := store.Close()
+		assert.NoError(t, err)
+	}()
+	assert.Len(t, store.records, 1)
+
+	record, reserved, err := store.Begin(ctx, "completed", "fingerprint", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, &response, record.Response)
+
+	_, reserved, err = store.Begin(ctx, "pending", "fingerprint", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestPostgresStore(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+	store := newPostgresStore(mock)
+	store.purgedAt = time.Time{}
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at").
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("key", "fingerprint", expiresAt, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow("key"))
+	_, reserved, err := store.Begin(ctx, "key", "fingerprint", expiresAt)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	status := 201
+	contentType := "text/plain"
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("key", "fingerprint", expiresAt, pgxmock.AnyArg()).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery("SELECT fingerprint, status, content_type, body, expires_at").
+		WithArgs("key").
+		WillReturnRows(pgxmock.NewRows([]string{"fingerprint", "status", "content_type", "body", "expires_at"}).
+			AddRow("fingerprint", &status, &contentType, []byte("abc"), expiresAt))
+	record, reserved, err := store.Begin(ctx, "key", "fingerprint", expiresAt)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, Record{
+		ExpiresAt:   expiresAt,
+		Response:    &Response{ContentType: contentType, Body: []byte("abc"), Status: status},
+		Key:         "key",
+		Fingerprint: "fingerprint",
+	}, record)
+
+	mock.ExpectExec("UPDATE idempotency_keys").
+		WithArgs("key", 201, "text/plain", []byte("abc")).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	err = store.Complete(ctx, "key", Response{ContentType: "text/plain", Body: []byte("abc"), Status: 201})
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE key").
+		WithArgs("key").
+		WillReturnError(errors.New("connection refused"))
+	err = store.Release(ctx, "key")
+	assert.ErrorContains(t, err, "can not release key")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(context.Background(), &config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+
+	store, err = NewStore(context.Background(), &config.Config{
+		IdempotencyTTL: config.Duration{Duration: time.Hour},
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryStore{}, store)
+
+	store, err = NewStore(context.Background(), &config.Config{
+		DatabaseDSN:     repository.SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db"),
+		IdempotencyTTL:  config.Duration{Duration: time.Hour},
+		IdempotencyPath: filepath.Join(t.TempDir(), "idempotency.jsonl"),
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &FileStore{}, store)
+	err = store.Close()
+	assert.NoError(t, err)
+}
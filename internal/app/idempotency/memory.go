@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a store of idempotency keys in memory.
+type MemoryStore struct {
+	purgedAt time.Time
+	records  map[string]Record
+	m        *sync.Mutex
+}
+
+// NewMemoryStore create new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		purgedAt: time.Now(),
+		records:  make(map[string]Record),
+		m:        &sync.Mutex{},
+	}
+}
+
+// Begin reserve key for request with fingerprint until expiresAt.
+func (s *MemoryStore) Begin(
+	_ context.Context,
+	key string,
+	fingerprint string,
+	expiresAt time.Time,
+) (Record, bool, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.purgedAt) >= purgeInterval {
+		for k, record := range s.records {
+			if !record.ExpiresAt.After(now) {
+				delete(s.records, k)
+			}
+		}
+		s.purgedAt = now
+	}
+
+	record, ok := s.records[key]
+	if ok && record.ExpiresAt.After(now) {
+		return record, false, nil
+	}
+
+	s.records[key] = Record{
+		ExpiresAt:   expiresAt,
+		Key:         key,
+		Fingerprint: fingerprint,
+	}
+	return Record{}, true, nil
+}
+
+// Complete store response of request with reserved key.
+func (s *MemoryStore) Complete(_ context.Context, key string, response Response) error {
+	_, err := s.complete(key, response)
+	return err
+}
+
+// complete store response of request with reserved key and return completed record.
+func (s *MemoryStore) complete(key string, response Response) (Record, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return Record{}, ErrRecordNotFound
+	}
+	record.Response = &response
+	s.records[key] = record
+	return record, nil
+}
+
+// Release remove reservation of key, so request can be repeated.
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	record, ok := s.records[key]
+	if ok && record.Response == nil {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// Close does nothing because records are not persisted.
+func (s *MemoryStore) Close() error {
+	return nil
+}
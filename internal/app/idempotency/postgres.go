@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresStore is a store of idempotency keys in idempotency_keys table of database.
+// Expired records are removed at most once per purge interval.
+type PostgresStore struct {
+	purgedAt time.Time
+	pool     repository.IPool
+	m        *sync.Mutex
+}
+
+// NewPostgresStore create new PostgresStore.
+func NewPostgresStore(ctx context.Context, connString string) (*PostgresStore, error) {
+	pool, err := repository.NewPool(ctx, connString, repository.DefaultRetryPolicy())
+	if err != nil {
+		return nil, fmt.Errorf("can not create new pool: %w", err)
+	}
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("can not ping PostgreSQL server: %w", err)
+	}
+	return newPostgresStore(pool), nil
+}
+
+// newPostgresStore create new PostgresStore with provided pool.
+func newPostgresStore(pool repository.IPool) *PostgresStore {
+	return &PostgresStore{
+		purgedAt: time.Now(),
+		pool:     pool,
+		m:        &sync.Mutex{},
+	}
+}
+
+// Begin reserve key for request with fingerprint until expiresAt.
+// Expired record of key is replaced by reservation.
+func (p *PostgresStore) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+	expiresAt time.Time,
+) (Record, bool, error) {
+	now := time.Now()
+	err := p.purge(ctx, now)
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	var reserved string
+	err = p.pool.QueryRow(ctx, `INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+								VALUES ($1, $2, $3)
+								ON CONFLICT (key)
+								DO UPDATE SET fingerprint=EXCLUDED.fingerprint, status=NULL, content_type=NULL,
+								body=NULL, expires_at=EXCLUDED.expires_at
+								WHERE idempotency_keys.expires_at <= $4
+								RETURNING key`, key, fingerprint, expiresAt, now).Scan(&reserved)
+	if err == nil {
+		return Record{}, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Record{}, false, fmt.Errorf("can not reserve key: %w", err)
+	}
+
+	record := Record{Key: key}
+	var status *int
+	var contentType *string
+	var body []byte
+	err = p.pool.QueryRow(ctx, `SELECT fingerprint, status, content_type, body, expires_at
+								FROM idempotency_keys WHERE key=$1`, key).
+		Scan(&record.Fingerprint, &status, &contentType, &body, &record.ExpiresAt)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("can not get record: %w", err)
+	}
+	if status != nil {
+		record.Response = &Response{Status: *status, Body: body}
+		if contentType != nil {
+			record.Response.ContentType = *contentType
+		}
+	}
+	return record, false, nil
+}
+
+// purge remove expired records if purge interval is passed.
+func (p *PostgresStore) purge(ctx context.Context, now time.Time) error {
+	p.m.Lock()
+	if now.Sub(p.purgedAt) < purgeInterval {
+		p.m.Unlock()
+		return nil
+	}
+	p.purgedAt = now
+	p.m.Unlock()
+
+	_, err := p.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return fmt.Errorf("can not delete expired records: %w", err)
+	}
+	return nil
+}
+
+// Complete store response of request with reserved key.
+func (p *PostgresStore) Complete(ctx context.Context, key string, response Response) error {
+	tag, err := p.pool.Exec(ctx, `UPDATE idempotency_keys
+								SET status=$2, content_type=$3, body=$4
+								WHERE key=$1`, key, response.Status, response.ContentType, response.Body)
+	if err != nil {
+		return fmt.Errorf("can not store response: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Release remove reservation of key, so request can be repeated.
+func (p *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE key=$1 AND status IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("can not release key: %w", err)
+	}
+	return nil
+}
+
+// Close close pool.
+func (p *PostgresStore) Close() error {
+	p.pool.Close()
+	return nil
+}
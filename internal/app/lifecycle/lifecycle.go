@@ -0,0 +1,75 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Manager is responsible for graceful shutdown of the service components.
+// Components are stopped in order of their registration within drain timeout.
+type Manager struct {
+	logger       *zap.Logger
+	draining     *atomic.Bool
+	hooks        []hook
+	drainTimeout time.Duration
+}
+
+// hook is a named stop function of component.
+type hook struct {
+	stop func(ctx context.Context) error
+	name string
+}
+
+// NewManager create new Manager.
+func NewManager(logger *zap.Logger, drainTimeout time.Duration) *Manager {
+	return &Manager{
+		logger:       logger,
+		draining:     &atomic.Bool{},
+		drainTimeout: drainTimeout,
+	}
+}
+
+// Add register stop function of component.
+func (m *Manager) Add(name string, stop func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, hook{
+		stop: stop,
+		name: name,
+	})
+}
+
+// Draining return true if shutdown has been started.
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// Shutdown stop all registered components within drain timeout.
+// All components are stopped even if some of them return error.
+func (m *Manager) Shutdown() error {
+	if m.draining.Swap(true) {
+		return errors.New("shutdown has been already started")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, h := range m.hooks {
+		start := time.Now()
+		err := h.stop(ctx)
+		if err != nil {
+			m.logger.Error("Can not stop component", zap.String("component", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("can not stop %s: %w", h.name, err))
+			continue
+		}
+		m.logger.Info("Component stopped",
+			zap.String("component", h.name),
+			zap.Duration("latency", time.Since(start)))
+	}
+
+	return errors.Join(errs...)
+}
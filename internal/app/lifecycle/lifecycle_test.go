@@ -0,0 +1,74 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestManagerShutdown(t *testing.T) {
+	t.Run("components are stopped in order of registration", func(t *testing.T) {
+		manager := NewManager(zap.NewNop(), time.Second)
+
+		var stopped []string
+		manager.Add("first", func(context.Context) error {
+			stopped = append(stopped, "first")
+			return nil
+		})
+		manager.Add("second", func(context.Context) error {
+			stopped = append(stopped, "second")
+			return nil
+		})
+
+		assert.False(t, manager.Draining())
+
+		err := manager.Shutdown()
+		assert.NoError(t, err)
+		assert.True(t, manager.Draining())
+		assert.Equal(t, []string{"first", "second"}, stopped)
+	})
+
+	t.Run("all components are stopped even if some of them fail", func(t *testing.T) {
+		manager := NewManager(zap.NewNop(), time.Second)
+
+		var stopped bool
+		manager.Add("failed", func(context.Context) error {
+			return errors.New("test")
+		})
+		manager.Add("last", func(context.Context) error {
+			stopped = true
+			return nil
+		})
+
+		err := manager.Shutdown()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "can not stop failed")
+		assert.True(t, stopped)
+	})
+
+	t.Run("components are limited by drain timeout", func(t *testing.T) {
+		manager := NewManager(zap.NewNop(), time.Millisecond*10)
+
+		manager.Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := manager.Shutdown()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("repeated shutdown", func(t *testing.T) {
+		manager := NewManager(zap.NewNop(), time.Second)
+
+		err := manager.Shutdown()
+		assert.NoError(t, err)
+
+		err = manager.Shutdown()
+		assert.Error(t, err)
+	})
+}
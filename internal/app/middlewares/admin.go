@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// adminMethodsPrefix is a prefix of full names of methods of gRPC admin service.
+var adminMethodsPrefix = "/" + pb.Admin_ServiceDesc.ServiceName + "/"
+
+// UseAdmins set users which are allowed to use admin API.
+// JWT of such users carry admin role.
+func (m *Middleware) UseAdmins(userIDs []uuid.UUID) {
+	m.admins = make(map[uuid.UUID]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		m.admins[userID] = struct{}{}
+	}
+}
+
+// isAdmin check that user is still allowed to use admin API.
+func (m *Middleware) isAdmin(userID uuid.UUID) bool {
+	_, ok := m.admins[userID]
+	return ok
+}
+
+// roles return roles of user which are put into JWT.
+func (m *Middleware) roles(userID uuid.UUID) []string {
+	if m.isAdmin(userID) {
+		return []string{models.RoleAdmin}
+	}
+	return nil
+}
+
+// HasRole check that JWT carries role.
+func (j *JWT) HasRole(role string) bool {
+	return slices.Contains(j.Roles, role)
+}
+
+// RequireAdmin reject requests of users which are not administrators.
+// Request has to be authenticated by JWT with admin role or by API key with admin scope,
+// and user has to be still an administrator. It has to be used after Auth.
+func (m *Middleware) RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, ok := ctx.Value(Authorization).(*JWT)
+		if !ok || ctx.GetBool(AuthorizationNew) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			ctx.Abort()
+			return
+		}
+
+		var granted bool
+		if value, ok := ctx.Get(APIKeyScopes); ok {
+			scopes, _ := value.([]string)
+			granted = slices.Contains(scopes, models.ScopeAdmin)
+		} else {
+			granted = token.HasRole(models.RoleAdmin)
+		}
+		if !granted || !m.isAdmin(token.UserID) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// isAdminMethod check that gRPC method belongs to admin service.
+func isAdminMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, adminMethodsPrefix)
+}
@@ -0,0 +1,209 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminTokens return tokens of administrator, of user and of former administrator.
+func adminTokens(t *testing.T, middleware *Middleware, admin uuid.UUID, user uuid.UUID) (string, string, string) {
+	former := uuid.New()
+	middleware.UseAdmins([]uuid.UUID{admin, former})
+
+	adminToken, claims, err := middleware.newToken(admin)
+	assert.NoError(t, err)
+	assert.True(t, claims.HasRole(models.RoleAdmin))
+	userToken, claims, err := middleware.newToken(user)
+	assert.NoError(t, err)
+	assert.False(t, claims.HasRole(models.RoleAdmin))
+	formerToken, _, err := middleware.newToken(former)
+	assert.NoError(t, err)
+
+	middleware.UseAdmins([]uuid.UUID{admin})
+	return adminToken, userToken, formerToken
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admin := uuid.New()
+	user := uuid.New()
+	authenticator := &testAuthenticator{key: usecases.APIKeyPrefix + "key"}
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+	middleware.UseAPIKeys(authenticator)
+	adminToken, userToken, formerToken := adminTokens(t, middleware, admin, user)
+
+	router := gin.New()
+	router.Use(middleware.Auth())
+	router.GET("/admin", middleware.RequireAdmin(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		keyUserID  uuid.UUID
+		keyScopes  []string
+		statusCode int
+	}{
+		{
+			name:       "admin token",
+			header:     Authorization,
+			value:      "Bearer " + adminToken,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "user token",
+			header:     Authorization,
+			value:      "Bearer " + userToken,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "token of former admin",
+			header:     Authorization,
+			value:      "Bearer " + formerToken,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "key with admin scope",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			keyUserID:  admin,
+			keyScopes:  []string{models.ScopeAdmin},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "key without admin scope",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			keyUserID:  admin,
+			keyScopes:  usecases.Scopes,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "key of user with admin scope",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			keyUserID:  user,
+			keyScopes:  []string{models.ScopeAdmin},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "anonymous",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator.userID = tt.keyUserID
+			authenticator.scopes = tt.keyScopes
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/admin", http.NoBody)
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.value)
+			}
+
+			router.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestGRPCAuthAdmin(t *testing.T) {
+	admin := uuid.New()
+	user := uuid.New()
+	authenticator := &testAuthenticator{key: usecases.APIKeyPrefix + "key"}
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+	middleware.UseAPIKeys(authenticator)
+	adminToken, userToken, formerToken := adminTokens(t, middleware, admin, user)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, []string{admin.String()}, md.Get(UserID))
+		return new(interface{}), nil
+	}
+
+	tests := []struct {
+		name      string
+		md        metadata.MD
+		keyUserID uuid.UUID
+		keyScopes []string
+		code      codes.Code
+	}{
+		{
+			name: "admin token",
+			md:   metadata.Pairs(Authorization, "Bearer "+adminToken),
+			code: codes.OK,
+		},
+		{
+			name: "user token",
+			md:   metadata.Pairs(Authorization, "Bearer "+userToken),
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "token of former admin",
+			md:   metadata.Pairs(Authorization, formerToken),
+			code: codes.PermissionDenied,
+		},
+		{
+			name:      "key with admin scope",
+			md:        metadata.Pairs(APIKeyHeader, authenticator.key),
+			keyUserID: admin,
+			keyScopes: []string{models.ScopeAdmin},
+			code:      codes.OK,
+		},
+		{
+			name:      "key without admin scope",
+			md:        metadata.Pairs(APIKeyHeader, authenticator.key),
+			keyUserID: admin,
+			keyScopes: usecases.Scopes,
+			code:      codes.PermissionDenied,
+		},
+		{
+			name:      "key of user with admin scope",
+			md:        metadata.Pairs(APIKeyHeader, authenticator.key),
+			keyUserID: user,
+			keyScopes: []string{models.ScopeAdmin},
+			code:      codes.PermissionDenied,
+		},
+		{
+			name: "anonymous",
+			md:   metadata.MD{},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator.userID = tt.keyUserID
+			authenticator.scopes = tt.keyScopes
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			_, err := middleware.GRPCAuth(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: pb.Admin_SearchLinks_FullMethodName},
+				handler,
+			)
+
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Names of API key constants.
+const (
+	APIKeyHeader = "X-API-Key"
+	APIKeyScopes = "APIKeyScopes"
+	bearerPrefix = "Bearer "
+)
+
+// methodScopes are scopes of API keys which are required by gRPC methods.
+// Methods which are not presented do not require any scope.
+var methodScopes = map[string]string{
+	pb.URLShortener_CreateShortLink_FullMethodName:          models.ScopeCreate,
+	pb.URLShortener_CreateShortLinkJSON_FullMethodName:      models.ScopeCreate,
+	pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName: models.ScopeCreate,
+	pb.URLShortener_GetShortLinksOfUser_FullMethodName:      models.ScopeRead,
+	pb.URLShortener_DeleteURLs_FullMethodName:               models.ScopeDelete,
+	pb.Admin_SearchLinks_FullMethodName:                     models.ScopeAdmin,
+	pb.Admin_SetLinkDisabled_FullMethodName:                 models.ScopeAdmin,
+	pb.Admin_GetUsers_FullMethodName:                        models.ScopeAdmin,
+	pb.Admin_TransferLinks_FullMethodName:                   models.ScopeAdmin,
+}
+
+// APIKeyAuthenticator is an interface of authenticators of API keys.
+type APIKeyAuthenticator interface {
+	// AuthenticateAPIKey return user and scopes of API key.
+	AuthenticateAPIKey(ctx context.Context, key string) (uuid.UUID, []string, error)
+}
+
+// UseAPIKeys turn on authentication by API keys in Auth and GRPCAuth.
+func (m *Middleware) UseAPIKeys(authenticator APIKeyAuthenticator) {
+	m.apiKeys = authenticator
+}
+
+// apiKey return API key from X-API-Key header or from Authorization header with Bearer scheme.
+// Bearer tokens which are not API keys are ignored.
+func apiKey(headers []string, authorizations []string) string {
+	for _, header := range headers {
+		if header != "" {
+			return header
+		}
+	}
+	token, ok := bearerToken(authorizations)
+	if ok && strings.HasPrefix(token, usecases.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authAPIKey authenticate request by API key.
+// Cookie is not set for such requests.
+func (m *Middleware) authAPIKey(ctx *gin.Context, key string) {
+	userID, scopes, err := m.apiKeys.AuthenticateAPIKey(ctx.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidAPIKey) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			ctx.Abort()
+			return
+		}
+		m.logger.Error("Can not authenticate api key", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set(Authorization, &JWT{UserID: userID})
+	ctx.Set(AuthorizationNew, false)
+	ctx.Set(APIKeyScopes, scopes)
+	withOrigin(ctx, usecases.AuthMethodAPIKey)
+
+	ctx.Next()
+}
+
+// RequireScope reject requests which are authenticated by API key without scope.
+// It has to be used after Auth.
+func (m *Middleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, ok := ctx.Get(APIKeyScopes)
+		if !ok {
+			ctx.Next()
+			return
+		}
+		scopes, ok := value.([]string)
+		if !ok || !slices.Contains(scopes, scope) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// DenyAPIKeys reject requests which are authenticated by API key.
+// It has to be used after Auth.
+func (m *Middleware) DenyAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(APIKeyScopes); ok {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// gRPCAPIKeyAuth authenticate gRPC request by API key and check scope of method.
+func (m *Middleware) gRPCAPIKeyAuth(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+	md metadata.MD,
+	key string,
+) (interface{}, error) {
+	userID, scopes, err := m.apiKeys.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidAPIKey) {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid api key")
+		}
+		m.logger.Error("Can not authenticate api key", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "internal error")
+	}
+	if scope, ok := methodScopes[info.FullMethod]; ok && !slices.Contains(scopes, scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "api key has no %s scope", scope)
+	}
+	if isAdminMethod(info.FullMethod) && !m.isAdmin(userID) {
+		return nil, status.Errorf(codes.PermissionDenied, "user is not an administrator")
+	}
+
+	md.Set(UserID, []string{userID.String()}...)
+	md.Set(AuthorizationNew, []string{}...)
+
+	ctx = metadata.NewIncomingContext(ctx, md)
+	ctx = withGRPCOrigin(ctx, md, usecases.AuthMethodAPIKey)
+
+	return handler(ctx, req)
+}
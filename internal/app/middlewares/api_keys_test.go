@@ -0,0 +1,221 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testAuthenticator is an authenticator of the only API key.
+type testAuthenticator struct {
+	key    string
+	scopes []string
+	userID uuid.UUID
+}
+
+// AuthenticateAPIKey return user and scopes of the only API key.
+func (a *testAuthenticator) AuthenticateAPIKey(_ context.Context, key string) (uuid.UUID, []string, error) {
+	switch key {
+	case a.key:
+		return a.userID, a.scopes, nil
+	case usecases.APIKeyPrefix + "failure":
+		return uuid.Nil, nil, errors.New("connection refused")
+	default:
+		return uuid.Nil, nil, usecases.ErrInvalidAPIKey
+	}
+}
+
+func TestAuthAPIKey(t *testing.T) {
+	authenticator := &testAuthenticator{
+		key:    usecases.APIKeyPrefix + "key",
+		scopes: []string{models.ScopeRead},
+		userID: uuid.New(),
+	}
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+	middleware.UseAPIKeys(authenticator)
+
+	router := gin.New()
+	router.Use(middleware.Auth())
+	router.GET("/read", middleware.RequireScope(models.ScopeRead), func(ctx *gin.Context) {
+		token, ok := ctx.Value(Authorization).(*JWT)
+		assert.True(t, ok)
+		assert.Equal(t, authenticator.userID, token.UserID)
+		assert.False(t, ctx.GetBool(AuthorizationNew))
+		ctx.Status(http.StatusOK)
+	})
+	router.POST("/create", middleware.RequireScope(models.ScopeCreate), func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+	router.GET("/keys", middleware.DenyAPIKeys(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		value      string
+		statusCode int
+		cookie     bool
+	}{
+		{
+			name:       "key in X-API-Key header",
+			method:     http.MethodGet,
+			path:       "/read",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "key in Authorization header",
+			method:     http.MethodGet,
+			path:       "/read",
+			header:     Authorization,
+			value:      "Bearer " + authenticator.key,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "key without scope",
+			method:     http.MethodPost,
+			path:       "/create",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "key on route without API keys",
+			method:     http.MethodGet,
+			path:       "/keys",
+			header:     APIKeyHeader,
+			value:      authenticator.key,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "invalid key",
+			method:     http.MethodGet,
+			path:       "/read",
+			header:     APIKeyHeader,
+			value:      usecases.APIKeyPrefix + "invalid",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "failed authentication",
+			method:     http.MethodGet,
+			path:       "/read",
+			header:     APIKeyHeader,
+			value:      usecases.APIKeyPrefix + "failure",
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "bearer token which is not key",
+			method:     http.MethodPost,
+			path:       "/create",
+			header:     Authorization,
+			value:      "Bearer token",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "without key",
+			method:     http.MethodPost,
+			path:       "/create",
+			statusCode: http.StatusCreated,
+			cookie:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.value)
+			}
+
+			router.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.cookie, w.Header().Get("Set-Cookie") != "")
+		})
+	}
+}
+
+func TestGRPCAuthAPIKey(t *testing.T) {
+	authenticator := &testAuthenticator{
+		key:    usecases.APIKeyPrefix + "key",
+		scopes: []string{models.ScopeRead},
+		userID: uuid.New(),
+	}
+	middleware := &Middleware{logger: zap.NewNop()}
+	middleware.UseAPIKeys(authenticator)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, []string{authenticator.userID.String()}, md.Get(UserID))
+		assert.Empty(t, md.Get(AuthorizationNew))
+		return new(interface{}), nil
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		code   codes.Code
+	}{
+		{
+			name:   "key in x-api-key metadata",
+			method: pb.URLShortener_GetShortLinksOfUser_FullMethodName,
+			md:     metadata.Pairs(APIKeyHeader, authenticator.key),
+			code:   codes.OK,
+		},
+		{
+			name:   "key in authorization metadata",
+			method: pb.URLShortener_GetShortLinksOfUser_FullMethodName,
+			md:     metadata.Pairs(Authorization, "Bearer "+authenticator.key),
+			code:   codes.OK,
+		},
+		{
+			name:   "method without scope",
+			method: pb.URLShortener_GetShortLink_FullMethodName,
+			md:     metadata.Pairs(APIKeyHeader, authenticator.key),
+			code:   codes.OK,
+		},
+		{
+			name:   "key without scope",
+			method: pb.URLShortener_DeleteURLs_FullMethodName,
+			md:     metadata.Pairs(APIKeyHeader, authenticator.key),
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "invalid key",
+			method: pb.URLShortener_GetShortLinksOfUser_FullMethodName,
+			md:     metadata.Pairs(APIKeyHeader, usecases.APIKeyPrefix+"invalid"),
+			code:   codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			_, err := middleware.GRPCAuth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
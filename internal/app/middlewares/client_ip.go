@@ -0,0 +1,133 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Names of headers of proxies with address of client.
+const (
+	ForwardedForHeader = "X-Forwarded-For"
+	RealIPHeader       = "X-Real-IP"
+)
+
+// clientIPKey is a key of resolved IP address of client in context.
+type clientIPKey struct{}
+
+// TrustProxies set CIDRs of proxies whose X-Forwarded-For and X-Real-IP headers are trusted.
+// Without trusted proxies address of client is always an address of peer.
+func (m *Middleware) TrustProxies(cidrs []string) error {
+	proxies := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, proxy, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return fmt.Errorf("can not parse cidr of trusted proxy: %w", err)
+		}
+		proxies = append(proxies, proxy)
+	}
+	m.trustedProxies = proxies
+	return nil
+}
+
+// ClientIP resolve IP address of client of HTTP request and store it in context of request.
+// It has to be used before other middlewares, so all of them and handlers see the same address.
+func (m *Middleware) ClientIP() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ip := m.clientIP(
+			ctx.Request.RemoteAddr,
+			ctx.Request.Header.Values(ForwardedForHeader),
+			ctx.Request.Header.Values(RealIPHeader),
+		)
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), clientIPKey{}, ip))
+
+		ctx.Next()
+	}
+}
+
+// GRPCClientIP resolve IP address of client of gRPC request and store it in context of request.
+func (m *Middleware) GRPCClientIP(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	ip := m.clientIP(remoteAddr, md.Get(ForwardedForHeader), md.Get(RealIPHeader))
+
+	return handler(context.WithValue(ctx, clientIPKey{}, ip), req)
+}
+
+// ClientIPFromContext return IP address of client which is resolved by ClientIP or GRPCClientIP.
+// It is empty if address is unknown.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// clientIP return IP address of client from address of peer and headers of proxies.
+// Headers are used only if peer is a trusted proxy. X-Forwarded-For is read from right to left
+// skipping trusted proxies, X-Real-IP is used if there is no X-Forwarded-For.
+func (m *Middleware) clientIP(remoteAddr string, forwardedFor []string, realIP []string) string {
+	ip := parseIP(remoteAddr)
+	if ip == nil {
+		return ""
+	}
+	if !m.trusted(ip) {
+		return ip.String()
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for j := len(hops) - 1; j >= 0; j-- {
+		hop := parseIP(hops[j])
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !m.trusted(hop) {
+			return ip.String()
+		}
+	}
+	if len(hops) != 0 {
+		return ip.String()
+	}
+
+	for _, header := range realIP {
+		if addr := parseIP(header); addr != nil {
+			return addr.String()
+		}
+	}
+	return ip.String()
+}
+
+// trusted check that IP address belongs to trusted proxy.
+func (m *Middleware) trusted(ip net.IP) bool {
+	for _, proxy := range m.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parse IP address with or without port.
+func parseIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(value)
+}
@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestTrustProxies(t *testing.T) {
+	middleware := &Middleware{}
+
+	err := middleware.TrustProxies([]string{"10.0.0.0/8", " 2001:db8::/32"})
+	assert.NoError(t, err)
+	assert.Len(t, middleware.trustedProxies, 2)
+
+	err = middleware.TrustProxies([]string{"10.0.0.1"})
+	assert.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       []string
+		want         string
+	}{
+		{
+			name:       "untrusted peer",
+			remoteAddr: "192.0.2.1:1234",
+			realIP:     []string{"198.51.100.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:         "untrusted peer with forwarded for",
+			remoteAddr:   "192.0.2.1:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "192.0.2.1",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "trusted peer with real ip",
+			remoteAddr: "10.0.0.1:1234",
+			realIP:     []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:         "trusted peer with forwarded for",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1, 198.51.100.1", "10.0.0.2"},
+			realIP:       []string{"203.0.113.2"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "all hops are trusted",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			want:         "10.0.0.3",
+		},
+		{
+			name:         "invalid hop",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"unknown, 10.0.0.2"},
+			want:         "10.0.0.2",
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::1]:1234",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "unknown peer",
+			remoteAddr: "",
+			realIP:     []string{"198.51.100.1"},
+			want:       "",
+		},
+	}
+
+	middleware := &Middleware{}
+	err := middleware.TrustProxies([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			ctx.Request.RemoteAddr = tt.remoteAddr
+			for _, header := range tt.forwardedFor {
+				ctx.Request.Header.Add(ForwardedForHeader, header)
+			}
+			for _, header := range tt.realIP {
+				ctx.Request.Header.Add(RealIPHeader, header)
+			}
+
+			middleware.ClientIP()(ctx)
+
+			assert.Equal(t, tt.want, ClientIPFromContext(ctx.Request.Context()))
+		})
+	}
+}
+
+func TestGRPCClientIP(t *testing.T) {
+	middleware := &Middleware{}
+	err := middleware.TrustProxies([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	resolve := func(ctx context.Context) string {
+		var ip string
+		_, err := middleware.GRPCClientIP(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "test"},
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				ip = ClientIPFromContext(ctx)
+				return new(interface{}), nil
+			})
+		assert.NoError(t, err)
+		return ip
+	}
+	withPeer := func(ip string, md metadata.MD) context.Context {
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+	}
+
+	assert.Equal(t, "192.0.2.1", resolve(withPeer("192.0.2.1", metadata.Pairs(RealIPHeader, "198.51.100.1"))))
+	assert.Equal(t, "198.51.100.1", resolve(withPeer("10.0.0.1", metadata.Pairs(RealIPHeader, "198.51.100.1"))))
+	assert.Equal(t, "198.51.100.1", resolve(withPeer("10.0.0.1", metadata.Pairs(ForwardedForHeader, "198.51.100.1"))))
+	assert.Empty(t, resolve(context.Background()))
+}
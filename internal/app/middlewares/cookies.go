@@ -0,0 +1,141 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of cookie constants.
+const (
+	sameSiteLax    = "lax"
+	sameSiteStrict = "strict"
+	sameSiteNone   = "none"
+	secFetchSite   = "Sec-Fetch-Site"
+)
+
+// Errors of cookies.
+var (
+	ErrInvalidSameSite  = errors.New("invalid samesite, it has to be lax, strict, none or empty")
+	ErrInsecureSameSite = errors.New("samesite none requires secure cookies")
+)
+
+// CookieConfig is a set of attributes of cookies with tokens.
+// Zero MaxAge makes session cookies.
+type CookieConfig struct {
+	Domain   string
+	Path     string
+	MaxAge   time.Duration
+	SameSite http.SameSite
+	Secure   bool
+	HTTPOnly bool
+}
+
+// DefaultCookieConfig is a cookie config of Middleware until other is used.
+var DefaultCookieConfig = CookieConfig{
+	Path:     "/",
+	MaxAge:   maxAge * time.Second,
+	SameSite: http.SameSiteLaxMode,
+	HTTPOnly: true,
+}
+
+// ParseSameSite parse SameSite attribute of cookie from lax, strict, none or empty string.
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case sameSiteLax:
+		return http.SameSiteLaxMode, nil
+	case sameSiteStrict:
+		return http.SameSiteStrictMode, nil
+	case sameSiteNone:
+		return http.SameSiteNoneMode, nil
+	case "":
+		return http.SameSiteDefaultMode, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSameSite, value)
+	}
+}
+
+// UseCookies set attributes of cookies with tokens.
+func (m *Middleware) UseCookies(cfg CookieConfig) error {
+	if cfg.SameSite == http.SameSiteNoneMode && !cfg.Secure {
+		return ErrInsecureSameSite
+	}
+	m.cookie = cfg
+	return nil
+}
+
+// TrustOrigins allow mutating requests authenticated by cookie from origins like https://example.com.
+func (m *Middleware) TrustOrigins(origins []string) {
+	m.trustedOrigins = make([]string, 0, len(origins))
+	for _, origin := range origins {
+		m.trustedOrigins = append(m.trustedOrigins, strings.TrimSuffix(origin, "/"))
+	}
+}
+
+// setCookie put JWT in cookie.
+func (m *Middleware) setCookie(ctx *gin.Context, tokenString string) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     Authorization,
+		Value:    url.QueryEscape(tokenString),
+		Path:     m.cookie.Path,
+		Domain:   m.cookie.Domain,
+		MaxAge:   int(m.cookie.MaxAge.Seconds()),
+		Secure:   m.cookie.Secure,
+		HttpOnly: m.cookie.HTTPOnly,
+		SameSite: m.cookie.SameSite,
+	})
+}
+
+// setRefreshCookie put refresh token in cookie which is sent only to refresh endpoints.
+// Negative maxAge deletes cookie.
+func (m *Middleware) setRefreshCookie(ctx *gin.Context, refreshToken string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     RefreshToken,
+		Value:    url.QueryEscape(refreshToken),
+		Path:     RefreshTokenPath,
+		Domain:   m.cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.cookie.Secure,
+		HttpOnly: true,
+		SameSite: m.cookie.SameSite,
+	})
+}
+
+// sameOrigin check that mutating request authenticated by cookie is not cross-site request forgery.
+// Request is allowed if browser reports it as same origin by Sec-Fetch-Site header,
+// its Origin header is trusted or matches host of request, or it is sent by client
+// which is not browser, so it has neither of headers.
+func (m *Middleware) sameOrigin(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	origin := request.Header.Get("Origin")
+	if origin != "" && slices.Contains(m.trustedOrigins, origin) {
+		return true
+	}
+
+	switch request.Header.Get(secFetchSite) {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	if origin == "" {
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return originURL.Host != "" && originURL.Host == request.Host
+}
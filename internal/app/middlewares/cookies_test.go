@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseSameSite(t *testing.T) {
+	sameSite, err := ParseSameSite("Strict")
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteStrictMode, sameSite)
+
+	sameSite, err = ParseSameSite("")
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteDefaultMode, sameSite)
+
+	_, err = ParseSameSite("abc")
+	assert.ErrorIs(t, err, ErrInvalidSameSite)
+}
+
+func TestCookies(t *testing.T) {
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+
+	err := middleware.UseCookies(CookieConfig{SameSite: http.SameSiteNoneMode})
+	assert.ErrorIs(t, err, ErrInsecureSameSite)
+
+	err = middleware.UseCookies(CookieConfig{
+		Domain:   "example.com",
+		Path:     "/",
+		MaxAge:   time.Hour,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   true,
+		HTTPOnly: true,
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	middleware.Auth()(ctx)
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, Authorization, cookies[0].Name)
+	assert.Equal(t, "example.com", cookies[0].Domain)
+	assert.Equal(t, 3600, cookies[0].MaxAge)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.True(t, cookies[0].Secure)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestCSRF(t *testing.T) {
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+	middleware.TrustOrigins([]string{"https://app.example.com/"})
+	tokenString, _, err := middleware.newToken(uuid.New())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		headers map[string]string
+		name    string
+		method  string
+		cookie  bool
+		status  int
+	}{
+		{
+			name:   "safe method",
+			method: http.MethodGet,
+			cookie: true,
+			headers: map[string]string{
+				"Origin":     "https://evil.com",
+				secFetchSite: "cross-site",
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:    "client which is not browser",
+			method:  http.MethodDelete,
+			cookie:  true,
+			headers: map[string]string{},
+			status:  http.StatusOK,
+		},
+		{
+			name:   "same origin",
+			method: http.MethodDelete,
+			cookie: true,
+			headers: map[string]string{
+				"Origin":     "https://evil.com",
+				secFetchSite: "same-origin",
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "cross site",
+			method: http.MethodDelete,
+			cookie: true,
+			headers: map[string]string{
+				"Origin":     "https://evil.com",
+				secFetchSite: "cross-site",
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "trusted origin",
+			method: http.MethodDelete,
+			cookie: true,
+			headers: map[string]string{
+				"Origin":     "https://app.example.com",
+				secFetchSite: "same-site",
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:    "origin of host",
+			method:  http.MethodPost,
+			cookie:  true,
+			headers: map[string]string{"Origin": "http://example.com"},
+			status:  http.StatusOK,
+		},
+		{
+			name:    "other origin",
+			method:  http.MethodPost,
+			cookie:  true,
+			headers: map[string]string{"Origin": "http://evil.com"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "null origin",
+			method:  http.MethodPost,
+			cookie:  true,
+			headers: map[string]string{"Origin": "null"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:   "without cookie",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Origin":     "https://evil.com",
+				secFetchSite: "cross-site",
+			},
+			status: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(tt.method, "http://example.com/api/user/urls", http.NoBody)
+			for key, value := range tt.headers {
+				ctx.Request.Header.Set(key, value)
+			}
+			if tt.cookie {
+				ctx.Request.AddCookie(&http.Cookie{Name: Authorization, Value: tokenString})
+			}
+
+			middleware.Auth()(ctx)
+
+			assert.Equal(t, tt.status, ctx.Writer.Status())
+			assert.Equal(t, tt.status != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
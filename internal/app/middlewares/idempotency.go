@@ -0,0 +1,304 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/idempotency"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// maxIdempotencyKeyLength is a max length of idempotency key.
+const maxIdempotencyKeyLength = 255
+
+// anonymousScope is a scope of idempotency keys of requests without JWT.
+const anonymousScope = "anonymous"
+
+// idempotentMethods are gRPC methods which support idempotency keys.
+var idempotentMethods = []string{
+	pb.URLShortener_CreateShortLink_FullMethodName,
+	pb.URLShortener_CreateShortLinkJSON_FullMethodName,
+	pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName,
+}
+
+// retryableCodes are codes of gRPC errors after which request with idempotency key can be repeated.
+var retryableCodes = []codes.Code{
+	codes.Canceled,
+	codes.Unknown,
+	codes.DeadlineExceeded,
+	codes.ResourceExhausted,
+	codes.Aborted,
+	codes.Internal,
+	codes.Unavailable,
+	codes.DataLoss,
+}
+
+// Idempotency replays responses of requests which are repeated with the same idempotency key.
+// Keys are scoped by user, so different users can use the same keys.
+type Idempotency struct {
+	store  idempotency.Store
+	logger *zap.Logger
+	ttl    time.Duration
+}
+
+// NewIdempotency create new Idempotency.
+func NewIdempotency(logger *zap.Logger, store idempotency.Store, ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		store:  store,
+		logger: logger,
+		ttl:    ttl,
+	}
+}
+
+// responseRecorder is a wrapper for gin.ResponseWriter which keeps copy of response body.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// WriteString write string into writer and keep its copy.
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s) //nolint:wrapcheck // error of underlying writer
+}
+
+// Write write bytes into writer and keep their copy.
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data) //nolint:wrapcheck // error of underlying writer
+}
+
+// Handler replay stored response if request has Idempotency-Key header which is already used.
+// It has to be used after Auth.
+// Responses with server errors and 429 are not stored, so such requests can be repeated.
+func (i *Idempotency) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotency.Header)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "idempotency key is too long"})
+			ctx.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := anonymousScope
+		if token, ok := ctx.Value(Authorization).(*JWT); ok && !ctx.GetBool(AuthorizationNew) {
+			scope = token.UserID.String()
+		}
+		key = scope + ":" + key
+		fingerprint := idempotency.Fingerprint(ctx.Request.Method, ctx.Request.URL.RequestURI(), body)
+
+		record, reserved, err := i.store.Begin(ctx.Request.Context(), key, fingerprint, time.Now().Add(i.ttl))
+		if err != nil {
+			i.logger.Error("Can not begin idempotent request", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			ctx.Abort()
+			return
+		}
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key is used with different request"})
+			case record.Response == nil:
+				ctx.JSON(http.StatusConflict, gin.H{"error": "request with idempotency key is in progress"})
+			default:
+				ctx.Header(idempotency.ReplayedHeader, "true")
+				ctx.Data(record.Response.Status, record.Response.ContentType, record.Response.Body)
+			}
+			ctx.Abort()
+			return
+		}
+
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+		defer func() {
+			if r := recover(); r != nil {
+				i.release(storeCtx, key)
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = recorder
+
+		ctx.Next()
+
+		ctx.Writer = recorder.ResponseWriter
+		statusCode := recorder.Status()
+		if statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests {
+			i.release(storeCtx, key)
+			return
+		}
+		i.complete(storeCtx, key, idempotency.Response{
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+			Status:      statusCode,
+		})
+	}
+}
+
+// DenyIdempotencyKey reject requests with Idempotency-Key header for endpoints which can not replay responses,
+// so clients do not rely on key which is ignored.
+func (m *Middleware) DenyIdempotencyKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader(idempotency.Header) != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "idempotency key is not supported"})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// GRPCInterceptor replay stored response if request has Idempotency-Key metadata which is already used.
+// It has to be used after GRPCAuth.
+// Responses with server errors are not stored, so such requests can be repeated.
+func (i *Idempotency) GRPCInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if !slices.Contains(idempotentMethods, info.FullMethod) {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(idempotency.Header)
+	message, ok := req.(proto.Message)
+	if len(keys) == 0 || keys[0] == "" || !ok {
+		return handler(ctx, req)
+	}
+	key := keys[0]
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "idempotency key is too long")
+	}
+
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		i.logger.Error("Can not marshal request", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "internal error")
+	}
+
+	scope := anonymousScope
+	userIDs := md.Get(UserID)
+	if len(md.Get(AuthorizationNew)) == 0 && len(userIDs) != 0 {
+		scope = userIDs[0]
+	}
+	key = scope + ":" + key
+	fingerprint := idempotency.Fingerprint("grpc", info.FullMethod, body)
+
+	record, reserved, err := i.store.Begin(ctx, key, fingerprint, time.Now().Add(i.ttl))
+	if err != nil {
+		i.logger.Error("Can not begin idempotent request", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "internal error")
+	}
+	if !reserved {
+		switch {
+		case record.Fingerprint != fingerprint:
+			return nil, status.Errorf(codes.InvalidArgument, "idempotency key is used with different request")
+		case record.Response == nil:
+			return nil, status.Errorf(codes.Aborted, "request with idempotency key is in progress")
+		default:
+			return i.replay(ctx, record.Response)
+		}
+	}
+
+	storeCtx := context.WithoutCancel(ctx)
+	resp, err := handler(ctx, req)
+	if err != nil {
+		statusErr, _ := status.FromError(err)
+		if slices.Contains(retryableCodes, statusErr.Code()) {
+			i.release(storeCtx, key)
+			return resp, err
+		}
+		i.complete(storeCtx, key, idempotency.Response{
+			Body:   []byte(statusErr.Message()),
+			Status: int(statusErr.Code()),
+		})
+		return resp, err
+	}
+
+	response, ok := resp.(proto.Message)
+	if !ok {
+		i.release(storeCtx, key)
+		return resp, nil
+	}
+	data, err := proto.Marshal(response)
+	if err != nil {
+		i.logger.Error("Can not marshal response", zap.Error(err))
+		i.release(storeCtx, key)
+		return resp, nil
+	}
+	i.complete(storeCtx, key, idempotency.Response{
+		ContentType: string(response.ProtoReflect().Descriptor().FullName()),
+		Body:        data,
+		Status:      int(codes.OK),
+	})
+	return resp, nil
+}
+
+// replay return stored gRPC response.
+// Status of error responses is stored in Status and its message in Body,
+// successful responses are stored as marshaled message with full name in ContentType.
+func (i *Idempotency) replay(ctx context.Context, response *idempotency.Response) (interface{}, error) {
+	err := grpc.SetHeader(ctx, metadata.Pairs(idempotency.ReplayedHeader, "true"))
+	if err != nil {
+		i.logger.Debug("Can not set header", zap.Error(err))
+	}
+
+	if codes.Code(response.Status) != codes.OK { //nolint:gosec // status is stored from codes.Code
+		return nil, status.Error(codes.Code(response.Status), string(response.Body)) //nolint:gosec // the same
+	}
+
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(response.ContentType))
+	if err != nil {
+		i.logger.Error("Can not find type of stored response", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "internal error")
+	}
+	message := messageType.New().Interface()
+	err = proto.Unmarshal(response.Body, message)
+	if err != nil {
+		i.logger.Error("Can not unmarshal stored response", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "internal error")
+	}
+	return message, nil
+}
+
+// complete store response of request with key.
+func (i *Idempotency) complete(ctx context.Context, key string, response idempotency.Response) {
+	err := i.store.Complete(ctx, key, response)
+	if err != nil {
+		i.logger.Error("Can not complete idempotent request", zap.Error(err))
+	}
+}
+
+// release remove reservation of key.
+func (i *Idempotency) release(ctx context.Context, key string) {
+	err := i.store.Release(ctx, key)
+	if err != nil {
+		i.logger.Error("Can not release idempotency key", zap.String("key", key), zap.Error(err))
+	}
+}
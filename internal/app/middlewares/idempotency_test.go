@@ -0,0 +1,209 @@
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/idempotency"
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/models/proto/model"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestIdempotencyHandler(t *testing.T) {
+	userID := uuid.New()
+	anotherUserID := uuid.New()
+	var calls int
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		user := userID
+		if ctx.GetHeader("X-User") == "another" {
+			user = anotherUserID
+		}
+		ctx.Set(Authorization, &JWT{UserID: user})
+		ctx.Set(AuthorizationNew, false)
+	})
+	router.Use(NewIdempotency(zap.NewNop(), idempotency.NewMemoryStore(), time.Hour).Handler())
+	router.POST("/", func(ctx *gin.Context) {
+		calls++
+		body, err := io.ReadAll(ctx.Request.Body)
+		assert.NoError(t, err)
+		if string(body) == "fail" {
+			ctx.String(http.StatusServiceUnavailable, "unavailable")
+			return
+		}
+		ctx.String(http.StatusCreated, string(body)+strconv.Itoa(calls))
+	})
+
+	type want struct {
+		body       string
+		statusCode int
+		calls      int
+		replayed   bool
+	}
+	tests := []struct {
+		name string
+		key  string
+		user string
+		body string
+		want want
+	}{
+		{
+			name: "without key",
+			body: "a",
+			want: want{statusCode: http.StatusCreated, body: "a1", calls: 1},
+		},
+		{
+			name: "first request",
+			key:  "key",
+			body: "a",
+			want: want{statusCode: http.StatusCreated, body: "a2", calls: 2},
+		},
+		{
+			name: "repeated request",
+			key:  "key",
+			body: "a",
+			want: want{statusCode: http.StatusCreated, body: "a2", calls: 2, replayed: true},
+		},
+		{
+			name: "different payload",
+			key:  "key",
+			body: "b",
+			want: want{
+				statusCode: http.StatusUnprocessableEntity,
+				body:       `{"error":"idempotency key is used with different request"}`,
+				calls:      2,
+			},
+		},
+		{
+			name: "another user",
+			key:  "key",
+			user: "another",
+			body: "b",
+			want: want{statusCode: http.StatusCreated, body: "b3", calls: 3},
+		},
+		{
+			name: "server error",
+			key:  "failed",
+			body: "fail",
+			want: want{statusCode: http.StatusServiceUnavailable, body: "unavailable", calls: 4},
+		},
+		{
+			name: "repeated server error",
+			key:  "failed",
+			body: "fail",
+			want: want{statusCode: http.StatusServiceUnavailable, body: "unavailable", calls: 5},
+		},
+		{
+			name: "too long key",
+			key:  strings.Repeat("k", maxIdempotencyKeyLength+1),
+			body: "a",
+			want: want{statusCode: http.StatusBadRequest, body: `{"error":"idempotency key is too long"}`, calls: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			request.Header.Set(idempotency.Header, tt.key)
+			request.Header.Set("X-User", tt.user)
+
+			router.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.want.statusCode, w.Code)
+			assert.Equal(t, tt.want.body, w.Body.String())
+			assert.Equal(t, tt.want.calls, calls)
+			assert.Equal(t, tt.want.replayed, w.Header().Get(idempotency.ReplayedHeader) == "true")
+		})
+	}
+}
+
+func TestDenyIdempotencyKey(t *testing.T) {
+	router := gin.New()
+	router.POST("/", (&Middleware{}).DenyIdempotencyKey(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	request.Header.Set(idempotency.Header, "key")
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"idempotency key is not supported"}`, w.Body.String())
+}
+
+func TestIdempotencyGRPCInterceptor(t *testing.T) {
+	interceptor := NewIdempotency(zap.NewNop(), idempotency.NewMemoryStore(), time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.URLShortener_CreateShortLink_FullMethodName}
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		request, ok := req.(*model.CreateShortLinkRequest)
+		assert.True(t, ok)
+		originalURL := request.GetOriginalUrl().GetOriginalUrl()
+		if originalURL == "invalid" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid url")
+		}
+		shortURL := originalURL + strconv.Itoa(calls)
+		return model.CreateShortLinkResponse_builder{
+			ShortUrl: model.ShortURL_builder{ShortUrl: &shortURL}.Build(),
+		}.Build(), nil
+	}
+	newRequest := func(originalURL string) *model.CreateShortLinkRequest {
+		return model.CreateShortLinkRequest_builder{
+			OriginalUrl: model.OriginalURL_builder{OriginalUrl: &originalURL}.Build(),
+		}.Build()
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		idempotency.Header, "key",
+		UserID, uuid.NewString(),
+	))
+
+	resp, err := interceptor.GRPCInterceptor(ctx, newRequest("a"), info, handler)
+	assert.NoError(t, err)
+	response, ok := resp.(*model.CreateShortLinkResponse)
+	assert.True(t, ok)
+	assert.Equal(t, "a1", response.GetShortUrl().GetShortUrl())
+
+	resp, err = interceptor.GRPCInterceptor(ctx, newRequest("a"), info, handler)
+	assert.NoError(t, err)
+	replayed, ok := resp.(*model.CreateShortLinkResponse)
+	assert.True(t, ok)
+	assert.True(t, proto.Equal(response, replayed))
+	assert.Equal(t, 1, calls)
+
+	_, err = interceptor.GRPCInterceptor(ctx, newRequest("b"), info, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, calls)
+
+	_, err = interceptor.GRPCInterceptor(context.Background(), newRequest("a"), info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.Header, "invalid"))
+	for range 2 {
+		_, err = interceptor.GRPCInterceptor(ctx, newRequest("invalid"), info, handler)
+		assert.Equal(t, status.Error(codes.InvalidArgument, "invalid url"), err)
+	}
+	assert.Equal(t, 3, calls)
+}
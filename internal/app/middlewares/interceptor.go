@@ -2,10 +2,10 @@ package middlewares
 
 import (
 	"context"
-	"errors"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -39,6 +39,9 @@ func (m *Middleware) GRPCLogger(
 }
 
 // GRPCAuth extract JWT if it is presented and generate new one if it is not presented.
+// Request with invalid or expired JWT with Bearer scheme is rejected, while other invalid JWT is replaced by new one.
+// Request is authenticated by API key instead if it is presented and API keys are used.
+// Methods of admin service require JWT with admin role or API key with admin scope of administrator.
 func (m *Middleware) GRPCAuth(
 	ctx context.Context,
 	req interface{},
@@ -48,38 +51,50 @@ func (m *Middleware) GRPCAuth(
 	md, ok := metadata.FromIncomingContext(ctx)
 
 	if !ok {
+		if isAdminMethod(info.FullMethod) {
+			return nil, status.Errorf(codes.Unauthenticated, "no token")
+		}
 		return m.gRPCAuth(ctx, req, handler, md)
 	}
 
+	if m.apiKeys != nil {
+		key := apiKey(md.Get(APIKeyHeader), md.Get(Authorization))
+		if key != "" {
+			return m.gRPCAPIKeyAuth(ctx, req, info, handler, md, key)
+		}
+	}
+
 	var claims *JWT
 	for _, item := range md.Get(Authorization) {
-		token, err := jwt.ParseWithClaims(
-			item,
-			&JWT{},
-			func(token *jwt.Token) (interface{}, error) {
-				if token.Method != jwt.SigningMethodEdDSA {
-					return nil, errors.New("jwt signature mismatch")
-				}
-				return m.publicKey, nil
-			},
-		)
+		tokenString, bearer := bearerToken([]string{item})
+		if !bearer {
+			tokenString = item
+		}
+		parsed, err := m.parseToken(tokenString)
 		if err != nil {
+			if bearer {
+				return nil, status.Errorf(codes.Unauthenticated, "invalid token")
+			}
 			continue
 		}
-
-		claims, ok = token.Claims.(*JWT)
-		if ok {
-			break
-		}
+		claims = parsed
+		break
 	}
 	if claims == nil {
+		if isAdminMethod(info.FullMethod) {
+			return nil, status.Errorf(codes.Unauthenticated, "no token")
+		}
 		return m.gRPCAuth(ctx, req, handler, md)
 	}
+	if isAdminMethod(info.FullMethod) && (!claims.HasRole(models.RoleAdmin) || !m.isAdmin(claims.UserID)) {
+		return nil, status.Errorf(codes.PermissionDenied, "user is not an administrator")
+	}
 
 	md.Set(UserID, []string{claims.UserID.String()}...)
 	md.Set(AuthorizationNew, []string{}...)
 
 	ctx = metadata.NewIncomingContext(ctx, md)
+	ctx = withGRPCOrigin(ctx, md, usecases.AuthMethodBearer)
 
 	return handler(ctx, req)
 }
@@ -91,22 +106,7 @@ func (m *Middleware) gRPCAuth(
 	md metadata.MD,
 ) (interface{}, error) {
 	userID := uuid.New()
-	claims := &JWT{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "url_shortener",
-			Subject:   userID.String(),
-			Audience:  jwt.ClaimStrings{},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * maxAge)),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ID:        uuid.New().String(),
-		},
-		UserID: userID,
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-
-	tokenString, err := token.SignedString(m.privateKey)
+	tokenString, _, err := m.newToken(userID)
 	if err != nil {
 		m.logger.Error("Can not sign token", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "internal error")
@@ -124,6 +124,7 @@ func (m *Middleware) gRPCAuth(
 	md.Set(AuthorizationNew, []string{AuthorizationNew}...)
 
 	ctx = metadata.NewIncomingContext(ctx, md)
+	ctx = withGRPCOrigin(ctx, md, usecases.AuthMethodNew)
 
 	return handler(ctx, req)
 }
@@ -2,7 +2,6 @@ package middlewares
 
 import (
 	"context"
-	"os"
 	"testing"
 	"time"
 
@@ -13,6 +12,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -57,20 +57,9 @@ func TestGRPCAuth(t *testing.T) {
 		testLogger, err := logger.InitLogger()
 		assert.NoError(t, err)
 
-		publicKeyFile, err := os.ReadFile("../../../public.pem")
-		assert.NoError(t, err)
-		publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicKeyFile)
-		assert.NoError(t, err)
-
-		privateKeyFile, err := os.ReadFile("../../../private.pem")
-		assert.NoError(t, err)
-		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyFile)
-		assert.NoError(t, err)
-
 		middleware := &Middleware{
-			publicKey:  publicKey,
-			privateKey: privateKey,
-			logger:     testLogger,
+			keys:   testKeyRing(t),
+			logger: testLogger,
 		}
 
 		testHandler := func(
@@ -105,7 +94,7 @@ func TestGRPCAuth(t *testing.T) {
 			UserID: userID,
 		}
 		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-		tokenString, err := token.SignedString(middleware.privateKey)
+		tokenString, err := token.SignedString(middleware.keys.keys().private)
 		assert.NoError(t, err)
 
 		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(Authorization, tokenString))
@@ -115,26 +104,31 @@ func TestGRPCAuth(t *testing.T) {
 			&grpc.UnaryServerInfo{FullMethod: "test"},
 			testHandler)
 		assert.NoError(t, err)
-	})
 
-	t.Run("generate token", func(t *testing.T) {
-		testLogger, err := logger.InitLogger()
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Authorization, "Bearer "+tokenString))
+		_, err = middleware.GRPCAuth(
+			ctx,
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "test"},
+			testHandler)
 		assert.NoError(t, err)
 
-		publicKeyFile, err := os.ReadFile("../../../public.pem")
-		assert.NoError(t, err)
-		publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicKeyFile)
-		assert.NoError(t, err)
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Authorization, "Bearer invalid"))
+		_, err = middleware.GRPCAuth(
+			ctx,
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "test"},
+			testHandler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
 
-		privateKeyFile, err := os.ReadFile("../../../private.pem")
-		assert.NoError(t, err)
-		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyFile)
+	t.Run("generate token", func(t *testing.T) {
+		testLogger, err := logger.InitLogger()
 		assert.NoError(t, err)
 
 		middleware := &Middleware{
-			publicKey:  publicKey,
-			privateKey: privateKey,
-			logger:     testLogger,
+			keys:   testKeyRing(t),
+			logger: testLogger,
 		}
 
 		testHandler := func(
@@ -0,0 +1,262 @@
+package middlewares
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// Names of JWT key constants.
+const (
+	keyIDHeader   = "kid"
+	keyExtension  = ".pem"
+	activeKeyFile = "active"
+	jwksMaxAge    = "public, max-age=300"
+)
+
+// Errors of JWT keys.
+var (
+	ErrNoSigningKey = errors.New("no signing key")
+	ErrUnknownKey   = errors.New("unknown jwt key")
+)
+
+// keySet is an immutable set of JWT keys.
+// Tokens are signed by active key and verified by any key of set.
+type keySet struct {
+	public   map[string]ed25519.PublicKey
+	private  ed25519.PrivateKey
+	activeID string
+}
+
+// keyRing holds current key set and replaces it on reload.
+type keyRing struct {
+	load    func() (*keySet, error)
+	current atomic.Pointer[keySet]
+}
+
+// newKeyRing create new keyRing with keys loaded by load.
+func newKeyRing(load func() (*keySet, error)) (*keyRing, error) {
+	keys, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &keyRing{load: load}
+	ring.current.Store(keys)
+	return ring, nil
+}
+
+// reload replace current key set by newly loaded one.
+// Current key set is kept if new one can not be loaded.
+func (k *keyRing) reload() (*keySet, error) {
+	keys, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	k.current.Store(keys)
+	return keys, nil
+}
+
+// keys return current key set.
+func (k *keyRing) keys() *keySet {
+	return k.current.Load()
+}
+
+// NewMiddlewareWithKeysDir create new Middleware with JWT keys from directory.
+// Every <kid>.pem file of directory holds Ed25519 private or public key with id kid.
+// Tokens are signed by private key with id from file "active" or by private key with the greatest id,
+// and verified by all keys of directory, so key is retired by removing its file.
+func NewMiddlewareWithKeysDir(keysDir string, logger *zap.Logger) (*Middleware, error) {
+	keys, err := newKeyRing(func() (*keySet, error) {
+		return loadKeysDir(keysDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Middleware{
+		keys:   keys,
+		logger: logger,
+		cookie: DefaultCookieConfig,
+	}, nil
+}
+
+// ReloadKeys load JWT keys again, so keys can be rotated without restart.
+// Current keys are kept if new ones can not be loaded.
+func (m *Middleware) ReloadKeys() error {
+	keys, err := m.keys.reload()
+	if err != nil {
+		return fmt.Errorf("can not reload keys: %w", err)
+	}
+
+	m.logger.Info("Keys are reloaded", zap.String("active", keys.activeID), zap.Int("count", len(keys.public)))
+	return nil
+}
+
+// JWKS respond with public JWT keys in JSON Web Key Set format, so other services can verify tokens.
+func (m *Middleware) JWKS() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keys := m.keys.keys()
+
+		ids := make([]string, 0, len(keys.public))
+		for id := range keys.public {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+
+		response := models.JWKS{Keys: make([]models.JWK, 0, len(ids))}
+		for _, id := range ids {
+			response.Keys = append(response.Keys, models.JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(keys.public[id]),
+				Kid: id,
+				Use: "sig",
+				Alg: jwt.SigningMethodEdDSA.Alg(),
+			})
+		}
+
+		ctx.Header("Cache-Control", jwksMaxAge)
+		ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// verificationKey return key which verifies token.
+// Token without key id is verified by all keys, as it is issued before key ids.
+func (s *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	id, ok := token.Header[keyIDHeader].(string)
+	if !ok {
+		verificationKeys := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(s.public))}
+		for _, key := range s.public {
+			verificationKeys.Keys = append(verificationKeys.Keys, key)
+		}
+		return verificationKeys, nil
+	}
+
+	key, ok := s.public[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// loadKeyPair load key set of single key pair.
+// Id of key is its thumbprint.
+func loadKeyPair(publicKeyPath string, privateKeyPath string) (*keySet, error) {
+	publicKeyFile, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("can not open public.pem file: %w", err)
+	}
+	publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse public key: %w", err)
+	}
+	edPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("can not parse public key: not ed25519 key")
+	}
+
+	privateKeyFile, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("can not open private.pem file: %w", err)
+	}
+	privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse private key: %w", err)
+	}
+	edPrivateKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("can not parse private key: not ed25519 key")
+	}
+
+	id := thumbprint(edPublicKey)
+	return &keySet{
+		public:   map[string]ed25519.PublicKey{id: edPublicKey},
+		private:  edPrivateKey,
+		activeID: id,
+	}, nil
+}
+
+// loadKeysDir load key set from directory.
+func loadKeysDir(keysDir string) (*keySet, error) {
+	entries, err := os.ReadDir(keysDir)
+	if err != nil {
+		return nil, fmt.Errorf("can not read keys directory: %w", err)
+	}
+
+	keys := &keySet{public: make(map[string]ed25519.PublicKey)}
+	privateKeys := make(map[string]ed25519.PrivateKey)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), keyExtension) {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), keyExtension)
+
+		data, err := os.ReadFile(filepath.Join(keysDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("can not read key %s: %w", id, err)
+		}
+
+		if privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			edPrivateKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("can not parse key %s: not ed25519 key", id)
+			}
+			privateKeys[id] = edPrivateKey
+			keys.public[id] = edPrivateKey.Public().(ed25519.PublicKey) //nolint:forcetypeassert // always ed25519
+			continue
+		}
+
+		publicKey, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse key %s: %w", id, err)
+		}
+		edPublicKey, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("can not parse key %s: not ed25519 key", id)
+		}
+		keys.public[id] = edPublicKey
+	}
+
+	active, err := os.ReadFile(filepath.Join(keysDir, activeKeyFile))
+	switch {
+	case err == nil:
+		keys.activeID = strings.TrimSpace(string(active))
+	case errors.Is(err, os.ErrNotExist):
+		for id := range privateKeys {
+			keys.activeID = max(keys.activeID, id)
+		}
+	default:
+		return nil, fmt.Errorf("can not read active key: %w", err)
+	}
+
+	privateKey, ok := privateKeys[keys.activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoSigningKey, keys.activeID)
+	}
+	keys.private = privateKey
+
+	return keys, nil
+}
+
+// thumbprint return JWK thumbprint of key.
+func thumbprint(key ed25519.PublicKey) string {
+	hash := sha256.Sum256(fmt.Appendf(nil,
+		`{"crv":"Ed25519","kty":"OKP","x":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(key),
+	))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
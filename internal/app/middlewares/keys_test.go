@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testKeyRing(t *testing.T) *keyRing {
+	t.Helper()
+
+	keys, err := newKeyRing(func() (*keySet, error) {
+		return loadKeyPair("../../../public.pem", "../../../private.pem")
+	})
+	assert.NoError(t, err)
+	return keys
+}
+
+func writeKey(t *testing.T, path string, public bool) ed25519.PrivateKey {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	block := &pem.Block{Type: "PRIVATE KEY"}
+	block.Bytes, err = x509.MarshalPKCS8PrivateKey(privateKey)
+	if public {
+		block.Type = "PUBLIC KEY"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(publicKey)
+	}
+	assert.NoError(t, err)
+
+	err = os.WriteFile(path, pem.EncodeToMemory(block), 0o600)
+	assert.NoError(t, err)
+	return privateKey
+}
+
+func TestKeysDir(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewMiddlewareWithKeysDir(dir, zap.NewNop())
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	oldKey := writeKey(t, filepath.Join(dir, "2024-01.pem"), false)
+	writeKey(t, filepath.Join(dir, "external.pem"), true)
+	middleware, err := NewMiddlewareWithKeysDir(dir, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01", middleware.keys.keys().activeID)
+
+	oldToken, _, err := middleware.newToken(uuid.New())
+	assert.NoError(t, err)
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &JWT{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		UserID:           uuid.New(),
+	}).SignedString(oldKey)
+	assert.NoError(t, err)
+
+	writeKey(t, filepath.Join(dir, "2024-02.pem"), false)
+	err = middleware.ReloadKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02", middleware.keys.keys().activeID)
+
+	newToken, _, err := middleware.newToken(uuid.New())
+	assert.NoError(t, err)
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &JWT{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02", token.Header[keyIDHeader])
+
+	for _, tokenString := range []string{oldToken, legacyToken, newToken} {
+		_, err = middleware.parseToken(tokenString)
+		assert.NoError(t, err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, activeKeyFile), []byte("2024-01\n"), 0o600)
+	assert.NoError(t, err)
+	err = middleware.ReloadKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01", middleware.keys.keys().activeID)
+
+	err = os.WriteFile(filepath.Join(dir, activeKeyFile), []byte("external"), 0o600)
+	assert.NoError(t, err)
+	err = middleware.ReloadKeys()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+	assert.Equal(t, "2024-01", middleware.keys.keys().activeID)
+
+	err = os.Remove(filepath.Join(dir, activeKeyFile))
+	assert.NoError(t, err)
+	err = os.Remove(filepath.Join(dir, "2024-01.pem"))
+	assert.NoError(t, err)
+	err = middleware.ReloadKeys()
+	assert.NoError(t, err)
+	_, err = middleware.parseToken(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = middleware.parseToken(legacyToken)
+	assert.Error(t, err)
+	_, err = middleware.parseToken(newToken)
+	assert.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("invalid key"), 0o600)
+	assert.NoError(t, err)
+	err = middleware.ReloadKeys()
+	assert.Error(t, err)
+	_, err = middleware.parseToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	privateKey := writeKey(t, filepath.Join(dir, "b.pem"), false)
+	writeKey(t, filepath.Join(dir, "a.pem"), true)
+	middleware, err := NewMiddlewareWithKeysDir(dir, zap.NewNop())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", middleware.JWKS())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+	var response models.JWKS
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Keys, 2)
+	assert.Equal(t, "a", response.Keys[0].Kid)
+	assert.Equal(t, "b", response.Keys[1].Kid)
+	assert.Equal(t, "OKP", response.Keys[1].Kty)
+	assert.Equal(t, "EdDSA", response.Keys[1].Alg)
+	x, err := base64.RawURLEncoding.DecodeString(response.Keys[1].X)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(privateKey.Public().(ed25519.PublicKey)), x)
+}
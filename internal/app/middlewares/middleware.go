@@ -2,14 +2,14 @@ package middlewares
 
 import (
 	"compress/gzip"
-	"crypto"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -28,35 +28,32 @@ var ErrNoJWT = errors.New("no jwt in cookie")
 
 // Middleware processes requests before and after execution by the handler.
 type Middleware struct {
-	publicKey  crypto.PublicKey
-	privateKey crypto.PrivateKey
-	logger     *zap.Logger
+	keys           *keyRing
+	apiKeys        APIKeyAuthenticator
+	refreshTokens  RefreshTokenIssuer
+	oidc           OIDCProvider
+	users          IdentityUsers
+	admins         map[uuid.UUID]struct{}
+	logger         *zap.Logger
+	cookie         CookieConfig
+	trustedOrigins []string
+	trustedProxies []*net.IPNet
+	refreshTTL     time.Duration
 }
 
-// NewMiddleware create new Middleware.
+// NewMiddleware create new Middleware with single JWT key pair.
 func NewMiddleware(publicKeyPath string, privateKeyPath string, logger *zap.Logger) (*Middleware, error) {
-	publicKeyFile, err := os.ReadFile(publicKeyPath)
+	keys, err := newKeyRing(func() (*keySet, error) {
+		return loadKeyPair(publicKeyPath, privateKeyPath)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("can not open public.pem file: %w", err)
-	}
-	publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicKeyFile)
-	if err != nil {
-		return nil, fmt.Errorf("can not parse public key: %w", err)
-	}
-
-	privateKeyFile, err := os.ReadFile(privateKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("can not open private.pem file: %w", err)
-	}
-	privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyFile)
-	if err != nil {
-		return nil, fmt.Errorf("can not parse private key: %w", err)
+		return nil, err
 	}
 
 	return &Middleware{
-		publicKey:  publicKey,
-		privateKey: privateKey,
-		logger:     logger,
+		keys:   keys,
+		logger: logger,
+		cookie: DefaultCookieConfig,
 	}, nil
 }
 
@@ -78,6 +75,7 @@ func (m *Middleware) Logger() gin.HandlerFunc {
 			zap.Duration("latency", time.Since(start)),
 			zap.String("method", ctx.Request.Method),
 			zap.String("path", path),
+			zap.String("ip", ClientIPFromContext(ctx.Request.Context())),
 			zap.Int("size", ctx.Writer.Size()))
 	}
 }
@@ -148,92 +146,140 @@ func (m *Middleware) Compressor() gin.HandlerFunc {
 type JWT struct {
 	jwt.RegisteredClaims
 	UserID uuid.UUID `json:"user_id"`
+	Roles  []string  `json:"roles,omitempty"`
 }
 
-// Auth extract JWT from cookie if it is presented and generate new one if it is not presented.
+// Auth extract JWT from Authorization header with Bearer scheme or from cookie and
+// generate new one if it is not presented.
+// Request with invalid or expired JWT in header is rejected, while invalid or expired JWT
+// in cookie is replaced by new one. Cross origin mutating request with JWT in cookie is rejected.
+// Request is authenticated by API key instead if it is presented and API keys are used.
 func (m *Middleware) Auth() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		tokenString, err := ctx.Cookie(Authorization)
-
-		if err != nil || tokenString == "" {
-			userID := uuid.New()
-			claims := &JWT{
-				RegisteredClaims: jwt.RegisteredClaims{
-					Issuer:    "url_shortener",
-					Subject:   userID.String(),
-					Audience:  jwt.ClaimStrings{},
-					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * maxAge)),
-					NotBefore: jwt.NewNumericDate(time.Now()),
-					IssuedAt:  jwt.NewNumericDate(time.Now()),
-					ID:        uuid.New().String(),
-				},
-				UserID: userID,
+		if m.apiKeys != nil {
+			key := apiKey(ctx.Request.Header.Values(APIKeyHeader), ctx.Request.Header.Values(Authorization))
+			if key != "" {
+				m.authAPIKey(ctx, key)
+				return
 			}
+		}
 
-			token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-
-			tokenString, err = token.SignedString(m.privateKey)
+		if tokenString, ok := bearerToken(ctx.Request.Header.Values(Authorization)); ok {
+			claims, err := m.parseToken(tokenString)
 			if err != nil {
-				m.logger.Error("Can not sign token", zap.Error(err))
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+				ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
 				ctx.Abort()
 				return
 			}
 
-			ctx.SetCookie(
-				Authorization,
-				tokenString,
-				maxAge,
-				"/",
-				"",
-				false,
-				false,
-			)
 			ctx.Set(Authorization, claims)
-			ctx.Set(AuthorizationNew, true)
+			ctx.Set(AuthorizationNew, false)
+			withOrigin(ctx, usecases.AuthMethodBearer)
 
 			ctx.Next()
 
 			return
 		}
 
-		token, err := jwt.ParseWithClaims(
-			tokenString,
-			&JWT{},
-			func(token *jwt.Token) (interface{}, error) {
-				if token.Method != jwt.SigningMethodEdDSA {
-					return nil, errors.New("jwt signature mismatch")
+		tokenString, err := ctx.Cookie(Authorization)
+		if err == nil && tokenString != "" {
+			claims, err := m.parseToken(tokenString)
+			if err == nil {
+				if !m.sameOrigin(ctx.Request) {
+					ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+					ctx.Abort()
+					return
 				}
-				return m.publicKey, nil
-			},
-		)
-		if err != nil {
-			m.logger.Error("Can not parse jwt", zap.Error(err))
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
-			ctx.Abort()
-			return
+
+				m.setCookie(ctx, tokenString)
+				ctx.Set(Authorization, claims)
+				ctx.Set(AuthorizationNew, false)
+				withOrigin(ctx, usecases.AuthMethodCookie)
+
+				ctx.Next()
+
+				return
+			}
+			m.logger.Debug("Can not parse jwt from cookie", zap.Error(err))
 		}
 
-		claims, ok := token.Claims.(*JWT)
-		if !ok {
-			m.logger.Error("Token is not jwt format")
+		tokenString, claims, err := m.newToken(uuid.New())
+		if err != nil {
+			m.logger.Error("Can not sign token", zap.Error(err))
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
 			ctx.Abort()
 			return
 		}
 
-		ctx.SetCookie(
-			Authorization,
-			tokenString,
-			maxAge,
-			"/",
-			"",
-			false,
-			false,
-		)
+		m.setCookie(ctx, tokenString)
 		ctx.Set(Authorization, claims)
-		ctx.Set(AuthorizationNew, false)
+		ctx.Set(AuthorizationNew, true)
+		withOrigin(ctx, usecases.AuthMethodNew)
 
 		ctx.Next()
 	}
 }
+
+// newToken create and sign new JWT of user with roles of user.
+func (m *Middleware) newToken(userID uuid.UUID) (string, *JWT, error) {
+	now := time.Now()
+	claims := &JWT{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "url_shortener",
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * maxAge)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		},
+		UserID: userID,
+		Roles:  m.roles(userID),
+	}
+
+	keys := m.keys.keys()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header[keyIDHeader] = keys.activeID
+	tokenString, err := token.SignedString(keys.private)
+	if err != nil {
+		return "", nil, fmt.Errorf("can not sign token: %w", err)
+	}
+
+	return tokenString, claims, nil
+}
+
+// parseToken parse JWT and validate its signature by key with id of token and expiration.
+func (m *Middleware) parseToken(tokenString string) (*JWT, error) {
+	keys := m.keys.keys()
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&JWT{},
+		func(token *jwt.Token) (interface{}, error) {
+			if token.Method != jwt.SigningMethodEdDSA {
+				return nil, errors.New("jwt signature mismatch")
+			}
+			return keys.verificationKey(token)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse jwt: %w", err)
+	}
+
+	claims, ok := token.Claims.(*JWT)
+	if !ok || claims.UserID == uuid.Nil {
+		return nil, errors.New("token is not jwt format")
+	}
+
+	return claims, nil
+}
+
+// bearerToken return token from Authorization header with Bearer scheme.
+func bearerToken(authorizations []string) (string, bool) {
+	for _, authorization := range authorizations {
+		if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+			return strings.TrimSpace(authorization[len(bearerPrefix):]), true
+		}
+	}
+	return "", false
+}
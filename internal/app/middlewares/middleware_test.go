@@ -30,8 +30,8 @@ func TestNewMiddleware(t *testing.T) {
 		middleware, err := NewMiddleware(publicKeyPath, privateKeyPath, testLogger)
 		assert.NoError(t, err)
 		assert.NotNil(t, middleware)
-		assert.NotNil(t, middleware.publicKey)
-		assert.NotNil(t, middleware.privateKey)
+		assert.NotNil(t, middleware.keys.keys().private)
+		assert.Len(t, middleware.keys.keys().public, 1)
 		assert.Equal(t, testLogger, middleware.logger)
 	})
 
@@ -278,20 +278,9 @@ func TestAuth(t *testing.T) {
 	core, recordedLogs := observer.New(zap.InfoLevel)
 	testLogger := zap.New(core)
 
-	publicKeyFile, err := os.ReadFile("../../../public.pem")
-	assert.NoError(t, err)
-	publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicKeyFile)
-	assert.NoError(t, err)
-
-	privateKeyFile, err := os.ReadFile("../../../private.pem")
-	assert.NoError(t, err)
-	privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyFile)
-	assert.NoError(t, err)
-
 	middleware := &Middleware{
-		publicKey:  publicKey,
-		privateKey: privateKey,
-		logger:     testLogger,
+		keys:   testKeyRing(t),
+		logger: testLogger,
 	}
 
 	t.Run("no JWT in cookie", func(t *testing.T) {
@@ -330,7 +319,7 @@ func TestAuth(t *testing.T) {
 			UserID: userID,
 		}
 		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-		tokenString, err := token.SignedString(middleware.privateKey)
+		tokenString, err := token.SignedString(middleware.keys.keys().private)
 		assert.NoError(t, err)
 
 		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
@@ -367,11 +356,10 @@ func TestAuth(t *testing.T) {
 
 		middleware.Auth()(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, ctx.Writer.Status())
-
-		assert.Equal(t, 1, recordedLogs.Len())
-		logEntry := recordedLogs.All()[0]
-		assert.Equal(t, "Can not parse jwt", logEntry.Message)
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.Contains(t, ctx.Writer.Header().Get("Set-Cookie"), Authorization)
+		assert.True(t, ctx.GetBool(AuthorizationNew))
+		assert.Equal(t, 0, recordedLogs.Len())
 	})
 
 	t.Run("JWT signature mismatch", func(t *testing.T) {
@@ -400,9 +388,75 @@ func TestAuth(t *testing.T) {
 
 		middleware.Auth()(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, ctx.Writer.Status())
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.True(t, ctx.GetBool(AuthorizationNew))
+		claimsCtx, ok := ctx.Value(Authorization).(*JWT)
+		assert.True(t, ok)
+		assert.NotEqual(t, userID, claimsCtx.UserID)
+	})
 
-		logEntry := recordedLogs.All()[0]
-		assert.Equal(t, "Can not parse jwt", logEntry.Message)
+	t.Run("expired JWT in cookie", func(t *testing.T) {
+		userID := uuid.New()
+		claims := &JWT{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Second)),
+			},
+			UserID: userID,
+		}
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(middleware.keys.keys().private)
+		assert.NoError(t, err)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		ctx.Request.AddCookie(&http.Cookie{
+			Name:  Authorization,
+			Value: tokenString,
+		})
+
+		middleware.Auth()(ctx)
+
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.True(t, ctx.GetBool(AuthorizationNew))
+	})
+
+	t.Run("valid JWT in header", func(t *testing.T) {
+		userID := uuid.New()
+		tokenString, _, err := middleware.newToken(userID)
+		assert.NoError(t, err)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		ctx.Request.Header.Set(Authorization, "Bearer "+tokenString)
+
+		middleware.Auth()(ctx)
+
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.Empty(t, ctx.Writer.Header().Get("Set-Cookie"))
+		assert.False(t, ctx.GetBool(AuthorizationNew))
+		claims, ok := ctx.Value(Authorization).(*JWT)
+		assert.True(t, ok)
+		assert.Equal(t, userID, claims.UserID)
+	})
+
+	t.Run("expired JWT in header", func(t *testing.T) {
+		claims := &JWT{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Second)),
+			},
+			UserID: uuid.New(),
+		}
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(middleware.keys.keys().private)
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		ctx.Request.Header.Set(Authorization, "Bearer "+tokenString)
+
+		middleware.Auth()(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.True(t, ctx.IsAborted())
+		assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
 	})
 }
@@ -0,0 +1,203 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/oidc"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Names of OpenID Connect constants.
+const (
+	OIDCState     = "OIDCState"
+	OIDCStatePath = "/api/auth/oidc"
+	oidcStateTTL  = 10 * time.Minute
+)
+
+// OIDCProvider is an interface of OpenID Connect providers.
+type OIDCProvider interface {
+	// AuthCodeURL return URL of provider where user is redirected to log in.
+	AuthCodeURL(state string, nonce string, codeChallenge string) string
+	// Exchange exchange authorization code for verified identity of user.
+	Exchange(ctx context.Context, code string, verifier string, nonce string) (*oidc.IDToken, error)
+}
+
+// IdentityUsers is an interface of users of external identities.
+type IdentityUsers interface {
+	// LoginUser return user of external identity and merge anonymous user into it if merge is set.
+	LoginUser(ctx context.Context, issuer string, subject string, anonymousID uuid.UUID, merge bool) (uuid.UUID, error)
+}
+
+// UseOIDC turn on login by OpenID Connect provider.
+func (m *Middleware) UseOIDC(provider OIDCProvider, users IdentityUsers) {
+	m.oidc = provider
+	m.users = users
+}
+
+// oidcState is a structure of claims of login which is in progress.
+// It is signed by JWT keys and kept in cookie until user returns from provider.
+type oidcState struct {
+	jwt.RegisteredClaims
+	State       string    `json:"state"`
+	Nonce       string    `json:"nonce"`
+	Verifier    string    `json:"verifier"`
+	AnonymousID uuid.UUID `json:"anonymous_id"`
+	Merge       bool      `json:"merge"`
+}
+
+// OIDCLogin redirect user to OpenID Connect provider to log in with authorization code flow and PKCE.
+// With query parameter merge=true links of current anonymous user are moved into logged-in user.
+// It has to be used after Auth.
+func (m *Middleware) OIDCLogin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if m.oidc == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+			return
+		}
+
+		state := oidcState{Merge: ctx.Query("merge") == "true"}
+		if token, ok := ctx.Value(Authorization).(*JWT); ok {
+			state.AnonymousID = token.UserID
+		}
+		var err error
+		for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+			*value, err = oidc.RandomString()
+			if err != nil {
+				m.logger.Error("Can not generate oidc state", zap.Error(err))
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+				return
+			}
+		}
+
+		now := time.Now()
+		state.RegisteredClaims = jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		}
+		keys := m.keys.keys()
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &state)
+		token.Header[keyIDHeader] = keys.activeID
+		stateString, err := token.SignedString(keys.private)
+		if err != nil {
+			m.logger.Error("Can not sign oidc state", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+
+		m.setStateCookie(ctx, stateString, int(oidcStateTTL.Seconds()))
+		ctx.Redirect(http.StatusFound, m.oidc.AuthCodeURL(state.State, state.Nonce, oidc.CodeChallenge(state.Verifier)))
+	}
+}
+
+// OIDCCallback finish login by OpenID Connect provider and issue tokens of user of external identity.
+// User is created on first login of identity.
+// It does not require Auth, so user is not created before login is finished.
+func (m *Middleware) OIDCCallback() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if m.oidc == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+			return
+		}
+
+		stateString, err := ctx.Cookie(OIDCState)
+		if err != nil || stateString == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+		m.setStateCookie(ctx, "", -1)
+		state, err := m.parseOIDCState(stateString)
+		if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(ctx.Query("state"))) != 1 {
+			m.logger.Debug("Invalid oidc state", zap.Error(err))
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+		if ctx.Query("error") != "" || ctx.Query("code") == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			return
+		}
+
+		identity, err := m.oidc.Exchange(ctx.Request.Context(), ctx.Query("code"), state.Verifier, state.Nonce)
+		if err != nil {
+			m.logger.Warn("Can not exchange oidc code", zap.Error(err))
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			return
+		}
+
+		withOrigin(ctx, usecases.AuthMethodOIDC)
+		userID, err := m.users.LoginUser(
+			ctx.Request.Context(),
+			identity.Issuer,
+			identity.Subject,
+			state.AnonymousID,
+			state.Merge,
+		)
+		if err != nil {
+			m.logger.Error("Can not login user", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+
+		var refreshToken string
+		if m.refreshTokens != nil {
+			refreshToken, err = m.refreshTokens.IssueRefreshToken(ctx.Request.Context(), userID, m.refreshTTL)
+			if err != nil {
+				m.logger.Error("Can not issue refresh token", zap.Error(err))
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+				return
+			}
+		}
+
+		m.tokens(ctx, userID, refreshToken)
+	}
+}
+
+// parseOIDCState parse state of login and validate its signature and expiration.
+func (m *Middleware) parseOIDCState(stateString string) (*oidcState, error) {
+	keys := m.keys.keys()
+	token, err := jwt.ParseWithClaims(
+		stateString,
+		&oidcState{},
+		func(token *jwt.Token) (interface{}, error) {
+			if token.Method != jwt.SigningMethodEdDSA {
+				return nil, errors.New("jwt signature mismatch")
+			}
+			return keys.verificationKey(token)
+		},
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse oidc state: %w", err)
+	}
+
+	state, ok := token.Claims.(*oidcState)
+	if !ok || state.State == "" {
+		return nil, errors.New("token is not oidc state format")
+	}
+
+	return state, nil
+}
+
+// setStateCookie put state of login in cookie which is sent only to OpenID Connect endpoints.
+// SameSite is lax as provider redirects user back by cross-site navigation. Negative maxAge deletes cookie.
+func (m *Middleware) setStateCookie(ctx *gin.Context, stateString string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     OIDCState,
+		Value:    url.QueryEscape(stateString),
+		Path:     OIDCStatePath,
+		Domain:   m.cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
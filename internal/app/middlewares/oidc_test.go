@@ -0,0 +1,141 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/oidc"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testOIDCProvider is a provider which logs in subject for code if verifier and nonce match login.
+type testOIDCProvider struct {
+	challenge string
+	nonce     string
+	code      string
+	subject   string
+}
+
+func (p *testOIDCProvider) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	p.challenge = codeChallenge
+	p.nonce = nonce
+	return "https://issuer.com/authorize?" + url.Values{"state": {state}}.Encode()
+}
+
+func (p *testOIDCProvider) Exchange(
+	_ context.Context,
+	code string,
+	verifier string,
+	nonce string,
+) (*oidc.IDToken, error) {
+	if code != p.code || oidc.CodeChallenge(verifier) != p.challenge || nonce != p.nonce {
+		return nil, errors.New("invalid code")
+	}
+	return &oidc.IDToken{Issuer: "https://issuer.com", Subject: p.subject}, nil
+}
+
+func TestOIDC(t *testing.T) {
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", middleware.Auth(), middleware.OIDCLogin())
+	router.GET("/api/auth/oidc/callback", middleware.OIDCCallback())
+
+	serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, request)
+		return w
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == name {
+				return cookie
+			}
+		}
+		return nil
+	}
+	login := func(query string, cookies ...*http.Cookie) (*http.Cookie, string) {
+		w := serve("/api/auth/oidc/login"+query, cookies...)
+		assert.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		assert.NoError(t, err)
+		state := cookie(w, OIDCState)
+		assert.NotNil(t, state)
+		assert.Equal(t, OIDCStatePath, state.Path)
+		assert.True(t, state.HttpOnly)
+		return state, location.Query().Get("state")
+	}
+
+	w := serve("/api/auth/oidc/login")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	provider := &testOIDCProvider{code: "code", subject: "subject"}
+	middleware.UseOIDC(provider, &interactor)
+
+	anonymousToken, anonymous, err := middleware.newToken(uuid.New())
+	assert.NoError(t, err)
+	_, err = interactor.CreateShortLink(context.Background(), "https://example.com", anonymous.UserID)
+	assert.NoError(t, err)
+	anonymousCookie := &http.Cookie{Name: Authorization, Value: anonymousToken}
+
+	state, stateValue := login("?merge=true", anonymousCookie)
+
+	w = serve("/api/auth/oidc/callback?code=code&state=another", state)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve("/api/auth/oidc/callback?code=code&state=" + stateValue)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve("/api/auth/oidc/callback?code=another&state="+stateValue, state)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve("/api/auth/oidc/callback?error=access_denied&state="+stateValue, state)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve("/api/auth/oidc/callback?code=code&state="+stateValue, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, -1, cookie(w, OIDCState).MaxAge)
+	assert.NotNil(t, cookie(w, Authorization))
+	var response models.TokenResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Empty(t, response.RefreshToken)
+	claims, err := middleware.parseToken(response.AccessToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, anonymous.UserID, claims.UserID)
+	links, err := interactor.GetShortLinksOfUser(context.Background(), claims.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+
+	state, stateValue = login("")
+	w = serve("/api/auth/oidc/callback?code=code&state="+stateValue, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	sameClaims, err := middleware.parseToken(response.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, claims.UserID, sameClaims.UserID)
+
+	_, err = middleware.parseToken(state.Value)
+	assert.Error(t, err)
+}
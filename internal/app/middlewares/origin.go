@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is a header of request ID which is stored in audit log.
+const RequestIDHeader = "X-Request-ID"
+
+// withOrigin store origin of HTTP request in context of request for audit log.
+// Request ID is generated if it is not presented and it is returned in response header.
+func withOrigin(ctx *gin.Context, authMethod string) {
+	requestID := ctx.GetHeader(RequestIDHeader)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	ctx.Header(RequestIDHeader, requestID)
+
+	ctx.Request = ctx.Request.WithContext(usecases.WithOrigin(ctx.Request.Context(), usecases.Origin{
+		AuthMethod: authMethod,
+		ClientIP:   ClientIPFromContext(ctx.Request.Context()),
+		RequestID:  requestID,
+	}))
+}
+
+// withGRPCOrigin return context of gRPC request with its origin for audit log.
+// Request ID is generated if it is not presented in metadata.
+func withGRPCOrigin(ctx context.Context, md metadata.MD, authMethod string) context.Context {
+	var requestID string
+	if values := md.Get(RequestIDHeader); len(values) != 0 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	return usecases.WithOrigin(ctx, usecases.Origin{
+		AuthMethod: authMethod,
+		ClientIP:   ClientIPFromContext(ctx),
+		RequestID:  requestID,
+	})
+}
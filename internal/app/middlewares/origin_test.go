@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestAuthOrigin(t *testing.T) {
+	middleware := &Middleware{
+		keys:   testKeyRing(t),
+		logger: zap.NewNop(),
+	}
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	ctx.Request.RemoteAddr = "192.0.2.1:1234"
+	ctx.Request.Header.Set(RequestIDHeader, "request")
+
+	middleware.ClientIP()(ctx)
+	middleware.Auth()(ctx)
+
+	origin := usecases.OriginFromContext(ctx.Request.Context())
+	assert.Equal(t, usecases.Origin{
+		AuthMethod: usecases.AuthMethodNew,
+		ClientIP:   "192.0.2.1",
+		RequestID:  "request",
+	}, origin)
+	assert.Equal(t, "request", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+
+	middleware.Auth()(ctx)
+
+	origin = usecases.OriginFromContext(ctx.Request.Context())
+	assert.NotEmpty(t, origin.RequestID)
+	assert.Equal(t, origin.RequestID, w.Header().Get(RequestIDHeader))
+}
+
+func TestGRPCAuthOrigin(t *testing.T) {
+	middleware := &Middleware{
+		keys:   testKeyRing(t),
+		logger: zap.NewNop(),
+	}
+	tokenString, _, err := middleware.newToken(uuid.New())
+	assert.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(Authorization, "Bearer "+tokenString, RequestIDHeader, "request"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+
+	var origin usecases.Origin
+	info := &grpc.UnaryServerInfo{FullMethod: "test"}
+	_, err = middleware.GRPCClientIP(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return middleware.GRPCAuth(ctx, req, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			origin = usecases.OriginFromContext(ctx)
+			return new(interface{}), nil
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, usecases.Origin{
+		AuthMethod: usecases.AuthMethodBearer,
+		ClientIP:   "192.0.2.1",
+		RequestID:  "request",
+	}, origin)
+}
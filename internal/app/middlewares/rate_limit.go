@@ -0,0 +1,203 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/ratelimit"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Names of rate limit headers.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// methodClasses are classes of rate limits of gRPC methods.
+// Methods of Admin service are limited by admin class, other methods which are not presented are not limited.
+var methodClasses = map[string]string{
+	pb.URLShortener_CreateShortLink_FullMethodName:          ratelimit.ClassCreate,
+	pb.URLShortener_CreateShortLinkJSON_FullMethodName:      ratelimit.ClassCreate,
+	pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName: ratelimit.ClassBatch,
+	pb.URLShortener_GetShortLink_FullMethodName:             ratelimit.ClassRedirect,
+}
+
+// RateLimit limit requests of each user, API key or client IP by token buckets of classes of requests.
+// Requests are allowed if limiter fails, so storage of limiter does not stop service.
+type RateLimit struct {
+	limiter ratelimit.Limiter
+	limits  map[string]ratelimit.Limit
+	logger  *zap.Logger
+}
+
+// NewRateLimit create new RateLimit.
+// Requests are not limited if limiter is nil.
+func NewRateLimit(logger *zap.Logger, limiter ratelimit.Limiter, limits map[string]ratelimit.Limit) *RateLimit {
+	return &RateLimit{
+		limiter: limiter,
+		limits:  limits,
+		logger:  logger,
+	}
+}
+
+// Handler limit requests of class and set RateLimit headers.
+// Request which exceeds limit is rejected with 429 and Retry-After header.
+// Requests are not limited by nil RateLimit. It has to be used after Auth.
+func (r *RateLimit) Handler(class string) gin.HandlerFunc {
+	if r == nil || r.limiter == nil || !r.limits[class].Enabled() {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	limit := r.limits[class]
+	return func(ctx *gin.Context) {
+		var userID string
+		if token, ok := ctx.Value(Authorization).(*JWT); ok && !ctx.GetBool(AuthorizationNew) {
+			userID = token.UserID.String()
+		}
+		identities := rateLimitIdentities(
+			ctx.Request.Context(),
+			userID,
+			apiKey(ctx.Request.Header.Values(APIKeyHeader), ctx.Request.Header.Values(Authorization)),
+		)
+
+		result, err := r.allow(ctx.Request.Context(), class, identities, limit)
+		if err != nil {
+			r.logger.Error("Can not check rate limit", zap.Error(err))
+			ctx.Next()
+			return
+		}
+
+		for key, value := range rateLimitHeaders(result) {
+			ctx.Header(key, value)
+		}
+		if !result.Allowed {
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"error": http.StatusText(http.StatusTooManyRequests)})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// GRPCInterceptor limit requests of methods by classes and set RateLimit headers.
+// Request which exceeds limit is rejected with ResourceExhausted and retry-after header.
+// It has to be used after GRPCAuth.
+func (r *RateLimit) GRPCInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	class, ok := methodClasses[info.FullMethod]
+	if isAdminMethod(info.FullMethod) {
+		class, ok = ratelimit.ClassAdmin, true
+	}
+	limit := r.limits[class]
+	if r.limiter == nil || !ok || !limit.Enabled() {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var userID string
+	if userIDs := md.Get(UserID); len(md.Get(AuthorizationNew)) == 0 && len(userIDs) != 0 {
+		userID = userIDs[0]
+	}
+	identities := rateLimitIdentities(ctx, userID, apiKey(md.Get(APIKeyHeader), md.Get(Authorization)))
+
+	result, err := r.allow(ctx, class, identities, limit)
+	if err != nil {
+		r.logger.Error("Can not check rate limit", zap.Error(err))
+		return handler(ctx, req)
+	}
+
+	headers := metadata.MD{}
+	for key, value := range rateLimitHeaders(result) {
+		headers.Set(key, value)
+	}
+	err = grpc.SetHeader(ctx, headers)
+	if err != nil {
+		r.logger.Debug("Can not set rate limit headers", zap.Error(err))
+	}
+	if !result.Allowed {
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit of %s requests is exceeded", class)
+	}
+	return handler(ctx, req)
+}
+
+// allow take token from buckets of class of all identities.
+// Request is rejected by the first bucket which has no tokens, buckets after it are not touched.
+// Result of allowed request is result of bucket with the least remaining tokens.
+func (r *RateLimit) allow(
+	ctx context.Context,
+	class string,
+	identities []string,
+	limit ratelimit.Limit,
+) (ratelimit.Result, error) {
+	var result ratelimit.Result
+	for i, identity := range identities {
+		current, err := r.limiter.Allow(ctx, class+":"+identity, limit)
+		if err != nil {
+			return ratelimit.Result{}, fmt.Errorf("can not take token of %s: %w", identity, err)
+		}
+		if !current.Allowed {
+			return current, nil
+		}
+		if i == 0 || current.Remaining < result.Remaining {
+			result = current
+		}
+	}
+	return result, nil
+}
+
+// rateLimitIdentities return identities whose requests are limited together.
+// Requests which are authenticated by API key are limited by key, so keys of one user have separate limits.
+// Other requests are limited by client IP and requests of users which are not new are limited by user too,
+// so client which rotates cookies of anonymous users does not get new limit with every user.
+func rateLimitIdentities(ctx context.Context, userID string, key string) []string {
+	if usecases.OriginFromContext(ctx).AuthMethod == usecases.AuthMethodAPIKey && key != "" {
+		hash := sha256.Sum256([]byte(key))
+		return []string{"api_key:" + hex.EncodeToString(hash[:])}
+	}
+	identities := []string{"ip:" + ClientIPFromContext(ctx)}
+	if userID != "" {
+		identities = append(identities, "user:"+userID)
+	}
+	return identities
+}
+
+// rateLimitHeaders return RateLimit headers of result, Retry-After is set only for rejected requests.
+// Durations are rounded up to seconds.
+func rateLimitHeaders(result ratelimit.Result) map[string]string {
+	headers := map[string]string{
+		RateLimitLimitHeader:     strconv.Itoa(result.Limit),
+		RateLimitRemainingHeader: strconv.Itoa(result.Remaining),
+		RateLimitResetHeader:     ceilSeconds(result.Reset),
+	}
+	if !result.Allowed {
+		headers[RetryAfterHeader] = ceilSeconds(result.RetryAfter)
+	}
+	return headers
+}
+
+// ceilSeconds format duration as amount of seconds which is rounded up.
+func ceilSeconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
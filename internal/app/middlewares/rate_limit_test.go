@@ -0,0 +1,234 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/RexArseny/url_shortener/internal/app/models/proto"
+	"github.com/RexArseny/url_shortener/internal/app/ratelimit"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func (failingLimiter) Close() error {
+	return nil
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	userID := uuid.New()
+	rateLimit := NewRateLimit(zap.NewNop(), ratelimit.NewMemoryLimiter(), map[string]ratelimit.Limit{
+		ratelimit.ClassCreate: {Rate: 0.001, Burst: 2},
+	})
+
+	router := gin.New()
+	router.Use((&Middleware{}).ClientIP(), func(ctx *gin.Context) {
+		switch ctx.GetHeader("X-User") {
+		case "user":
+			ctx.Set(Authorization, &JWT{UserID: userID})
+			ctx.Set(AuthorizationNew, false)
+		case "api_key":
+			ctx.Set(Authorization, &JWT{UserID: userID})
+			ctx.Set(AuthorizationNew, false)
+			withOrigin(ctx, usecases.AuthMethodAPIKey)
+		case "rotated":
+			ctx.Set(Authorization, &JWT{UserID: uuid.New()})
+			ctx.Set(AuthorizationNew, false)
+		default:
+			ctx.Set(Authorization, &JWT{UserID: uuid.New()})
+			ctx.Set(AuthorizationNew, true)
+		}
+	})
+	router.POST("/", rateLimit.Handler(ratelimit.ClassCreate), func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+	router.GET("/", rateLimit.Handler(ratelimit.ClassRedirect), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	type want struct {
+		remaining  string
+		retryAfter string
+		statusCode int
+	}
+	tests := []struct {
+		name   string
+		method string
+		user   string
+		apiKey string
+		ip     string
+		want   want
+	}{
+		{
+			name:   "first request of ip",
+			method: http.MethodPost,
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusCreated, remaining: "1"},
+		},
+		{
+			name:   "second request of ip",
+			method: http.MethodPost,
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusCreated, remaining: "0"},
+		},
+		{
+			name:   "exceeded limit of ip",
+			method: http.MethodPost,
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusTooManyRequests, remaining: "0", retryAfter: "1000"},
+		},
+		{
+			name:   "another ip",
+			method: http.MethodPost,
+			ip:     "192.0.2.2",
+			want:   want{statusCode: http.StatusCreated, remaining: "1"},
+		},
+		{
+			name:   "user from exceeded ip",
+			method: http.MethodPost,
+			user:   "user",
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusTooManyRequests, remaining: "0", retryAfter: "1000"},
+		},
+		{
+			name:   "user",
+			method: http.MethodPost,
+			user:   "user",
+			ip:     "192.0.2.3",
+			want:   want{statusCode: http.StatusCreated, remaining: "1"},
+		},
+		{
+			name:   "user from another ip",
+			method: http.MethodPost,
+			user:   "user",
+			ip:     "192.0.2.4",
+			want:   want{statusCode: http.StatusCreated, remaining: "0"},
+		},
+		{
+			name:   "first rotated anonymous user",
+			method: http.MethodPost,
+			user:   "rotated",
+			ip:     "192.0.2.5",
+			want:   want{statusCode: http.StatusCreated, remaining: "1"},
+		},
+		{
+			name:   "second rotated anonymous user",
+			method: http.MethodPost,
+			user:   "rotated",
+			ip:     "192.0.2.5",
+			want:   want{statusCode: http.StatusCreated, remaining: "0"},
+		},
+		{
+			name:   "third rotated anonymous user",
+			method: http.MethodPost,
+			user:   "rotated",
+			ip:     "192.0.2.5",
+			want:   want{statusCode: http.StatusTooManyRequests, remaining: "0", retryAfter: "1000"},
+		},
+		{
+			name:   "api key of user",
+			method: http.MethodPost,
+			user:   "api_key",
+			apiKey: "usk_key",
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusCreated, remaining: "1"},
+		},
+		{
+			name:   "disabled class",
+			method: http.MethodGet,
+			ip:     "192.0.2.1",
+			want:   want{statusCode: http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(tt.method, "/", nil)
+			request.RemoteAddr = tt.ip + ":1234"
+			request.Header.Set("X-User", tt.user)
+			request.Header.Set(APIKeyHeader, tt.apiKey)
+
+			router.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.want.statusCode, w.Code)
+			assert.Equal(t, tt.want.remaining, w.Header().Get(RateLimitRemainingHeader))
+			assert.Equal(t, tt.want.retryAfter, w.Header().Get(RetryAfterHeader))
+		})
+	}
+
+	router = gin.New()
+	failing := NewRateLimit(zap.NewNop(), failingLimiter{}, map[string]ratelimit.Limit{
+		ratelimit.ClassCreate: {Rate: 1},
+	})
+	router.POST("/", failing.Handler(ratelimit.ClassCreate), func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestRateLimitGRPCInterceptor(t *testing.T) {
+	rateLimit := NewRateLimit(zap.NewNop(), ratelimit.NewMemoryLimiter(), map[string]ratelimit.Limit{
+		ratelimit.ClassBatch: {Rate: 0.001, Burst: 1},
+		ratelimit.ClassAdmin: {Rate: 0.001, Burst: 1},
+	})
+	var calls int
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return new(interface{}), nil
+	}
+	call := func(ctx context.Context, method string) error {
+		_, err := rateLimit.GRPCInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	newContext := func(ip string) context.Context {
+		ctx := context.WithValue(context.Background(), clientIPKey{}, ip)
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(UserID, uuid.NewString()))
+	}
+	ctx := newContext("192.0.2.1")
+
+	assert.NoError(t, call(ctx, pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName))
+	err := call(ctx, pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NoError(t, call(ctx, pb.Admin_GetUsers_FullMethodName))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(ctx, pb.Admin_SearchLinks_FullMethodName)))
+	assert.NoError(t, call(ctx, pb.URLShortener_CreateShortLink_FullMethodName))
+	assert.NoError(t, call(ctx, pb.URLShortener_GetShortLinksOfUser_FullMethodName))
+
+	err = call(newContext("192.0.2.1"), pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	another := newContext("192.0.2.2")
+	assert.NoError(t, call(another, pb.URLShortener_CreateShortLinkJSONBatch_FullMethodName))
+	assert.Equal(t, 5, calls)
+}
+
+func TestRateLimitIdentities(t *testing.T) {
+	ctx := context.WithValue(context.Background(), clientIPKey{}, "192.0.2.1")
+	assert.Equal(t, []string{"ip:192.0.2.1"}, rateLimitIdentities(ctx, "", ""))
+	assert.Equal(t, []string{"ip:192.0.2.1", "user:id"}, rateLimitIdentities(ctx, "id", "usk_key"))
+
+	ctx = usecases.WithOrigin(ctx, usecases.Origin{AuthMethod: usecases.AuthMethodAPIKey})
+	identities := rateLimitIdentities(ctx, "id", "usk_key")
+	if assert.Len(t, identities, 1) {
+		assert.Contains(t, identities[0], "api_key:")
+		assert.NotContains(t, identities[0], "usk_key")
+	}
+	assert.NotEqual(t, identities, rateLimitIdentities(ctx, "id", "usk_another"))
+}
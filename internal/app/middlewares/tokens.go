@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Names of refresh token constants.
+const (
+	RefreshToken     = "RefreshToken"
+	RefreshTokenPath = "/api/auth"
+	tokenType        = "Bearer"
+)
+
+// RefreshTokenIssuer is an interface of issuers of refresh tokens.
+type RefreshTokenIssuer interface {
+	// IssueRefreshToken issue refresh token of user which starts new family of tokens.
+	IssueRefreshToken(ctx context.Context, userID uuid.UUID, ttl time.Duration) (string, error)
+	// RotateRefreshToken exchange refresh token for new one and return user of token.
+	RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (uuid.UUID, string, error)
+}
+
+// UseRefreshTokens turn on issuance of refresh tokens which live for ttl.
+func (m *Middleware) UseRefreshTokens(issuer RefreshTokenIssuer, ttl time.Duration) {
+	m.refreshTokens = issuer
+	m.refreshTTL = ttl
+}
+
+// Token issue access and refresh tokens of user of request.
+// User is created if request has no JWT, so clients which use only headers can get their first tokens.
+// It has to be used after Auth.
+func (m *Middleware) Token() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if m.refreshTokens == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+			return
+		}
+		token, ok := ctx.Value(Authorization).(*JWT)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			return
+		}
+
+		refreshToken, err := m.refreshTokens.IssueRefreshToken(ctx.Request.Context(), token.UserID, m.refreshTTL)
+		if err != nil {
+			m.logger.Error("Can not issue refresh token", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+
+		ctx.Writer.Header().Del("Set-Cookie")
+		m.tokens(ctx, token.UserID, refreshToken)
+	}
+}
+
+// Refresh exchange refresh token from request body or cookie for new access and refresh tokens.
+// Refresh token is rotated on every use and reuse of rotated token revokes all tokens of its family.
+// It does not require Auth, so expired access token does not create new user.
+// Refresh token from cookie is accepted only from the same origin as cookies of Auth.
+func (m *Middleware) Refresh() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if m.refreshTokens == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": http.StatusText(http.StatusNotFound)})
+			return
+		}
+
+		data, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+		var request models.RefreshRequest
+		if len(data) != 0 {
+			err = json.Unmarshal(data, &request)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+				return
+			}
+		}
+		if request.RefreshToken == "" {
+			request.RefreshToken, _ = ctx.Cookie(RefreshToken)
+			if request.RefreshToken != "" && !m.sameOrigin(ctx.Request) {
+				ctx.JSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+				return
+			}
+		}
+		if request.RefreshToken == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			return
+		}
+
+		userID, refreshToken, err := m.refreshTokens.RotateRefreshToken(
+			ctx.Request.Context(),
+			request.RefreshToken,
+			m.refreshTTL,
+		)
+		if err != nil {
+			if errors.Is(err, usecases.ErrRefreshTokenReused) {
+				m.logger.Warn("Refresh token is reused, its family is revoked")
+			}
+			if errors.Is(err, usecases.ErrInvalidRefreshToken) {
+				m.setRefreshCookie(ctx, "", -1)
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+				return
+			}
+			m.logger.Error("Can not rotate refresh token", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+
+		m.tokens(ctx, userID, refreshToken)
+	}
+}
+
+// tokens respond with new access token of user and refresh token and put them in cookies.
+// Refresh token is omitted if it is empty.
+func (m *Middleware) tokens(ctx *gin.Context, userID uuid.UUID, refreshToken string) {
+	accessToken, _, err := m.newToken(userID)
+	if err != nil {
+		m.logger.Error("Can not sign token", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	m.setCookie(ctx, accessToken)
+	if refreshToken != "" {
+		m.setRefreshCookie(ctx, refreshToken, int(m.refreshTTL.Seconds()))
+	}
+	ctx.JSON(http.StatusOK, models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		ExpiresIn:    maxAge,
+	})
+}
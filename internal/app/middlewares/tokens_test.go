@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/RexArseny/url_shortener/internal/app/usecases"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTokens(t *testing.T) {
+	middleware := &Middleware{keys: testKeyRing(t), logger: zap.NewNop()}
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", middleware.Refresh())
+	router.POST("/api/auth/token", middleware.Auth(), middleware.Token())
+
+	serve := func(path string, body string, header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if header != "" {
+			request.Header.Set(Authorization, header)
+		}
+		router.ServeHTTP(w, request)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) models.TokenResponse {
+		var response models.TokenResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	w := serve("/api/auth/token", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	interactor := usecases.NewInteractor(
+		context.Background(),
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		usecases.DeleterConfig{},
+	)
+	middleware.UseRefreshTokens(&interactor, time.Hour)
+
+	w = serve("/api/auth/token", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, w.Result().Cookies(), 2)
+	first := decode(w)
+	assert.Equal(t, "Bearer", first.TokenType)
+	assert.Equal(t, maxAge, first.ExpiresIn)
+	claims, err := middleware.parseToken(first.AccessToken)
+	assert.NoError(t, err)
+
+	w = serve("/api/auth/token", "", "Bearer "+first.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	another := decode(w)
+	anotherClaims, err := middleware.parseToken(another.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, claims.UserID, anotherClaims.UserID)
+
+	w = serve("/api/auth/refresh", `{"refresh_token":"`+first.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	second := decode(w)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	refreshedClaims, err := middleware.parseToken(second.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, claims.UserID, refreshedClaims.UserID)
+
+	w = serve("/api/auth/refresh", `{"refresh_token":"`+first.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve("/api/auth/refresh", `{"refresh_token":"`+second.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve("/api/auth/refresh", `{"refresh_token":"`+another.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	third := decode(w)
+
+	refreshByCookie := func(site string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+		request.AddCookie(&http.Cookie{Name: RefreshToken, Value: third.RefreshToken})
+		request.Header.Set(secFetchSite, site)
+		router.ServeHTTP(w, request)
+		return w
+	}
+	w = refreshByCookie("cross-site")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = refreshByCookie("same-origin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve("/api/auth/refresh", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve("/api/auth/refresh", `{"refresh_token":`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // ShortenRequest is a model for URL shortening request.
 type ShortenRequest struct {
 	URL string `json:"url"`
@@ -17,9 +23,38 @@ type ShortenBatchRequest struct {
 }
 
 // ShortenBatchResponse is a model for URLs shortening response.
+// Status and Error are set only in per item mode.
 type ShortenBatchResponse struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
+	Status        string `json:"status,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Statuses of items of batch shortening.
+const (
+	BatchStatusCreated  = "created"
+	BatchStatusExisting = "existing"
+	BatchStatusInvalid  = "invalid"
+	BatchStatusError    = "error"
+)
+
+// Statuses of rows of bulk shortening.
+const (
+	BulkStatusCreated  = "created"
+	BulkStatusConflict = "conflict"
+	BulkStatusInvalid  = "invalid"
+	BulkStatusError    = "error"
+)
+
+// ShortenBulkResult is a model for result of row of bulk shortening.
+// Row is a number of row of upload starting from 1.
+type ShortenBulkResult struct {
+	CorrelationID string `json:"correlation_id,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	Row           int    `json:"row"`
 }
 
 // ShortenOfUserResponse is a model for URL of user response.
@@ -33,3 +68,121 @@ type Stats struct {
 	URLs  int `json:"urls"`
 	Users int `json:"users"`
 }
+
+// Statuses of service readiness.
+const (
+	ReadinessReady    = "ready"
+	ReadinessNotReady = "not_ready"
+)
+
+// Readiness is a model for readiness response.
+type Readiness struct {
+	Components map[string]any `json:"components,omitempty"`
+	Status     string         `json:"status"`
+}
+
+// Scopes of API keys.
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeDelete = "delete"
+	ScopeAdmin  = "admin"
+)
+
+// Roles of users which are carried in JWT.
+const (
+	RoleAdmin = "admin"
+)
+
+// APIKeyRequest is a model for API key creation request.
+// Key gets all scopes if Scopes is empty.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse is a model for API key response.
+// Key is presented only in response of creation.
+type APIKeyResponse struct {
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	ID        string     `json:"id"`
+	Key       string     `json:"key,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Scopes    []string   `json:"scopes"`
+}
+
+// RefreshRequest is a model for refresh of tokens request.
+// Refresh token is taken from cookie if it is not presented in request.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is a model for tokens response.
+// ExpiresIn is a lifetime of access token in seconds.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// JWK is a model for public key of JWT in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKS is a model for JSON Web Key Set response.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// AdminLink is a model for link in admin response.
+type AdminLink struct {
+	CreatedAt   time.Time `json:"created_at"`
+	ID          string    `json:"id"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	UserID      uuid.UUID `json:"user_id"`
+	Deleted     bool      `json:"deleted"`
+	Disabled    bool      `json:"disabled"`
+}
+
+// AdminUser is a model for user in admin response.
+type AdminUser struct {
+	UserID uuid.UUID `json:"user_id"`
+	URLs   int       `json:"urls"`
+}
+
+// TransferRequest is a model for transfer of links to another user request.
+// URLs are ids of short URLs.
+type TransferRequest struct {
+	URLs   []string  `json:"urls"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// TransferResponse is a model for transfer of links response.
+type TransferResponse struct {
+	Transferred int `json:"transferred"`
+}
+
+// AuditEvent is a model for event of audit log in admin response.
+// LinkID is an id of short URL, UserID is an owner of link and ActorID is a user who made the change.
+type AuditEvent struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	Before     *AdminLink `json:"before,omitempty"`
+	After      *AdminLink `json:"after,omitempty"`
+	Action     string     `json:"action"`
+	LinkID     string     `json:"link_id"`
+	AuthMethod string     `json:"auth_method,omitempty"`
+	ClientIP   string     `json:"client_ip,omitempty"`
+	RequestID  string     `json:"request_id,omitempty"`
+	ID         int64      `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ActorID    uuid.UUID  `json:"actor_id"`
+}
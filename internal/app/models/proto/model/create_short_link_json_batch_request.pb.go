@@ -22,10 +22,13 @@ const (
 )
 
 type CreateShortLinkJSONBatchRequest struct {
-	state               protoimpl.MessageState `protogen:"opaque.v1"`
-	xxx_hidden_Requests *[]*BatchRequest       `protobuf:"bytes,1,rep,name=requests"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Requests    *[]*BatchRequest       `protobuf:"bytes,1,rep,name=requests"`
+	xxx_hidden_PerItem     bool                   `protobuf:"varint,2,opt,name=per_item,json=perItem"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *CreateShortLinkJSONBatchRequest) Reset() {
@@ -62,14 +65,39 @@ func (x *CreateShortLinkJSONBatchRequest) GetRequests() []*BatchRequest {
 	return nil
 }
 
+func (x *CreateShortLinkJSONBatchRequest) GetPerItem() bool {
+	if x != nil {
+		return x.xxx_hidden_PerItem
+	}
+	return false
+}
+
 func (x *CreateShortLinkJSONBatchRequest) SetRequests(v []*BatchRequest) {
 	x.xxx_hidden_Requests = &v
 }
 
+func (x *CreateShortLinkJSONBatchRequest) SetPerItem(v bool) {
+	x.xxx_hidden_PerItem = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 1, 2)
+}
+
+func (x *CreateShortLinkJSONBatchRequest) HasPerItem() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 1)
+}
+
+func (x *CreateShortLinkJSONBatchRequest) ClearPerItem() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 1)
+	x.xxx_hidden_PerItem = false
+}
+
 type CreateShortLinkJSONBatchRequest_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	Requests []*BatchRequest
+	PerItem  *bool
 }
 
 func (b0 CreateShortLinkJSONBatchRequest_builder) Build() *CreateShortLinkJSONBatchRequest {
@@ -77,6 +105,10 @@ func (b0 CreateShortLinkJSONBatchRequest_builder) Build() *CreateShortLinkJSONBa
 	b, x := &b0, m0
 	_, _ = b, x
 	x.xxx_hidden_Requests = &b.Requests
+	if b.PerItem != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 1, 2)
+		x.xxx_hidden_PerItem = *b.PerItem
+	}
 	return m0
 }
 
@@ -84,9 +116,10 @@ var File_create_short_link_json_batch_request_proto protoreflect.FileDescriptor
 
 const file_create_short_link_json_batch_request_proto_rawDesc = "" +
 	"\n" +
-	"*create_short_link_json_batch_request.proto\x12\vproto.model\x1a\x13batch_request.proto\x1a!google/protobuf/go_features.proto\"X\n" +
+	"*create_short_link_json_batch_request.proto\x12\vproto.model\x1a\x13batch_request.proto\x1a!google/protobuf/go_features.proto\"s\n" +
 	"\x1fCreateShortLinkJSONBatchRequest\x125\n" +
-	"\brequests\x18\x01 \x03(\v2\x19.proto.model.BatchRequestR\brequestsBLZBgithub.com/RexArseny/url_shortener/internal/app/models/proto/model\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe8\a"
+	"\brequests\x18\x01 \x03(\v2\x19.proto.model.BatchRequestR\brequests\x12\x19\n" +
+	"\bper_item\x18\x02 \x01(\bR\aperItemBLZBgithub.com/RexArseny/url_shortener/internal/app/models/proto/model\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe8\a"
 
 var file_create_short_link_json_batch_request_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_create_short_link_json_batch_request_proto_goTypes = []any{
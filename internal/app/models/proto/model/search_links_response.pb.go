@@ -0,0 +1,128 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: search_links_response.proto
+
+package model
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	_ "google.golang.org/protobuf/types/gofeaturespb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SearchLinksResponse struct {
+	state            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Links *[]*AdminLink          `protobuf:"bytes,1,rep,name=links"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SearchLinksResponse) Reset() {
+	*x = SearchLinksResponse{}
+	mi := &file_search_links_response_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchLinksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchLinksResponse) ProtoMessage() {}
+
+func (x *SearchLinksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_search_links_response_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *SearchLinksResponse) GetLinks() []*AdminLink {
+	if x != nil {
+		if x.xxx_hidden_Links != nil {
+			return *x.xxx_hidden_Links
+		}
+	}
+	return nil
+}
+
+func (x *SearchLinksResponse) SetLinks(v []*AdminLink) {
+	x.xxx_hidden_Links = &v
+}
+
+type SearchLinksResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Links []*AdminLink
+}
+
+func (b0 SearchLinksResponse_builder) Build() *SearchLinksResponse {
+	m0 := &SearchLinksResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Links = &b.Links
+	return m0
+}
+
+var File_search_links_response_proto protoreflect.FileDescriptor
+
+const file_search_links_response_proto_rawDesc = "" +
+	"\n" +
+	"\x1bsearch_links_response.proto\x12\vproto.model\x1a\x10admin_link.proto\x1a!google/protobuf/go_features.proto\"C\n" +
+	"\x13SearchLinksResponse\x12,\n" +
+	"\x05links\x18\x01 \x03(\v2\x16.proto.model.AdminLinkR\x05linksBLZBgithub.com/RexArseny/url_shortener/internal/app/models/proto/model\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe8\a"
+
+var file_search_links_response_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_search_links_response_proto_goTypes = []any{
+	(*SearchLinksResponse)(nil), // 0: proto.model.SearchLinksResponse
+	(*AdminLink)(nil),           // 1: proto.model.AdminLink
+}
+var file_search_links_response_proto_depIdxs = []int32{
+	1, // 0: proto.model.SearchLinksResponse.links:type_name -> proto.model.AdminLink
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_search_links_response_proto_init() }
+func file_search_links_response_proto_init() {
+	if File_search_links_response_proto != nil {
+		return
+	}
+	file_admin_link_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_search_links_response_proto_rawDesc), len(file_search_links_response_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_search_links_response_proto_goTypes,
+		DependencyIndexes: file_search_links_response_proto_depIdxs,
+		MessageInfos:      file_search_links_response_proto_msgTypes,
+	}.Build()
+	File_search_links_response_proto = out.File
+	file_search_links_response_proto_goTypes = nil
+	file_search_links_response_proto_depIdxs = nil
+}
@@ -0,0 +1,108 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: set_link_disabled_response.proto
+
+package model
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	_ "google.golang.org/protobuf/types/gofeaturespb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SetLinkDisabledResponse struct {
+	state         protoimpl.MessageState `protogen:"opaque.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLinkDisabledResponse) Reset() {
+	*x = SetLinkDisabledResponse{}
+	mi := &file_set_link_disabled_response_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLinkDisabledResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkDisabledResponse) ProtoMessage() {}
+
+func (x *SetLinkDisabledResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_set_link_disabled_response_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+type SetLinkDisabledResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+}
+
+func (b0 SetLinkDisabledResponse_builder) Build() *SetLinkDisabledResponse {
+	m0 := &SetLinkDisabledResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	return m0
+}
+
+var File_set_link_disabled_response_proto protoreflect.FileDescriptor
+
+const file_set_link_disabled_response_proto_rawDesc = "" +
+	"\n" +
+	" set_link_disabled_response.proto\x12\vproto.model\x1a!google/protobuf/go_features.proto\"\x19\n" +
+	"\x17SetLinkDisabledResponseBLZBgithub.com/RexArseny/url_shortener/internal/app/models/proto/model\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe8\a"
+
+var file_set_link_disabled_response_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_set_link_disabled_response_proto_goTypes = []any{
+	(*SetLinkDisabledResponse)(nil), // 0: proto.model.SetLinkDisabledResponse
+}
+var file_set_link_disabled_response_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_set_link_disabled_response_proto_init() }
+func file_set_link_disabled_response_proto_init() {
+	if File_set_link_disabled_response_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_set_link_disabled_response_proto_rawDesc), len(file_set_link_disabled_response_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_set_link_disabled_response_proto_goTypes,
+		DependencyIndexes: file_set_link_disabled_response_proto_depIdxs,
+		MessageInfos:      file_set_link_disabled_response_proto_msgTypes,
+	}.Build()
+	File_set_link_disabled_response_proto = out.File
+	file_set_link_disabled_response_proto_goTypes = nil
+	file_set_link_disabled_response_proto_depIdxs = nil
+}
@@ -0,0 +1,141 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: transfer_links_response.proto
+
+package model
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	_ "google.golang.org/protobuf/types/gofeaturespb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TransferLinksResponse struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Transferred int32                  `protobuf:"varint,1,opt,name=transferred"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *TransferLinksResponse) Reset() {
+	*x = TransferLinksResponse{}
+	mi := &file_transfer_links_response_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLinksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLinksResponse) ProtoMessage() {}
+
+func (x *TransferLinksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_transfer_links_response_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *TransferLinksResponse) GetTransferred() int32 {
+	if x != nil {
+		return x.xxx_hidden_Transferred
+	}
+	return 0
+}
+
+func (x *TransferLinksResponse) SetTransferred(v int32) {
+	x.xxx_hidden_Transferred = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 1)
+}
+
+func (x *TransferLinksResponse) HasTransferred() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *TransferLinksResponse) ClearTransferred() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Transferred = 0
+}
+
+type TransferLinksResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Transferred *int32
+}
+
+func (b0 TransferLinksResponse_builder) Build() *TransferLinksResponse {
+	m0 := &TransferLinksResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Transferred != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 1)
+		x.xxx_hidden_Transferred = *b.Transferred
+	}
+	return m0
+}
+
+var File_transfer_links_response_proto protoreflect.FileDescriptor
+
+const file_transfer_links_response_proto_rawDesc = "" +
+	"\n" +
+	"\x1dtransfer_links_response.proto\x12\vproto.model\x1a!google/protobuf/go_features.proto\"9\n" +
+	"\x15TransferLinksResponse\x12 \n" +
+	"\vtransferred\x18\x01 \x01(\x05R\vtransferredBLZBgithub.com/RexArseny/url_shortener/internal/app/models/proto/model\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe8\a"
+
+var file_transfer_links_response_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_transfer_links_response_proto_goTypes = []any{
+	(*TransferLinksResponse)(nil), // 0: proto.model.TransferLinksResponse
+}
+var file_transfer_links_response_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_transfer_links_response_proto_init() }
+func file_transfer_links_response_proto_init() {
+	if File_transfer_links_response_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_transfer_links_response_proto_rawDesc), len(file_transfer_links_response_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_transfer_links_response_proto_goTypes,
+		DependencyIndexes: file_transfer_links_response_proto_depIdxs,
+		MessageInfos:      file_transfer_links_response_proto_msgTypes,
+	}.Build()
+	File_transfer_links_response_proto = out.File
+	file_transfer_links_response_proto_goTypes = nil
+	file_transfer_links_response_proto_depIdxs = nil
+}
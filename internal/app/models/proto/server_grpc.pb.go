@@ -386,3 +386,219 @@ var URLShortener_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "server.proto",
 }
+
+const (
+	Admin_SearchLinks_FullMethodName     = "/proto.Admin/SearchLinks"
+	Admin_SetLinkDisabled_FullMethodName = "/proto.Admin/SetLinkDisabled"
+	Admin_GetUsers_FullMethodName        = "/proto.Admin/GetUsers"
+	Admin_TransferLinks_FullMethodName   = "/proto.Admin/TransferLinks"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	SearchLinks(ctx context.Context, in *model.SearchLinksRequest, opts ...grpc.CallOption) (*model.SearchLinksResponse, error)
+	SetLinkDisabled(ctx context.Context, in *model.SetLinkDisabledRequest, opts ...grpc.CallOption) (*model.SetLinkDisabledResponse, error)
+	GetUsers(ctx context.Context, in *model.GetUsersRequest, opts ...grpc.CallOption) (*model.GetUsersResponse, error)
+	TransferLinks(ctx context.Context, in *model.TransferLinksRequest, opts ...grpc.CallOption) (*model.TransferLinksResponse, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) SearchLinks(ctx context.Context, in *model.SearchLinksRequest, opts ...grpc.CallOption) (*model.SearchLinksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(model.SearchLinksResponse)
+	err := c.cc.Invoke(ctx, Admin_SearchLinks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetLinkDisabled(ctx context.Context, in *model.SetLinkDisabledRequest, opts ...grpc.CallOption) (*model.SetLinkDisabledResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(model.SetLinkDisabledResponse)
+	err := c.cc.Invoke(ctx, Admin_SetLinkDisabled_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetUsers(ctx context.Context, in *model.GetUsersRequest, opts ...grpc.CallOption) (*model.GetUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(model.GetUsersResponse)
+	err := c.cc.Invoke(ctx, Admin_GetUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) TransferLinks(ctx context.Context, in *model.TransferLinksRequest, opts ...grpc.CallOption) (*model.TransferLinksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(model.TransferLinksResponse)
+	err := c.cc.Invoke(ctx, Admin_TransferLinks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+type AdminServer interface {
+	SearchLinks(context.Context, *model.SearchLinksRequest) (*model.SearchLinksResponse, error)
+	SetLinkDisabled(context.Context, *model.SetLinkDisabledRequest) (*model.SetLinkDisabledResponse, error)
+	GetUsers(context.Context, *model.GetUsersRequest) (*model.GetUsersResponse, error)
+	TransferLinks(context.Context, *model.TransferLinksRequest) (*model.TransferLinksResponse, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) SearchLinks(context.Context, *model.SearchLinksRequest) (*model.SearchLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchLinks not implemented")
+}
+func (UnimplementedAdminServer) SetLinkDisabled(context.Context, *model.SetLinkDisabledRequest) (*model.SetLinkDisabledResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLinkDisabled not implemented")
+}
+func (UnimplementedAdminServer) GetUsers(context.Context, *model.GetUsersRequest) (*model.GetUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsers not implemented")
+}
+func (UnimplementedAdminServer) TransferLinks(context.Context, *model.TransferLinksRequest) (*model.TransferLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferLinks not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_SearchLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(model.SearchLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SearchLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SearchLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SearchLinks(ctx, req.(*model.SearchLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetLinkDisabled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(model.SetLinkDisabledRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetLinkDisabled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetLinkDisabled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetLinkDisabled(ctx, req.(*model.SetLinkDisabledRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(model.GetUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetUsers(ctx, req.(*model.GetUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_TransferLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(model.TransferLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).TransferLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_TransferLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).TransferLinks(ctx, req.(*model.TransferLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchLinks",
+			Handler:    _Admin_SearchLinks_Handler,
+		},
+		{
+			MethodName: "SetLinkDisabled",
+			Handler:    _Admin_SetLinkDisabled_Handler,
+		},
+		{
+			MethodName: "GetUsers",
+			Handler:    _Admin_GetUsers_Handler,
+		},
+		{
+			MethodName: "TransferLinks",
+			Handler:    _Admin_TransferLinks_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "server.proto",
+}
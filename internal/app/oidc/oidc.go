@@ -0,0 +1,320 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Names of OpenID Connect constants.
+const (
+	discoveryPath   = "/.well-known/openid-configuration"
+	scopeOpenID     = "openid"
+	challengeMethod = "S256"
+	maxResponseSize = 1 << 20
+	refreshInterval = time.Minute
+	randomSize      = 32
+	requestTimeout  = 10 * time.Second
+)
+
+// Errors of OpenID Connect.
+var (
+	ErrIssuerMismatch = errors.New("issuer of provider does not match configured issuer")
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrExchange       = errors.New("can not exchange code")
+)
+
+// Config is a configuration of OpenID Connect client.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// IDToken is an identity of user verified by provider.
+type IDToken struct {
+	Issuer  string
+	Subject string
+}
+
+// metadata is a part of provider metadata from discovery document.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// tokenResponse is a response of token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// claims is a set of claims of ID token.
+type claims struct {
+	jwt.RegisteredClaims
+	Nonce string `json:"nonce"`
+}
+
+// Provider is a client of OpenID Connect provider which logs users in by authorization code flow with PKCE.
+type Provider struct {
+	client    *http.Client
+	keys      map[string]any
+	m         *sync.RWMutex
+	fetchedAt time.Time
+	cfg       Config
+	metadata  metadata
+}
+
+// NewProvider create new Provider with metadata of issuer from discovery document.
+// Client with request timeout is used if client is nil.
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	provider := &Provider{
+		client: client,
+		m:      &sync.RWMutex{},
+		cfg:    cfg,
+	}
+
+	err := provider.get(ctx, strings.TrimSuffix(cfg.Issuer, "/")+discoveryPath, &provider.metadata)
+	if err != nil {
+		return nil, fmt.Errorf("can not discover provider: %w", err)
+	}
+	if provider.metadata.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("%w: %s", ErrIssuerMismatch, provider.metadata.Issuer)
+	}
+
+	err = provider.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// AuthCodeURL return URL of provider where user is redirected to log in.
+func (p *Provider) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {scopeOpenID},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {challengeMethod},
+	}
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange exchange authorization code for ID token and return verified identity of user.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*IDToken, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("can not create token request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("can not send token request: %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	var token tokenResponse
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("can not decode token response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("%w: %d %s %s", ErrExchange, response.StatusCode, token.Error, token.ErrorDescription)
+	}
+
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify verify signature, issuer, audience, expiration and nonce of ID token.
+// Keys of provider are fetched again if token is signed by unknown key, so provider can rotate keys.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (*IDToken, error) {
+	token, err := jwt.ParseWithClaims(
+		rawIDToken,
+		&claims{},
+		func(token *jwt.Token) (interface{}, error) {
+			return p.verificationKey(ctx, token)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	idClaims, ok := token.Claims.(*claims)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown claims", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(idClaims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if idClaims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return &IDToken{
+		Issuer:  idClaims.Issuer,
+		Subject: idClaims.Subject,
+	}, nil
+}
+
+// verificationKey return key of provider which verifies token.
+func (p *Provider) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+
+	key, ok := p.key(id)
+	if ok {
+		return key, nil
+	}
+
+	p.m.RLock()
+	fetchedAt := p.fetchedAt
+	p.m.RUnlock()
+	if time.Since(fetchedAt) < refreshInterval {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+
+	err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, ok = p.key(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+// key return key with id, token without id is verified by all keys.
+func (p *Provider) key(id string) (interface{}, bool) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+
+	if id == "" {
+		keys := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(p.keys))}
+		for _, key := range p.keys {
+			keys.Keys = append(keys.Keys, key)
+		}
+		return keys, len(keys.Keys) != 0
+	}
+
+	key, ok := p.keys[id]
+	return key, ok
+}
+
+// fetchKeys fetch keys of provider from its JSON Web Key Set.
+func (p *Provider) fetchKeys(ctx context.Context) error {
+	var set jwks
+	err := p.get(ctx, p.metadata.JWKSURI, &set)
+	if err != nil {
+		return fmt.Errorf("can not fetch keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+// get fetch JSON document from url.
+func (p *Provider) get(ctx context.Context, documentURL string, document any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("can not create request: %w", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("can not send request: %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d of %s", response.StatusCode, documentURL)
+	}
+
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(document)
+	if err != nil {
+		return fmt.Errorf("can not decode %s: %w", documentURL, err)
+	}
+	return nil
+}
+
+// RandomString return random URL safe string for state, nonce or code verifier.
+func RandomString() (string, error) {
+	data := make([]byte, randomSize)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("can not generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// CodeChallenge return S256 code challenge of code verifier.
+func CodeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
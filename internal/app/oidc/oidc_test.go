@@ -0,0 +1,218 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientID     = "client"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:8080/api/auth/oidc/callback"
+)
+
+// mockProvider is a local OpenID Connect provider which issues ID tokens for single code.
+type mockProvider struct {
+	server    *httptest.Server
+	keys      []jwk
+	signer    any
+	method    jwt.SigningMethod
+	kid       string
+	code      string
+	challenge string
+	nonce     string
+	audience  string
+	expiresIn time.Duration
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	provider := &mockProvider{audience: testClientID, expiresIn: time.Minute}
+	provider.rotateRSA(t, "rsa-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+discoveryPath, func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(metadata{
+			Issuer:                provider.server.URL,
+			AuthorizationEndpoint: provider.server.URL + "/authorize",
+			TokenEndpoint:         provider.server.URL + "/token",
+			JWKSURI:               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks{Keys: provider.keys})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != testClientID || clientSecret != testClientSecret ||
+			r.PostFormValue("grant_type") != "authorization_code" ||
+			r.PostFormValue("redirect_uri") != testRedirectURL ||
+			r.PostFormValue("code") != provider.code ||
+			CodeChallenge(r.PostFormValue("code_verifier")) != provider.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{IDToken: provider.idToken(t, "subject")})
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (p *mockProvider) rotateRSA(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	p.signer, p.method, p.kid = key, jwt.SigningMethodRS256, kid
+	p.keys = append(p.keys, jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	})
+}
+
+func (p *mockProvider) rotateEC(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	p.signer, p.method, p.kid = key, jwt.SigningMethodES256, kid
+	p.keys = append(p.keys, jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	})
+}
+
+func (p *mockProvider) idToken(t *testing.T, subject string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(p.method, &claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.server.URL,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{p.audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(p.expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Nonce: p.nonce,
+	})
+	token.Header["kid"] = p.kid
+	tokenString, err := token.SignedString(p.signer)
+	assert.NoError(t, err)
+	return tokenString
+}
+
+func (p *mockProvider) config() Config {
+	return Config{
+		Issuer:       p.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	mock := newMockProvider(t)
+
+	_, err := NewProvider(context.Background(), Config{Issuer: mock.server.URL + "/other"}, nil)
+	assert.Error(t, err)
+
+	cfg := mock.config()
+	cfg.Issuer += "/"
+	_, err = NewProvider(context.Background(), cfg, mock.server.Client())
+	assert.ErrorIs(t, err, ErrIssuerMismatch)
+
+	provider, err := NewProvider(context.Background(), mock.config(), mock.server.Client())
+	assert.NoError(t, err)
+	assert.Len(t, provider.keys, 1)
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state", "nonce", CodeChallenge("verifier")))
+	assert.NoError(t, err)
+	assert.Equal(t, mock.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURL},
+		"scope":                 {"openid"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {CodeChallenge("verifier")},
+		"code_challenge_method": {"S256"},
+	}, authURL.Query())
+}
+
+func TestExchange(t *testing.T) {
+	mock := newMockProvider(t)
+	provider, err := NewProvider(context.Background(), mock.config(), mock.server.Client())
+	assert.NoError(t, err)
+
+	verifier, err := RandomString()
+	assert.NoError(t, err)
+	mock.code, mock.challenge, mock.nonce = "code", CodeChallenge(verifier), "nonce"
+
+	idToken, err := provider.Exchange(context.Background(), "code", verifier, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &IDToken{Issuer: mock.server.URL, Subject: "subject"}, idToken)
+
+	_, err = provider.Exchange(context.Background(), "code", "another", "nonce")
+	assert.ErrorIs(t, err, ErrExchange)
+	_, err = provider.Exchange(context.Background(), "another", verifier, "nonce")
+	assert.ErrorIs(t, err, ErrExchange)
+	_, err = provider.Exchange(context.Background(), "code", verifier, "another")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestVerify(t *testing.T) {
+	mock := newMockProvider(t)
+	mock.nonce = "nonce"
+	provider, err := NewProvider(context.Background(), mock.config(), mock.server.Client())
+	assert.NoError(t, err)
+
+	_, err = provider.Verify(context.Background(), mock.idToken(t, "subject"), "nonce")
+	assert.NoError(t, err)
+
+	_, err = provider.Verify(context.Background(), mock.idToken(t, ""), "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	mock.audience = "another"
+	_, err = provider.Verify(context.Background(), mock.idToken(t, "subject"), "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+	mock.audience = testClientID
+
+	mock.expiresIn = -time.Minute
+	_, err = provider.Verify(context.Background(), mock.idToken(t, "subject"), "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+	mock.expiresIn = time.Minute
+
+	mock.rotateEC(t, "ec-1")
+	_, err = provider.Verify(context.Background(), mock.idToken(t, "subject"), "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	provider.fetchedAt = time.Time{}
+	_, err = provider.Verify(context.Background(), mock.idToken(t, "subject"), "nonce")
+	assert.NoError(t, err)
+	assert.Len(t, provider.keys, 2)
+}
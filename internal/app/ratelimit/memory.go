@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter is a storage of token buckets in memory.
+// Full buckets are removed at most once per purge interval.
+type MemoryLimiter struct {
+	purgedAt time.Time
+	buckets  map[string]bucket
+	now      func() time.Time
+	m        *sync.Mutex
+}
+
+// NewMemoryLimiter create new MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		purgedAt: time.Now(),
+		buckets:  make(map[string]bucket),
+		now:      time.Now,
+		m:        &sync.Mutex{},
+	}
+}
+
+// Allow take one token from bucket of key with limit.
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := l.now()
+	if now.Sub(l.purgedAt) >= purgeInterval {
+		for k, state := range l.buckets {
+			if !state.fullAt.After(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.purgedAt = now
+	}
+
+	var state *bucket
+	if current, ok := l.buckets[key]; ok {
+		state = &current
+	}
+	next, result := take(state, now, limit)
+	l.buckets[key] = next
+	return result, nil
+}
+
+// Close does nothing because buckets are not persisted.
+func (l *MemoryLimiter) Close() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresLimiter is a storage of token buckets in rate_limits table of database,
+// so limits are shared by all instances of service.
+// Full buckets are removed at most once per purge interval.
+type PostgresLimiter struct {
+	purgedAt time.Time
+	pool     repository.IPool
+	now      func() time.Time
+	m        *sync.Mutex
+}
+
+// NewPostgresLimiter create new PostgresLimiter.
+func NewPostgresLimiter(ctx context.Context, connString string) (*PostgresLimiter, error) {
+	pool, err := repository.NewPool(ctx, connString, repository.DefaultRetryPolicy())
+	if err != nil {
+		return nil, fmt.Errorf("can not create new pool: %w", err)
+	}
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("can not ping PostgreSQL server: %w", err)
+	}
+	return newPostgresLimiter(pool), nil
+}
+
+// newPostgresLimiter create new PostgresLimiter with provided pool.
+func newPostgresLimiter(pool repository.IPool) *PostgresLimiter {
+	return &PostgresLimiter{
+		purgedAt: time.Now(),
+		pool:     pool,
+		now:      time.Now,
+		m:        &sync.Mutex{},
+	}
+}
+
+// Allow take one token from bucket of key with limit.
+// Absent bucket is created full and then locked in transaction,
+// so concurrent requests of instances take tokens one by one.
+func (p *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := p.now()
+	err := p.purge(ctx, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	err = repository.WithTx(ctx, p.pool, repository.RetryPolicy{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO rate_limits (key, tokens, updated_at, full_at) 
+								VALUES ($1, $2, $3, $3) 
+								ON CONFLICT (key) DO NOTHING`, key, limit.burst(), now)
+		if err != nil {
+			return fmt.Errorf("can not create bucket: %w", err)
+		}
+
+		var state bucket
+		err = tx.QueryRow(ctx, `SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE`, key).
+			Scan(&state.tokens, &state.updatedAt)
+		if err != nil {
+			return fmt.Errorf("can not get bucket: %w", err)
+		}
+
+		state, result = take(&state, now, limit)
+		_, err = tx.Exec(ctx, `UPDATE rate_limits SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1`,
+			key, state.tokens, state.updatedAt, state.fullAt)
+		if err != nil {
+			return fmt.Errorf("can not update bucket: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, err //nolint:wrapcheck // errors are wrapped in transaction
+	}
+	return result, nil
+}
+
+// purge remove full buckets if purge interval is passed.
+func (p *PostgresLimiter) purge(ctx context.Context, now time.Time) error {
+	p.m.Lock()
+	if now.Sub(p.purgedAt) < purgeInterval {
+		p.m.Unlock()
+		return nil
+	}
+	p.purgedAt = now
+	p.m.Unlock()
+
+	_, err := p.pool.Exec(ctx, `DELETE FROM rate_limits WHERE full_at <= $1`, now)
+	if err != nil {
+		return fmt.Errorf("can not delete full buckets: %w", err)
+	}
+	return nil
+}
+
+// Close close pool.
+func (p *PostgresLimiter) Close() error {
+	p.pool.Close()
+	return nil
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+)
+
+// Classes of requests which have separate limits.
+const (
+	ClassCreate   = "create"
+	ClassBatch    = "batch"
+	ClassRedirect = "redirect"
+	ClassAdmin    = "admin"
+)
+
+// purgeInterval is a minimal interval between removals of full buckets.
+const purgeInterval = time.Minute
+
+// Limit is a limit of token bucket which is refilled with Rate tokens per second up to Burst tokens.
+// Limit with not positive rate does not limit requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is a result of taking token from bucket.
+// Reset is a time after which bucket is full, RetryAfter is a time after which request is allowed again.
+type Result struct {
+	Reset      time.Duration
+	RetryAfter time.Duration
+	Limit      int
+	Remaining  int
+	Allowed    bool
+}
+
+// Limiter is an interface of storages of token buckets.
+type Limiter interface {
+	// Allow take one token from bucket of key with limit.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	Close() error
+}
+
+// bucket is a state of token bucket.
+// Bucket which is full at fullAt is the same as absent bucket, so it can be removed after it.
+type bucket struct {
+	updatedAt time.Time
+	fullAt    time.Time
+	tokens    float64
+}
+
+// Limits return limits of classes of requests from configuration.
+func Limits(cfg *config.Config) map[string]Limit {
+	return map[string]Limit{
+		ClassCreate:   {Rate: cfg.RateLimitCreate, Burst: cfg.RateLimitCreateBurst},
+		ClassBatch:    {Rate: cfg.RateLimitBatch, Burst: cfg.RateLimitBatchBurst},
+		ClassRedirect: {Rate: cfg.RateLimitRedirect, Burst: cfg.RateLimitRedirectBurst},
+		ClassAdmin:    {Rate: cfg.RateLimitAdmin, Burst: cfg.RateLimitAdminBurst},
+	}
+}
+
+// Enabled check that limit limits requests.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// burst return capacity of bucket, it is at least one token.
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// take refill bucket up to now and take one token from it if it has one.
+// Absent bucket is full.
+func take(state *bucket, now time.Time, limit Limit) (bucket, Result) {
+	capacity := limit.burst()
+	tokens := capacity
+	if state != nil {
+		elapsed := max(now.Sub(state.updatedAt).Seconds(), 0)
+		tokens = math.Min(capacity, state.tokens+elapsed*limit.Rate)
+	}
+
+	result := Result{Limit: int(capacity)}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((capacity - tokens) / limit.Rate)
+
+	return bucket{
+		updatedAt: now,
+		fullAt:    now.Add(result.Reset),
+		tokens:    tokens,
+	}, result
+}
+
+// seconds convert amount of seconds into duration.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// NewLimiter creates new limiter of type which depends on configuration.
+// Limiter is shared by instances of service through PostgreSQL database if it is used.
+// Return nil limiter if all limits are disabled.
+func NewLimiter(ctx context.Context, cfg *config.Config) (Limiter, error) {
+	enabled := false
+	for _, limit := range Limits(cfg) {
+		enabled = enabled || limit.Enabled()
+	}
+
+	switch {
+	case !enabled:
+		return nil, nil //nolint:nilnil // rate limits are disabled
+	case cfg.DatabaseDSN != "" && !strings.HasPrefix(cfg.DatabaseDSN, repository.SQLiteScheme):
+		limiter, err := NewPostgresLimiter(ctx, cfg.DatabaseDSN)
+		if err != nil {
+			return nil, fmt.Errorf("can not init postgres limiter: %w", err)
+		}
+		return limiter, nil
+	default:
+		return NewMemoryLimiter(), nil
+	}
+}
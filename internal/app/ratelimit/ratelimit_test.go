@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Now()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	state, result := take(nil, now, limit)
+	assert.Equal(t, Result{Reset: 500 * time.Millisecond, Limit: 3, Remaining: 2, Allowed: true}, result)
+	assert.Equal(t, bucket{updatedAt: now, fullAt: now.Add(500 * time.Millisecond), tokens: 2}, state)
+
+	state.tokens = 0.5
+	state, result = take(&state, now, limit)
+	assert.Equal(t, Result{Reset: 1250 * time.Millisecond, RetryAfter: 250 * time.Millisecond, Limit: 3}, result)
+	assert.InDelta(t, 0.5, state.tokens, 0.001)
+
+	state, result = take(&state, now.Add(time.Hour), limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.InDelta(t, 2, state.tokens, 0.001)
+
+	_, result = take(nil, now, Limit{Rate: 0.5})
+	assert.Equal(t, Result{Reset: 2 * time.Second, Limit: 1, Allowed: true}, result)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for _, allowed := range []bool{true, true, false} {
+		result, err := limiter.Allow(ctx, "create:ip:127.0.0.1", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "create:ip:127.0.0.2", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "create:ip:127.0.0.1", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(purgeInterval)
+	_, err = limiter.Allow(ctx, "create:ip:127.0.0.3", limit)
+	assert.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+
+	assert.NoError(t, limiter.Close())
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+	limiter := newPostgresLimiter(mock)
+	limiter.purgedAt = time.Time{}
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 2}
+
+	mock.ExpectExec("DELETE FROM rate_limits").
+		WithArgs(now).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rate_limits").
+		WithArgs("key", 2.0, now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery("SELECT tokens, updated_at FROM rate_limits").
+		WithArgs("key").
+		WillReturnRows(pgxmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.5, now))
+	mock.ExpectExec("UPDATE rate_limits").
+		WithArgs("key", 0.5, now, now.Add(1500*time.Millisecond)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	result, err := limiter.Allow(ctx, "key", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond, Limit: 2}, result)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rate_limits").
+		WithArgs("key", 2.0, now).
+		WillReturnError(errors.New("connection refused"))
+	mock.ExpectRollback()
+	_, err = limiter.Allow(ctx, "key", limit)
+	assert.ErrorContains(t, err, "can not create bucket")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewLimiter(t *testing.T) {
+	limiter, err := NewLimiter(context.Background(), &config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, limiter)
+
+	limiter, err = NewLimiter(context.Background(), &config.Config{
+		DatabaseDSN:       repository.SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db"),
+		RateLimitRedirect: 10,
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryLimiter{}, limiter)
+	assert.NoError(t, limiter.Close())
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// LinkFilter is a filter of links which are searched by administrator.
+// Query is matched as substring of short URL or original URL, zero UserID matches all users,
+// not positive Limit means no limit.
+type LinkFilter struct {
+	Query  string
+	UserID uuid.UUID
+	Limit  int
+	Offset int
+}
+
+// UserLinks is a user with amount of their links.
+type UserLinks struct {
+	UserID uuid.UUID `json:"user_id"`
+	URLs   int       `json:"urls"`
+}
+
+// AdminRepository is an interface of repositories which support administration of links.
+type AdminRepository interface {
+	// SearchLinks return links which match filter including deleted and disabled ones in order of creation.
+	SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error)
+	// SetLinkDisabled disable or reenable link, disabled link is not redirected.
+	// Return link as it was before change.
+	SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) (*Record, error)
+	// GetUsers return users which have links with amount of their links in order of user ID.
+	GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error)
+	// TransferLinks move links to another user.
+	// Return existing links as they were before transfer, unknown short URLs are skipped.
+	TransferLinks(ctx context.Context, shortURLs []string, to uuid.UUID) ([]Record, error)
+}
+
+// searchRecords return records which match filter, records have to be in order of creation.
+func searchRecords(records []Record, filter LinkFilter) []Record {
+	result := make([]Record, 0, len(records))
+	for i := range records {
+		if filter.UserID != uuid.Nil && records[i].UserID != filter.UserID {
+			continue
+		}
+		if !strings.Contains(records[i].ShortURL, filter.Query) &&
+			!strings.Contains(records[i].OriginalURL, filter.Query) {
+			continue
+		}
+		result = append(result, records[i])
+	}
+	return page(result, filter.Limit, filter.Offset)
+}
+
+// usersOfRecords return users of records with amount of their links in order of user ID.
+func usersOfRecords(records []Record, limit int, offset int) []UserLinks {
+	counts := make(map[uuid.UUID]int)
+	for i := range records {
+		counts[records[i].UserID]++
+	}
+
+	users := make([]UserLinks, 0, len(counts))
+	for userID, urls := range counts {
+		users = append(users, UserLinks{UserID: userID, URLs: urls})
+	}
+	slices.SortFunc(users, func(a, b UserLinks) int {
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	})
+	return page(users, limit, offset)
+}
+
+// page return part of items by limit and offset, not positive limit means no limit.
+func page[T any](items []T, limit int, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[max(offset, 0):]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// exportRecords return all links of dumper in order of creation.
+func exportRecords(ctx context.Context, dumper Dumper) ([]Record, error) {
+	var records []Record
+	err := dumper.Export(ctx, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // error of dumper
+	}
+	return records, nil
+}
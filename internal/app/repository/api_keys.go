@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a long-lived key of user for server-to-server clients.
+// Only hash of key is stored, zero RevokedAt means that key is active.
+type APIKey struct {
+	CreatedAt time.Time `json:"created_at"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// APIKeyRepository is an interface of repositories which store API keys.
+type APIKeyRepository interface {
+	// SetAPIKey store new API key.
+	SetAPIKey(ctx context.Context, key APIKey) error
+	// GetAPIKey return API key by hash including revoked one.
+	GetAPIKey(ctx context.Context, hash string) (*APIKey, error)
+	// GetAPIKeysOfUser return API keys of user in order of creation.
+	GetAPIKeysOfUser(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	// RevokeAPIKey mark API key of user as revoked.
+	// Revocation of revoked key keeps its time of revocation.
+	RevokeAPIKey(ctx context.Context, id string, userID uuid.UUID) error
+}
+
+// sortAPIKeys sort API keys in order of creation.
+func sortAPIKeys(keys []APIKey) {
+	slices.SortFunc(keys, func(a, b APIKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// memoryAPIKeys is a storage of API keys in memory.
+type memoryAPIKeys struct {
+	m      *sync.RWMutex
+	keys   map[string]*APIKey
+	hashes map[string]string
+}
+
+// newMemoryAPIKeys create new memoryAPIKeys.
+func newMemoryAPIKeys() *memoryAPIKeys {
+	return &memoryAPIKeys{
+		m:      &sync.RWMutex{},
+		keys:   make(map[string]*APIKey),
+		hashes: make(map[string]string),
+	}
+}
+
+// SetAPIKey store new API key.
+func (k *memoryAPIKeys) SetAPIKey(_ context.Context, key APIKey) error {
+	k.m.Lock()
+	defer k.m.Unlock()
+	k.set(key)
+	return nil
+}
+
+// set store API key replacing key with the same hash.
+func (k *memoryAPIKeys) set(key APIKey) {
+	key.Scopes = slices.Clone(key.Scopes)
+	k.keys[key.Hash] = &key
+	k.hashes[key.ID] = key.Hash
+}
+
+// GetAPIKey return API key by hash including revoked one.
+func (k *memoryAPIKeys) GetAPIKey(_ context.Context, hash string) (*APIKey, error) {
+	k.m.RLock()
+	defer k.m.RUnlock()
+	key, ok := k.keys[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	result := *key
+	result.Scopes = slices.Clone(key.Scopes)
+	return &result, nil
+}
+
+// GetAPIKeysOfUser return API keys of user in order of creation.
+func (k *memoryAPIKeys) GetAPIKeysOfUser(_ context.Context, userID uuid.UUID) ([]APIKey, error) {
+	k.m.RLock()
+	defer k.m.RUnlock()
+	var keys []APIKey
+	for _, key := range k.keys {
+		if key.UserID == userID {
+			result := *key
+			result.Scopes = slices.Clone(key.Scopes)
+			keys = append(keys, result)
+		}
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey mark API key of user as revoked.
+func (k *memoryAPIKeys) RevokeAPIKey(_ context.Context, id string, userID uuid.UUID) error {
+	k.m.Lock()
+	defer k.m.Unlock()
+	key, ok := k.keys[k.hashes[id]]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = time.Now().UTC()
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent is a record of audit log about change of link.
+// Before is empty for created links and After is empty if link state after change is unknown.
+// UserID is an owner of link, ActorID is a user who made the change.
+type AuditEvent struct {
+	CreatedAt  time.Time `json:"created_at"`
+	Before     *Record   `json:"before,omitempty"`
+	After      *Record   `json:"after,omitempty"`
+	Action     string    `json:"action"`
+	ShortURL   string    `json:"short_url"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	ID         int64     `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	ActorID    uuid.UUID `json:"actor_id"`
+}
+
+// AuditFilter is a filter of audit events.
+// Zero UserID matches all users, otherwise events where user is owner or actor are matched.
+// Zero From and To mean no bound of time range, From is inclusive and To is exclusive.
+// Not positive Limit means no limit.
+type AuditFilter struct {
+	From     time.Time
+	To       time.Time
+	ShortURL string
+	UserID   uuid.UUID
+	Limit    int
+}
+
+// AuditRepository is an interface of repositories which store append-only audit log.
+type AuditRepository interface {
+	// AppendAudit store events in audit log, events get their ids from storage.
+	AppendAudit(ctx context.Context, events []AuditEvent) error
+	// ExportAudit call fn for every event which matches filter in order of appending.
+	ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error
+}
+
+// match check that event matches filter without limit.
+func (f AuditFilter) match(event AuditEvent) bool {
+	if f.ShortURL != "" && event.ShortURL != f.ShortURL {
+		return false
+	}
+	if f.UserID != uuid.Nil && event.UserID != f.UserID && event.ActorID != f.UserID {
+		return false
+	}
+	if !f.From.IsZero() && event.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !event.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// marshalAuditRecord marshal state of link of audit event, nil is returned for unknown state.
+func marshalAuditRecord(record *Record) ([]byte, error) {
+	if record == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal audit record: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalAuditRecord unmarshal state of link of audit event, empty data means unknown state.
+func unmarshalAuditRecord(data []byte) (*Record, error) {
+	if len(data) == 0 {
+		return nil, nil //nolint:nilnil // state of link is unknown
+	}
+	var record Record
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return nil, fmt.Errorf("can not unmarshal audit record: %w", err)
+	}
+	return &record, nil
+}
+
+// memoryAudit is a storage of audit log in memory.
+type memoryAudit struct {
+	m      *sync.RWMutex
+	events []AuditEvent
+}
+
+// newMemoryAudit create new memoryAudit.
+func newMemoryAudit() *memoryAudit {
+	return &memoryAudit{
+		m: &sync.RWMutex{},
+	}
+}
+
+// AppendAudit store events in audit log.
+func (a *memoryAudit) AppendAudit(_ context.Context, events []AuditEvent) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.append(a.number(events))
+	return nil
+}
+
+// number set ids of events which follow the last stored event.
+func (a *memoryAudit) number(events []AuditEvent) []AuditEvent {
+	numbered := make([]AuditEvent, 0, len(events))
+	for i := range events {
+		event := events[i]
+		event.ID = int64(len(a.events) + i + 1)
+		numbered = append(numbered, event)
+	}
+	return numbered
+}
+
+// append store numbered events.
+func (a *memoryAudit) append(events []AuditEvent) {
+	a.events = append(a.events, events...)
+}
+
+// ExportAudit call fn for every event which matches filter in order of appending.
+func (a *memoryAudit) ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	a.m.RLock()
+	events := make([]AuditEvent, 0, len(a.events))
+	for i := range a.events {
+		if filter.match(a.events[i]) {
+			events = append(events, a.events[i])
+		}
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	a.m.RUnlock()
+
+	for i := range events {
+		if ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck // error of context
+		}
+		err := fn(events[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
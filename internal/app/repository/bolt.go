@@ -0,0 +1,950 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Timeout of obtaining lock of database file.
+const boltOpenTimeout = time.Second
+
+// Buckets of BoltRepository.
+var (
+	// bucketOriginalURLs maps short URL to original URL.
+	bucketOriginalURLs = []byte("original_urls")
+	// bucketShortURLs maps original URL to short URL.
+	bucketShortURLs = []byte("short_urls")
+	// bucketUsers contains bucket of short URLs per user.
+	bucketUsers = []byte("users")
+	// bucketDeleted contains short URLs which are deleted.
+	bucketDeleted = []byte("deleted")
+	// bucketDisabled contains short URLs which are disabled by administrator.
+	bucketDisabled = []byte("disabled")
+	// bucketCreatedAt maps short URL to time of its creation.
+	bucketCreatedAt = []byte("created_at")
+	// bucketAPIKeys maps hash of API key to the key.
+	bucketAPIKeys = []byte("api_keys")
+	// bucketRefreshTokens maps hash of refresh token to the token.
+	bucketRefreshTokens = []byte("refresh_tokens")
+	// bucketIdentities maps issuer and subject of identity to the identity.
+	bucketIdentities = []byte("identities")
+	// bucketAudit maps sequence number of audit event to the event.
+	bucketAudit = []byte("audit")
+)
+
+// BoltRepository is a repository which stores data in embedded key-value database.
+type BoltRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository create new BoltRepository.
+func NewBoltRepository(path string) (*BoltRepository, error) {
+	db, err := bolt.Open(path, fileMode, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("can not open bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{
+			bucketOriginalURLs,
+			bucketShortURLs,
+			bucketUsers,
+			bucketDeleted,
+			bucketDisabled,
+			bucketCreatedAt,
+			bucketAPIKeys,
+			bucketRefreshTokens,
+			bucketIdentities,
+			bucketAudit,
+		}
+		for _, bucket := range buckets {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return fmt.Errorf("can not create bucket %s: %w", bucket, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		closeErr := db.Close()
+		if closeErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("can not close bolt database: %w", closeErr))
+		}
+		return nil, err
+	}
+
+	return &BoltRepository{
+		db: db,
+	}, nil
+}
+
+// GetOriginalURL return original URL by short URL.
+func (b *BoltRepository) GetOriginalURL(_ context.Context, shortLink string) (*string, error) {
+	var originalURL string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketOriginalURLs).Get([]byte(shortLink))
+		if value == nil {
+			return ErrNotFound
+		}
+		if tx.Bucket(bucketDeleted).Get([]byte(shortLink)) != nil {
+			return ErrURLIsDeleted
+		}
+		if tx.Bucket(bucketDisabled).Get([]byte(shortLink)) != nil {
+			return ErrURLIsDisabled
+		}
+		originalURL = string(value)
+		return nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // errors are created inside transaction
+	}
+
+	return &originalURL, nil
+}
+
+// SetLink add short URL if such does not exist already.
+func (b *BoltRepository) SetLink(
+	_ context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	var result string
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		if shortLink := tx.Bucket(bucketShortURLs).Get([]byte(originalURL)); shortLink != nil {
+			result = string(shortLink)
+			return ErrOriginalURLUniqueViolation
+		}
+
+		shortURL, err := b.put(tx, originalURL, shortURLs, userID)
+		if err != nil {
+			return err
+		}
+		result = shortURL
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrOriginalURLUniqueViolation) {
+			return &result, ErrOriginalURLUniqueViolation
+		}
+		return nil, err //nolint:wrapcheck // errors are wrapped inside transaction
+	}
+
+	return &result, nil
+}
+
+// SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
+func (b *BoltRepository) SetLinks(
+	_ context.Context,
+	batch []models.ShortenBatchRequest,
+	shortURLs [][]string,
+	userID uuid.UUID,
+) ([]LinkResult, error) {
+	var result []LinkResult
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		result = make([]LinkResult, 0, len(batch))
+		for i := range batch {
+			_, err := url.ParseRequestURI(batch[i].OriginalURL)
+			if err != nil {
+				result = append(result, LinkResult{Status: models.BatchStatusInvalid})
+				continue
+			}
+
+			if shortLink := tx.Bucket(bucketShortURLs).Get([]byte(batch[i].OriginalURL)); shortLink != nil {
+				result = append(result, LinkResult{ShortURL: string(shortLink), Status: models.BatchStatusExisting})
+				continue
+			}
+
+			shortURL, err := b.put(tx, batch[i].OriginalURL, shortURLs[i], userID)
+			if errors.Is(err, ErrReachedMaxGenerationRetries) {
+				result = append(result, LinkResult{Status: models.BatchStatusError})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			result = append(result, LinkResult{ShortURL: shortURL, Status: models.BatchStatusCreated})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // errors are wrapped inside transaction
+	}
+
+	return result, nil
+}
+
+// put store original URL with the first free short URL from provided ones.
+func (b *BoltRepository) put(tx *bolt.Tx, originalURL string, shortURLs []string, userID uuid.UUID) (string, error) {
+	originalURLs := tx.Bucket(bucketOriginalURLs)
+	for _, shortURL := range shortURLs {
+		if originalURLs.Get([]byte(shortURL)) != nil {
+			continue
+		}
+
+		err := originalURLs.Put([]byte(shortURL), []byte(originalURL))
+		if err != nil {
+			return "", fmt.Errorf("can not put original url: %w", err)
+		}
+		err = tx.Bucket(bucketShortURLs).Put([]byte(originalURL), []byte(shortURL))
+		if err != nil {
+			return "", fmt.Errorf("can not put short url: %w", err)
+		}
+		user, err := tx.Bucket(bucketUsers).CreateBucketIfNotExists(userID[:])
+		if err != nil {
+			return "", fmt.Errorf("can not create bucket of user: %w", err)
+		}
+		err = user.Put([]byte(shortURL), []byte{})
+		if err != nil {
+			return "", fmt.Errorf("can not put short url of user: %w", err)
+		}
+		err = putCreatedAt(tx, shortURL, time.Now().UTC())
+		if err != nil {
+			return "", err
+		}
+
+		return shortURL, nil
+	}
+	return "", ErrReachedMaxGenerationRetries
+}
+
+// putCreatedAt store time of creation of short URL.
+func putCreatedAt(tx *bolt.Tx, shortURL string, createdAt time.Time) error {
+	if createdAt.IsZero() {
+		return nil
+	}
+	value, err := createdAt.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("can not marshal creation time: %w", err)
+	}
+	err = tx.Bucket(bucketCreatedAt).Put([]byte(shortURL), value)
+	if err != nil {
+		return fmt.Errorf("can not put creation time: %w", err)
+	}
+	return nil
+}
+
+// GetShortLinksOfUser return URLs of user if such exist.
+func (b *BoltRepository) GetShortLinksOfUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.ShortenOfUserResponse, error) {
+	var urls []models.ShortenOfUserResponse
+	err := b.db.View(func(tx *bolt.Tx) error {
+		user := tx.Bucket(bucketUsers).Bucket(userID[:])
+		if user == nil {
+			return nil
+		}
+
+		originalURLs := tx.Bucket(bucketOriginalURLs)
+		return user.ForEach(func(shortURL, _ []byte) error {
+			urls = append(urls, models.ShortenOfUserResponse{
+				ShortURL:    string(shortURL),
+				OriginalURL: string(originalURLs.Get(shortURL)),
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get urls of user: %w", err)
+	}
+
+	return urls, nil
+}
+
+// DeleteURLs delete URLs.
+func (b *BoltRepository) DeleteURLs(_ context.Context, urls []string, userID uuid.UUID) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		user := tx.Bucket(bucketUsers).Bucket(userID[:])
+		if user == nil {
+			return nil
+		}
+
+		deleted := tx.Bucket(bucketDeleted)
+		for _, shortURL := range urls {
+			if user.Get([]byte(shortURL)) == nil {
+				continue
+			}
+			err := deleted.Put([]byte(shortURL), []byte{})
+			if err != nil {
+				return fmt.Errorf("can not put deletion flag: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not delete urls: %w", err)
+	}
+
+	return nil
+}
+
+// Ping check that database is open.
+func (b *BoltRepository) Ping(_ context.Context) error {
+	err := b.db.View(func(_ *bolt.Tx) error {
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not access bolt database: %w", err)
+	}
+	return nil
+}
+
+// Stats return statistic of shortened urls and users in service.
+func (b *BoltRepository) Stats(_ context.Context) (*models.Stats, error) {
+	var stats models.Stats
+	err := b.db.View(func(tx *bolt.Tx) error {
+		stats.URLs = tx.Bucket(bucketOriginalURLs).Stats().KeyN
+		return tx.Bucket(bucketUsers).ForEach(func(_, _ []byte) error {
+			stats.Users++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// SetAPIKey store new API key.
+func (b *BoltRepository) SetAPIKey(_ context.Context, key APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("can not marshal api key: %w", err)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAPIKeys).Put([]byte(key.Hash), data)
+	})
+	if err != nil {
+		return fmt.Errorf("can not set api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey return API key by hash including revoked one.
+func (b *BoltRepository) GetAPIKey(_ context.Context, hash string) (*APIKey, error) {
+	var key APIKey
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketAPIKeys).Get([]byte(hash))
+		if value == nil {
+			return ErrAPIKeyNotFound
+		}
+		return json.Unmarshal(value, &key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get api key: %w", err)
+	}
+	return &key, nil
+}
+
+// GetAPIKeysOfUser return API keys of user in order of creation.
+func (b *BoltRepository) GetAPIKeysOfUser(_ context.Context, userID uuid.UUID) ([]APIKey, error) {
+	var keys []APIKey
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAPIKeys).ForEach(func(_, value []byte) error {
+			var key APIKey
+			err := json.Unmarshal(value, &key)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			if key.UserID == userID {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get api keys of user: %w", err)
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey mark API key of user as revoked.
+func (b *BoltRepository) RevokeAPIKey(_ context.Context, id string, userID uuid.UUID) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketAPIKeys)
+		cursor := bucket.Cursor()
+		for hash, value := cursor.First(); hash != nil; hash, value = cursor.Next() {
+			var key APIKey
+			err := json.Unmarshal(value, &key)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			if key.ID != id || key.UserID != userID {
+				continue
+			}
+			if !key.RevokedAt.IsZero() {
+				return nil
+			}
+			key.RevokedAt = time.Now().UTC()
+			data, err := json.Marshal(key)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			return bucket.Put(hash, data)
+		}
+		return ErrAPIKeyNotFound
+	})
+	if err != nil {
+		return fmt.Errorf("can not revoke api key: %w", err)
+	}
+	return nil
+}
+
+// SetRefreshToken store new refresh token and remove expired tokens of its user.
+func (b *BoltRepository) SetRefreshToken(_ context.Context, token RefreshToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("can not marshal refresh token: %w", err)
+	}
+	now := time.Now()
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRefreshTokens)
+		cursor := bucket.Cursor()
+		for hash, value := cursor.First(); hash != nil; {
+			var stored RefreshToken
+			err := json.Unmarshal(value, &stored)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			if stored.UserID == token.UserID && !stored.ExpiresAt.After(now) {
+				err = cursor.Delete()
+				if err != nil {
+					return err //nolint:wrapcheck // error is wrapped outside of transaction
+				}
+				hash, value = cursor.Seek(hash)
+				continue
+			}
+			hash, value = cursor.Next()
+		}
+		return bucket.Put([]byte(token.Hash), data)
+	})
+	if err != nil {
+		return fmt.Errorf("can not set refresh token: %w", err)
+	}
+	return nil
+}
+
+// UseRefreshToken mark refresh token as used and return its state before marking.
+func (b *BoltRepository) UseRefreshToken(_ context.Context, hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRefreshTokens)
+		value := bucket.Get([]byte(hash))
+		if value == nil {
+			return ErrRefreshTokenNotFound
+		}
+		err := json.Unmarshal(value, &token)
+		if err != nil {
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		if !token.UsedAt.IsZero() {
+			return nil
+		}
+		used := token
+		used.UsedAt = time.Now().UTC()
+		data, err := json.Marshal(used)
+		if err != nil {
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		return bucket.Put([]byte(hash), data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not use refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+func (b *BoltRepository) RevokeRefreshTokens(_ context.Context, familyID string) error {
+	now := time.Now().UTC()
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRefreshTokens)
+		revoked := make(map[string][]byte)
+		err := bucket.ForEach(func(hash, value []byte) error {
+			var token RefreshToken
+			err := json.Unmarshal(value, &token)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			if token.FamilyID != familyID || !token.RevokedAt.IsZero() {
+				return nil
+			}
+			token.RevokedAt = now
+			data, err := json.Marshal(token)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			revoked[string(hash)] = data
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for hash, data := range revoked {
+			err = bucket.Put([]byte(hash), data)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// SetIdentity store identity if it is not stored yet and return stored identity.
+func (b *BoltRepository) SetIdentity(_ context.Context, identity Identity) (*Identity, error) {
+	key := identityBoltKey(identity)
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketIdentities)
+		if value := bucket.Get(key); value != nil {
+			return json.Unmarshal(value, &identity) //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		data, err := json.Marshal(identity)
+		if err != nil {
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not set identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// MergeUser move links of anonymous user into another user.
+// Links of user with identity are not moved.
+func (b *BoltRepository) MergeUser(_ context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to {
+		return nil
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var identified bool
+		err := tx.Bucket(bucketIdentities).ForEach(func(_, value []byte) error {
+			var identity Identity
+			err := json.Unmarshal(value, &identity)
+			identified = identified || identity.UserID == from
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		})
+		if err != nil || identified {
+			return err
+		}
+
+		users := tx.Bucket(bucketUsers)
+		fromUser := users.Bucket(from[:])
+		if fromUser == nil {
+			return nil
+		}
+		toUser, err := users.CreateBucketIfNotExists(to[:])
+		if err != nil {
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		err = fromUser.ForEach(func(shortURL, _ []byte) error {
+			return toUser.Put(shortURL, []byte{})
+		})
+		if err != nil {
+			return err //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		return users.DeleteBucket(from[:])
+	})
+	if err != nil {
+		return fmt.Errorf("can not merge user: %w", err)
+	}
+	return nil
+}
+
+// SearchLinks return links which match filter in order of creation.
+func (b *BoltRepository) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	records, err := exportRecords(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("can not search links: %w", err)
+	}
+	sortRecords(records)
+	return searchRecords(records, filter), nil
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+func (b *BoltRepository) SetLinkDisabled(_ context.Context, shortURL string, disabled bool) (*Record, error) {
+	var record Record
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketOriginalURLs).Get([]byte(shortURL)) == nil {
+			return ErrNotFound
+		}
+		userID, err := owner(tx, shortURL)
+		if err != nil {
+			return err
+		}
+		record, err = boltRecord(tx, shortURL, userID)
+		if err != nil {
+			return err
+		}
+
+		flags := tx.Bucket(bucketDisabled)
+		if disabled {
+			return flags.Put([]byte(shortURL), []byte{}) //nolint:wrapcheck // error is wrapped outside of transaction
+		}
+		return flags.Delete([]byte(shortURL)) //nolint:wrapcheck // error is wrapped outside of transaction
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not set disabling flag: %w", err)
+	}
+	return &record, nil
+}
+
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (b *BoltRepository) GetUsers(_ context.Context, limit int, offset int) ([]UserLinks, error) {
+	var users []UserLinks
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return forEachUser(tx, func(userID uuid.UUID, user *bolt.Bucket) error {
+			users = append(users, UserLinks{UserID: userID, URLs: user.Stats().KeyN})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get users: %w", err)
+	}
+	return page(users, limit, offset), nil
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+func (b *BoltRepository) TransferLinks(_ context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	var records []Record
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(bucketUsers)
+		for _, shortURL := range shortURLs {
+			if tx.Bucket(bucketOriginalURLs).Get([]byte(shortURL)) == nil {
+				continue
+			}
+			from, err := owner(tx, shortURL)
+			if err != nil {
+				return err
+			}
+			record, err := boltRecord(tx, shortURL, from)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+			if from == to {
+				continue
+			}
+
+			if fromUser := users.Bucket(from[:]); fromUser != nil {
+				err = fromUser.Delete([]byte(shortURL))
+				if err != nil {
+					return fmt.Errorf("can not delete short url of user: %w", err)
+				}
+				if key, _ := fromUser.Cursor().First(); key == nil {
+					err = users.DeleteBucket(from[:])
+					if err != nil {
+						return fmt.Errorf("can not delete bucket of user: %w", err)
+					}
+				}
+			}
+			toUser, err := users.CreateBucketIfNotExists(to[:])
+			if err != nil {
+				return fmt.Errorf("can not create bucket of user: %w", err)
+			}
+			err = toUser.Put([]byte(shortURL), []byte{})
+			if err != nil {
+				return fmt.Errorf("can not put short url of user: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not transfer links: %w", err)
+	}
+	return records, nil
+}
+
+// AppendAudit store events in audit log, sequence of bucket is used as ids of events.
+func (b *BoltRepository) AppendAudit(_ context.Context, events []AuditEvent) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketAudit)
+		for i := range events {
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			event := events[i]
+			event.ID = int64(id) //nolint:gosec // sequence does not exceed int64
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+			err = bucket.Put(binary.BigEndian.AppendUint64(nil, id), data)
+			if err != nil {
+				return err //nolint:wrapcheck // error is wrapped outside of transaction
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not append audit events: %w", err)
+	}
+	return nil
+}
+
+// ExportAudit call fn for every event which matches filter in order of appending.
+func (b *BoltRepository) ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	return b.db.View(func(tx *bolt.Tx) error { //nolint:wrapcheck // errors are wrapped inside transaction
+		var count int
+		cursor := tx.Bucket(bucketAudit).Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if ctx.Err() != nil {
+				return ctx.Err() //nolint:wrapcheck // error of context
+			}
+			var event AuditEvent
+			err := json.Unmarshal(value, &event)
+			if err != nil {
+				return fmt.Errorf("can not unmarshal audit event: %w", err)
+			}
+			if !filter.match(event) {
+				continue
+			}
+			err = fn(event)
+			if err != nil {
+				return err
+			}
+			count++
+			if filter.Limit > 0 && count == filter.Limit {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// identityBoltKey return key of identity in bucket of identities.
+func identityBoltKey(identity Identity) []byte {
+	return []byte(identity.Issuer + "\x00" + identity.Subject)
+}
+
+// Close close the database.
+func (b *BoltRepository) Close() error {
+	err := b.db.Close()
+	if err != nil {
+		return fmt.Errorf("can not close bolt database: %w", err)
+	}
+	return nil
+}
+
+// Export call fn for every link in order of short URLs.
+func (b *BoltRepository) Export(ctx context.Context, fn func(Record) error) error {
+	return b.db.View(func(tx *bolt.Tx) error { //nolint:wrapcheck // errors are wrapped inside transaction
+		owners := make(map[string]uuid.UUID)
+		err := forEachUser(tx, func(userID uuid.UUID, user *bolt.Bucket) error {
+			return user.ForEach(func(shortURL, _ []byte) error {
+				owners[string(shortURL)] = userID
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(bucketOriginalURLs).ForEach(func(shortURL, _ []byte) error {
+			if ctx.Err() != nil {
+				return ctx.Err() //nolint:wrapcheck // error of context
+			}
+			record, err := boltRecord(tx, string(shortURL), owners[string(shortURL)])
+			if err != nil {
+				return err
+			}
+			return fn(record)
+		})
+	})
+}
+
+// Lookup return existing links which have short URL or original URL of one of records.
+func (b *BoltRepository) Lookup(_ context.Context, records []Record) ([]Record, error) {
+	var result []Record
+	err := b.db.View(func(tx *bolt.Tx) error {
+		shortURLs, originalURLs := lookupKeys(records)
+		for originalURL := range originalURLs {
+			if shortURL := tx.Bucket(bucketShortURLs).Get([]byte(originalURL)); shortURL != nil {
+				shortURLs[string(shortURL)] = struct{}{}
+			}
+		}
+
+		for shortURL := range shortURLs {
+			if tx.Bucket(bucketOriginalURLs).Get([]byte(shortURL)) == nil {
+				continue
+			}
+			userID, err := owner(tx, shortURL)
+			if err != nil {
+				return err
+			}
+			record, err := boltRecord(tx, shortURL, userID)
+			if err != nil {
+				return err
+			}
+			result = append(result, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not lookup links: %w", err)
+	}
+
+	return result, nil
+}
+
+// Import store records as is replacing links with the same short URL or original URL.
+func (b *BoltRepository) Import(_ context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		for i := range records {
+			if shortURL := tx.Bucket(bucketShortURLs).Get([]byte(records[i].OriginalURL)); shortURL != nil {
+				err := drop(tx, string(shortURL))
+				if err != nil {
+					return err
+				}
+			}
+			err := drop(tx, records[i].ShortURL)
+			if err != nil {
+				return err
+			}
+
+			_, err = b.put(tx, records[i].OriginalURL, []string{records[i].ShortURL}, records[i].UserID)
+			if err != nil {
+				return err
+			}
+			err = tx.Bucket(bucketCreatedAt).Delete([]byte(records[i].ShortURL))
+			if err != nil {
+				return fmt.Errorf("can not delete creation time: %w", err)
+			}
+			err = putCreatedAt(tx, records[i].ShortURL, records[i].CreatedAt)
+			if err != nil {
+				return err
+			}
+			if records[i].Deleted {
+				err = tx.Bucket(bucketDeleted).Put([]byte(records[i].ShortURL), []byte{})
+				if err != nil {
+					return fmt.Errorf("can not put deletion flag: %w", err)
+				}
+			}
+			if records[i].Disabled {
+				err = tx.Bucket(bucketDisabled).Put([]byte(records[i].ShortURL), []byte{})
+				if err != nil {
+					return fmt.Errorf("can not put disabling flag: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not import links: %w", err)
+	}
+
+	return nil
+}
+
+// forEachUser call fn for bucket of every user.
+func forEachUser(tx *bolt.Tx, fn func(userID uuid.UUID, user *bolt.Bucket) error) error {
+	users := tx.Bucket(bucketUsers)
+	return users.ForEach(func(key, _ []byte) error { //nolint:wrapcheck // errors are wrapped inside transaction
+		userID, err := uuid.FromBytes(key)
+		if err != nil {
+			return fmt.Errorf("can not parse user id: %w", err)
+		}
+		return fn(userID, users.Bucket(key))
+	})
+}
+
+// owner return user who owns short URL.
+func owner(tx *bolt.Tx, shortURL string) (uuid.UUID, error) {
+	var result uuid.UUID
+	err := forEachUser(tx, func(userID uuid.UUID, user *bolt.Bucket) error {
+		if user.Get([]byte(shortURL)) != nil {
+			result = userID
+		}
+		return nil
+	})
+	return result, err
+}
+
+// boltRecord return link by short URL as record of export.
+func boltRecord(tx *bolt.Tx, shortURL string, userID uuid.UUID) (Record, error) {
+	record := Record{
+		ShortURL:    shortURL,
+		OriginalURL: string(tx.Bucket(bucketOriginalURLs).Get([]byte(shortURL))),
+		UserID:      userID,
+		Deleted:     tx.Bucket(bucketDeleted).Get([]byte(shortURL)) != nil,
+		Disabled:    tx.Bucket(bucketDisabled).Get([]byte(shortURL)) != nil,
+	}
+	if value := tx.Bucket(bucketCreatedAt).Get([]byte(shortURL)); value != nil {
+		err := record.CreatedAt.UnmarshalBinary(value)
+		if err != nil {
+			return Record{}, fmt.Errorf("can not unmarshal creation time: %w", err)
+		}
+	}
+	return record, nil
+}
+
+// drop delete link by short URL from all buckets.
+func drop(tx *bolt.Tx, shortURL string) error {
+	originalURL := tx.Bucket(bucketOriginalURLs).Get([]byte(shortURL))
+	if originalURL == nil {
+		return nil
+	}
+	userID, err := owner(tx, shortURL)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(bucketShortURLs).Delete(originalURL)
+	if err != nil {
+		return fmt.Errorf("can not delete short url: %w", err)
+	}
+	for _, bucket := range [][]byte{bucketOriginalURLs, bucketDeleted, bucketDisabled, bucketCreatedAt} {
+		err = tx.Bucket(bucket).Delete([]byte(shortURL))
+		if err != nil {
+			return fmt.Errorf("can not delete link from bucket %s: %w", bucket, err)
+		}
+	}
+
+	user := tx.Bucket(bucketUsers).Bucket(userID[:])
+	if user == nil {
+		return nil
+	}
+	err = user.Delete([]byte(shortURL))
+	if err != nil {
+		return fmt.Errorf("can not delete short url of user: %w", err)
+	}
+	if key, _ := user.Cursor().First(); key == nil {
+		err = tx.Bucket(bucketUsers).DeleteBucket(userID[:])
+		if err != nil {
+			return fmt.Errorf("can not delete bucket of user: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBoltRepository create BoltRepository in temporary directory.
+func newTestBoltRepository(t *testing.T) *BoltRepository {
+	t.Helper()
+	repo, err := NewBoltRepository(filepath.Join(t.TempDir(), "shorturls.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		err := repo.Close()
+		assert.NoError(t, err)
+	})
+	return repo
+}
+
+func TestNewBoltRepository(t *testing.T) {
+	t.Run("successful creation", func(t *testing.T) {
+		repo := newTestBoltRepository(t)
+		assert.NotNil(t, repo)
+
+		err := repo.Ping(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		repo, err := NewBoltRepository(filepath.Join(t.TempDir(), "nonexistent", "shorturls.db"))
+		assert.Error(t, err)
+		assert.Nil(t, repo)
+	})
+}
+
+func TestBoltRepositorySetLink(t *testing.T) {
+	repo := newTestBoltRepository(t)
+
+	originalURL := "http://example.com"
+	userID := uuid.New()
+
+	result, err := repo.SetLink(context.Background(), originalURL, []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", *result)
+
+	result, err = repo.SetLink(context.Background(), originalURL, []string{"ghi789"}, userID)
+	assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+	assert.Equal(t, "abc123", *result)
+
+	result, err = repo.SetLink(context.Background(), "http://another.com", []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", *result)
+
+	result, err = repo.SetLink(context.Background(), "http://third.com", []string{"abc123"}, userID)
+	assert.ErrorIs(t, err, ErrReachedMaxGenerationRetries)
+	assert.Nil(t, result)
+
+	result, err = repo.SetLink(context.Background(), "invalid", []string{"jkl012"}, userID)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	assert.Nil(t, result)
+
+	originalURLResult, err := repo.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, originalURL, *originalURLResult)
+
+	_, err = repo.GetOriginalURL(context.Background(), "nonexistent")
+	assert.Error(t, err)
+}
+
+func TestBoltRepositorySetLinks(t *testing.T) {
+	repo := newTestBoltRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	batch := []models.ShortenBatchRequest{
+		{OriginalURL: "http://example.com"},
+		{OriginalURL: "http://another.com"},
+	}
+	result, err := repo.SetLinks(context.Background(), batch, [][]string{{"def456"}, {"abc123", "ghi789"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		{ShortURL: "ghi789", Status: models.BatchStatusCreated},
+	}, result)
+
+	batch = []models.ShortenBatchRequest{
+		{OriginalURL: "http://third.com"},
+		{OriginalURL: "invalid"},
+	}
+	result, err = repo.SetLinks(context.Background(), batch, [][]string{{"jkl012"}, {"mno345"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "jkl012", Status: models.BatchStatusCreated},
+		{Status: models.BatchStatusInvalid},
+	}, result)
+
+	_, err = repo.GetOriginalURL(context.Background(), "mno345")
+	assert.Error(t, err)
+}
+
+func TestBoltRepositoryGetShortLinksOfUser(t *testing.T) {
+	repo := newTestBoltRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, uuid.New())
+	assert.NoError(t, err)
+
+	result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ShortenOfUserResponse{{
+		ShortURL:    "abc123",
+		OriginalURL: "http://example.com",
+	}}, result)
+
+	result, err = repo.GetShortLinksOfUser(context.Background(), uuid.New())
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestBoltRepositoryDeleteURLs(t *testing.T) {
+	repo := newTestBoltRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, uuid.New())
+	assert.NoError(t, err)
+
+	err = repo.DeleteURLs(context.Background(), []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+
+	_, err = repo.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+	_, err = repo.GetOriginalURL(context.Background(), "def456")
+	assert.NoError(t, err)
+}
+
+func TestBoltRepositoryStats(t *testing.T) {
+	repo := newTestBoltRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://third.com", []string{"ghi789"}, uuid.New())
+	assert.NoError(t, err)
+
+	stats, err := repo.Stats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Stats{URLs: 3, Users: 2}, stats)
+}
+
+func TestBoltRepositoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.db")
+	repo, err := NewBoltRepository(path)
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	_, err = repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	err = repo.DeleteURLs(context.Background(), []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	err = repo.Close()
+	assert.NoError(t, err)
+
+	err = repo.Ping(context.Background())
+	assert.Error(t, err)
+
+	repo, err = NewBoltRepository(path)
+	assert.NoError(t, err)
+
+	_, err = repo.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+	result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+
+	err = repo.Close()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,650 @@
+//nolint:wrapcheck // errors of wrapped repository are passed through
+package repository
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// States of circuit breaker.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// Default values of BreakerConfig.
+const (
+	DefaultBreakerThreshold   = 5
+	DefaultBreakerOpenTimeout = 10 * time.Second
+	DefaultBreakerCacheSize   = 10000
+)
+
+// BreakerConfig is a set of parameters of circuit breaker.
+// Zero values are replaced with default ones.
+type BreakerConfig struct {
+	Threshold   int
+	OpenTimeout time.Duration
+	CacheSize   int
+}
+
+// BreakerStatus is a state of circuit breaker which is reported in readiness check.
+type BreakerStatus struct {
+	State      string `json:"state"`
+	Failures   int    `json:"failures"`
+	CachedURLs int    `json:"cached_urls"`
+}
+
+// UnavailableError is an error of operation which was rejected because storage is unavailable.
+type UnavailableError struct {
+	RetryAfter time.Duration
+}
+
+// Breaker is a circuit breaker around Repository.
+// It opens after Threshold consecutive storage failures and rejects operations
+// until OpenTimeout passes, then lets one trial operation through.
+// Redirects are served from cache of recently resolved URLs while breaker is open.
+type Breaker struct {
+	openedAt   time.Time
+	repository Repository
+	logger     *zap.Logger
+	m          *sync.Mutex
+	cache      *urlCache
+	now        func() time.Time
+	state      string
+	cfg        BreakerConfig
+	failures   int
+	trial      bool
+}
+
+// urlCache is a LRU cache of original URLs by short URLs.
+type urlCache struct {
+	m     *sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	size  int
+}
+
+// cacheEntry is an entry of urlCache.
+type cacheEntry struct {
+	shortURL    string
+	originalURL string
+}
+
+// Error return text of UnavailableError.
+func (e *UnavailableError) Error() string {
+	return ErrUnavailable.Error()
+}
+
+// Is make UnavailableError match ErrUnavailable.
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// NewBreaker create new Breaker around repository.
+func NewBreaker(logger *zap.Logger, repository Repository, cfg BreakerConfig) *Breaker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultBreakerThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = DefaultBreakerCacheSize
+	}
+
+	return &Breaker{
+		repository: repository,
+		logger:     logger,
+		m:          &sync.Mutex{},
+		cache:      newURLCache(cfg.CacheSize),
+		now:        time.Now,
+		state:      BreakerClosed,
+		cfg:        cfg,
+	}
+}
+
+// GetOriginalURL return original URL by short URL.
+// Original URL is taken from cache if storage is unavailable.
+func (b *Breaker) GetOriginalURL(ctx context.Context, shortLink string) (*string, error) {
+	err := b.allow()
+	if err != nil {
+		return b.cached(shortLink, err)
+	}
+
+	originalURL, err := b.repository.GetOriginalURL(ctx, shortLink)
+	b.done(err)
+	switch {
+	case err == nil:
+		b.cache.add(shortLink, *originalURL)
+	case errors.Is(err, ErrURLIsDeleted), errors.Is(err, ErrURLIsDisabled):
+		b.cache.remove(shortLink)
+	case isStorageFailure(err):
+		return b.cached(shortLink, err)
+	}
+	return originalURL, err
+}
+
+// SetLink add short URL if such does not exist already.
+func (b *Breaker) SetLink(
+	ctx context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	shortURL, err := b.repository.SetLink(ctx, originalURL, shortURLs, userID)
+	b.done(err)
+	if shortURL != nil {
+		b.cache.add(*shortURL, originalURL)
+	}
+	return shortURL, err
+}
+
+// SetLinks add short URLs if such do not exist already.
+func (b *Breaker) SetLinks(
+	ctx context.Context,
+	batch []models.ShortenBatchRequest,
+	shortURLs [][]string,
+	userID uuid.UUID,
+) ([]LinkResult, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := b.repository.SetLinks(ctx, batch, shortURLs, userID)
+	b.done(err)
+	if len(result) == len(batch) {
+		for i := range result {
+			if result[i].ShortURL != "" {
+				b.cache.add(result[i].ShortURL, batch[i].OriginalURL)
+			}
+		}
+	}
+	return result, err
+}
+
+// GetShortLinksOfUser return URLs of user if such exist.
+func (b *Breaker) GetShortLinksOfUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.ShortenOfUserResponse, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := b.repository.GetShortLinksOfUser(ctx, userID)
+	b.done(err)
+	return urls, err
+}
+
+// DeleteURLs delete URLs of user.
+// Deleted URLs are removed from cache, so they are not served while breaker is open.
+func (b *Breaker) DeleteURLs(ctx context.Context, urls []string, userID uuid.UUID) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.DeleteURLs(ctx, urls, userID)
+	b.done(err)
+	if err == nil {
+		for i := range urls {
+			b.cache.remove(urls[i])
+		}
+	}
+	return err
+}
+
+// DeleteURLsInDB process deletion queue of repository if it has such.
+func (b *Breaker) DeleteURLsInDB(ctx context.Context, limit int) (int, error) {
+	queue, ok := b.repository.(DeletionQueue)
+	if !ok {
+		return 0, nil
+	}
+
+	err := b.allow()
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := queue.DeleteURLsInDB(ctx, limit)
+	b.done(err)
+	return count, err
+}
+
+// Export call fn for every link of wrapped repository if it supports export.
+func (b *Breaker) Export(ctx context.Context, fn func(Record) error) error {
+	dumper, ok := b.repository.(Dumper)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = dumper.Export(ctx, fn)
+	b.done(err)
+	return err
+}
+
+// Lookup return existing links of wrapped repository if it supports import.
+func (b *Breaker) Lookup(ctx context.Context, records []Record) ([]Record, error) {
+	dumper, ok := b.repository.(Dumper)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dumper.Lookup(ctx, records)
+	b.done(err)
+	return result, err
+}
+
+// Import store records in wrapped repository if it supports import.
+func (b *Breaker) Import(ctx context.Context, records []Record) error {
+	dumper, ok := b.repository.(Dumper)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = dumper.Import(ctx, records)
+	b.done(err)
+	if err == nil {
+		for i := range records {
+			b.cache.remove(records[i].ShortURL)
+		}
+	}
+	return err
+}
+
+// SetAPIKey store new API key.
+func (b *Breaker) SetAPIKey(ctx context.Context, key APIKey) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.SetAPIKey(ctx, key)
+	b.done(err)
+	return err
+}
+
+// GetAPIKey return API key by hash including revoked one.
+func (b *Breaker) GetAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := b.repository.GetAPIKey(ctx, hash)
+	b.done(err)
+	return key, err
+}
+
+// GetAPIKeysOfUser return API keys of user in order of creation.
+func (b *Breaker) GetAPIKeysOfUser(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := b.repository.GetAPIKeysOfUser(ctx, userID)
+	b.done(err)
+	return keys, err
+}
+
+// RevokeAPIKey mark API key of user as revoked.
+func (b *Breaker) RevokeAPIKey(ctx context.Context, id string, userID uuid.UUID) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.RevokeAPIKey(ctx, id, userID)
+	b.done(err)
+	return err
+}
+
+// SetRefreshToken store new refresh token and remove expired tokens of its user.
+func (b *Breaker) SetRefreshToken(ctx context.Context, token RefreshToken) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.SetRefreshToken(ctx, token)
+	b.done(err)
+	return err
+}
+
+// UseRefreshToken mark refresh token as used and return its state before marking.
+func (b *Breaker) UseRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := b.repository.UseRefreshToken(ctx, hash)
+	b.done(err)
+	return token, err
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+func (b *Breaker) RevokeRefreshTokens(ctx context.Context, familyID string) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.RevokeRefreshTokens(ctx, familyID)
+	b.done(err)
+	return err
+}
+
+// SetIdentity store identity if it is not stored yet and return stored identity.
+func (b *Breaker) SetIdentity(ctx context.Context, identity Identity) (*Identity, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := b.repository.SetIdentity(ctx, identity)
+	b.done(err)
+	return stored, err
+}
+
+// MergeUser move links of anonymous user into another user.
+func (b *Breaker) MergeUser(ctx context.Context, from uuid.UUID, to uuid.UUID) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.MergeUser(ctx, from, to)
+	b.done(err)
+	return err
+}
+
+// SearchLinks return links which match filter in order of creation.
+func (b *Breaker) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := b.repository.SearchLinks(ctx, filter)
+	b.done(err)
+	return records, err
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+// Disabled link is removed from cache, so it is not redirected while storage is unavailable.
+func (b *Breaker) SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) (*Record, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := b.repository.SetLinkDisabled(ctx, shortURL, disabled)
+	b.done(err)
+	if err == nil && disabled {
+		b.cache.remove(shortURL)
+	}
+	return record, err
+}
+
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (b *Breaker) GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := b.repository.GetUsers(ctx, limit, offset)
+	b.done(err)
+	return users, err
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+func (b *Breaker) TransferLinks(ctx context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := b.repository.TransferLinks(ctx, shortURLs, to)
+	b.done(err)
+	return records, err
+}
+
+// Ping check connection with storage regardless of breaker state.
+func (b *Breaker) Ping(ctx context.Context) error {
+	return b.repository.Ping(ctx)
+}
+
+// Stats return statistic of shortened urls and users in service.
+func (b *Breaker) Stats(ctx context.Context) (*models.Stats, error) {
+	err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := b.repository.Stats(ctx)
+	b.done(err)
+	return stats, err
+}
+
+// Status return state of breaker and wrapped repository.
+func (b *Breaker) Status() map[string]any {
+	status := make(map[string]any)
+	if reporter, ok := b.repository.(StatusReporter); ok {
+		maps.Copy(status, reporter.Status())
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+	status["breaker"] = BreakerStatus{
+		State:      b.state,
+		Failures:   b.failures,
+		CachedURLs: b.cache.len(),
+	}
+	return status
+}
+
+// State return current state of breaker.
+func (b *Breaker) State() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.state
+}
+
+// allow check if operation can be performed and switch open breaker to half-open after timeout.
+func (b *Breaker) allow() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		wait := b.cfg.OpenTimeout - b.now().Sub(b.openedAt)
+		if wait > 0 {
+			return &UnavailableError{RetryAfter: wait}
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		b.logger.Info("Circuit breaker is half-open")
+	case BreakerHalfOpen:
+		if b.trial {
+			return &UnavailableError{RetryAfter: time.Second}
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// done record result of operation and switch state of breaker.
+// Canceled operation releases trial of half-open breaker without changing state,
+// because it tells nothing about storage.
+func (b *Breaker) done(err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.trial = false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if !isStorageFailure(err) {
+		if b.state != BreakerClosed {
+			b.logger.Info("Circuit breaker is closed")
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.cfg.Threshold) {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.logger.Warn("Circuit breaker is open", zap.Int("failures", b.failures), zap.Error(err))
+	}
+}
+
+// cached return original URL from cache or provided error if there is no such.
+func (b *Breaker) cached(shortLink string, err error) (*string, error) {
+	originalURL, ok := b.cache.get(shortLink)
+	if !ok {
+		return nil, err
+	}
+	return &originalURL, nil
+}
+
+// isStorageFailure check if error is caused by storage failure and not by data.
+func isStorageFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, ErrInvalidURL) &&
+		!errors.Is(err, ErrOriginalURLUniqueViolation) &&
+		!errors.Is(err, ErrReachedMaxGenerationRetries) &&
+		!errors.Is(err, ErrURLIsDeleted) &&
+		!errors.Is(err, ErrURLIsDisabled) &&
+		!errors.Is(err, ErrNotFound) &&
+		!errors.Is(err, ErrInvalidShortURL) &&
+		!errors.Is(err, ErrAPIKeyNotFound) &&
+		!errors.Is(err, ErrRefreshTokenNotFound) &&
+		!errors.Is(err, pgx.ErrNoRows) &&
+		!errors.Is(err, sql.ErrNoRows) &&
+		!errors.Is(err, context.Canceled)
+}
+
+// newURLCache create new urlCache of provided size.
+func newURLCache(size int) *urlCache {
+	return &urlCache{
+		m:     &sync.Mutex{},
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		size:  size,
+	}
+}
+
+// get return original URL by short URL and mark it as recently used.
+func (c *urlCache) get(shortURL string) (string, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	element, ok := c.items[shortURL]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	entry, _ := element.Value.(cacheEntry)
+	return entry.originalURL, true
+}
+
+// add put original URL by short URL and evict least recently used one if cache is full.
+func (c *urlCache) add(shortURL string, originalURL string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if element, ok := c.items[shortURL]; ok {
+		element.Value = cacheEntry{shortURL: shortURL, originalURL: originalURL}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[shortURL] = c.order.PushFront(cacheEntry{shortURL: shortURL, originalURL: originalURL})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry, _ := oldest.Value.(cacheEntry)
+		delete(c.items, entry.shortURL)
+	}
+}
+
+// remove delete original URL by short URL.
+func (c *urlCache) remove(shortURL string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if element, ok := c.items[shortURL]; ok {
+		c.order.Remove(element)
+		delete(c.items, shortURL)
+	}
+}
+
+// len return amount of cached URLs.
+func (c *urlCache) len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.order.Len()
+}
+
+// AppendAudit store events in audit log.
+func (b *Breaker) AppendAudit(ctx context.Context, events []AuditEvent) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.AppendAudit(ctx, events)
+	b.done(err)
+	return err
+}
+
+// ExportAudit call fn for every event which matches filter in order of appending.
+func (b *Breaker) ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = b.repository.ExportAudit(ctx, filter, fn)
+	b.done(err)
+	return err
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// errStorage is an error of failed storage.
+var errStorage = errors.New("connection refused")
+
+// failingRepository is a repository which fails all operations while it is down.
+type failingRepository struct {
+	*Links
+	down bool
+}
+
+// GetOriginalURL return error if repository is down.
+func (f *failingRepository) GetOriginalURL(ctx context.Context, shortLink string) (*string, error) {
+	if f.down {
+		return nil, errStorage
+	}
+	return f.Links.GetOriginalURL(ctx, shortLink)
+}
+
+// SetLink return error if repository is down.
+func (f *failingRepository) SetLink(
+	ctx context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	if f.down {
+		return nil, errStorage
+	}
+	return f.Links.SetLink(ctx, originalURL, shortURLs, userID)
+}
+
+// Stats return error if repository is down.
+func (f *failingRepository) Stats(ctx context.Context) (*models.Stats, error) {
+	if f.down {
+		return nil, errStorage
+	}
+	return f.Links.Stats(ctx)
+}
+
+// newTestBreaker create Breaker around failingRepository with controlled clock.
+func newTestBreaker(threshold int) (*Breaker, *failingRepository, *time.Time) {
+	repo := &failingRepository{Links: NewLinks()}
+	breaker := NewBreaker(zap.NewNop(), repo, BreakerConfig{Threshold: threshold, OpenTimeout: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time {
+		return now
+	}
+	return breaker, repo, &now
+}
+
+func TestBreakerOpen(t *testing.T) {
+	breaker, repo, _ := newTestBreaker(3)
+	userID := uuid.New()
+
+	_, err := breaker.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	repo.down = true
+	for range 3 {
+		assert.Equal(t, BreakerClosed, breaker.State())
+		_, err = breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+	}
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	_, err = breaker.SetLink(context.Background(), "http://another.com", []string{"def456"}, userID)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	var unavailable *UnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, time.Minute, unavailable.RetryAfter)
+
+	result, err := breaker.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com", *result)
+
+	_, err = breaker.GetOriginalURL(context.Background(), "def456")
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	status := breaker.Status()
+	assert.Equal(t, BreakerStatus{State: BreakerOpen, Failures: 3, CachedURLs: 1}, status["breaker"])
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	t.Run("successful trial", func(t *testing.T) {
+		breaker, repo, now := newTestBreaker(1)
+
+		repo.down = true
+		_, err := breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+		assert.Equal(t, BreakerOpen, breaker.State())
+
+		*now = now.Add(time.Minute)
+		repo.down = false
+		err = breaker.allow()
+		assert.NoError(t, err)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+
+		_, err = breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, ErrUnavailable)
+
+		breaker.done(nil)
+		assert.Equal(t, BreakerClosed, breaker.State())
+
+		_, err = breaker.Stats(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed trial", func(t *testing.T) {
+		breaker, repo, now := newTestBreaker(1)
+
+		repo.down = true
+		_, err := breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+
+		*now = now.Add(time.Minute)
+		_, err = breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+		assert.Equal(t, BreakerOpen, breaker.State())
+
+		_, err = breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	t.Run("canceled trial", func(t *testing.T) {
+		breaker, repo, now := newTestBreaker(1)
+
+		repo.down = true
+		_, err := breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+
+		*now = now.Add(time.Minute)
+		err = breaker.allow()
+		assert.NoError(t, err)
+		breaker.done(context.Canceled)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+
+		_, err = breaker.Stats(context.Background())
+		assert.ErrorIs(t, err, errStorage)
+		assert.Equal(t, BreakerOpen, breaker.State())
+	})
+}
+
+func TestBreakerDeleteURLs(t *testing.T) {
+	breaker, repo, _ := newTestBreaker(1)
+	userID := uuid.New()
+
+	_, err := breaker.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = breaker.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+
+	err = breaker.DeleteURLs(context.Background(), []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	repo.down = true
+	_, err = breaker.Stats(context.Background())
+	assert.ErrorIs(t, err, errStorage)
+	_, err = breaker.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestBreakerDomainErrors(t *testing.T) {
+	breaker, _, _ := newTestBreaker(1)
+	userID := uuid.New()
+
+	_, err := breaker.SetLink(context.Background(), "invalid", []string{"abc123"}, userID)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	_, err = breaker.GetOriginalURL(context.Background(), "nonexistent")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = breaker.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = breaker.SetLink(context.Background(), "http://example.com", []string{"def456"}, userID)
+	assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestURLCache(t *testing.T) {
+	cache := newURLCache(2)
+	cache.add("abc123", "http://example.com")
+	cache.add("def456", "http://another.com")
+
+	_, ok := cache.get("abc123")
+	assert.True(t, ok)
+
+	cache.add("ghi789", "http://third.com")
+	_, ok = cache.get("def456")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.len())
+
+	cache.add("abc123", "http://changed.com")
+	originalURL, ok := cache.get("abc123")
+	assert.True(t, ok)
+	assert.Equal(t, "http://changed.com", originalURL)
+
+	cache.remove("abc123")
+	_, ok = cache.get("abc123")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.len())
+}
@@ -0,0 +1,798 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// Environment variable with DSN of PostgreSQL database for conformance tests.
+const testDatabaseDSN = "TEST_DATABASE_DSN"
+
+// Size of deletion queue batch in conformance tests.
+const conformanceBatchSize = 10
+
+// conformanceFactory create empty repository for conformance test.
+type conformanceFactory func(t *testing.T) Repository
+
+// deleteURLs delete URLs and wait until deletion queue of repository is drained if it has such.
+func deleteURLs(t *testing.T, repo Repository, urls []string, userID uuid.UUID) {
+	t.Helper()
+	err := repo.DeleteURLs(context.Background(), urls, userID)
+	assert.NoError(t, err)
+
+	queue, ok := repo.(DeletionQueue)
+	if !ok {
+		return
+	}
+	for {
+		count, err := queue.DeleteURLsInDB(context.Background(), conformanceBatchSize)
+		assert.NoError(t, err)
+		if err != nil || count == 0 {
+			return
+		}
+	}
+}
+
+// runConformance check that repository behaves as all other repositories.
+func runConformance(t *testing.T, newRepository conformanceFactory) {
+	t.Helper()
+
+	t.Run("get nonexistent original url", func(t *testing.T) {
+		repo := newRepository(t)
+
+		result, err := repo.GetOriginalURL(context.Background(), "nonexistent")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrURLIsDeleted)
+		assert.Nil(t, result)
+	})
+
+	t.Run("set link", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		result, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123", "def456"}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", *result)
+
+		result, err = repo.SetLink(context.Background(), "https://example.com", []string{"ghi789"}, uuid.New())
+		assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+		assert.Equal(t, "abc123", *result)
+
+		result, err = repo.SetLink(context.Background(), "https://another.com", []string{"abc123", "def456"}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, "def456", *result)
+
+		result, err = repo.SetLink(context.Background(), "https://third.com", []string{"abc123", "def456"}, userID)
+		assert.ErrorIs(t, err, ErrReachedMaxGenerationRetries)
+		assert.Nil(t, result)
+
+		result, err = repo.SetLink(context.Background(), "invalid", []string{"jkl012"}, userID)
+		assert.ErrorIs(t, err, ErrInvalidURL)
+		assert.Nil(t, result)
+
+		originalURL, err := repo.GetOriginalURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", *originalURL)
+
+		_, err = repo.GetOriginalURL(context.Background(), "jkl012")
+		assert.Error(t, err)
+	})
+
+	t.Run("set links", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+
+		result, err := repo.SetLinks(context.Background(), []models.ShortenBatchRequest{
+			{OriginalURL: "https://another.com"},
+			{OriginalURL: "https://third.com"},
+		}, [][]string{{"abc123", "def456"}, {"ghi789"}}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []LinkResult{
+			{ShortURL: "def456", Status: models.BatchStatusCreated},
+			{ShortURL: "ghi789", Status: models.BatchStatusCreated},
+		}, result)
+
+		result, err = repo.SetLinks(context.Background(), []models.ShortenBatchRequest{
+			{OriginalURL: "https://fourth.com"},
+			{OriginalURL: "https://example.com"},
+			{OriginalURL: "https://fourth.com"},
+		}, [][]string{{"jkl012"}, {"mno345"}, {"pqr678"}}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []LinkResult{
+			{ShortURL: "jkl012", Status: models.BatchStatusCreated},
+			{ShortURL: "abc123", Status: models.BatchStatusExisting},
+			{ShortURL: "jkl012", Status: models.BatchStatusExisting},
+		}, result)
+
+		originalURL, err := repo.GetOriginalURL(context.Background(), "ghi789")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://third.com", *originalURL)
+	})
+
+	t.Run("set links per item", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+
+		result, err := repo.SetLinks(context.Background(), []models.ShortenBatchRequest{
+			{OriginalURL: "https://another.com"},
+			{OriginalURL: "invalid"},
+			{OriginalURL: "https://third.com"},
+			{OriginalURL: "https://example.com"},
+		}, [][]string{{"def456"}, {"ghi789"}, {"abc123"}, {"jkl012"}}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []LinkResult{
+			{ShortURL: "def456", Status: models.BatchStatusCreated},
+			{Status: models.BatchStatusInvalid},
+			{Status: models.BatchStatusError},
+			{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		}, result)
+
+		originalURL, err := repo.GetOriginalURL(context.Background(), "def456")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://another.com", *originalURL)
+
+		_, err = repo.GetOriginalURL(context.Background(), "ghi789")
+		assert.Error(t, err)
+
+		result, err = repo.SetLinks(context.Background(), []models.ShortenBatchRequest{
+			{OriginalURL: "invalid"},
+		}, [][]string{{"mno345"}}, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []LinkResult{{Status: models.BatchStatusInvalid}}, result)
+
+		stats, err := repo.Stats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, stats.URLs)
+	})
+
+	t.Run("get short links of user", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLinks(context.Background(), []models.ShortenBatchRequest{
+			{OriginalURL: "https://another.com"},
+		}, [][]string{{"def456"}}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://third.com", []string{"ghi789"}, uuid.New())
+		assert.NoError(t, err)
+
+		result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []models.ShortenOfUserResponse{
+			{ShortURL: "abc123", OriginalURL: "https://example.com"},
+			{ShortURL: "def456", OriginalURL: "https://another.com"},
+		}, result)
+
+		result, err = repo.GetShortLinksOfUser(context.Background(), uuid.New())
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("delete urls", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		anotherUserID := uuid.New()
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://another.com", []string{"def456"}, anotherUserID)
+		assert.NoError(t, err)
+
+		deleteURLs(t, repo, []string{"abc123", "def456", "nonexistent"}, userID)
+
+		_, err = repo.GetOriginalURL(context.Background(), "abc123")
+		assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+		originalURL, err := repo.GetOriginalURL(context.Background(), "def456")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://another.com", *originalURL)
+
+		deleteURLs(t, repo, []string{"abc123"}, userID)
+
+		_, err = repo.GetOriginalURL(context.Background(), "abc123")
+		assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+		result, err := repo.SetLink(context.Background(), "https://example.com", []string{"ghi789"}, userID)
+		assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+		assert.Equal(t, "abc123", *result)
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		repo := newRepository(t)
+
+		err := repo.Ping(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		stats, err := repo.Stats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Stats{}, stats)
+
+		_, err = repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://third.com", []string{"ghi789"}, uuid.New())
+		assert.NoError(t, err)
+
+		stats, err = repo.Stats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Stats{URLs: 3, Users: 2}, stats)
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		repo := newRepository(t)
+		dumper, ok := repo.(Dumper)
+		if !assert.True(t, ok) {
+			return
+		}
+		userID := uuid.New()
+		anotherUserID := uuid.New()
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+		assert.NoError(t, err)
+		deleteURLs(t, repo, []string{"def456"}, userID)
+
+		var records []Record
+		err = dumper.Export(context.Background(), func(record Record) error {
+			assert.False(t, record.CreatedAt.IsZero())
+			record.CreatedAt = time.Time{}
+			records = append(records, record)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []Record{
+			{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID},
+			{ShortURL: "def456", OriginalURL: "https://another.com", UserID: userID, Deleted: true},
+		}, records)
+
+		err = dumper.Import(context.Background(), []Record{
+			{ShortURL: "ghi789", OriginalURL: "https://third.com", UserID: anotherUserID},
+			{ShortURL: "jkl012", OriginalURL: "invalid", UserID: anotherUserID},
+		})
+		assert.ErrorIs(t, err, ErrInvalidURL)
+
+		createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+		imported := []Record{
+			{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "https://changed.com", UserID: anotherUserID},
+			{CreatedAt: createdAt, ShortURL: "ghi789", OriginalURL: "https://another.com", UserID: anotherUserID},
+			{CreatedAt: createdAt, ShortURL: "jkl012", OriginalURL: "https://fourth.com", Deleted: true},
+		}
+		err = dumper.Import(context.Background(), imported)
+		assert.NoError(t, err)
+
+		found, err := dumper.Lookup(context.Background(), []Record{
+			{ShortURL: "abc123", OriginalURL: "https://example.com"},
+			{ShortURL: "def456", OriginalURL: "https://another.com"},
+			{ShortURL: "jkl012", OriginalURL: "https://fourth.com"},
+		})
+		assert.NoError(t, err)
+		for i := range found {
+			assert.True(t, createdAt.Equal(found[i].CreatedAt))
+			found[i].CreatedAt = createdAt
+		}
+		assert.ElementsMatch(t, imported, found)
+
+		_, err = repo.GetOriginalURL(context.Background(), "def456")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrURLIsDeleted)
+		originalURL, err := repo.GetOriginalURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://changed.com", *originalURL)
+		_, err = repo.GetOriginalURL(context.Background(), "jkl012")
+		assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+		result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		result, err = repo.GetShortLinksOfUser(context.Background(), anotherUserID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []models.ShortenOfUserResponse{
+			{ShortURL: "abc123", OriginalURL: "https://changed.com"},
+			{ShortURL: "ghi789", OriginalURL: "https://another.com"},
+		}, result)
+
+		stats, err := repo.Stats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Stats{URLs: 3, Users: 2}, stats)
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+
+		const writers = 20
+		wg := &sync.WaitGroup{}
+		results := make(chan string, writers)
+		for i := range writers {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				_, err := repo.SetLink(
+					context.Background(),
+					fmt.Sprintf("https://example%d.com", i),
+					[]string{fmt.Sprintf("short%d", i)},
+					userID,
+				)
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				result, err := repo.SetLink(
+					context.Background(),
+					"https://same.com",
+					[]string{fmt.Sprintf("same%d", i)},
+					userID,
+				)
+				if err != nil {
+					assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+				}
+				results <- *result
+			}()
+			go func() {
+				defer wg.Done()
+				_, _ = repo.GetOriginalURL(context.Background(), fmt.Sprintf("short%d", i))
+				_, err := repo.GetShortLinksOfUser(context.Background(), userID)
+				assert.NoError(t, err)
+				_, err = repo.Stats(context.Background())
+				assert.NoError(t, err)
+				err = repo.DeleteURLs(context.Background(), []string{fmt.Sprintf("short%d", i)}, uuid.New())
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		var same string
+		for result := range results {
+			if same == "" {
+				same = result
+			}
+			assert.Equal(t, same, result)
+		}
+
+		result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Len(t, result, writers+1)
+
+		stats, err := repo.Stats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Stats{URLs: writers + 1, Users: 1}, stats)
+	})
+
+	t.Run("api keys", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		createdAt := time.Now().UTC().Truncate(time.Millisecond)
+		keys := []APIKey{
+			{
+				CreatedAt: createdAt,
+				ID:        uuid.NewString(),
+				Hash:      "hash1",
+				Name:      "first",
+				Scopes:    []string{"create", "read"},
+				UserID:    userID,
+			},
+			{
+				CreatedAt: createdAt.Add(time.Second),
+				ID:        uuid.NewString(),
+				Hash:      "hash2",
+				Scopes:    []string{"delete"},
+				UserID:    userID,
+			},
+			{
+				CreatedAt: createdAt,
+				ID:        uuid.NewString(),
+				Hash:      "hash3",
+				Scopes:    []string{"read"},
+				UserID:    uuid.New(),
+			},
+		}
+		for i := range keys {
+			err := repo.SetAPIKey(context.Background(), keys[i])
+			assert.NoError(t, err)
+		}
+
+		key, err := repo.GetAPIKey(context.Background(), "hash1")
+		assert.NoError(t, err)
+		assert.Equal(t, keys[0].ID, key.ID)
+		assert.Equal(t, keys[0].Scopes, key.Scopes)
+		assert.True(t, keys[0].CreatedAt.Equal(key.CreatedAt))
+		assert.True(t, key.RevokedAt.IsZero())
+
+		_, err = repo.GetAPIKey(context.Background(), "unknown")
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+		userKeys, err := repo.GetAPIKeysOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Len(t, userKeys, 2)
+		if len(userKeys) == 2 {
+			assert.Equal(t, keys[0].ID, userKeys[0].ID)
+			assert.Equal(t, keys[1].ID, userKeys[1].ID)
+		}
+
+		err = repo.RevokeAPIKey(context.Background(), keys[2].ID, userID)
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+		err = repo.RevokeAPIKey(context.Background(), uuid.NewString(), userID)
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+		err = repo.RevokeAPIKey(context.Background(), keys[0].ID, userID)
+		assert.NoError(t, err)
+		key, err = repo.GetAPIKey(context.Background(), "hash1")
+		assert.NoError(t, err)
+		assert.False(t, key.RevokedAt.IsZero())
+		err = repo.RevokeAPIKey(context.Background(), keys[0].ID, userID)
+		assert.NoError(t, err)
+
+		key, err = repo.GetAPIKey(context.Background(), "hash3")
+		assert.NoError(t, err)
+		assert.True(t, key.RevokedAt.IsZero())
+	})
+
+	t.Run("refresh tokens", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		familyID := uuid.NewString()
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+		tokens := []RefreshToken{
+			{ExpiresAt: time.Now().Add(-time.Hour).UTC(), Hash: "expired", FamilyID: familyID, UserID: userID},
+			{ExpiresAt: expiresAt, Hash: "first", FamilyID: familyID, UserID: userID},
+			{ExpiresAt: expiresAt, Hash: "second", FamilyID: familyID, UserID: userID},
+			{ExpiresAt: expiresAt, Hash: "another", FamilyID: uuid.NewString(), UserID: userID},
+		}
+		for i := range tokens {
+			err := repo.SetRefreshToken(context.Background(), tokens[i])
+			assert.NoError(t, err)
+		}
+
+		_, err := repo.UseRefreshToken(context.Background(), "expired")
+		assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+		_, err = repo.UseRefreshToken(context.Background(), "unknown")
+		assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+		token, err := repo.UseRefreshToken(context.Background(), "first")
+		assert.NoError(t, err)
+		assert.Equal(t, familyID, token.FamilyID)
+		assert.Equal(t, userID, token.UserID)
+		assert.True(t, expiresAt.Equal(token.ExpiresAt))
+		assert.True(t, token.UsedAt.IsZero())
+
+		token, err = repo.UseRefreshToken(context.Background(), "first")
+		assert.NoError(t, err)
+		assert.False(t, token.UsedAt.IsZero())
+		assert.True(t, token.RevokedAt.IsZero())
+
+		err = repo.RevokeRefreshTokens(context.Background(), familyID)
+		assert.NoError(t, err)
+		err = repo.RevokeRefreshTokens(context.Background(), uuid.NewString())
+		assert.NoError(t, err)
+
+		token, err = repo.UseRefreshToken(context.Background(), "second")
+		assert.NoError(t, err)
+		assert.False(t, token.RevokedAt.IsZero())
+		token, err = repo.UseRefreshToken(context.Background(), "another")
+		assert.NoError(t, err)
+		assert.True(t, token.RevokedAt.IsZero())
+	})
+
+	t.Run("users", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		anonymousID := uuid.New()
+		createdAt := time.Now().UTC().Truncate(time.Millisecond)
+
+		identity, err := repo.SetIdentity(context.Background(), Identity{
+			CreatedAt: createdAt,
+			Issuer:    "https://issuer.com",
+			Subject:   "subject",
+			UserID:    userID,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, userID, identity.UserID)
+
+		identity, err = repo.SetIdentity(context.Background(), Identity{
+			CreatedAt: time.Now().UTC(),
+			Issuer:    "https://issuer.com",
+			Subject:   "subject",
+			UserID:    uuid.New(),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, userID, identity.UserID)
+		assert.True(t, createdAt.Equal(identity.CreatedAt))
+
+		identity, err = repo.SetIdentity(context.Background(), Identity{
+			CreatedAt: createdAt,
+			Issuer:    "https://another.com",
+			Subject:   "subject",
+			UserID:    anonymousID,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, anonymousID, identity.UserID)
+
+		fromID := uuid.New()
+		_, err = repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, fromID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://another.com", []string{"def456"}, anonymousID)
+		assert.NoError(t, err)
+
+		err = repo.MergeUser(context.Background(), fromID, userID)
+		assert.NoError(t, err)
+		err = repo.MergeUser(context.Background(), anonymousID, userID)
+		assert.NoError(t, err)
+		err = repo.MergeUser(context.Background(), userID, userID)
+		assert.NoError(t, err)
+
+		result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ShortenOfUserResponse{
+			{ShortURL: "abc123", OriginalURL: "https://example.com"},
+		}, result)
+		result, err = repo.GetShortLinksOfUser(context.Background(), fromID)
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		result, err = repo.GetShortLinksOfUser(context.Background(), anonymousID)
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+
+		deleteURLs(t, repo, []string{"abc123"}, userID)
+		_, err = repo.GetOriginalURL(context.Background(), "abc123")
+		assert.ErrorIs(t, err, ErrURLIsDeleted)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		anotherUserID := uuid.New()
+		shortURLs := func(records []Record) []string {
+			result := make([]string, 0, len(records))
+			for i := range records {
+				result = append(result, records[i].ShortURL)
+			}
+			return result
+		}
+
+		_, err := repo.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+		assert.NoError(t, err)
+		_, err = repo.SetLink(context.Background(), "https://example.org", []string{"ghi789"}, anotherUserID)
+		assert.NoError(t, err)
+
+		records, err := repo.SearchLinks(context.Background(), LinkFilter{Query: "example"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"abc123", "ghi789"}, shortURLs(records))
+		records, err = repo.SearchLinks(context.Background(), LinkFilter{UserID: userID, Limit: 1, Offset: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"def456"}, shortURLs(records))
+		records, err = repo.SearchLinks(context.Background(), LinkFilter{Query: "nothing"})
+		assert.NoError(t, err)
+		assert.Empty(t, records)
+
+		record, err := repo.SetLinkDisabled(context.Background(), "abc123", true)
+		assert.NoError(t, err)
+		assert.False(t, record.Disabled)
+		assert.Equal(t, userID, record.UserID)
+		_, err = repo.GetOriginalURL(context.Background(), "abc123")
+		assert.ErrorIs(t, err, ErrURLIsDisabled)
+		records, err = repo.SearchLinks(context.Background(), LinkFilter{Query: "abc123"})
+		assert.NoError(t, err)
+		if assert.Len(t, records, 1) {
+			assert.True(t, records[0].Disabled)
+		}
+		record, err = repo.SetLinkDisabled(context.Background(), "abc123", false)
+		assert.NoError(t, err)
+		assert.True(t, record.Disabled)
+		originalURL, err := repo.GetOriginalURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", *originalURL)
+		_, err = repo.SetLinkDisabled(context.Background(), "nonexistent", true)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		users := []UserLinks{{UserID: userID, URLs: 2}, {UserID: anotherUserID, URLs: 1}}
+		if bytes.Compare(anotherUserID[:], userID[:]) < 0 {
+			users[0], users[1] = users[1], users[0]
+		}
+		result, err := repo.GetUsers(context.Background(), 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, users, result)
+		result, err = repo.GetUsers(context.Background(), 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, users[1:], result)
+
+		records, err = repo.TransferLinks(context.Background(), []string{"def456", "nonexistent"}, anotherUserID)
+		assert.NoError(t, err)
+		if assert.Len(t, records, 1) {
+			assert.Equal(t, userID, records[0].UserID)
+		}
+		links, err := repo.GetShortLinksOfUser(context.Background(), anotherUserID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []models.ShortenOfUserResponse{
+			{ShortURL: "def456", OriginalURL: "https://another.com"},
+			{ShortURL: "ghi789", OriginalURL: "https://example.org"},
+		}, links)
+		links, err = repo.GetShortLinksOfUser(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Len(t, links, 1)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		repo := newRepository(t)
+		userID := uuid.New()
+		actorID := uuid.New()
+		createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+		record := Record{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID}
+		disabled := record
+		disabled.Disabled = true
+
+		err := repo.AppendAudit(context.Background(), []AuditEvent{
+			{
+				CreatedAt: createdAt,
+				After:     &record,
+				Action:    "create",
+				ShortURL:  "abc123",
+				UserID:    userID,
+				ActorID:   userID,
+			},
+			{
+				CreatedAt: createdAt.Add(time.Hour),
+				After:     &record,
+				Action:    "create",
+				ShortURL:  "def456",
+				UserID:    userID,
+				ActorID:   userID,
+			},
+		})
+		assert.NoError(t, err)
+		err = repo.AppendAudit(context.Background(), []AuditEvent{{
+			CreatedAt:  createdAt.Add(2 * time.Hour),
+			Before:     &record,
+			After:      &disabled,
+			Action:     "disable",
+			ShortURL:   "abc123",
+			AuthMethod: "cookie",
+			ClientIP:   "192.0.2.1",
+			RequestID:  "request",
+			UserID:     userID,
+			ActorID:    actorID,
+		}})
+		assert.NoError(t, err)
+
+		export := func(filter AuditFilter) []AuditEvent {
+			var events []AuditEvent
+			err := repo.ExportAudit(context.Background(), filter, func(event AuditEvent) error {
+				events = append(events, event)
+				return nil
+			})
+			assert.NoError(t, err)
+			return events
+		}
+
+		events := export(AuditFilter{})
+		if assert.Len(t, events, 3) {
+			assert.Less(t, events[0].ID, events[1].ID)
+			assert.Less(t, events[1].ID, events[2].ID)
+			assert.Nil(t, events[0].Before)
+			assert.Equal(t, &record, events[0].After)
+			assert.True(t, createdAt.Add(2*time.Hour).Equal(events[2].CreatedAt))
+			assert.Equal(t, &record, events[2].Before)
+			assert.Equal(t, &disabled, events[2].After)
+			assert.Equal(t, "disable", events[2].Action)
+			assert.Equal(t, "cookie", events[2].AuthMethod)
+			assert.Equal(t, "192.0.2.1", events[2].ClientIP)
+			assert.Equal(t, "request", events[2].RequestID)
+			assert.Equal(t, userID, events[2].UserID)
+			assert.Equal(t, actorID, events[2].ActorID)
+		}
+		assert.Len(t, export(AuditFilter{ShortURL: "abc123"}), 2)
+		assert.Len(t, export(AuditFilter{UserID: userID}), 3)
+		assert.Len(t, export(AuditFilter{UserID: actorID}), 1)
+		assert.Empty(t, export(AuditFilter{UserID: uuid.New()}))
+		assert.Len(t, export(AuditFilter{From: createdAt.Add(time.Hour)}), 2)
+		assert.Len(t, export(AuditFilter{To: createdAt.Add(time.Hour)}), 1)
+		events = export(AuditFilter{Limit: 1})
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "abc123", events[0].ShortURL)
+		}
+
+		stop := errors.New("stop")
+		err = repo.ExportAudit(context.Background(), AuditFilter{}, func(AuditEvent) error {
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	factories := map[string]conformanceFactory{
+		"links": func(_ *testing.T) Repository {
+			return NewLinks()
+		},
+		"sharded links": func(_ *testing.T) Repository {
+			return NewShardedLinks()
+		},
+		"breaker": func(_ *testing.T) Repository {
+			return NewBreaker(zap.NewNop(), NewLinks(), BreakerConfig{})
+		},
+		"links with file": func(t *testing.T) Repository {
+			t.Helper()
+			linksWithFile, err := NewLinksWithFile(zap.NewNop(), filepath.Join(t.TempDir(), "shorturls.txt"), FileConfig{})
+			assert.NoError(t, err)
+			t.Cleanup(func() {
+				err := linksWithFile.Close()
+				assert.NoError(t, err)
+			})
+			return linksWithFile
+		},
+		"bolt": func(t *testing.T) Repository {
+			t.Helper()
+			return newTestBoltRepository(t)
+		},
+		"sqlite": func(t *testing.T) Repository {
+			t.Helper()
+			return newTestSQLiteRepository(t)
+		},
+	}
+
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			runConformance(t, factory)
+		})
+	}
+}
+
+// TestDBRepositoryConformance run conformance tests against PostgreSQL database
+// from TEST_DATABASE_DSN environment variable, for example local docker container
+// or service container of CI workflow. Test fails in CI if database is not set.
+// All data of database is removed.
+func TestDBRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSN)
+	if dsn == "" && os.Getenv("CI") != "" {
+		t.Fatalf("%s is not set in CI", testDatabaseDSN)
+	}
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSN)
+	}
+	err := Migrate(dsn)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	runConformance(t, func(t *testing.T) Repository {
+		t.Helper()
+		repo, err := NewDBRepository(context.Background(), zap.NewNop(), dsn, DBConfig{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(repo.Close)
+
+		_, err = repo.pool.Exec(context.Background(), "TRUNCATE urls, urls_for_delete, api_keys, refresh_tokens, users, audit_log")
+		assert.NoError(t, err)
+		return repo
+	})
+}
@@ -5,12 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"strings"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/models"
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -19,51 +16,89 @@ import (
 )
 
 // DBRepository is a repository which stores data in database.
+// Reads are routed to healthy replicas if such are configured, writes and
+// deletion queue are always served by primary.
 type DBRepository struct {
-	logger *zap.Logger
-	pool   IPool
+	logger   *zap.Logger
+	pool     IPool
+	replicas *replicaSet
+	retry    RetryPolicy
 }
 
-// NewDBRepository create new DBRepository.
-func NewDBRepository(ctx context.Context, logger *zap.Logger, connString string) (*DBRepository, error) {
-	m, err := migrate.New("file://./internal/app/repository/migrations", connString)
-	if err != nil {
-		return nil, fmt.Errorf("can not create migration instance: %w", err)
-	}
-	err = m.Up()
-	if err != nil {
-		if !errors.Is(err, migrate.ErrNoChange) {
-			return nil, fmt.Errorf("can not migrate up: %w", err)
-		}
-	}
-
-	pool, err := NewPool(ctx, connString)
+// NewDBRepository create new DBRepository with primary database and optional replicas.
+func NewDBRepository(
+	ctx context.Context,
+	logger *zap.Logger,
+	connString string,
+	cfg DBConfig,
+) (*DBRepository, error) {
+	cfg.Retry = cfg.Retry.withDefaults()
+	pool, err := NewPool(ctx, connString, cfg.Retry)
 	if err != nil {
 		return nil, fmt.Errorf("can not create new pool: %w", err)
 	}
 	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("can not ping PostgreSQL server: %w", err)
 	}
 
+	var replicas *replicaSet
+	if len(cfg.ReplicaDSNs) != 0 {
+		replicas, err = newReplicaSet(ctx, logger, cfg)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
 	return &DBRepository{
-		logger: logger,
-		pool:   pool,
+		logger:   logger,
+		pool:     pool,
+		replicas: replicas,
+		retry:    cfg.Retry,
 	}, nil
 }
 
+// read run query on healthy replica and fall back to primary on failure.
+// Replica which is unavailable is marked as unhealthy. Not found rows are
+// looked up on primary too, because replica can lag behind.
+// Query is not repeated on primary if context is done.
+func (d *DBRepository) read(ctx context.Context, userID *uuid.UUID, query func(pool IPool) error) error {
+	replica := d.replicas.pick(userID)
+	if replica != nil {
+		err := query(replica.pool)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if isReplicaFailure(err) {
+			d.replicas.fail(replica, err)
+		}
+	}
+	return query(d.pool)
+}
+
 // GetOriginalURL return original URL by short URL.
 func (d *DBRepository) GetOriginalURL(ctx context.Context, shortLink string) (*string, error) {
 	var originalURL string
 	var deleted bool
-	err := d.pool.QueryRow(ctx, `SELECT original_url, deleted 
-								FROM urls WHERE short_url=$1`, shortLink).Scan(&originalURL, &deleted)
+	var disabled bool
+	err := d.read(ctx, nil, func(pool IPool) error {
+		return pool.QueryRow(ctx, `SELECT original_url, deleted, disabled 
+								FROM urls WHERE short_url=$1`, shortLink).Scan(&originalURL, &deleted, &disabled)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("can not get original url: %w", err)
 	}
 	if deleted {
 		return nil, ErrURLIsDeleted
 	}
+	if disabled {
+		return nil, ErrURLIsDisabled
+	}
 	return &originalURL, nil
 }
 
@@ -74,9 +109,14 @@ func (d *DBRepository) SetLink(
 	shortURLs []string,
 	userID uuid.UUID,
 ) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
 	for _, shortURL := range shortURLs {
 		var link string
-		err := d.pool.QueryRow(ctx, `INSERT INTO urls (short_url, original_url, user_id) 
+		err = d.pool.QueryRow(ctx, `INSERT INTO urls (short_url, original_url, user_id) 
 									VALUES ($1, $2, $3) 
 									ON CONFLICT (original_url) 
 									DO UPDATE SET original_url=EXCLUDED.original_url 
@@ -90,6 +130,7 @@ func (d *DBRepository) SetLink(
 			}
 			return nil, fmt.Errorf("can not set link: %w", err)
 		}
+		d.replicas.wrote(userID)
 		if link != shortURL {
 			return &link, ErrOriginalURLUniqueViolation
 		}
@@ -100,39 +141,80 @@ func (d *DBRepository) SetLink(
 }
 
 // SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
 func (d *DBRepository) SetLinks(
 	ctx context.Context,
 	batch []models.ShortenBatchRequest,
 	shortURLs [][]string,
 	userID uuid.UUID,
-) ([]string, error) {
-	urls := make(map[string]string)
+) ([]LinkResult, error) {
+	invalid := make([]bool, len(batch))
 	originalURLs := make([]string, 0, len(batch))
-	for _, originalURL := range batch {
-		originalURLs = append(originalURLs, originalURL.OriginalURL)
+	for i := range batch {
+		_, err := url.ParseRequestURI(batch[i].OriginalURL)
+		if err != nil {
+			invalid[i] = true
+			continue
+		}
+		originalURLs = append(originalURLs, batch[i].OriginalURL)
 	}
 
-	tx, err := d.pool.Begin(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("can not start transaction: %w", err)
+	var urls map[string]string
+	var created map[string]struct{}
+	if len(originalURLs) != 0 {
+		err := WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+			var err error
+			urls, created, err = d.setLinks(ctx, tx, batch, invalid, originalURLs, shortURLs, userID)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.replicas.wrote(userID)
 	}
-	defer func() {
-		err = tx.Rollback(ctx)
-		if err != nil && !strings.Contains(err.Error(), "tx is closed") {
-			d.logger.Error("Can not rollback transaction", zap.Error(err))
+
+	result := make([]LinkResult, 0, len(batch))
+	for i := range batch {
+		shortURL, ok := urls[batch[i].OriginalURL]
+		switch {
+		case invalid[i]:
+			result = append(result, LinkResult{Status: models.BatchStatusInvalid})
+		case !ok:
+			result = append(result, LinkResult{Status: models.BatchStatusError})
+		default:
+			status := models.BatchStatusExisting
+			if _, ok := created[batch[i].OriginalURL]; ok {
+				status = models.BatchStatusCreated
+				delete(created, batch[i].OriginalURL)
+			}
+			result = append(result, LinkResult{ShortURL: shortURL, Status: status})
 		}
-	}()
+	}
+
+	return result, nil
+}
 
-	var originalURLUniqueViolation bool
+// setLinks add short URLs of valid links in transaction.
+// Return short URLs by original URLs and set of created original URLs.
+// Original URLs which short URL can not be generated for are absent in result.
+func (d *DBRepository) setLinks(
+	ctx context.Context,
+	tx pgx.Tx,
+	batch []models.ShortenBatchRequest,
+	invalid []bool,
+	originalURLs []string,
+	shortURLs [][]string,
+	userID uuid.UUID,
+) (map[string]string, map[string]struct{}, error) {
+	urls := make(map[string]string)
 	rows, err := tx.Query(ctx, "SELECT short_url, original_url FROM urls WHERE original_url = ANY ($1)", originalURLs)
 	if err != nil {
-		return nil, fmt.Errorf("can not get original urls: %w", err)
+		return nil, nil, fmt.Errorf("can not get original urls: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		originalURLUniqueViolation = true
-
 		var shortURL string
 		var originalURL string
 		err = rows.Scan(
@@ -140,79 +222,74 @@ func (d *DBRepository) SetLinks(
 			&originalURL,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("can not read row: %w", err)
+			return nil, nil, fmt.Errorf("can not read row: %w", err)
 		}
 
 		urls[originalURL] = shortURL
 	}
 	if rows.Err() != nil {
-		return nil, fmt.Errorf("can not read rows: %w", err)
+		return nil, nil, fmt.Errorf("can not read rows: %w", rows.Err())
 	}
+	rows.Close()
 
-	for i := len(originalURLs) - 1; i >= 0; i-- {
-		if _, ok := urls[originalURLs[i]]; ok {
-			originalURLs = append(originalURLs[:i], originalURLs[i+1:]...)
+	pending := make([]int, 0, len(batch))
+	created := make(map[string]struct{})
+	for i := range batch {
+		if invalid[i] {
+			continue
+		}
+		if _, ok := urls[batch[i].OriginalURL]; ok {
+			continue
+		}
+		if _, ok := created[batch[i].OriginalURL]; ok {
+			continue
 		}
+		created[batch[i].OriginalURL] = struct{}{}
+		pending = append(pending, i)
 	}
 
-	for i := range shortURLs {
+	for attempt := 0; len(pending) != 0; attempt++ {
 		b := &pgx.Batch{}
-
-		for j := range originalURLs {
-			_, err = url.ParseRequestURI(originalURLs[j])
-			if err != nil {
-				return nil, ErrInvalidURL
+		queued := make([]int, 0, len(pending))
+		for _, i := range pending {
+			if attempt >= len(shortURLs[i]) {
+				delete(created, batch[i].OriginalURL)
+				continue
 			}
-
 			b.Queue(`INSERT INTO urls (short_url, original_url, user_id) 
 			VALUES ($1, $2, $3) 
 			ON CONFLICT (short_url) 
-			DO NOTHING`, shortURLs[j][i], originalURLs[j], userID)
+			DO NOTHING`, shortURLs[i][attempt], batch[i].OriginalURL, userID)
+			queued = append(queued, i)
+		}
+		if len(queued) == 0 {
+			break
 		}
 
 		br := tx.SendBatch(ctx, b)
 
-		var j int
-		for k, originalURL := range originalURLs {
+		var conflicted []int
+		for _, i := range queued {
 			commandTag, err := br.Exec()
 			if err != nil {
-				return nil, fmt.Errorf("can not set link: %w", err)
+				return nil, nil, errors.Join(fmt.Errorf("can not set link: %w", err), br.Close())
 			}
 			if commandTag.RowsAffected() == 0 {
-				originalURLs[j] = originalURL
-				j++
+				conflicted = append(conflicted, i)
 				continue
 			}
-			urls[originalURL] = shortURLs[k][i]
+			urls[batch[i].OriginalURL] = shortURLs[i][attempt]
 		}
 
 		err = br.Close()
 		if err != nil {
-			return nil, fmt.Errorf("can not close batch: %w", err)
-		}
-
-		if j == 0 {
-			err = tx.Commit(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("can not commit transaction: %w", err)
-			}
-
-			result := make([]string, 0, len(batch))
-			for j := range batch {
-				result = append(result, urls[batch[j].OriginalURL])
-			}
-
-			if originalURLUniqueViolation {
-				return result, ErrOriginalURLUniqueViolation
-			}
-
-			return result, nil
+			return nil, nil, fmt.Errorf("can not close batch: %w", err)
 		}
 
-		originalURLs = originalURLs[:j]
+		pending = conflicted
 	}
 
-	return nil, ErrReachedMaxGenerationRetries
+	return urls, created, nil
 }
 
 // GetShortLinksOfUser return URLs of user if such exist.
@@ -220,7 +297,25 @@ func (d *DBRepository) GetShortLinksOfUser(
 	ctx context.Context,
 	userID uuid.UUID,
 ) ([]models.ShortenOfUserResponse, error) {
-	rows, err := d.pool.Query(ctx, "SELECT short_url, original_url FROM urls WHERE user_id = $1", userID)
+	var urls []models.ShortenOfUserResponse
+	err := d.read(ctx, &userID, func(pool IPool) error {
+		var err error
+		urls, err = d.getShortLinksOfUser(ctx, pool, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// getShortLinksOfUser return URLs of user from provided database.
+func (d *DBRepository) getShortLinksOfUser(
+	ctx context.Context,
+	pool IPool,
+	userID uuid.UUID,
+) ([]models.ShortenOfUserResponse, error) {
+	rows, err := pool.Query(ctx, "SELECT short_url, original_url FROM urls WHERE user_id = $1", userID)
 	if err != nil {
 		return nil, fmt.Errorf("can not get urls of user: %w", err)
 	}
@@ -256,53 +351,478 @@ func (d *DBRepository) DeleteURLs(ctx context.Context, urls []string, userID uui
 	if err != nil {
 		return fmt.Errorf("can not add urls for delete: %w", err)
 	}
+	d.replicas.wrote(userID)
 
 	return nil
 }
 
 // DeleteURLsInDB get and delete URLs from deletion queue.
-func (d *DBRepository) DeleteURLsInDB(ctx context.Context) error {
-	tx, err := d.pool.Begin(ctx)
+// Up to limit entries are processed in one transaction. Entries which are locked
+// by other transactions are skipped, so queue can be shared by several instances.
+// Return amount of processed deletion queue entries.
+func (d *DBRepository) DeleteURLsInDB(ctx context.Context, limit int) (int, error) {
+	var count int
+	err := WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		var err error
+		count, err = d.deleteURLsInDB(ctx, tx, limit)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("can not start transaction: %w", err)
+		return 0, err
 	}
-	defer func() {
-		err = tx.Rollback(ctx)
-		if err != nil && !strings.Contains(err.Error(), "tx is closed") {
-			d.logger.Error("Can not rollback transaction", zap.Error(err))
+	return count, nil
+}
+
+// deleteURLsInDB get and delete URLs from deletion queue in transaction.
+func (d *DBRepository) deleteURLsInDB(ctx context.Context, tx pgx.Tx, limit int) (int, error) {
+	rows, err := tx.Query(ctx, `SELECT id, urls, user_id FROM urls_for_delete 
+								ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("can not get urls for delete: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	var userIDs []uuid.UUID
+	urlsOfUsers := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var id int
+		var urls []string
+		var userID uuid.UUID
+		err = rows.Scan(&id, &urls, &userID)
+		if err != nil {
+			return 0, fmt.Errorf("can not read row: %w", err)
+		}
+
+		ids = append(ids, id)
+		if _, ok := urlsOfUsers[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+		urlsOfUsers[userID] = append(urlsOfUsers[userID], urls...)
+	}
+	if rows.Err() != nil {
+		return 0, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	b := &pgx.Batch{}
+	for _, userID := range userIDs {
+		b.Queue(`UPDATE urls SET deleted = true 
+				WHERE user_id = $1 AND short_url = ANY ($2)`, userID, urlsOfUsers[userID])
+	}
+	br := tx.SendBatch(ctx, b)
+	for range userIDs {
+		_, err = br.Exec()
+		if err != nil {
+			return 0, fmt.Errorf("can not delete urls: %w", err)
+		}
+	}
+	err = br.Close()
+	if err != nil {
+		return 0, fmt.Errorf("can not close batch: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM urls_for_delete WHERE id = ANY ($1)", ids)
+	if err != nil {
+		return 0, fmt.Errorf("can not clear urls for delete: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// SetAPIKey store new API key.
+func (d *DBRepository) SetAPIKey(ctx context.Context, key APIKey) error {
+	_, err := d.pool.Exec(ctx, `INSERT INTO api_keys (id, hash, user_id, name, scopes, created_at)
+								VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.ID, key.Hash, key.UserID, key.Name, key.Scopes, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("can not set api key: %w", err)
+	}
+	d.replicas.wrote(key.UserID)
+	return nil
+}
+
+// GetAPIKey return API key by hash including revoked one.
+// Key is always read from primary, so revocation takes effect immediately.
+func (d *DBRepository) GetAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	rows, err := d.pool.Query(ctx, `SELECT id, hash, user_id, name, scopes, created_at, revoked_at
+									FROM api_keys WHERE hash = $1`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("can not get api key: %w", err)
+	}
+	keys, err := scanAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &keys[0], nil
+}
+
+// GetAPIKeysOfUser return API keys of user in order of creation.
+func (d *DBRepository) GetAPIKeysOfUser(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	var keys []APIKey
+	err := d.read(ctx, &userID, func(pool IPool) error {
+		rows, err := pool.Query(ctx, `SELECT id, hash, user_id, name, scopes, created_at, revoked_at
+									FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`, userID)
+		if err != nil {
+			return fmt.Errorf("can not get api keys of user: %w", err)
+		}
+		keys, err = scanAPIKeys(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// scanAPIKeys read API keys from rows and close them.
+func scanAPIKeys(rows pgx.Rows) ([]APIKey, error) {
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		var id uuid.UUID
+		var revokedAt *time.Time
+		err := rows.Scan(&id, &key.Hash, &key.UserID, &key.Name, &key.Scopes, &key.CreatedAt, &revokedAt)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+		key.ID = id.String()
+		if revokedAt != nil {
+			key.RevokedAt = *revokedAt
 		}
-	}()
+		keys = append(keys, key)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	return keys, nil
+}
 
-	var id int
-	var urls []string
-	var userID uuid.UUID
-	err = tx.QueryRow(ctx, "SELECT id, urls, user_id FROM urls_for_delete LIMIT 1").Scan(&id, &urls, &userID)
+// RevokeAPIKey mark API key of user as revoked.
+func (d *DBRepository) RevokeAPIKey(ctx context.Context, id string, userID uuid.UUID) error {
+	keyID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+	tag, err := d.pool.Exec(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+								WHERE id = $1 AND user_id = $2`, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("can not revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	d.replicas.wrote(userID)
+	return nil
+}
+
+// SetRefreshToken store new refresh token and remove expired tokens of its user.
+func (d *DBRepository) SetRefreshToken(ctx context.Context, token RefreshToken) error {
+	_, err := d.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at <= now()`, token.UserID)
+	if err != nil {
+		return fmt.Errorf("can not delete expired refresh tokens: %w", err)
+	}
+	_, err = d.pool.Exec(ctx, `INSERT INTO refresh_tokens (hash, family_id, user_id, expires_at)
+								VALUES ($1, $2, $3, $4)`, token.Hash, token.FamilyID, token.UserID, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("can not set refresh token: %w", err)
+	}
+	return nil
+}
+
+// UseRefreshToken mark refresh token as used and return its state before marking.
+// Row is locked, so only one of concurrent uses gets token which is not used.
+func (d *DBRepository) UseRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	token := RefreshToken{Hash: hash}
+	var familyID uuid.UUID
+	var usedAt *time.Time
+	var revokedAt *time.Time
+	err := d.pool.QueryRow(ctx, `UPDATE refresh_tokens AS t SET used_at = COALESCE(t.used_at, now())
+								FROM (SELECT hash, used_at FROM refresh_tokens WHERE hash = $1 FOR UPDATE) AS old
+								WHERE t.hash = old.hash
+								RETURNING t.family_id, t.user_id, t.expires_at, old.used_at, t.revoked_at`, hash).
+		Scan(&familyID, &token.UserID, &token.ExpiresAt, &usedAt, &revokedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("can not use refresh token: %w", err)
+	}
+	token.FamilyID = familyID.String()
+	if usedAt != nil {
+		token.UsedAt = *usedAt
+	}
+	if revokedAt != nil {
+		token.RevokedAt = *revokedAt
+	}
+	return &token, nil
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+func (d *DBRepository) RevokeRefreshTokens(ctx context.Context, familyID string) error {
+	family, err := uuid.Parse(familyID)
+	if err != nil {
+		return nil //nolint:nilerr // family with invalid id does not exist
+	}
+	_, err = d.pool.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = now()
+							WHERE family_id = $1 AND revoked_at IS NULL`, family)
+	if err != nil {
+		return fmt.Errorf("can not revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// SetIdentity store identity if it is not stored yet and return stored identity.
+func (d *DBRepository) SetIdentity(ctx context.Context, identity Identity) (*Identity, error) {
+	err := d.pool.QueryRow(ctx, `INSERT INTO users (issuer, subject, user_id, created_at)
+								VALUES ($1, $2, $3, $4)
+								ON CONFLICT (issuer, subject) DO UPDATE SET issuer = EXCLUDED.issuer
+								RETURNING user_id, created_at`,
+		identity.Issuer, identity.Subject, identity.UserID, identity.CreatedAt).
+		Scan(&identity.UserID, &identity.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("can not set identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// MergeUser move links and queued deletions of anonymous user into another user.
+// Links of user with identity are not moved.
+func (d *DBRepository) MergeUser(ctx context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to {
+		return nil
+	}
+	err := WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE urls SET user_id = $2
+								WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`, from, to)
+		if err != nil {
+			return fmt.Errorf("can not move urls: %w", err)
+		}
+		_, err = tx.Exec(ctx, `UPDATE urls_for_delete SET user_id = $2
+								WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`, from, to)
+		if err != nil {
+			return fmt.Errorf("can not move urls for delete: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.replicas.wrote(to)
+	return nil
+}
+
+// SearchLinks return links which match filter in order of creation.
+// Links are searched on primary, so administrator sees own changes.
+func (d *DBRepository) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	var userID *uuid.UUID
+	if filter.UserID != uuid.Nil {
+		userID = &filter.UserID
+	}
+	rows, err := d.pool.Query(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at 
+									FROM urls 
+									WHERE ($1::uuid IS NULL OR user_id = $1) 
+									AND (strpos(short_url, $2) > 0 OR strpos(original_url, $2) > 0) 
+									ORDER BY id LIMIT NULLIF($3, 0) OFFSET $4`,
+		userID, filter.Query, max(filter.Limit, 0), max(filter.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("can not search links: %w", err)
+	}
+	return scanRecords(rows)
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+func (d *DBRepository) SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) (*Record, error) {
+	var record *Record
+	err := WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		records, err := lockRecords(ctx, tx, []string{shortURL})
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return ErrNotFound
 		}
-		return fmt.Errorf("can not get urls for delete: %w", err)
+		record = &records[0]
+
+		_, err = tx.Exec(ctx, "UPDATE urls SET disabled = $2 WHERE short_url = $1", shortURL, disabled)
+		if err != nil {
+			return fmt.Errorf("can not set disabling flag: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return record, nil
+}
 
-	_, err = tx.Exec(ctx, `UPDATE urls SET deleted = true 
-								WHERE user_id = $1 AND short_url = ANY ($2)`, userID, urls)
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (d *DBRepository) GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error) {
+	rows, err := d.pool.Query(ctx, `SELECT user_id, COUNT(*) FROM urls 
+									GROUP BY user_id ORDER BY user_id LIMIT NULLIF($1, 0) OFFSET $2`,
+		max(limit, 0), max(offset, 0))
 	if err != nil {
-		return fmt.Errorf("can not delete urls: %w", err)
+		return nil, fmt.Errorf("can not get users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []UserLinks
+	for rows.Next() {
+		var user UserLinks
+		err = rows.Scan(&user.UserID, &user.URLs)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+		users = append(users, user)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
 	}
 
-	_, err = tx.Exec(ctx, "DELETE FROM urls_for_delete WHERE id = $1", id)
+	return users, nil
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+func (d *DBRepository) TransferLinks(ctx context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	var records []Record
+	err := WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		var err error
+		records, err = lockRecords(ctx, tx, shortURLs)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE urls SET user_id = $2 WHERE short_url = ANY ($1)", shortURLs, to)
+		if err != nil {
+			return fmt.Errorf("can not transfer links: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("can not clear urls for delete: %w", err)
+		return nil, err
 	}
+	d.replicas.wrote(to)
+	return records, nil
+}
+
+// AppendAudit store events in audit log in one transaction.
+func (d *DBRepository) AppendAudit(ctx context.Context, events []AuditEvent) error {
+	return WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		for i := range events {
+			before, err := marshalAuditRecord(events[i].Before)
+			if err != nil {
+				return err
+			}
+			after, err := marshalAuditRecord(events[i].After)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO audit_log (created_at, action, short_url, user_id, actor_id, 
+									auth_method, client_ip, request_id, before, after) 
+									VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				events[i].CreatedAt, events[i].Action, events[i].ShortURL, events[i].UserID, events[i].ActorID,
+				events[i].AuthMethod, events[i].ClientIP, events[i].RequestID, before, after)
+			if err != nil {
+				return fmt.Errorf("can not insert audit event: %w", err)
+			}
+		}
+		return nil
+	})
+}
 
-	err = tx.Commit(ctx)
+// ExportAudit call fn for every event which matches filter in order of appending.
+// Events are read from primary, so they are not behind the changes.
+func (d *DBRepository) ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	var userID *uuid.UUID
+	if filter.UserID != uuid.Nil {
+		userID = &filter.UserID
+	}
+	var from, to *time.Time
+	if !filter.From.IsZero() {
+		from = &filter.From
+	}
+	if !filter.To.IsZero() {
+		to = &filter.To
+	}
+	rows, err := d.pool.Query(ctx, `SELECT id, created_at, action, short_url, user_id, actor_id, 
+									auth_method, client_ip, request_id, before, after 
+									FROM audit_log 
+									WHERE ($1 = '' OR short_url = $1) 
+									AND ($2::uuid IS NULL OR user_id = $2 OR actor_id = $2) 
+									AND ($3::timestamptz IS NULL OR created_at >= $3) 
+									AND ($4::timestamptz IS NULL OR created_at < $4) 
+									ORDER BY id LIMIT NULLIF($5, 0)`,
+		filter.ShortURL, userID, from, to, max(filter.Limit, 0))
 	if err != nil {
-		return fmt.Errorf("can not commit transaction: %w", err)
+		return fmt.Errorf("can not get audit events: %w", err)
 	}
+	defer rows.Close()
 
+	for rows.Next() {
+		var event AuditEvent
+		var before, after []byte
+		err = rows.Scan(&event.ID, &event.CreatedAt, &event.Action, &event.ShortURL, &event.UserID, &event.ActorID,
+			&event.AuthMethod, &event.ClientIP, &event.RequestID, &before, &after)
+		if err != nil {
+			return fmt.Errorf("can not read row: %w", err)
+		}
+		event.Before, err = unmarshalAuditRecord(before)
+		if err != nil {
+			return err
+		}
+		event.After, err = unmarshalAuditRecord(after)
+		if err != nil {
+			return err
+		}
+		err = fn(event)
+		if err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("can not read rows: %w", rows.Err())
+	}
 	return nil
 }
 
+// lockRecords lock links by short URLs in transaction and return them in order of creation.
+func lockRecords(ctx context.Context, tx pgx.Tx, shortURLs []string) ([]Record, error) {
+	rows, err := tx.Query(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at 
+								FROM urls WHERE short_url = ANY ($1) ORDER BY id FOR UPDATE`, shortURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not lock links: %w", err)
+	}
+	return scanRecords(rows)
+}
+
+// scanRecords read links from rows as records of export and close rows.
+func scanRecords(rows pgx.Rows) ([]Record, error) {
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var record Record
+		err := rows.Scan(&record.ShortURL, &record.OriginalURL, &record.UserID, &record.Deleted, &record.Disabled,
+			&record.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+		records = append(records, record)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	return records, nil
+}
+
 // Ping check connection with database.
 func (d *DBRepository) Ping(ctx context.Context) error {
 	err := d.pool.Ping(ctx)
@@ -316,7 +836,10 @@ func (d *DBRepository) Ping(ctx context.Context) error {
 func (d *DBRepository) Stats(ctx context.Context) (*models.Stats, error) {
 	var urls int
 	var users int
-	err := d.pool.QueryRow(ctx, "SELECT COUNT(DISTINCT short_url), COUNT(DISTINCT user_id) FROM urls").Scan(&urls, &users)
+	err := d.read(ctx, nil, func(pool IPool) error {
+		return pool.QueryRow(ctx, "SELECT COUNT(DISTINCT short_url), COUNT(DISTINCT user_id) FROM urls").
+			Scan(&urls, &users)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("can not get stats: %w", err)
 	}
@@ -327,7 +850,91 @@ func (d *DBRepository) Stats(ctx context.Context) (*models.Stats, error) {
 	}, nil
 }
 
+// Export call fn for every link in order of creation.
+func (d *DBRepository) Export(ctx context.Context, fn func(Record) error) error {
+	rows, err := d.pool.Query(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at 
+									FROM urls ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("can not get links: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record Record
+		err = rows.Scan(&record.ShortURL, &record.OriginalURL, &record.UserID, &record.Deleted, &record.Disabled,
+			&record.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("can not read row: %w", err)
+		}
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return nil
+}
+
+// Lookup return existing links which have short URL or original URL of one of records.
+func (d *DBRepository) Lookup(ctx context.Context, records []Record) ([]Record, error) {
+	shortURLs := make([]string, 0, len(records))
+	originalURLs := make([]string, 0, len(records))
+	for i := range records {
+		shortURLs = append(shortURLs, records[i].ShortURL)
+		originalURLs = append(originalURLs, records[i].OriginalURL)
+	}
+
+	rows, err := d.pool.Query(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at 
+									FROM urls WHERE short_url = ANY ($1) OR original_url = ANY ($2)`,
+		shortURLs, originalURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not lookup links: %w", err)
+	}
+	return scanRecords(rows)
+}
+
+// Import store records as is replacing links with the same short URL or original URL.
+// Records with unknown creation time get current time.
+func (d *DBRepository) Import(ctx context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
+	}
+
+	return WithTx(ctx, d.pool, d.retry, func(tx pgx.Tx) error {
+		b := &pgx.Batch{}
+		for i := range records {
+			var createdAt *time.Time
+			if !records[i].CreatedAt.IsZero() {
+				createdAt = &records[i].CreatedAt
+			}
+			b.Queue("DELETE FROM urls WHERE short_url = $1 OR original_url = $2",
+				records[i].ShortURL, records[i].OriginalURL)
+			b.Queue(`INSERT INTO urls (short_url, original_url, user_id, deleted, disabled, created_at) 
+					VALUES ($1, $2, $3, $4, $5, COALESCE($6, now()))`, records[i].ShortURL, records[i].OriginalURL,
+				records[i].UserID, records[i].Deleted, records[i].Disabled, createdAt)
+		}
+
+		br := tx.SendBatch(ctx, b)
+		for range b.Len() {
+			_, err := br.Exec()
+			if err != nil {
+				return errors.Join(fmt.Errorf("can not import link: %w", err), br.Close())
+			}
+		}
+		err := br.Close()
+		if err != nil {
+			return fmt.Errorf("can not close batch: %w", err)
+		}
+		return nil
+	})
+}
+
 // Close all connections with database.
 func (d *DBRepository) Close() {
+	d.replicas.close()
 	d.pool.Close()
 }
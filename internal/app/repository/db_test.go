@@ -2,21 +2,25 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/logger"
 	"github.com/RexArseny/url_shortener/internal/app/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestDBRepositoryNewDBRepository(t *testing.T) {
 	testLogger, err := logger.InitLogger()
 	assert.NoError(t, err)
-	db, err := NewDBRepository(context.Background(), testLogger, "")
+	db, err := NewDBRepository(context.Background(), testLogger, "", DBConfig{})
 	assert.Error(t, err)
 	assert.Empty(t, db)
 }
@@ -38,10 +42,10 @@ func TestDBRepositoryGetOriginalURL(t *testing.T) {
 	originalURL := "http://example.com"
 	deleted := false
 
-	mock.ExpectQuery("SELECT original_url, deleted FROM urls WHERE short_url=").
+	mock.ExpectQuery("SELECT original_url, deleted, disabled FROM urls WHERE short_url=").
 		WithArgs(shortLink).
-		WillReturnRows(pgxmock.NewRows([]string{"original_url", "deleted"}).
-			AddRow(originalURL, deleted))
+		WillReturnRows(pgxmock.NewRows([]string{"original_url", "deleted", "disabled"}).
+			AddRow(originalURL, deleted, false))
 
 	result, err := repo.GetOriginalURL(context.Background(), shortLink)
 	assert.NoError(t, err)
@@ -102,22 +106,37 @@ func TestDBRepositorySetLinks(t *testing.T) {
 
 	batch := []models.ShortenBatchRequest{
 		{OriginalURL: "http://example2.com"},
+		{OriginalURL: "http://example.com"},
+		{OriginalURL: "invalid"},
+		{OriginalURL: "http://example3.com"},
+		{OriginalURL: "http://example2.com"},
 	}
-	shortURLs := [][]string{{"abc1234"}, {"abc123"}}
+	shortURLs := [][]string{{"abc1234"}, {"abc123"}, {"def456"}, {"ghi789"}, {"jkl012"}}
 	userID := uuid.New()
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT short_url, original_url FROM urls WHERE original_url = ANY").
 		WithArgs(pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"short_url", "original_url"}).AddRow("abc123", "http://example.com"))
-	mock.ExpectBatch().ExpectExec("INSERT INTO urls").
+	batchExpectation := mock.ExpectBatch()
+	batchExpectation.ExpectExec("INSERT INTO urls").
 		WithArgs(shortURLs[0][0], batch[0].OriginalURL, userID).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	batchExpectation.ExpectExec("INSERT INTO urls").
+		WithArgs(shortURLs[3][0], batch[3].OriginalURL, userID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
 	mock.ExpectCommit()
 
 	result, err := repo.SetLinks(context.Background(), batch, shortURLs, userID)
-	assert.Error(t, err)
-	assert.NotNil(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc1234", Status: models.BatchStatusCreated},
+		{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		{Status: models.BatchStatusInvalid},
+		{Status: models.BatchStatusError},
+		{ShortURL: "abc1234", Status: models.BatchStatusExisting},
+	}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestDBRepositoryGetShortLinksOfUser(t *testing.T) {
@@ -145,6 +164,277 @@ func TestDBRepositoryGetShortLinksOfUser(t *testing.T) {
 	assert.NotNil(t, result)
 }
 
+func TestDBRepositoryAPIKeys(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: zap.NewNop(),
+		pool:   mock,
+	}
+
+	id := uuid.New()
+	userID := uuid.New()
+	createdAt := time.Now()
+	revokedAt := createdAt.Add(time.Hour)
+
+	mock.ExpectQuery("SELECT id, hash, user_id, name, scopes, created_at, revoked_at FROM api_keys WHERE hash =").
+		WithArgs("hash").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "hash", "user_id", "name", "scopes", "created_at", "revoked_at"}).
+			AddRow(id, "hash", userID, "name", []string{"create"}, createdAt, &revokedAt))
+
+	key, err := repo.GetAPIKey(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, &APIKey{
+		CreatedAt: createdAt,
+		RevokedAt: revokedAt,
+		ID:        id.String(),
+		Hash:      "hash",
+		Name:      "name",
+		Scopes:    []string{"create"},
+		UserID:    userID,
+	}, key)
+
+	mock.ExpectQuery("SELECT id, hash, user_id, name, scopes, created_at, revoked_at FROM api_keys WHERE hash =").
+		WithArgs("unknown").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "hash", "user_id", "name", "scopes", "created_at", "revoked_at"}))
+
+	_, err = repo.GetAPIKey(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	mock.ExpectExec("UPDATE api_keys SET revoked_at").
+		WithArgs(id, userID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err = repo.RevokeAPIKey(context.Background(), id.String(), userID)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	err = repo.RevokeAPIKey(context.Background(), "invalid", userID)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBRepositoryRefreshTokens(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: zap.NewNop(),
+		pool:   mock,
+	}
+
+	familyID := uuid.New()
+	userID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	usedAt := time.Now()
+
+	mock.ExpectQuery("UPDATE refresh_tokens AS t SET used_at").
+		WithArgs("hash").
+		WillReturnRows(pgxmock.NewRows([]string{"family_id", "user_id", "expires_at", "used_at", "revoked_at"}).
+			AddRow(familyID, userID, expiresAt, &usedAt, (*time.Time)(nil)))
+
+	token, err := repo.UseRefreshToken(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, &RefreshToken{
+		ExpiresAt: expiresAt,
+		UsedAt:    usedAt,
+		Hash:      "hash",
+		FamilyID:  familyID.String(),
+		UserID:    userID,
+	}, token)
+
+	mock.ExpectQuery("UPDATE refresh_tokens AS t SET used_at").
+		WithArgs("unknown").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = repo.UseRefreshToken(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
+		WithArgs(familyID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	err = repo.RevokeRefreshTokens(context.Background(), familyID.String())
+	assert.NoError(t, err)
+
+	err = repo.RevokeRefreshTokens(context.Background(), "invalid")
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBRepositoryUsers(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: zap.NewNop(),
+		pool:   mock,
+	}
+
+	userID := uuid.New()
+	storedUserID := uuid.New()
+	createdAt := time.Now()
+
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("https://issuer.com", "subject", userID, createdAt).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "created_at"}).AddRow(storedUserID, createdAt))
+
+	identity, err := repo.SetIdentity(context.Background(), Identity{
+		CreatedAt: createdAt,
+		Issuer:    "https://issuer.com",
+		Subject:   "subject",
+		UserID:    userID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, storedUserID, identity.UserID)
+
+	anonymousID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE urls SET user_id").
+		WithArgs(anonymousID, storedUserID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectExec("UPDATE urls_for_delete SET user_id").
+		WithArgs(anonymousID, storedUserID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectCommit()
+
+	err = repo.MergeUser(context.Background(), anonymousID, storedUserID)
+	assert.NoError(t, err)
+
+	err = repo.MergeUser(context.Background(), storedUserID, storedUserID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBRepositoryAdmin(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: zap.NewNop(),
+		pool:   mock,
+	}
+
+	userID := uuid.New()
+	anotherUserID := uuid.New()
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"short_url", "original_url", "user_id", "deleted", "disabled", "created_at"}
+	record := Record{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "http://example.com", UserID: userID}
+
+	mock.ExpectQuery("SELECT short_url, original_url, user_id, deleted, disabled, created_at FROM urls WHERE").
+		WithArgs(&userID, "example", 10, 0).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow("abc123", "http://example.com", userID, false, false, createdAt))
+
+	records, err := repo.SearchLinks(context.Background(), LinkFilter{Query: "example", UserID: userID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FOR UPDATE").
+		WithArgs([]string{"abc123"}).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow("abc123", "http://example.com", userID, false, false, createdAt))
+	mock.ExpectExec("UPDATE urls SET disabled").
+		WithArgs("abc123", true).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	previous, err := repo.SetLinkDisabled(context.Background(), "abc123", true)
+	assert.NoError(t, err)
+	assert.Equal(t, &record, previous)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FOR UPDATE").
+		WithArgs([]string{"nonexistent"}).
+		WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectRollback()
+
+	_, err = repo.SetLinkDisabled(context.Background(), "nonexistent", true)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mock.ExpectQuery("SELECT user_id, COUNT").
+		WithArgs(0, 0).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "count"}).AddRow(userID, 1))
+
+	users, err := repo.GetUsers(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []UserLinks{{UserID: userID, URLs: 1}}, users)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FOR UPDATE").
+		WithArgs([]string{"abc123"}).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow("abc123", "http://example.com", userID, false, false, createdAt))
+	mock.ExpectExec("UPDATE urls SET user_id").
+		WithArgs([]string{"abc123"}, anotherUserID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	records, err = repo.TransferLinks(context.Background(), []string{"abc123"}, anotherUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBRepositoryAudit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: zap.NewNop(),
+		pool:   mock,
+	}
+
+	userID := uuid.New()
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	record := Record{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "http://example.com", UserID: userID}
+	after, err := json.Marshal(record)
+	assert.NoError(t, err)
+	event := AuditEvent{
+		CreatedAt: createdAt,
+		After:     &record,
+		Action:    "create",
+		ShortURL:  "abc123",
+		UserID:    userID,
+		ActorID:   userID,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(createdAt, "create", "abc123", userID, userID, "", "", "", []byte(nil), after).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	err = repo.AppendAudit(context.Background(), []AuditEvent{event})
+	assert.NoError(t, err)
+
+	columns := []string{"id", "created_at", "action", "short_url", "user_id", "actor_id",
+		"auth_method", "client_ip", "request_id", "before", "after"}
+	mock.ExpectQuery("SELECT (.+) FROM audit_log WHERE").
+		WithArgs("abc123", &userID, (*time.Time)(nil), (*time.Time)(nil), 10).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(1), createdAt, "create", "abc123", userID, userID, "", "", "", []byte(nil), after))
+
+	var events []AuditEvent
+	err = repo.ExportAudit(context.Background(), AuditFilter{ShortURL: "abc123", UserID: userID, Limit: 10},
+		func(event AuditEvent) error {
+			events = append(events, event)
+			return nil
+		})
+	assert.NoError(t, err)
+	event.ID = 1
+	assert.Equal(t, []AuditEvent{event}, events)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestDBRepositoryDeleteURLs(t *testing.T) {
 	testLogger, err := logger.InitLogger()
 	assert.NoError(t, err)
@@ -182,20 +472,133 @@ func TestDBRepositoryDeleteURLsInDB(t *testing.T) {
 		pool:   mock,
 	}
 
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+
 	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT id, urls, user_id FROM urls_for_delete LIMIT 1").
+	mock.ExpectQuery("SELECT id, urls, user_id FROM urls_for_delete ORDER BY id LIMIT \\$1 FOR UPDATE SKIP LOCKED").
+		WithArgs(10).
 		WillReturnRows(pgxmock.NewRows([]string{"id", "urls", "user_id"}).
-			AddRow(1, []string{"abc123"}, uuid.New()))
-	mock.ExpectExec("UPDATE urls SET deleted = true").
-		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+			AddRow(1, []string{"abc123"}, userID1).
+			AddRow(2, []string{"def456"}, userID2).
+			AddRow(3, []string{"ghi789"}, userID1))
+	batch := mock.ExpectBatch()
+	batch.ExpectExec("UPDATE urls SET deleted = true").
+		WithArgs(userID1, []string{"abc123", "ghi789"}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	batch.ExpectExec("UPDATE urls SET deleted = true").
+		WithArgs(userID2, []string{"def456"}).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 	mock.ExpectExec("DELETE FROM urls_for_delete").
-		WithArgs(1).
+		WithArgs([]int{1, 2, 3}).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	mock.ExpectCommit()
+
+	count, err := repo.DeleteURLsInDB(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, urls, user_id FROM urls_for_delete").
+		WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "urls", "user_id"}))
+	mock.ExpectCommit()
+
+	count, err = repo.DeleteURLsInDB(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	repo.retry = RetryPolicy{MaxAttempts: 2}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, urls, user_id FROM urls_for_delete").
+		WithArgs(10).
+		WillReturnError(&pgconn.PgError{Code: pgerrcode.DeadlockDetected})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, urls, user_id FROM urls_for_delete").
+		WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "urls", "user_id"}).
+			AddRow(4, []string{"jkl012"}, userID1))
+	batch = mock.ExpectBatch()
+	batch.ExpectExec("UPDATE urls SET deleted = true").
+		WithArgs(userID1, []string{"jkl012"}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("DELETE FROM urls_for_delete").
+		WithArgs([]int{4}).
 		WillReturnResult(pgxmock.NewResult("DELETE", 1))
 	mock.ExpectCommit()
 
-	err = repo.DeleteURLsInDB(context.Background())
+	count, err = repo.DeleteURLsInDB(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBRepositoryExportImport(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	repo := &DBRepository{
+		logger: testLogger.Named("repository"),
+		pool:   mock,
+	}
+
+	userID := uuid.New()
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	records := []Record{
+		{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "http://example.com", UserID: userID},
+		{ShortURL: "def456", OriginalURL: "http://another.com", UserID: userID, Deleted: true},
+	}
+	columns := []string{"short_url", "original_url", "user_id", "deleted", "disabled", "created_at"}
+
+	mock.ExpectQuery("SELECT short_url, original_url, user_id, deleted, disabled, created_at FROM urls ORDER BY id").
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow("abc123", "http://example.com", userID, false, false, createdAt).
+			AddRow("def456", "http://another.com", userID, true, false, createdAt))
+
+	var exported []Record
+	err = repo.Export(context.Background(), func(record Record) error {
+		exported = append(exported, record)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, exported, 2)
+	assert.Equal(t, records[0], exported[0])
+
+	mock.ExpectQuery("SELECT short_url, original_url, user_id, deleted, disabled, created_at FROM urls WHERE short_url = ANY").
+		WithArgs([]string{"abc123", "def456"}, []string{"http://example.com", "http://another.com"}).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow("abc123", "http://example.com", userID, false, false, createdAt))
+
+	found, err := repo.Lookup(context.Background(), records)
+	assert.NoError(t, err)
+	assert.Equal(t, records[:1], found)
+
+	mock.ExpectBegin()
+	batch := mock.ExpectBatch()
+	for i := range records {
+		batch.ExpectExec("DELETE FROM urls WHERE short_url = \\$1 OR original_url = \\$2").
+			WithArgs(records[i].ShortURL, records[i].OriginalURL).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		batch.ExpectExec("INSERT INTO urls").
+			WithArgs(records[i].ShortURL, records[i].OriginalURL, userID, records[i].Deleted, records[i].Disabled,
+				pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mock.ExpectCommit()
+
+	err = repo.Import(context.Background(), records)
 	assert.NoError(t, err)
+
+	err = repo.Import(context.Background(), []Record{{ShortURL: "abc123", OriginalURL: "invalid"}})
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestDBRepositoryPing(t *testing.T) {
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record is a link with all its attributes which is used in export and import of data.
+// Zero creation time means that it is unknown.
+type Record struct {
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	UserID      uuid.UUID `json:"user_id"`
+	Deleted     bool      `json:"deleted"`
+	Disabled    bool      `json:"disabled,omitempty"`
+}
+
+// Dumper is an interface of repositories which support export and import of links.
+type Dumper interface {
+	// Export call fn for every link including deleted ones.
+	Export(ctx context.Context, fn func(Record) error) error
+	// Lookup return existing links which have short URL or original URL of one of records.
+	Lookup(ctx context.Context, records []Record) ([]Record, error)
+	// Import store records as is in provided order.
+	// Existing links with the same short URL or original URL are replaced.
+	Import(ctx context.Context, records []Record) error
+}
+
+// validateRecords check that records can be imported.
+func validateRecords(records []Record) error {
+	for i := range records {
+		if records[i].ShortURL == "" {
+			return ErrInvalidShortURL
+		}
+		_, err := url.ParseRequestURI(records[i].OriginalURL)
+		if err != nil {
+			return ErrInvalidURL
+		}
+	}
+	return nil
+}
+
+// sortRecords sort records in order of creation.
+func sortRecords(records []Record) {
+	slices.SortFunc(records, func(a, b Record) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ShortURL, b.ShortURL))
+	})
+}
+
+// lookupKeys return sets of short URLs and original URLs of records.
+func lookupKeys(records []Record) (map[string]struct{}, map[string]struct{}) {
+	shortURLs := make(map[string]struct{}, len(records))
+	originalURLs := make(map[string]struct{}, len(records))
+	for i := range records {
+		shortURLs[records[i].ShortURL] = struct{}{}
+		originalURLs[records[i].OriginalURL] = struct{}{}
+	}
+	return shortURLs, originalURLs
+}
@@ -2,9 +2,9 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/models"
 	"github.com/google/uuid"
@@ -12,6 +12,10 @@ import (
 
 // Links is a repository which stores data in memory.
 type Links struct {
+	*memoryAPIKeys
+	*memoryRefreshTokens
+	*memoryIdentities
+	*memoryAudit
 	m            *sync.Mutex
 	shortLinks   map[string]string
 	originalURLs map[string]ShortlURLInfo
@@ -19,17 +23,23 @@ type Links struct {
 
 // ShortlURLInfo is a model of URLs which stored in memory.
 type ShortlURLInfo struct {
+	createdAt   time.Time
 	originalURL string
 	userID      uuid.UUID
 	deleted     bool
+	disabled    bool
 }
 
 // NewLinks create new Links.
 func NewLinks() *Links {
 	return &Links{
-		m:            &sync.Mutex{},
-		shortLinks:   make(map[string]string),
-		originalURLs: make(map[string]ShortlURLInfo),
+		memoryAPIKeys:       newMemoryAPIKeys(),
+		memoryRefreshTokens: newMemoryRefreshTokens(),
+		memoryIdentities:    newMemoryIdentities(),
+		memoryAudit:         newMemoryAudit(),
+		m:                   &sync.Mutex{},
+		shortLinks:          make(map[string]string),
+		originalURLs:        make(map[string]ShortlURLInfo),
 	}
 }
 
@@ -39,11 +49,14 @@ func (l *Links) GetOriginalURL(_ context.Context, shortLink string) (*string, er
 	defer l.m.Unlock()
 	originalURL := l.originalURLs[shortLink]
 	if originalURL.originalURL == "" {
-		return nil, errors.New("no original url by provided short url")
+		return nil, ErrNotFound
 	}
 	if originalURL.deleted {
 		return nil, ErrURLIsDeleted
 	}
+	if originalURL.disabled {
+		return nil, ErrURLIsDisabled
+	}
 	return &originalURL.originalURL, nil
 }
 
@@ -54,6 +67,11 @@ func (l *Links) SetLink(
 	shortURLs []string,
 	userID uuid.UUID,
 ) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
 	l.m.Lock()
 	defer l.m.Unlock()
 	if shortLink, ok := l.shortLinks[originalURL]; ok {
@@ -66,6 +84,7 @@ func (l *Links) SetLink(
 		}
 		l.shortLinks[originalURL] = shortURL
 		l.originalURLs[shortURL] = ShortlURLInfo{
+			createdAt:   time.Now().UTC(),
 			originalURL: originalURL,
 			userID:      userID,
 			deleted:     false,
@@ -77,26 +96,58 @@ func (l *Links) SetLink(
 }
 
 // SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
 func (l *Links) SetLinks(
 	_ context.Context,
 	batch []models.ShortenBatchRequest,
 	shortURLs [][]string,
 	userID uuid.UUID,
-) ([]string, error) {
-	result := make([]string, 0, len(batch))
+) ([]LinkResult, error) {
 	l.m.Lock()
 	defer l.m.Unlock()
 
-	var originalURLUniqueViolation bool
+	result := l.planLinks(batch, shortURLs)
+
+	createdAt := time.Now().UTC()
+	for i := range batch {
+		if result[i].Status != models.BatchStatusCreated {
+			continue
+		}
+		l.shortLinks[batch[i].OriginalURL] = result[i].ShortURL
+		l.originalURLs[result[i].ShortURL] = ShortlURLInfo{
+			createdAt:   createdAt,
+			originalURL: batch[i].OriginalURL,
+			userID:      userID,
+			deleted:     false,
+		}
+	}
+
+	return result, nil
+}
+
+// planLinks choose short URL for every original URL of batch without changing data.
+// Return result of every link, links with status created have to be stored.
+func (l *Links) planLinks(
+	batch []models.ShortenBatchRequest,
+	shortURLs [][]string,
+) []LinkResult {
+	result := make([]LinkResult, 0, len(batch))
+	planned := make(map[string]string)
+	reserved := make(map[string]struct{})
 	for i := range batch {
 		_, err := url.ParseRequestURI(batch[i].OriginalURL)
 		if err != nil {
-			return nil, ErrInvalidURL
+			result = append(result, LinkResult{Status: models.BatchStatusInvalid})
+			continue
 		}
 
-		if shortLink, ok := l.shortLinks[batch[i].OriginalURL]; ok {
-			originalURLUniqueViolation = true
-			result = append(result, shortLink)
+		shortLink, ok := l.shortLinks[batch[i].OriginalURL]
+		if !ok {
+			shortLink, ok = planned[batch[i].OriginalURL]
+		}
+		if ok {
+			result = append(result, LinkResult{ShortURL: shortLink, Status: models.BatchStatusExisting})
 			continue
 		}
 
@@ -106,28 +157,23 @@ func (l *Links) SetLinks(
 			if _, ok := l.originalURLs[shortURL]; ok {
 				continue
 			}
-			l.shortLinks[batch[i].OriginalURL] = shortURL
-			l.originalURLs[shortURL] = ShortlURLInfo{
-				originalURL: batch[i].OriginalURL,
-				userID:      userID,
-				deleted:     false,
+			if _, ok := reserved[shortURL]; ok {
+				continue
 			}
-
 			generated = true
 			break
 		}
 
 		if !generated {
-			return nil, ErrReachedMaxGenerationRetries
+			result = append(result, LinkResult{Status: models.BatchStatusError})
+			continue
 		}
-		result = append(result, shortURL)
+		planned[batch[i].OriginalURL] = shortURL
+		reserved[shortURL] = struct{}{}
+		result = append(result, LinkResult{ShortURL: shortURL, Status: models.BatchStatusCreated})
 	}
 
-	if originalURLUniqueViolation {
-		return result, ErrOriginalURLUniqueViolation
-	}
-
-	return result, nil
+	return result
 }
 
 // GetShortLinksOfUser return URLs of user if such exist.
@@ -170,8 +216,81 @@ func (l *Links) Ping(_ context.Context) error {
 	return nil
 }
 
+// MergeUser move links of anonymous user into another user.
+// Links of user with identity are not moved.
+func (l *Links) MergeUser(_ context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to || l.memoryIdentities.has(from) {
+		return nil
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+	for shortURL, info := range l.originalURLs {
+		if info.userID == from {
+			info.userID = to
+			l.originalURLs[shortURL] = info
+		}
+	}
+
+	return nil
+}
+
+// SearchLinks return links which match filter in order of creation.
+func (l *Links) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	records, err := exportRecords(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return searchRecords(records, filter), nil
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+func (l *Links) SetLinkDisabled(_ context.Context, shortURL string, disabled bool) (*Record, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	info, ok := l.originalURLs[shortURL]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := info.record(shortURL)
+	info.disabled = disabled
+	l.originalURLs[shortURL] = info
+	return &record, nil
+}
+
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (l *Links) GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error) {
+	records, err := exportRecords(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return usersOfRecords(records, limit, offset), nil
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+func (l *Links) TransferLinks(_ context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	var records []Record
+	for _, shortURL := range shortURLs {
+		info, ok := l.originalURLs[shortURL]
+		if !ok {
+			continue
+		}
+		records = append(records, info.record(shortURL))
+		info.userID = to
+		l.originalURLs[shortURL] = info
+	}
+	return records, nil
+}
+
 // Stats return statistic of shortened urls and users in service.
 func (l *Links) Stats(_ context.Context) (*models.Stats, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
 	var urls int
 	usersMap := make(map[uuid.UUID]struct{})
 
@@ -188,3 +307,92 @@ func (l *Links) Stats(_ context.Context) (*models.Stats, error) {
 		Users: len(usersMap),
 	}, nil
 }
+
+// Export call fn for every link in order of creation.
+func (l *Links) Export(ctx context.Context, fn func(Record) error) error {
+	l.m.Lock()
+	records := make([]Record, 0, len(l.originalURLs))
+	for shortURL, info := range l.originalURLs {
+		records = append(records, info.record(shortURL))
+	}
+	l.m.Unlock()
+
+	sortRecords(records)
+	for i := range records {
+		if ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck // error of context
+		}
+		err := fn(records[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup return existing links which have short URL or original URL of one of records.
+func (l *Links) Lookup(_ context.Context, records []Record) ([]Record, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	shortURLs, originalURLs := lookupKeys(records)
+	for originalURL := range originalURLs {
+		if shortURL, ok := l.shortLinks[originalURL]; ok {
+			shortURLs[shortURL] = struct{}{}
+		}
+	}
+
+	var result []Record
+	for shortURL := range shortURLs {
+		if info, ok := l.originalURLs[shortURL]; ok {
+			result = append(result, info.record(shortURL))
+		}
+	}
+	return result, nil
+}
+
+// Import store records as is replacing links with the same short URL or original URL.
+func (l *Links) Import(_ context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+	for i := range records {
+		l.replace(records[i])
+	}
+	return nil
+}
+
+// replace store record instead of links with the same short URL or original URL.
+func (l *Links) replace(record Record) {
+	if shortURL, ok := l.shortLinks[record.OriginalURL]; ok {
+		delete(l.originalURLs, shortURL)
+	}
+	if info, ok := l.originalURLs[record.ShortURL]; ok {
+		delete(l.shortLinks, info.originalURL)
+	}
+
+	l.shortLinks[record.OriginalURL] = record.ShortURL
+	l.originalURLs[record.ShortURL] = ShortlURLInfo{
+		createdAt:   record.CreatedAt,
+		originalURL: record.OriginalURL,
+		userID:      record.UserID,
+		deleted:     record.Deleted,
+		disabled:    record.Disabled,
+	}
+}
+
+// record return link as record of export.
+func (i ShortlURLInfo) record(shortURL string) Record {
+	return Record{
+		CreatedAt:   i.createdAt,
+		ShortURL:    shortURL,
+		OriginalURL: i.originalURL,
+		UserID:      i.userID,
+		Deleted:     i.deleted,
+		Disabled:    i.disabled,
+	}
+}
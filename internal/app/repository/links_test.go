@@ -102,18 +102,24 @@ func TestLinksSetLinks(t *testing.T) {
 
 	result, err := links.SetLinks(context.Background(), batch, shortURLs, userID)
 	assert.NoError(t, err)
-	assert.Equal(t, []string{"abc123", "def456"}, result)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusCreated},
+		{ShortURL: "def456", Status: models.BatchStatusCreated},
+	}, result)
 
-	_, err = links.SetLinks(context.Background(), batch, shortURLs, userID)
-	assert.Error(t, err)
-	assert.Equal(t, ErrOriginalURLUniqueViolation, err)
+	result, err = links.SetLinks(context.Background(), batch, shortURLs, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		{ShortURL: "def456", Status: models.BatchStatusExisting},
+	}, result)
 
 	invalidBatch := []models.ShortenBatchRequest{
 		{OriginalURL: "invalid-url"},
 	}
-	_, err = links.SetLinks(context.Background(), invalidBatch, [][]string{{"ghi789"}}, userID)
-	assert.Error(t, err)
-	assert.Equal(t, ErrInvalidURL, err)
+	result, err = links.SetLinks(context.Background(), invalidBatch, [][]string{{"ghi789"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{{Status: models.BatchStatusInvalid}}, result)
 }
 
 func TestLinksGetShortLinksOfUser(t *testing.T) {
@@ -6,34 +6,158 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/models"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // Mode to operate with file with URLs data.
 const fileMode = 0o600
 
+// Policies of file synchronization with disk.
+const (
+	// FsyncAlways sync file after every write.
+	FsyncAlways = "always"
+	// FsyncInterval sync file once per fsync interval.
+	FsyncInterval = "interval"
+	// FsyncNever leave file synchronization to operating system.
+	FsyncNever = "never"
+)
+
+// Suffix of file with records which are rejected on load in recovery mode.
+const quarantineSuffix = ".quarantine"
+
+// Suffixes of files with API keys, refresh tokens and identities.
+const (
+	apiKeysSuffix       = ".keys"
+	refreshTokensSuffix = ".refresh"
+	identitiesSuffix    = ".users"
+)
+
+// Table of records checksum calculation.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Version of format of records with checksum.
+const recordVersion = 1
+
+// Default values of FileConfig.
+const (
+	DefaultFsyncPolicy     = FsyncInterval
+	DefaultFsyncInterval   = time.Second
+	DefaultCompactInterval = time.Minute
+)
+
+// Operations of records in file.
+const (
+	opCreate = "create"
+	opDelete = "delete"
+	opUpdate = "update"
+	opImport = "import"
+)
+
+// FileConfig is a set of parameters of file storage.
+// Zero values are replaced with default ones.
+// In recovery mode corrupt records are skipped and moved into quarantine file
+// and the latest record is kept on duplicates instead of failing the load.
+// Audit log is written into AuditPath and it is kept only in memory if AuditPath is empty.
+type FileConfig struct {
+	AuditPath       string
+	FsyncPolicy     string
+	FsyncInterval   time.Duration
+	CompactInterval time.Duration
+	Recovery        bool
+}
+
+// LoadReport is a summary of file loading.
+type LoadReport struct {
+	QuarantinePath string `json:"quarantine_path,omitempty"`
+	Records        int    `json:"records"`
+	Skipped        int    `json:"skipped"`
+	Replaced       int    `json:"replaced"`
+	Truncated      bool   `json:"truncated"`
+}
+
 // URL is a model of URLs which stored in file.
+// File is an append-only log of operations with URLs.
+// Records without operation are treated as creation.
+// Records with version or checksum are always verified. Records without both of them are
+// accepted for compatibility with old files only before the first verified record.
+// Imported records replace links with the same short URL or original URL.
 type URL struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	UserID      string `json:"user_id"`
-	ID          int    `json:"id"`
-	Deleted     bool   `json:"deleted"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	Op          string    `json:"op,omitempty"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	UserID      string    `json:"user_id"`
+	ID          int       `json:"id"`
+	Version     int       `json:"version,omitempty"`
+	Checksum    uint32    `json:"checksum,omitempty"`
+	Deleted     bool      `json:"deleted"`
+	Disabled    bool      `json:"disabled,omitempty"`
+}
+
+// logFile is a log file of LinksWithFile.
+type logFile interface {
+	io.ReadWriteCloser
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+	Sync() error
 }
 
-// LinksWithFile is a repository which stores data in file.
+// LinksWithFile is a repository which stores data in memory and
+// writes every change into append-only log file.
+// Log is periodically compacted into snapshot of current data.
+// API keys, refresh tokens and identities are written into separate append-only files where
+// later record of key or token replaces earlier one. Audit log is written into its own append-only file.
 type LinksWithFile struct {
 	*Links
-	file      *os.File
-	currentID int
+	logger     *zap.Logger
+	file       logFile
+	failed     error
+	path       string
+	keysFile   *os.File
+	keysPath   string
+	tokensFile *os.File
+	tokensPath string
+	usersFile  *os.File
+	usersPath  string
+	auditFile  *os.File
+	auditPath  string
+	ids        map[string]int
+	stop       chan struct{}
+	stopped    chan struct{}
+	cfg        FileConfig
+	report     LoadReport
+	currentID  int
+	records    int
+	unsynced   bool
+	verified   bool
 }
 
 // NewLinksWithFile create new LinksWithFile.
-func NewLinksWithFile(fileStoragePath string) (*LinksWithFile, error) {
+func NewLinksWithFile(logger *zap.Logger, fileStoragePath string, cfg FileConfig) (*LinksWithFile, error) {
+	switch cfg.FsyncPolicy {
+	case "":
+		cfg.FsyncPolicy = DefaultFsyncPolicy
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unknown fsync policy: %s", cfg.FsyncPolicy)
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = DefaultFsyncInterval
+	}
+	if cfg.CompactInterval <= 0 {
+		cfg.CompactInterval = DefaultCompactInterval
+	}
+
 	file, err := os.OpenFile(fileStoragePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, fileMode)
 	if err != nil {
 		return nil, fmt.Errorf("can not open file: %w", err)
@@ -41,38 +165,404 @@ func NewLinksWithFile(fileStoragePath string) (*LinksWithFile, error) {
 
 	linksWithFile := &LinksWithFile{
 		Links:     NewLinks(),
+		logger:    logger,
 		file:      file,
+		path:      fileStoragePath,
+		ids:       make(map[string]int),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		cfg:       cfg,
 		currentID: 0,
 	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var data URL
-		err = json.Unmarshal(scanner.Bytes(), &data)
+	err = linksWithFile.load()
+	if err == nil {
+		err = linksWithFile.loadAPIKeys(fileStoragePath + apiKeysSuffix)
+	}
+	if err == nil {
+		err = linksWithFile.loadRefreshTokens(fileStoragePath + refreshTokensSuffix)
+	}
+	if err == nil {
+		err = linksWithFile.loadIdentities(fileStoragePath + identitiesSuffix)
+	}
+	if err == nil {
+		err = linksWithFile.loadAudit(cfg.AuditPath)
+	}
+	if err != nil {
+		closeErr := linksWithFile.file.Close()
+		if closeErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("can not close file: %w", closeErr))
+		}
+		return nil, err
+	}
+
+	go linksWithFile.run()
+
+	return linksWithFile, nil
+}
+
+// load replay log from file.
+// Incomplete last record, which is a result of interrupted write, is cut off.
+// In recovery mode corrupt records are moved into quarantine file and log is compacted.
+func (l *LinksWithFile) load() error {
+	reader := bufio.NewReader(l.file)
+	var offset int64
+	var rejected [][]byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return fmt.Errorf("can not read file: %w", err)
+			}
+			if len(line) != 0 {
+				err = l.file.Truncate(offset)
+				if err != nil {
+					return fmt.Errorf("can not truncate incomplete record: %w", err)
+				}
+				l.report.Truncated = true
+			}
+			break
+		}
+
+		err = l.loadRecord(line)
+		if err != nil {
+			if !l.cfg.Recovery {
+				return err
+			}
+			l.logger.Warn("Skip corrupt record of file", zap.Int64("offset", offset), zap.Error(err))
+			rejected = append(rejected, line)
+		}
+
+		offset += int64(len(line))
+		l.records++
+	}
+
+	l.report.Skipped = len(rejected)
+	l.report.Records = l.records - l.report.Skipped
+
+	if len(rejected) != 0 {
+		err := l.quarantine(rejected)
+		if err != nil {
+			return err
+		}
+	}
+	if l.report.Skipped != 0 || l.report.Replaced != 0 {
+		err := l.Compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := []zap.Field{
+		zap.Int("records", l.report.Records),
+		zap.Int("skipped", l.report.Skipped),
+		zap.Int("replaced", l.report.Replaced),
+		zap.Bool("truncated", l.report.Truncated),
+	}
+	if l.report.Skipped != 0 || l.report.Truncated {
+		l.logger.Warn("File storage loaded with recovery", fields...)
+	} else {
+		l.logger.Info("File storage loaded", fields...)
+	}
+
+	return nil
+}
+
+// loadRecord decode record of log, verify its checksum and apply it.
+// Legacy record without version and checksum is accepted only before the first record with them,
+// so record which lost its checksum in file which is already written with checksums is rejected.
+func (l *LinksWithFile) loadRecord(line []byte) error {
+	var data URL
+	err := json.Unmarshal(line, &data)
+	if err != nil {
+		return fmt.Errorf("can not unmarshal data from file: %w", err)
+	}
+
+	switch {
+	case data.Version > recordVersion:
+		return fmt.Errorf("unsupported version %d of record in file", data.Version)
+	case data.Version == 0 && data.Checksum == 0 && l.verified:
+		return errors.New("record without checksum after records with checksum in file")
+	case data.Version == 0 && data.Checksum == 0:
+		return l.apply(data)
+	}
+
+	l.verified = true
+	sum, err := checksum(data)
+	if err != nil {
+		return err
+	}
+	if sum != data.Checksum {
+		return errors.New("checksum mismatch of record in file")
+	}
+
+	return l.apply(data)
+}
+
+// loadAPIKeys load API keys from file if it exists.
+func (l *LinksWithFile) loadAPIKeys(path string) error {
+	l.keysPath = path
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("can not open api keys file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	decoder := json.NewDecoder(file)
+	for {
+		var key APIKey
+		err = decoder.Decode(&key)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can not decode api key from file: %w", err)
+		}
+		l.memoryAPIKeys.set(key)
+	}
+}
+
+// loadRefreshTokens load refresh tokens from file if it exists.
+// Expired tokens are skipped.
+func (l *LinksWithFile) loadRefreshTokens(path string) error {
+	l.tokensPath = path
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("can not open refresh tokens file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	now := time.Now()
+	decoder := json.NewDecoder(file)
+	for {
+		var token RefreshToken
+		err = decoder.Decode(&token)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
-			return nil, fmt.Errorf("can not unmarshal data from file: %w", err)
+			return fmt.Errorf("can not decode refresh token from file: %w", err)
 		}
+		if !token.ExpiresAt.After(now) {
+			delete(l.memoryRefreshTokens.tokens, token.Hash)
+			continue
+		}
+		l.memoryRefreshTokens.set(token)
+	}
+}
 
-		if _, ok := linksWithFile.Links.shortLinks[data.OriginalURL]; ok {
-			return nil, errors.New("duplicate original url in file")
+// loadIdentities load identities from file if it exists.
+func (l *LinksWithFile) loadIdentities(path string) error {
+	l.usersPath = path
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
 		}
-		if _, ok := linksWithFile.Links.originalURLs[data.ShortURL]; ok {
-			return nil, errors.New("duplicate short url in file")
+		return fmt.Errorf("can not open identities file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	decoder := json.NewDecoder(file)
+	for {
+		var identity Identity
+		err = decoder.Decode(&identity)
+		if errors.Is(err, io.EOF) {
+			return nil
 		}
-		userID, err := uuid.Parse(data.UserID)
 		if err != nil {
-			userID = uuid.UUID{}
+			return fmt.Errorf("can not decode identity from file: %w", err)
 		}
-		linksWithFile.Links.shortLinks[data.OriginalURL] = data.ShortURL
-		linksWithFile.Links.originalURLs[data.ShortURL] = ShortlURLInfo{
-			originalURL: data.OriginalURL,
-			userID:      userID,
-			deleted:     data.Deleted,
+		l.memoryIdentities.set(identity)
+	}
+}
+
+// loadAudit load audit log from file if it exists.
+func (l *LinksWithFile) loadAudit(path string) error {
+	l.auditPath = path
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
 		}
-		linksWithFile.currentID++
+		return fmt.Errorf("can not open audit file: %w", err)
 	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	return linksWithFile, nil
+	decoder := json.NewDecoder(file)
+	for {
+		var event AuditEvent
+		err = decoder.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can not decode audit event from file: %w", err)
+		}
+		l.memoryAudit.append([]AuditEvent{event})
+	}
+}
+
+// quarantine append rejected records into quarantine file.
+func (l *LinksWithFile) quarantine(lines [][]byte) error {
+	path := l.path + quarantineSuffix
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("can not open quarantine file: %w", err)
+	}
+
+	for _, line := range lines {
+		_, err = file.Write(line)
+		if err != nil {
+			return errors.Join(fmt.Errorf("can not write data to quarantine file: %w", err), file.Close())
+		}
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return errors.Join(fmt.Errorf("can not sync quarantine file: %w", err), file.Close())
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("can not close quarantine file: %w", err)
+	}
+
+	l.report.QuarantinePath = path
+
+	return nil
+}
+
+// apply change data in memory according to record of log.
+func (l *LinksWithFile) apply(data URL) error {
+	userID, err := uuid.Parse(data.UserID)
+	if err != nil {
+		userID = uuid.UUID{}
+	}
+
+	switch data.Op {
+	case "", opCreate:
+		_, originalURLExists := l.Links.shortLinks[data.OriginalURL]
+		_, shortURLExists := l.Links.originalURLs[data.ShortURL]
+		if !l.cfg.Recovery {
+			if originalURLExists {
+				return errors.New("duplicate original url in file")
+			}
+			if shortURLExists {
+				return errors.New("duplicate short url in file")
+			}
+		}
+		if originalURLExists || shortURLExists {
+			l.remove(data.OriginalURL, data.ShortURL)
+			l.report.Replaced++
+		}
+	case opDelete:
+		info, ok := l.Links.originalURLs[data.ShortURL]
+		if ok {
+			info.deleted = true
+			l.Links.originalURLs[data.ShortURL] = info
+		}
+		return nil
+	case opUpdate:
+		info, ok := l.Links.originalURLs[data.ShortURL]
+		if ok {
+			delete(l.Links.shortLinks, info.originalURL)
+		}
+	case opImport:
+		l.remove(data.OriginalURL, data.ShortURL)
+	default:
+		return fmt.Errorf("unknown operation in file: %s", data.Op)
+	}
+
+	id := data.ID
+	if existingID, ok := l.ids[data.ShortURL]; ok {
+		id = existingID
+	}
+	if id <= 0 {
+		id = l.currentID + 1
+	}
+	l.currentID = max(l.currentID, id)
+
+	l.Links.shortLinks[data.OriginalURL] = data.ShortURL
+	l.Links.originalURLs[data.ShortURL] = ShortlURLInfo{
+		createdAt:   data.CreatedAt,
+		originalURL: data.OriginalURL,
+		userID:      userID,
+		deleted:     data.Deleted,
+		disabled:    data.Disabled,
+	}
+	l.ids[data.ShortURL] = id
+
+	return nil
+}
+
+// remove delete links which conflict with original or short URL.
+func (l *LinksWithFile) remove(originalURL string, shortURL string) {
+	if oldShortURL, ok := l.Links.shortLinks[originalURL]; ok {
+		delete(l.Links.originalURLs, oldShortURL)
+		delete(l.ids, oldShortURL)
+		delete(l.Links.shortLinks, originalURL)
+	}
+	if info, ok := l.Links.originalURLs[shortURL]; ok {
+		delete(l.Links.shortLinks, info.originalURL)
+		delete(l.Links.originalURLs, shortURL)
+		delete(l.ids, shortURL)
+	}
+}
+
+// Status return summary of file loading.
+func (l *LinksWithFile) Status() map[string]any {
+	return map[string]any{"file": l.report}
+}
+
+// run sync file and compact log periodically until file is closed.
+func (l *LinksWithFile) run() {
+	defer close(l.stopped)
+
+	var fsync <-chan time.Time
+	if l.cfg.FsyncPolicy == FsyncInterval {
+		fsyncTicker := time.NewTicker(l.cfg.FsyncInterval)
+		defer fsyncTicker.Stop()
+		fsync = fsyncTicker.C
+	}
+
+	compactTicker := time.NewTicker(l.cfg.CompactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-fsync:
+			l.m.Lock()
+			err := l.sync()
+			l.m.Unlock()
+			if err != nil {
+				l.logger.Error("Can not sync file", zap.Error(err))
+			}
+		case <-compactTicker.C:
+			err := l.Compact()
+			if err != nil {
+				l.logger.Error("Can not compact file", zap.Error(err))
+			}
+		}
+	}
 }
 
 // SetLink add short URL if such does not exist already.
@@ -82,6 +572,11 @@ func (l *LinksWithFile) SetLink(
 	shortURLs []string,
 	userID uuid.UUID,
 ) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
 	l.m.Lock()
 	defer l.m.Unlock()
 	if shortLink, ok := l.shortLinks[originalURL]; ok {
@@ -92,27 +587,10 @@ func (l *LinksWithFile) SetLink(
 		if _, ok := l.originalURLs[shortURL]; ok {
 			continue
 		}
-		l.shortLinks[originalURL] = shortURL
-		l.originalURLs[shortURL] = ShortlURLInfo{
-			originalURL: originalURL,
-			userID:      userID,
-			deleted:     false,
-		}
-		l.currentID++
 
-		data, err := json.Marshal(URL{
-			ID:          l.currentID,
-			ShortURL:    shortURL,
-			OriginalURL: originalURL,
-			UserID:      userID.String(),
-			Deleted:     false,
-		})
+		err = l.create(originalURL, shortURL, userID)
 		if err != nil {
-			return nil, fmt.Errorf("can not marshal data: %w", err)
-		}
-		_, err = fmt.Fprintf(l.file, "%s\n", data)
-		if err != nil {
-			return nil, fmt.Errorf("can not write data to file: %w", err)
+			return nil, err
 		}
 
 		return &shortURL, nil
@@ -121,131 +599,682 @@ func (l *LinksWithFile) SetLink(
 }
 
 // SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
+// Links which are written before failure of file remain stored.
 func (l *LinksWithFile) SetLinks(
 	_ context.Context,
 	batch []models.ShortenBatchRequest,
 	shortURLs [][]string,
 	userID uuid.UUID,
-) ([]string, error) {
-	result := make([]string, 0, len(batch))
+) ([]LinkResult, error) {
 	l.m.Lock()
 	defer l.m.Unlock()
 
-	var originalURLUniqueViolation bool
+	result := l.planLinks(batch, shortURLs)
+
 	for i := range batch {
-		_, err := url.ParseRequestURI(batch[i].OriginalURL)
+		if result[i].Status != models.BatchStatusCreated {
+			continue
+		}
+		err := l.create(batch[i].OriginalURL, result[i].ShortURL, userID)
 		if err != nil {
-			return nil, ErrInvalidURL
+			return nil, err
 		}
+	}
 
-		if shortLink, ok := l.shortLinks[batch[i].OriginalURL]; ok {
-			originalURLUniqueViolation = true
-			result = append(result, shortLink)
-			continue
-		}
+	return result, nil
+}
 
-		var generated bool
-		var shortURL string
-		for _, shortURL = range shortURLs[i] {
-			if _, ok := l.originalURLs[shortURL]; ok {
-				continue
-			}
-			l.shortLinks[batch[i].OriginalURL] = shortURL
-			l.originalURLs[shortURL] = ShortlURLInfo{
-				originalURL: batch[i].OriginalURL,
-				userID:      userID,
-				deleted:     false,
-			}
-			l.currentID++
-
-			data, err := json.Marshal(URL{
-				ID:          l.currentID,
-				ShortURL:    shortURL,
-				OriginalURL: batch[i].OriginalURL,
-				UserID:      userID.String(),
-				Deleted:     false,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("can not marshal data: %w", err)
-			}
-			_, err = fmt.Fprintf(l.file, "%s\n", data)
-			if err != nil {
-				return nil, fmt.Errorf("can not write data to file: %w", err)
-			}
+// create add short URL in memory and write creation record into log.
+func (l *LinksWithFile) create(originalURL string, shortURL string, userID uuid.UUID) error {
+	createdAt := time.Now().UTC()
+	l.currentID++
+	err := l.write(URL{
+		CreatedAt:   createdAt,
+		Op:          opCreate,
+		ID:          l.currentID,
+		ShortURL:    shortURL,
+		OriginalURL: originalURL,
+		UserID:      userID.String(),
+		Deleted:     false,
+	})
+	if err != nil {
+		l.currentID--
+		return err
+	}
 
-			generated = true
-			break
-		}
+	l.shortLinks[originalURL] = shortURL
+	l.originalURLs[shortURL] = ShortlURLInfo{
+		createdAt:   createdAt,
+		originalURL: originalURL,
+		userID:      userID,
+		deleted:     false,
+	}
+	l.ids[shortURL] = l.currentID
 
-		if !generated {
-			return nil, ErrReachedMaxGenerationRetries
-		}
-		result = append(result, shortURL)
+	return nil
+}
+
+// Import store records as is and write import records into log.
+// Links with the same short URL or original URL are replaced.
+func (l *LinksWithFile) Import(_ context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
 	}
 
-	if originalURLUniqueViolation {
-		return result, ErrOriginalURLUniqueViolation
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	for i := range records {
+		id, ok := l.ids[records[i].ShortURL]
+		if !ok {
+			id = l.currentID + 1
+		}
+		data := URL{
+			CreatedAt:   records[i].CreatedAt,
+			Op:          opImport,
+			ID:          id,
+			ShortURL:    records[i].ShortURL,
+			OriginalURL: records[i].OriginalURL,
+			UserID:      records[i].UserID.String(),
+			Deleted:     records[i].Deleted,
+			Disabled:    records[i].Disabled,
+		}
+		err = l.write(data)
+		if err != nil {
+			return err
+		}
+		err = l.apply(data)
+		if err != nil {
+			return err
+		}
 	}
 
-	return result, nil
+	return nil
 }
 
-// DeleteURLs delete URLs.
+// DeleteURLs delete URLs and write deletion records into log.
 func (l *LinksWithFile) DeleteURLs(_ context.Context, urls []string, userID uuid.UUID) error {
 	l.m.Lock()
 	defer l.m.Unlock()
 
 	for _, shortURL := range urls {
-		if shortlURLInfo, ok := l.originalURLs[shortURL]; ok {
-			if shortlURLInfo.userID == userID {
-				shortlURLInfo.deleted = true
-				l.originalURLs[shortURL] = shortlURLInfo
-			}
+		shortlURLInfo, ok := l.originalURLs[shortURL]
+		if !ok || shortlURLInfo.userID != userID || shortlURLInfo.deleted {
+			continue
 		}
+
+		err := l.write(URL{
+			Op:          opDelete,
+			ID:          l.ids[shortURL],
+			ShortURL:    shortURL,
+			OriginalURL: shortlURLInfo.originalURL,
+			UserID:      userID.String(),
+			Deleted:     true,
+		})
+		if err != nil {
+			return err
+		}
+
+		shortlURLInfo.deleted = true
+		l.originalURLs[shortURL] = shortlURLInfo
 	}
 
-	err := l.file.Close()
+	return nil
+}
+
+// write append record into log and sync it according to fsync policy.
+// Partially written record is cut off, storage stops accepting writes if it can not be cut off.
+func (l *LinksWithFile) write(data URL) error {
+	line, err := encode(data)
 	if err != nil {
-		return fmt.Errorf("can not close file: %w", err)
+		return err
+	}
+	if l.failed != nil {
+		return fmt.Errorf("can not write data to failed file: %w", l.failed)
 	}
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("can not get size of file: %w", err)
+	}
+	_, err = l.file.Write(line)
+	if err != nil {
+		truncateErr := l.file.Truncate(info.Size())
+		if truncateErr != nil {
+			l.failed = truncateErr
+			l.logger.Error("Can not truncate partially written record, file storage is failed", zap.Error(truncateErr))
+		}
+		return errors.Join(fmt.Errorf("can not write data to file: %w", err), truncateErr)
+	}
+	l.records++
+	l.unsynced = true
+
+	if l.cfg.FsyncPolicy == FsyncAlways {
+		return l.sync()
+	}
+
+	return nil
+}
+
+// encode marshal record with its version and checksum into line of log.
+func encode(data URL) ([]byte, error) {
+	data.Version = recordVersion
+	sum, err := checksum(data)
+	if err != nil {
+		return nil, err
+	}
+	data.Checksum = sum
+
+	line, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal data: %w", err)
+	}
+	return append(line, '\n'), nil
+}
 
-	err = os.Truncate(l.file.Name(), 0)
+// checksum calculate checksum of record without its own checksum.
+func checksum(data URL) (uint32, error) {
+	data.Checksum = 0
+	line, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("can not truncate file: %w", err)
+		return 0, fmt.Errorf("can not marshal data: %w", err)
+	}
+	return crc32.Checksum(line, checksumTable), nil
+}
+
+// sync commit written records to disk.
+func (l *LinksWithFile) sync() error {
+	if !l.unsynced {
+		return nil
+	}
+	err := l.file.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync file: %w", err)
+	}
+	l.unsynced = false
+	return nil
+}
+
+// Compact replace log with snapshot of current data if log contains outdated records.
+// Snapshot is written into temporary file which atomically replaces log.
+// Snapshot is opened before replacement, so log is never left open at removed file.
+func (l *LinksWithFile) Compact() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.records <= len(l.originalURLs) {
+		return nil
+	}
+
+	shortURLs := make([]string, 0, len(l.originalURLs))
+	for shortURL := range l.originalURLs {
+		shortURLs = append(shortURLs, shortURL)
 	}
+	sort.Slice(shortURLs, func(i, j int) bool {
+		return l.ids[shortURLs[i]] < l.ids[shortURLs[j]]
+	})
 
-	l.file, err = os.OpenFile(l.file.Name(), os.O_RDWR|os.O_CREATE|os.O_APPEND, fileMode)
+	path := l.path
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".compact-*")
 	if err != nil {
-		return fmt.Errorf("can not open file: %w", err)
+		return fmt.Errorf("can not create temporary file: %w", err)
 	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
 
-	var i int
-	for shortURL, url := range l.originalURLs {
-		data, err := json.Marshal(URL{
-			ID:          i,
+	writer := bufio.NewWriter(tmp)
+	for _, shortURL := range shortURLs {
+		info := l.originalURLs[shortURL]
+		line, err := encode(URL{
+			CreatedAt:   info.createdAt,
+			Op:          opCreate,
+			ID:          l.ids[shortURL],
 			ShortURL:    shortURL,
-			OriginalURL: url.originalURL,
-			UserID:      url.userID.String(),
-			Deleted:     url.deleted,
+			OriginalURL: info.originalURL,
+			UserID:      info.userID.String(),
+			Deleted:     info.deleted,
+			Disabled:    info.disabled,
 		})
 		if err != nil {
-			return fmt.Errorf("can not marshal data: %w", err)
+			return err
 		}
-		_, err = fmt.Fprintf(l.file, "%s\n", data)
+		_, err = writer.Write(line)
 		if err != nil {
-			return fmt.Errorf("can not write data to file: %w", err)
+			return fmt.Errorf("can not write data to temporary file: %w", err)
 		}
-		i++
 	}
 
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("can not flush temporary file: %w", err)
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync temporary file: %w", err)
+	}
+	err = tmp.Chmod(fileMode)
+	if err != nil {
+		return fmt.Errorf("can not change mode of temporary file: %w", err)
+	}
+	file, err := os.OpenFile(tmp.Name(), os.O_RDWR|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("can not open temporary file: %w", err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return errors.Join(fmt.Errorf("can not replace file: %w", err), file.Close())
+	}
+
+	old := l.file
+	l.file = file
+	l.records = len(shortURLs)
+	l.unsynced = false
+
+	err = old.Close()
+	if err != nil {
+		return fmt.Errorf("can not close file: %w", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir commit directory entries to disk.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("can not open directory: %w", err)
+	}
+	err = dir.Sync()
+	if err != nil {
+		return errors.Join(fmt.Errorf("can not sync directory: %w", err), dir.Close())
+	}
+	err = dir.Close()
+	if err != nil {
+		return fmt.Errorf("can not close directory: %w", err)
+	}
 	return nil
 }
 
-// Close close the file.
+// Close flush and close the file.
 func (l *LinksWithFile) Close() error {
-	err := l.file.Close()
+	select {
+	case <-l.stop:
+	default:
+		close(l.stop)
+	}
+	<-l.stopped
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	err := l.file.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync file: %w", err)
+	}
+
+	err = l.file.Close()
 	if err != nil {
 		return fmt.Errorf("can not close file: %w", err)
 	}
+
+	l.memoryAPIKeys.m.Lock()
+	defer l.memoryAPIKeys.m.Unlock()
+	if l.keysFile != nil {
+		err = l.keysFile.Close()
+		if err != nil {
+			return fmt.Errorf("can not close api keys file: %w", err)
+		}
+		l.keysFile = nil
+	}
+
+	l.memoryRefreshTokens.m.Lock()
+	defer l.memoryRefreshTokens.m.Unlock()
+	if l.tokensFile != nil {
+		err = l.tokensFile.Close()
+		if err != nil {
+			return fmt.Errorf("can not close refresh tokens file: %w", err)
+		}
+		l.tokensFile = nil
+	}
+
+	l.memoryIdentities.m.Lock()
+	defer l.memoryIdentities.m.Unlock()
+	if l.usersFile != nil {
+		err = l.usersFile.Close()
+		if err != nil {
+			return fmt.Errorf("can not close identities file: %w", err)
+		}
+		l.usersFile = nil
+	}
+
+	l.memoryAudit.m.Lock()
+	defer l.memoryAudit.m.Unlock()
+	if l.auditFile != nil {
+		err = l.auditFile.Close()
+		if err != nil {
+			return fmt.Errorf("can not close audit file: %w", err)
+		}
+		l.auditFile = nil
+	}
+	return nil
+}
+
+// SetAPIKey store new API key and write it into file.
+func (l *LinksWithFile) SetAPIKey(_ context.Context, key APIKey) error {
+	l.memoryAPIKeys.m.Lock()
+	defer l.memoryAPIKeys.m.Unlock()
+
+	err := l.writeAPIKey(key)
+	if err != nil {
+		return err
+	}
+	l.memoryAPIKeys.set(key)
+	return nil
+}
+
+// RevokeAPIKey mark API key of user as revoked and write it into file.
+func (l *LinksWithFile) RevokeAPIKey(_ context.Context, id string, userID uuid.UUID) error {
+	l.memoryAPIKeys.m.Lock()
+	defer l.memoryAPIKeys.m.Unlock()
+
+	key, ok := l.memoryAPIKeys.keys[l.memoryAPIKeys.hashes[id]]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	if !key.RevokedAt.IsZero() {
+		return nil
+	}
+
+	revoked := *key
+	revoked.RevokedAt = time.Now().UTC()
+	err := l.writeAPIKey(revoked)
+	if err != nil {
+		return err
+	}
+	l.memoryAPIKeys.set(revoked)
+	return nil
+}
+
+// writeAPIKey append API key into file and sync it with disk.
+// File is created on the first write.
+func (l *LinksWithFile) writeAPIKey(key APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("can not marshal api key: %w", err)
+	}
+	if l.keysFile == nil {
+		l.keysFile, err = os.OpenFile(filepath.Clean(l.keysPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+		if err != nil {
+			return fmt.Errorf("can not open api keys file: %w", err)
+		}
+	}
+	_, err = l.keysFile.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("can not write api key into file: %w", err)
+	}
+	err = l.keysFile.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync api keys file: %w", err)
+	}
+	return nil
+}
+
+// SetRefreshToken store new refresh token and write it into file.
+func (l *LinksWithFile) SetRefreshToken(_ context.Context, token RefreshToken) error {
+	l.memoryRefreshTokens.m.Lock()
+	defer l.memoryRefreshTokens.m.Unlock()
+
+	err := l.writeRefreshTokens([]RefreshToken{token})
+	if err != nil {
+		return err
+	}
+	l.memoryRefreshTokens.purge(token.UserID, time.Now())
+	l.memoryRefreshTokens.set(token)
+	return nil
+}
+
+// UseRefreshToken mark refresh token as used, write it into file and return its state before marking.
+func (l *LinksWithFile) UseRefreshToken(_ context.Context, hash string) (*RefreshToken, error) {
+	l.memoryRefreshTokens.m.Lock()
+	defer l.memoryRefreshTokens.m.Unlock()
+
+	token, ok := l.memoryRefreshTokens.tokens[hash]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	result := *token
+	if !token.UsedAt.IsZero() {
+		return &result, nil
+	}
+
+	used := *token
+	used.UsedAt = time.Now().UTC()
+	err := l.writeRefreshTokens([]RefreshToken{used})
+	if err != nil {
+		return nil, err
+	}
+	l.memoryRefreshTokens.set(used)
+	return &result, nil
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked and write them into file.
+func (l *LinksWithFile) RevokeRefreshTokens(_ context.Context, familyID string) error {
+	l.memoryRefreshTokens.m.Lock()
+	defer l.memoryRefreshTokens.m.Unlock()
+
+	now := time.Now().UTC()
+	var revoked []RefreshToken
+	for _, token := range l.memoryRefreshTokens.tokens {
+		if token.FamilyID == familyID && token.RevokedAt.IsZero() {
+			item := *token
+			item.RevokedAt = now
+			revoked = append(revoked, item)
+		}
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+
+	err := l.writeRefreshTokens(revoked)
+	if err != nil {
+		return err
+	}
+	for _, token := range revoked {
+		l.memoryRefreshTokens.set(token)
+	}
+	return nil
+}
+
+// writeRefreshTokens append refresh tokens into file and sync it with disk.
+// File is created on the first write.
+func (l *LinksWithFile) writeRefreshTokens(tokens []RefreshToken) error {
+	var data []byte
+	for _, token := range tokens {
+		line, err := json.Marshal(token)
+		if err != nil {
+			return fmt.Errorf("can not marshal refresh token: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	var err error
+	if l.tokensFile == nil {
+		l.tokensFile, err = os.OpenFile(filepath.Clean(l.tokensPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+		if err != nil {
+			return fmt.Errorf("can not open refresh tokens file: %w", err)
+		}
+	}
+	_, err = l.tokensFile.Write(data)
+	if err != nil {
+		return fmt.Errorf("can not write refresh tokens into file: %w", err)
+	}
+	err = l.tokensFile.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync refresh tokens file: %w", err)
+	}
+	return nil
+}
+
+// SetIdentity store identity if it is not stored yet, write it into file and return stored identity.
+func (l *LinksWithFile) SetIdentity(_ context.Context, identity Identity) (*Identity, error) {
+	l.memoryIdentities.m.Lock()
+	defer l.memoryIdentities.m.Unlock()
+
+	if stored, ok := l.memoryIdentities.get(identity); ok {
+		return &stored, nil
+	}
+
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal identity: %w", err)
+	}
+	if l.usersFile == nil {
+		l.usersFile, err = os.OpenFile(filepath.Clean(l.usersPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+		if err != nil {
+			return nil, fmt.Errorf("can not open identities file: %w", err)
+		}
+	}
+	_, err = l.usersFile.Write(append(data, '\n'))
+	if err != nil {
+		return nil, fmt.Errorf("can not write identity into file: %w", err)
+	}
+	err = l.usersFile.Sync()
+	if err != nil {
+		return nil, fmt.Errorf("can not sync identities file: %w", err)
+	}
+
+	l.memoryIdentities.set(identity)
+	return &identity, nil
+}
+
+// AppendAudit store events in audit log and write them into file if path of audit file is set.
+func (l *LinksWithFile) AppendAudit(_ context.Context, events []AuditEvent) error {
+	l.memoryAudit.m.Lock()
+	defer l.memoryAudit.m.Unlock()
+
+	events = l.memoryAudit.number(events)
+	if l.auditPath == "" {
+		l.memoryAudit.append(events)
+		return nil
+	}
+	var data []byte
+	for i := range events {
+		event, err := json.Marshal(events[i])
+		if err != nil {
+			return fmt.Errorf("can not marshal audit event: %w", err)
+		}
+		data = append(append(data, event...), '\n')
+	}
+	var err error
+	if l.auditFile == nil {
+		l.auditFile, err = os.OpenFile(filepath.Clean(l.auditPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+		if err != nil {
+			return fmt.Errorf("can not open audit file: %w", err)
+		}
+	}
+	_, err = l.auditFile.Write(data)
+	if err != nil {
+		return fmt.Errorf("can not write audit events into file: %w", err)
+	}
+	err = l.auditFile.Sync()
+	if err != nil {
+		return fmt.Errorf("can not sync audit file: %w", err)
+	}
+
+	l.memoryAudit.append(events)
+	return nil
+}
+
+// MergeUser move links of anonymous user into another user and write update records into log.
+// Links of user with identity are not moved.
+func (l *LinksWithFile) MergeUser(_ context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to || l.memoryIdentities.has(from) {
+		return nil
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	for shortURL, info := range l.originalURLs {
+		if info.userID != from {
+			continue
+		}
+
+		info.userID = to
+		err := l.update(shortURL, info)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetLinkDisabled disable or reenable link, write update record into log
+// and return link as it was before change.
+func (l *LinksWithFile) SetLinkDisabled(_ context.Context, shortURL string, disabled bool) (*Record, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	info, ok := l.originalURLs[shortURL]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := info.record(shortURL)
+	if info.disabled == disabled {
+		return &record, nil
+	}
+
+	info.disabled = disabled
+	err := l.update(shortURL, info)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// TransferLinks move links to another user, write update records into log
+// and return links as they were before transfer.
+func (l *LinksWithFile) TransferLinks(_ context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	var records []Record
+	for _, shortURL := range shortURLs {
+		info, ok := l.originalURLs[shortURL]
+		if !ok {
+			continue
+		}
+		record := info.record(shortURL)
+
+		info.userID = to
+		err := l.update(shortURL, info)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// update write update record of link into log and replace link in memory.
+func (l *LinksWithFile) update(shortURL string, info ShortlURLInfo) error {
+	err := l.write(URL{
+		CreatedAt:   info.createdAt,
+		Op:          opUpdate,
+		ID:          l.ids[shortURL],
+		ShortURL:    shortURL,
+		OriginalURL: info.originalURL,
+		UserID:      info.userID.String(),
+		Deleted:     info.deleted,
+		Disabled:    info.disabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	l.originalURLs[shortURL] = info
 	return nil
 }
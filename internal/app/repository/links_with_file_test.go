@@ -4,20 +4,27 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/models"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestNewLinksWithFile(t *testing.T) {
 	t.Run("successful creation with empty file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.NoError(t, err)
 		assert.NotNil(t, linksWithFile)
 		assert.Equal(t, 0, linksWithFile.currentID)
@@ -31,7 +38,7 @@ func TestNewLinksWithFile(t *testing.T) {
 	})
 
 	t.Run("successful creation with valid data in file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
 		data := URL{
@@ -48,7 +55,7 @@ func TestNewLinksWithFile(t *testing.T) {
 		err = file.Close()
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.NoError(t, err)
 		assert.NotNil(t, linksWithFile)
 		assert.Equal(t, 1, linksWithFile.currentID)
@@ -63,14 +70,14 @@ func TestNewLinksWithFile(t *testing.T) {
 	})
 
 	t.Run("file open error", func(t *testing.T) {
-		linksWithFile, err := NewLinksWithFile("/invalid/path")
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), "/invalid/path", FileConfig{})
 		assert.Error(t, err)
 		assert.Nil(t, linksWithFile)
 		assert.Contains(t, err.Error(), "can not open file")
 	})
 
 	t.Run("invalid JSON data in file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
 		_, err = file.WriteString("invalid json data\n")
@@ -78,14 +85,14 @@ func TestNewLinksWithFile(t *testing.T) {
 		err = file.Close()
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.Error(t, err)
 		assert.Nil(t, linksWithFile)
 		assert.Contains(t, err.Error(), "can not unmarshal data from file")
 	})
 
 	t.Run("duplicate original URL in file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
 		data1 := URL{
@@ -110,14 +117,14 @@ func TestNewLinksWithFile(t *testing.T) {
 		err = file.Close()
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.Error(t, err)
 		assert.Nil(t, linksWithFile)
 		assert.Equal(t, "duplicate original url in file", err.Error())
 	})
 
 	t.Run("duplicate short URL in file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
 		data1 := URL{
@@ -142,14 +149,14 @@ func TestNewLinksWithFile(t *testing.T) {
 		err = file.Close()
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.Error(t, err)
 		assert.Nil(t, linksWithFile)
 		assert.Equal(t, "duplicate short url in file", err.Error())
 	})
 
 	t.Run("invalid user ID in file", func(t *testing.T) {
-		file, err := os.CreateTemp("", "testfile")
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
 		assert.NoError(t, err)
 
 		data := URL{
@@ -166,7 +173,7 @@ func TestNewLinksWithFile(t *testing.T) {
 		err = file.Close()
 		assert.NoError(t, err)
 
-		linksWithFile, err := NewLinksWithFile(file.Name())
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
 		assert.NoError(t, err)
 		assert.NotNil(t, linksWithFile)
 
@@ -175,10 +182,10 @@ func TestNewLinksWithFile(t *testing.T) {
 }
 
 func TestLinksWithFileSetLink(t *testing.T) {
-	tmpFile, err := os.CreateTemp("./", "*.test")
+	tmpFile, err := os.CreateTemp(t.TempDir(), "*.test")
 	assert.NoError(t, err)
 
-	linksWithFile, err := NewLinksWithFile(tmpFile.Name())
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), tmpFile.Name(), FileConfig{})
 	assert.NoError(t, err)
 
 	defer func() {
@@ -214,10 +221,10 @@ func TestLinksWithFileSetLink(t *testing.T) {
 }
 
 func TestSetLinks(t *testing.T) {
-	tmpFile, err := os.CreateTemp("./", "*.test")
+	tmpFile, err := os.CreateTemp(t.TempDir(), "*.test")
 	assert.NoError(t, err)
 
-	linksWithFile, err := NewLinksWithFile(tmpFile.Name())
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), tmpFile.Name(), FileConfig{})
 	assert.NoError(t, err)
 
 	defer func() {
@@ -238,7 +245,10 @@ func TestSetLinks(t *testing.T) {
 
 	result, err := linksWithFile.SetLinks(context.Background(), batch, shortURLs, userID)
 	assert.NoError(t, err)
-	assert.Equal(t, []string{"abc123", "def456"}, result)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusCreated},
+		{ShortURL: "def456", Status: models.BatchStatusCreated},
+	}, result)
 
 	fileContent, err := os.ReadFile(tmpFile.Name())
 	assert.NoError(t, err)
@@ -251,12 +261,15 @@ func TestSetLinks(t *testing.T) {
 	shortURLs2 := [][]string{{"abc123"}}
 
 	result, err = linksWithFile.SetLinks(context.Background(), batch2, shortURLs2, userID)
-	assert.Error(t, err)
-	assert.Empty(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{{Status: models.BatchStatusInvalid}}, result)
 
 	result, err = linksWithFile.SetLinks(context.Background(), batch, shortURLs, userID)
-	assert.Error(t, err)
-	assert.Equal(t, []string{"abc123", "def456"}, result)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		{ShortURL: "def456", Status: models.BatchStatusExisting},
+	}, result)
 
 	batch3 := []models.ShortenBatchRequest{
 		{OriginalURL: "http://example1.com"},
@@ -264,15 +277,15 @@ func TestSetLinks(t *testing.T) {
 	shortURLs3 := [][]string{{"abc123", "abc123", "abc123", "abc123", "abc123"}}
 
 	result, err = linksWithFile.SetLinks(context.Background(), batch3, shortURLs3, userID)
-	assert.Error(t, err)
-	assert.Empty(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{{Status: models.BatchStatusError}}, result)
 }
 
 func TestDeleteURLs(t *testing.T) {
-	tmpFile, err := os.CreateTemp("./", "*.test")
+	tmpFile, err := os.CreateTemp(t.TempDir(), "*.test")
 	assert.NoError(t, err)
 
-	linksWithFile, err := NewLinksWithFile(tmpFile.Name())
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), tmpFile.Name(), FileConfig{})
 	assert.NoError(t, err)
 
 	defer func() {
@@ -301,10 +314,10 @@ func TestDeleteURLs(t *testing.T) {
 }
 
 func TestClose(t *testing.T) {
-	tmpFile, err := os.CreateTemp("./", "*.test")
+	tmpFile, err := os.CreateTemp(t.TempDir(), "*.test")
 	assert.NoError(t, err)
 
-	linksWithFile, err := NewLinksWithFile(tmpFile.Name())
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), tmpFile.Name(), FileConfig{})
 	assert.NoError(t, err)
 
 	defer func() {
@@ -317,6 +330,592 @@ func TestClose(t *testing.T) {
 	err = linksWithFile.Close()
 	assert.NoError(t, err)
 
-	_, err = linksWithFile.file.WriteString("test")
+	_, err = linksWithFile.file.Write([]byte("test"))
+	assert.Error(t, err)
+}
+
+// partialFile is a log file which writes only half of data and fails if it is requested.
+type partialFile struct {
+	*os.File
+	failWrite    bool
+	failTruncate bool
+}
+
+// Write write half of data and return error if failure is requested.
+func (p *partialFile) Write(data []byte) (int, error) {
+	if !p.failWrite {
+		return p.File.Write(data)
+	}
+	n, err := p.File.Write(data[:len(data)/2])
+	return n, errors.Join(errors.New("no space left on device"), err)
+}
+
+// Truncate return error if failure is requested.
+func (p *partialFile) Truncate(size int64) error {
+	if p.failTruncate {
+		return errors.New("input/output error")
+	}
+	return p.File.Truncate(size)
+}
+
+func TestLinksWithFilePartialWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	userID := uuid.New()
+
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	file := &partialFile{File: linksWithFile.file.(*os.File)}
+	linksWithFile.file = file
+
+	_, err = linksWithFile.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	file.failWrite = true
+	_, err = linksWithFile.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+	assert.ErrorContains(t, err, "can not write data to file")
+
+	file.failWrite = false
+	_, err = linksWithFile.SetLink(context.Background(), "https://third.com", []string{"ghi789"}, userID)
+	assert.NoError(t, err)
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+
+	linksWithFile, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, LoadReport{Records: 2}, linksWithFile.Status()["file"])
+	originalURL, err := linksWithFile.GetOriginalURL(context.Background(), "ghi789")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://third.com", *originalURL)
+
+	file = &partialFile{File: linksWithFile.file.(*os.File), failWrite: true, failTruncate: true}
+	linksWithFile.file = file
+	_, err = linksWithFile.SetLink(context.Background(), "https://fourth.com", []string{"jkl012"}, userID)
+	assert.ErrorContains(t, err, "can not write data to file")
+	file.failWrite = false
+	_, err = linksWithFile.SetLink(context.Background(), "https://fifth.com", []string{"mno345"}, userID)
+	assert.ErrorContains(t, err, "can not write data to failed file")
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+}
+
+func TestLinksWithFileLoadLog(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "testfile")
+	assert.NoError(t, err)
+	defer func() {
+		err = os.Remove(file.Name())
+		assert.NoError(t, err)
+	}()
+
+	userID := uuid.New()
+	records := []URL{
+		{Op: opCreate, ID: 1, ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID.String()},
+		{Op: opCreate, ID: 2, ShortURL: "def456", OriginalURL: "https://another.com", UserID: userID.String()},
+		{Op: opDelete, ID: 1, ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID.String()},
+		{Op: opUpdate, ID: 2, ShortURL: "def456", OriginalURL: "https://updated.com", UserID: userID.String()},
+	}
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		assert.NoError(t, err)
+		_, err = file.Write(append(data, '\n'))
+		assert.NoError(t, err)
+	}
+	err = file.Close()
+	assert.NoError(t, err)
+
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
+	assert.NoError(t, err)
+
+	_, err = linksWithFile.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+	originalURL, err := linksWithFile.GetOriginalURL(context.Background(), "def456")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://updated.com", *originalURL)
+	assert.NotContains(t, linksWithFile.shortLinks, "https://another.com")
+	assert.Equal(t, 2, linksWithFile.currentID)
+	assert.Equal(t, 4, linksWithFile.records)
+
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+}
+
+func TestLinksWithFileImport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	_, err = linksWithFile.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = linksWithFile.SetLink(context.Background(), "https://another.com", []string{"def456"}, userID)
+	assert.NoError(t, err)
+
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	err = linksWithFile.Import(context.Background(), []Record{
+		{CreatedAt: createdAt, ShortURL: "abc123", OriginalURL: "https://another.com", UserID: userID, Deleted: true},
+		{CreatedAt: createdAt, ShortURL: "ghi789", OriginalURL: "https://third.com", UserID: userID},
+	})
+	assert.NoError(t, err)
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+
+	linksWithFile, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	defer func() {
+		err = linksWithFile.Close()
+		assert.NoError(t, err)
+	}()
+
+	_, err = linksWithFile.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+	_, err = linksWithFile.GetOriginalURL(context.Background(), "def456")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, map[string]int{"abc123": 1, "ghi789": 3}, linksWithFile.ids)
+	assert.Equal(t, createdAt, linksWithFile.originalURLs["ghi789"].createdAt)
+	assert.Len(t, linksWithFile.shortLinks, 2)
+}
+
+func TestLinksWithFileCompact(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "testfile")
+	assert.NoError(t, err)
+	err = file.Close()
+	assert.NoError(t, err)
+	defer func() {
+		err = os.Remove(file.Name())
+		assert.NoError(t, err)
+	}()
+
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{FsyncPolicy: FsyncAlways})
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	for i, shortURL := range []string{"a", "b", "c"} {
+		_, err = linksWithFile.SetLink(
+			context.Background(),
+			fmt.Sprintf("https://example%d.com", i),
+			[]string{shortURL},
+			userID,
+		)
+		assert.NoError(t, err)
+	}
+	err = linksWithFile.DeleteURLs(context.Background(), []string{"b"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, linksWithFile.records)
+
+	err = linksWithFile.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, linksWithFile.records)
+
+	fileContent, err := os.ReadFile(file.Name())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(fileContent)), "\n")
+	assert.Len(t, lines, 3)
+	for i, line := range lines {
+		var data URL
+		err = json.Unmarshal([]byte(line), &data)
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, data.ID)
+		assert.Equal(t, opCreate, data.Op)
+		assert.Equal(t, data.ShortURL == "b", data.Deleted)
+	}
+
+	err = linksWithFile.DeleteURLs(context.Background(), []string{"c"}, userID)
+	assert.NoError(t, err)
+	linksWithFile.path = t.TempDir()
+	err = linksWithFile.Compact()
+	assert.ErrorContains(t, err, "can not replace file")
+	assert.Equal(t, 4, linksWithFile.records)
+	linksWithFile.path = file.Name()
+
+	_, err = linksWithFile.SetLink(context.Background(), "https://example3.com", []string{"d"}, userID)
+	assert.NoError(t, err)
+
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+
+	matches, err := filepath.Glob(file.Name() + ".compact-*")
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	linksWithFile, err = NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{})
+	assert.NoError(t, err)
+	assert.Len(t, linksWithFile.originalURLs, 4)
+	assert.Equal(t, 4, linksWithFile.ids["d"])
+	assert.True(t, linksWithFile.originalURLs["b"].deleted)
+	assert.True(t, linksWithFile.originalURLs["c"].deleted)
+
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+}
+
+func TestLinksWithFileCrashRecovery(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "testfile")
+	assert.NoError(t, err)
+	err = file.Close()
+	assert.NoError(t, err)
+	defer func() {
+		err = os.Remove(file.Name())
+		assert.NoError(t, err)
+	}()
+
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), file.Name(), FileConfig{FsyncPolicy: FsyncAlways})
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	for i := range 20 {
+		_, err = linksWithFile.SetLink(
+			context.Background(),
+			fmt.Sprintf("https://example%d.com", i),
+			[]string{fmt.Sprintf("short%d", i)},
+			userID,
+		)
+		assert.NoError(t, err)
+		if i%3 == 0 {
+			err = linksWithFile.DeleteURLs(context.Background(), []string{fmt.Sprintf("short%d", i)}, userID)
+			assert.NoError(t, err)
+		}
+	}
+	err = linksWithFile.Close()
+	assert.NoError(t, err)
+
+	fileContent, err := os.ReadFile(file.Name())
+	assert.NoError(t, err)
+
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		offset := random.IntN(len(fileContent) + 1)
+		complete := fileContent[:strings.LastIndex(string(fileContent[:offset]), "\n")+1]
+
+		truncated, err := os.CreateTemp(t.TempDir(), "truncated")
+		assert.NoError(t, err)
+		_, err = truncated.Write(fileContent[:offset])
+		assert.NoError(t, err)
+		err = truncated.Close()
+		assert.NoError(t, err)
+
+		recovered, err := NewLinksWithFile(zap.NewNop(), truncated.Name(), FileConfig{})
+		assert.NoError(t, err)
+
+		prefix, err := os.CreateTemp(t.TempDir(), "prefix")
+		assert.NoError(t, err)
+		_, err = prefix.Write(complete)
+		assert.NoError(t, err)
+		err = prefix.Close()
+		assert.NoError(t, err)
+		expected, err := NewLinksWithFile(zap.NewNop(), prefix.Name(), FileConfig{})
+		assert.NoError(t, err)
+		assert.Equal(t, expected.originalURLs, recovered.originalURLs)
+		err = expected.Close()
+		assert.NoError(t, err)
+		err = os.Remove(prefix.Name())
+		assert.NoError(t, err)
+
+		_, err = recovered.SetLink(context.Background(), "https://new.com", []string{"new"}, userID)
+		assert.NoError(t, err)
+		err = recovered.Close()
+		assert.NoError(t, err)
+
+		recovered, err = NewLinksWithFile(zap.NewNop(), truncated.Name(), FileConfig{})
+		assert.NoError(t, err)
+		assert.Len(t, recovered.originalURLs, len(expected.originalURLs)+1)
+		err = recovered.Close()
+		assert.NoError(t, err)
+
+		err = os.Remove(truncated.Name())
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewLinksWithFileUnknownFsyncPolicy(t *testing.T) {
+	linksWithFile, err := NewLinksWithFile(zap.NewNop(), "testfile", FileConfig{FsyncPolicy: "sometimes"})
 	assert.Error(t, err)
+	assert.Nil(t, linksWithFile)
+}
+
+func TestLinksWithFileRecovery(t *testing.T) {
+	userID := uuid.New()
+	valid := func(data URL) string {
+		line, err := encode(data)
+		assert.NoError(t, err)
+		return string(line)
+	}
+	corrupted := valid(URL{Op: opCreate, ID: 3, ShortURL: "ghi789", OriginalURL: "https://third.com"})
+	corrupted = strings.Replace(corrupted, "third", "fifth", 1)
+	content := valid(URL{Op: opCreate, ID: 1, ShortURL: "abc123", OriginalURL: "https://example.com", UserID: userID.String()}) +
+		"not a json\n" +
+		valid(URL{Op: opCreate, ID: 2, ShortURL: "def456", OriginalURL: "https://another.com", UserID: userID.String()}) +
+		corrupted +
+		valid(URL{Op: opCreate, ID: 4, ShortURL: "abc123", OriginalURL: "https://latest.com", UserID: userID.String()})
+
+	newFile := func(t *testing.T) string {
+		t.Helper()
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
+		assert.NoError(t, err)
+		_, err = file.WriteString(content)
+		assert.NoError(t, err)
+		err = file.Close()
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			_ = os.Remove(file.Name())
+			_ = os.Remove(file.Name() + quarantineSuffix)
+		})
+		return file.Name()
+	}
+
+	t.Run("strict mode fails on corrupt record", func(t *testing.T) {
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), newFile(t), FileConfig{})
+		assert.Error(t, err)
+		assert.Nil(t, linksWithFile)
+	})
+
+	t.Run("checksum mismatch is detected", func(t *testing.T) {
+		linksWithFile := &LinksWithFile{Links: NewLinks(), ids: make(map[string]int)}
+		err := linksWithFile.loadRecord([]byte(corrupted))
+		assert.Error(t, err)
+		assert.Empty(t, linksWithFile.originalURLs)
+	})
+
+	t.Run("records without checksum are accepted only before checksummed records", func(t *testing.T) {
+		legacy := `{"short_url":"jkl012","original_url":"https://legacy.com","user_id":"","id":5,"deleted":false}` + "\n"
+		stripped := strings.Replace(
+			valid(URL{Op: opCreate, ID: 6, ShortURL: "mno345", OriginalURL: "https://stripped.com"}),
+			`"checksum":`, `"other":`, 1,
+		)
+
+		linksWithFile := &LinksWithFile{Links: NewLinks(), ids: make(map[string]int)}
+		err := linksWithFile.loadRecord([]byte(legacy))
+		assert.NoError(t, err)
+		err = linksWithFile.loadRecord([]byte(stripped))
+		assert.ErrorContains(t, err, "checksum mismatch")
+		err = linksWithFile.loadRecord([]byte(strings.Replace(legacy, "jkl012", "pqr678", 1)))
+		assert.ErrorContains(t, err, "record without checksum")
+		assert.Len(t, linksWithFile.originalURLs, 1)
+
+		err = linksWithFile.loadRecord([]byte(strings.Replace(valid(URL{ID: 7}), `"version":1`, `"version":2`, 1)))
+		assert.ErrorContains(t, err, "unsupported version")
+	})
+
+	t.Run("recovery mode skips corrupt records", func(t *testing.T) {
+		path := newFile(t)
+		linksWithFile, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{Recovery: true})
+		assert.NoError(t, err)
+
+		assert.Equal(t, LoadReport{
+			QuarantinePath: path + quarantineSuffix,
+			Records:        3,
+			Skipped:        2,
+			Replaced:       1,
+		}, linksWithFile.Status()["file"])
+
+		originalURL, err := linksWithFile.GetOriginalURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://latest.com", *originalURL)
+		assert.NotContains(t, linksWithFile.shortLinks, "https://example.com")
+		assert.Len(t, linksWithFile.originalURLs, 2)
+
+		quarantine, err := os.ReadFile(path + quarantineSuffix)
+		assert.NoError(t, err)
+		assert.Equal(t, "not a json\n"+corrupted, string(quarantine))
+
+		err = linksWithFile.Close()
+		assert.NoError(t, err)
+
+		linksWithFile, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+		assert.NoError(t, err)
+		assert.Equal(t, LoadReport{Records: 2}, linksWithFile.Status()["file"])
+		err = linksWithFile.Close()
+		assert.NoError(t, err)
+	})
+}
+
+func TestLinksWithFileAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	userID := uuid.New()
+	key := APIKey{
+		CreatedAt: time.Now().UTC(),
+		ID:        uuid.NewString(),
+		Hash:      "hash",
+		Scopes:    []string{"create"},
+		UserID:    userID,
+	}
+
+	links, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	_, err = os.Stat(path + apiKeysSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	err = links.SetAPIKey(context.Background(), key)
+	assert.NoError(t, err)
+	err = links.RevokeAPIKey(context.Background(), key.ID, userID)
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+
+	links, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	defer func() {
+		err := links.Close()
+		assert.NoError(t, err)
+	}()
+	result, err := links.GetAPIKey(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, result.ID)
+	assert.Equal(t, key.Scopes, result.Scopes)
+	assert.False(t, result.RevokedAt.IsZero())
+}
+
+func TestLinksWithFileRefreshTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	userID := uuid.New()
+	familyID := uuid.NewString()
+
+	links, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	_, err = os.Stat(path + refreshTokensSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	for _, token := range []RefreshToken{
+		{ExpiresAt: time.Now().Add(time.Hour), Hash: "used", FamilyID: familyID, UserID: userID},
+		{ExpiresAt: time.Now().Add(time.Hour), Hash: "revoked", FamilyID: familyID, UserID: userID},
+		{ExpiresAt: time.Now().Add(time.Millisecond), Hash: "expired", FamilyID: familyID, UserID: userID},
+	} {
+		err = links.SetRefreshToken(context.Background(), token)
+		assert.NoError(t, err)
+	}
+	_, err = links.UseRefreshToken(context.Background(), "used")
+	assert.NoError(t, err)
+	err = links.RevokeRefreshTokens(context.Background(), familyID)
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	links, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	defer func() {
+		err := links.Close()
+		assert.NoError(t, err)
+	}()
+	token, err := links.UseRefreshToken(context.Background(), "used")
+	assert.NoError(t, err)
+	assert.False(t, token.UsedAt.IsZero())
+	assert.False(t, token.RevokedAt.IsZero())
+	token, err = links.UseRefreshToken(context.Background(), "revoked")
+	assert.NoError(t, err)
+	assert.True(t, token.UsedAt.IsZero())
+	assert.False(t, token.RevokedAt.IsZero())
+	_, err = links.UseRefreshToken(context.Background(), "expired")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+}
+
+func TestLinksWithFileUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	userID := uuid.New()
+	anonymousID := uuid.New()
+
+	links, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	_, err = os.Stat(path + identitiesSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = links.SetIdentity(context.Background(), Identity{
+		CreatedAt: time.Now(),
+		Issuer:    "https://issuer.com",
+		Subject:   "subject",
+		UserID:    userID,
+	})
+	assert.NoError(t, err)
+	_, err = links.SetLink(context.Background(), "https://example.com", []string{"abc123"}, anonymousID)
+	assert.NoError(t, err)
+	err = links.MergeUser(context.Background(), anonymousID, userID)
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+
+	links, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	defer func() {
+		err := links.Close()
+		assert.NoError(t, err)
+	}()
+	identity, err := links.SetIdentity(context.Background(), Identity{
+		CreatedAt: time.Now(),
+		Issuer:    "https://issuer.com",
+		Subject:   "subject",
+		UserID:    uuid.New(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, userID, identity.UserID)
+	result, err := links.GetShortLinksOfUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	result, err = links.GetShortLinksOfUser(context.Background(), anonymousID)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestLinksWithFileAdmin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	userID := uuid.New()
+	anotherUserID := uuid.New()
+
+	links, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+	assert.NoError(t, err)
+	_, err = links.SetLink(context.Background(), "https://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = links.SetLinkDisabled(context.Background(), "abc123", true)
+	assert.NoError(t, err)
+	_, err = links.TransferLinks(context.Background(), []string{"abc123"}, anotherUserID)
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+
+	for range 2 {
+		links, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{})
+		assert.NoError(t, err)
+		_, err = links.GetOriginalURL(context.Background(), "abc123")
+		assert.ErrorIs(t, err, ErrURLIsDisabled)
+		result, err := links.GetShortLinksOfUser(context.Background(), anotherUserID)
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		err = links.Compact()
+		assert.NoError(t, err)
+		err = links.Close()
+		assert.NoError(t, err)
+	}
+}
+
+func TestLinksWithFileAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shorturls.txt")
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	userID := uuid.New()
+
+	links, err := NewLinksWithFile(zap.NewNop(), path, FileConfig{AuditPath: auditPath})
+	assert.NoError(t, err)
+	_, err = os.Stat(auditPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	err = links.AppendAudit(context.Background(), []AuditEvent{
+		{CreatedAt: time.Now(), Action: "create", ShortURL: "abc123", UserID: userID, ActorID: userID},
+	})
+	assert.NoError(t, err)
+	err = links.Close()
+	assert.NoError(t, err)
+	_, err = os.Stat(auditPath)
+	assert.NoError(t, err)
+
+	links, err = NewLinksWithFile(zap.NewNop(), path, FileConfig{AuditPath: auditPath})
+	assert.NoError(t, err)
+	defer func() {
+		err := links.Close()
+		assert.NoError(t, err)
+	}()
+	err = links.AppendAudit(context.Background(), []AuditEvent{
+		{CreatedAt: time.Now(), Action: "delete", ShortURL: "abc123", UserID: userID, ActorID: userID},
+	})
+	assert.NoError(t, err)
+
+	var ids []int64
+	err = links.ExportAudit(context.Background(), AuditFilter{ShortURL: "abc123"}, func(event AuditEvent) error {
+		ids = append(ids, event.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
 }
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrations of PostgreSQL database.
+//
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// Migrations of SQLite database.
+//
+//go:embed migrations_sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// Migration is a model of migration with its state.
+type Migration struct {
+	Identifier string
+	Version    uint
+	Applied    bool
+}
+
+// NewMigrationsSource create source of embedded migrations for database from DSN.
+func NewMigrationsSource(connString string) (source.Driver, error) {
+	var migrations fs.FS = postgresMigrations
+	path := "migrations"
+	if strings.HasPrefix(connString, SQLiteScheme) {
+		migrations = sqliteMigrations
+		path = "migrations_sqlite"
+	}
+
+	driver, err := iofs.New(migrations, path)
+	if err != nil {
+		return nil, fmt.Errorf("can not create migrations source: %w", err)
+	}
+	return driver, nil
+}
+
+// NewMigrate create migration instance with embedded migrations for database from DSN.
+func NewMigrate(connString string) (*migrate.Migrate, error) {
+	driver, err := NewMigrationsSource(connString)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", driver, connString)
+	if err != nil {
+		return nil, fmt.Errorf("can not create migration instance: %w", err)
+	}
+	return m, nil
+}
+
+// Migrate apply all migrations to database from DSN.
+func Migrate(connString string) error {
+	m, err := NewMigrate(connString)
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return errors.Join(fmt.Errorf("can not migrate up: %w", err), closeMigrate(m))
+	}
+
+	return closeMigrate(m)
+}
+
+// Migrations return all migrations with their state.
+func Migrations(m *migrate.Migrate, driver source.Driver) ([]Migration, error) {
+	current, _, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("can not get version: %w", err)
+	}
+	applied := err == nil
+
+	var migrations []Migration
+	version, err := driver.First()
+	for err == nil {
+		r, identifier, readErr := driver.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("can not read migration %d: %w", version, readErr)
+		}
+		readErr = r.Close()
+		if readErr != nil {
+			return nil, fmt.Errorf("can not close migration %d: %w", version, readErr)
+		}
+		migrations = append(migrations, Migration{
+			Identifier: identifier,
+			Version:    version,
+			Applied:    applied && version <= current,
+		})
+		version, err = driver.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("can not read migrations: %w", err)
+	}
+
+	return migrations, nil
+}
+
+// closeMigrate close source and database of migration instance.
+func closeMigrate(m *migrate.Migrate) error {
+	sourceErr, dbErr := m.Close()
+	err := errors.Join(sourceErr, dbErr)
+	if err != nil {
+		return fmt.Errorf("can not close migration instance: %w", err)
+	}
+	return nil
+}
@@ -1,5 +1,5 @@
 START TRANSACTION;
 
-ALTER TABLE users RENAME COLUMN user_id TO __user_id;
+ALTER TABLE urls DROP COLUMN user_id;
 
 COMMIT;
\ No newline at end of file
@@ -1,6 +1,6 @@
 START TRANSACTION;
 
-ALTER TABLE users RENAME COLUMN deleted TO __deleted;
+ALTER TABLE urls DROP COLUMN deleted;
 
 DROP TABLE urls_for_delete;
 
@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE urls DROP COLUMN created_at;
+
+COMMIT;
\ No newline at end of file
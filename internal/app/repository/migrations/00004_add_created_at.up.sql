@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE urls ADD created_at timestamptz NOT NULL DEFAULT now();
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,15 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS idempotency_keys (
+    key text PRIMARY KEY,
+    fingerprint text NOT NULL,
+    status integer,
+    content_type text,
+    body bytea,
+    expires_at timestamptz NOT NULL
+  );
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_index ON idempotency_keys (expires_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,17 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY,
+    hash text NOT NULL,
+    user_id uuid NOT NULL,
+    name text NOT NULL DEFAULT '',
+    scopes text[] NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    revoked_at timestamptz,
+    CONSTRAINT api_key_hash_constraint UNIQUE(hash)
+  );
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_index ON api_keys (user_id);
+
+COMMIT;
\ No newline at end of file
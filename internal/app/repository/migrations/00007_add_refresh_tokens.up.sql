@@ -0,0 +1,17 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS refresh_tokens (
+    hash text PRIMARY KEY,
+    family_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    revoked_at timestamptz
+  );
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_index ON refresh_tokens (family_id);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_index ON refresh_tokens (user_id);
+
+COMMIT;
\ No newline at end of file
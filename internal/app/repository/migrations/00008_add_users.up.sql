@@ -0,0 +1,14 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS users (
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    user_id uuid NOT NULL,
+    created_at timestamptz NOT NULL,
+    PRIMARY KEY (issuer, subject)
+  );
+
+CREATE INDEX IF NOT EXISTS users_user_id_index ON users (user_id);
+
+COMMIT;
\ No newline at end of file
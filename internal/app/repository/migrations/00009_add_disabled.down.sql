@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE urls DROP COLUMN disabled;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE urls ADD disabled bool NOT NULL DEFAULT false;
+
+COMMIT;
\ No newline at end of file
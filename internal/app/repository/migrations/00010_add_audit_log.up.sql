@@ -0,0 +1,26 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    action text NOT NULL,
+    short_url text NOT NULL,
+    user_id uuid NOT NULL,
+    actor_id uuid NOT NULL,
+    auth_method text NOT NULL,
+    client_ip text NOT NULL,
+    request_id text NOT NULL,
+    before jsonb,
+    after jsonb
+  );
+
+CREATE INDEX IF NOT EXISTS audit_log_short_url_index ON audit_log (short_url);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_index ON audit_log (user_id);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_index ON audit_log (actor_id);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_index ON audit_log (created_at);
+
+COMMIT;
\ No newline at end of file
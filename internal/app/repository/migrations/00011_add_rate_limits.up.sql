@@ -0,0 +1,13 @@
+START TRANSACTION;
+
+CREATE TABLE
+  IF NOT EXISTS rate_limits (
+    key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    full_at timestamptz NOT NULL
+  );
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_index ON rate_limits (full_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE
+  IF NOT EXISTS urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url text NOT NULL,
+    original_url text NOT NULL,
+    CONSTRAINT short_url_constraint UNIQUE(short_url),
+    CONSTRAINT original_url_constraint UNIQUE(original_url)
+  );
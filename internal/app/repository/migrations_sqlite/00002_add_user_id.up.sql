@@ -0,0 +1 @@
+ALTER TABLE urls ADD user_id text NOT NULL DEFAULT '';
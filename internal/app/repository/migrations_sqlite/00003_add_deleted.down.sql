@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN deleted;
+
+DROP TABLE urls_for_delete;
@@ -0,0 +1,8 @@
+ALTER TABLE urls ADD deleted boolean NOT NULL DEFAULT false;
+
+CREATE TABLE
+  IF NOT EXISTS urls_for_delete (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    urls text NOT NULL,
+    user_id text NOT NULL
+  );
@@ -0,0 +1 @@
+ALTER TABLE urls ADD created_at datetime;
@@ -0,0 +1,13 @@
+CREATE TABLE
+  IF NOT EXISTS api_keys (
+    id text PRIMARY KEY,
+    hash text NOT NULL,
+    user_id text NOT NULL,
+    name text NOT NULL DEFAULT '',
+    scopes text NOT NULL,
+    created_at datetime NOT NULL,
+    revoked_at datetime,
+    CONSTRAINT api_key_hash_constraint UNIQUE(hash)
+  );
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_index ON api_keys (user_id);
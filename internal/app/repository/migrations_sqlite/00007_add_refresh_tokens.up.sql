@@ -0,0 +1,13 @@
+CREATE TABLE
+  IF NOT EXISTS refresh_tokens (
+    hash text PRIMARY KEY,
+    family_id text NOT NULL,
+    user_id text NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at datetime,
+    revoked_at datetime
+  );
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_index ON refresh_tokens (family_id);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_index ON refresh_tokens (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE
+  IF NOT EXISTS users (
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    user_id text NOT NULL,
+    created_at datetime NOT NULL,
+    PRIMARY KEY (issuer, subject)
+  );
+
+CREATE INDEX IF NOT EXISTS users_user_id_index ON users (user_id);
@@ -0,0 +1 @@
+ALTER TABLE urls ADD disabled boolean NOT NULL DEFAULT false;
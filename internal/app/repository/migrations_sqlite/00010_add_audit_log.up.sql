@@ -0,0 +1,20 @@
+CREATE TABLE
+  IF NOT EXISTS audit_log (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    action text NOT NULL,
+    short_url text NOT NULL,
+    user_id text NOT NULL,
+    actor_id text NOT NULL,
+    auth_method text NOT NULL,
+    client_ip text NOT NULL,
+    request_id text NOT NULL,
+    before text,
+    after text
+  );
+
+CREATE INDEX IF NOT EXISTS audit_log_short_url_index ON audit_log (short_url);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_index ON audit_log (user_id);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_index ON audit_log (actor_id);
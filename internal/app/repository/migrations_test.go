@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	dsn := SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db")
+
+	err := Migrate(dsn)
+	assert.NoError(t, err)
+
+	err = Migrate(dsn)
+	assert.NoError(t, err)
+
+	err = Migrate("unknown://")
+	assert.Error(t, err)
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	dsns := map[string]string{
+		"sqlite": SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db"),
+	}
+	if dsn := os.Getenv(testDatabaseDSN); dsn != "" {
+		dsns["postgres"] = dsn
+	}
+
+	for name, dsn := range dsns {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMigrate(dsn)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+
+			err = m.Up()
+			assert.NoError(t, err)
+			err = m.Down()
+			assert.NoError(t, err)
+			err = m.Up()
+			assert.NoError(t, err)
+
+			version, dirty, err := m.Version()
+			assert.NoError(t, err)
+			assert.False(t, dirty)
+			assert.NotZero(t, version)
+
+			err = closeMigrate(m)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	dsn := SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db")
+
+	m, err := NewMigrate(dsn)
+	assert.NoError(t, err)
+	driver, err := NewMigrationsSource(dsn)
+	assert.NoError(t, err)
+
+	migrations, err := Migrations(m, driver)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Identifier: "init", Version: 1},
+		{Identifier: "add_user_id", Version: 2},
+		{Identifier: "add_deleted", Version: 3},
+		{Identifier: "add_created_at", Version: 4},
+		{Identifier: "add_api_keys", Version: 6},
+		{Identifier: "add_refresh_tokens", Version: 7},
+		{Identifier: "add_users", Version: 8},
+		{Identifier: "add_disabled", Version: 9},
+		{Identifier: "add_audit_log", Version: 10},
+	}, migrations)
+
+	err = m.Steps(2)
+	assert.NoError(t, err)
+
+	migrations, err = Migrations(m, driver)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Identifier: "init", Version: 1, Applied: true},
+		{Identifier: "add_user_id", Version: 2, Applied: true},
+		{Identifier: "add_deleted", Version: 3},
+		{Identifier: "add_created_at", Version: 4},
+		{Identifier: "add_api_keys", Version: 6},
+		{Identifier: "add_refresh_tokens", Version: 7},
+		{Identifier: "add_users", Version: 8},
+		{Identifier: "add_disabled", Version: 9},
+		{Identifier: "add_audit_log", Version: 10},
+	}, migrations)
+
+	err = m.Up()
+	assert.NoError(t, err)
+	err = m.Steps(-9)
+	assert.NoError(t, err)
+
+	err = closeMigrate(m)
+	assert.NoError(t, err)
+	err = driver.Close()
+	assert.NoError(t, err)
+
+	driver, err = NewMigrationsSource("postgres://localhost/db")
+	assert.NoError(t, err)
+	version, err := driver.First()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+	err = driver.Close()
+	assert.NoError(t, err)
+}
@@ -3,20 +3,46 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
-	"strings"
+	"math/rand/v2"
+	"slices"
+	"time"
 
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Retry variables.
+// Default values of RetryPolicy.
 const (
-	retry      = 3
-	connClosed = "conn closed"
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = 50 * time.Millisecond
+	DefaultRetryMaxDelay  = time.Second
 )
 
+// Names of retry metrics.
+const (
+	metricQueries      = "queries"
+	metricTransactions = "transactions"
+	metricExhausted    = "exhausted"
+)
+
+// retryMetrics counts retried queries and transactions and operations which ran out of attempts.
+var retryMetrics = expvar.NewMap("database_retries")
+
+// retryableCodes are codes of PostgreSQL errors after which operation can be safely repeated.
+var retryableCodes = []string{
+	pgerrcode.SerializationFailure,
+	pgerrcode.DeadlockDetected,
+	pgerrcode.AdminShutdown,
+	pgerrcode.CrashShutdown,
+	pgerrcode.CannotConnectNow,
+	pgerrcode.TooManyConnections,
+}
+
 // IPool is a Pool interface.
 type IPool interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
@@ -27,160 +53,193 @@ type IPool interface {
 	Close()
 }
 
-// Pool is a wrapper for database Pool.
+// RetryPolicy is a policy of retrying failed database operations with exponential backoff and full jitter.
+// Operation is performed at most MaxAttempts times, so zero RetryPolicy performs operation once.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Pool is a wrapper for database Pool which retries failed operations.
 type Pool struct {
 	*pgxpool.Pool
+	policy RetryPolicy
 }
 
 // Row is a wrapper for database Row.
 type Row struct {
 	pgx.Row
-	retry func() pgx.Row
+	ctx    context.Context
+	retry  func() pgx.Row
+	policy RetryPolicy
 }
 
-// Tx is a wrapper for database Tx.
-type Tx struct {
-	pgx.Tx
+// DefaultRetryPolicy return RetryPolicy with default values.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryAttempts,
+		BaseDelay:   DefaultRetryBaseDelay,
+		MaxDelay:    DefaultRetryMaxDelay,
+	}
 }
 
-// BatchResults is a wrapper for database BatchResults.
-type BatchResults struct {
-	pgx.BatchResults
-	retry func() pgx.BatchResults
+// withDefaults return RetryPolicy with zero values replaced with default ones.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryMaxDelay
+	}
+	return p
 }
 
-// NewPool create new pool.
-func NewPool(ctx context.Context, connString string) (*Pool, error) {
-	pool, err := pgxpool.New(ctx, connString)
-	if err != nil {
-		return nil, fmt.Errorf("can not create new pool for PostgreSQL server: %w", err)
+// backoff return random delay before retry which grows exponentially with number of failed attempts.
+func (p RetryPolicy) backoff(failed int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
 	}
-	return &Pool{
-		Pool: pool,
-	}, nil
+	delay := p.BaseDelay << min(failed-1, 30)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = max(p.MaxDelay, p.BaseDelay)
+	}
+	return rand.N(delay + 1)
 }
 
-// Scan is a wrapper for database Scan.
-func (r *Row) Scan(dest ...any) error {
+// do perform operation until it succeeds, fails with not retryable error or runs out of attempts.
+func (p RetryPolicy) do(ctx context.Context, metric string, operation func(attempt int) error) error {
 	var err error
-	for range retry {
-		err = r.Row.Scan(dest...)
-		if err == nil {
-			return nil
+	for attempt := range max(p.MaxAttempts, 1) {
+		if attempt > 0 {
+			retryMetrics.Add(metric, 1)
+			timer := time.NewTimer(p.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-timer.C:
+			}
 		}
-		if !strings.Contains(err.Error(), connClosed) {
+
+		err = operation(attempt)
+		if err == nil || !isRetryable(err) {
 			return err
 		}
-		r.Row = r.retry()
 	}
 
+	retryMetrics.Add(metricExhausted, 1)
 	return err
 }
 
-// QueryRow is a wrapper for database QueryRow.
-func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
-	row := p.Pool.QueryRow(ctx, sql, args...)
-	return &Row{
-		Row: row,
-		retry: func() pgx.Row {
-			return p.Pool.QueryRow(ctx, sql, args...)
-		},
+// isRetryable check if operation which failed with error can be safely repeated.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgerrcode.IsConnectionException(pgErr.Code) || slices.Contains(retryableCodes, pgErr.Code)
 	}
+	return pgconn.SafeToRetry(err)
 }
 
-// Begin is a wrapper for database Begin.
-func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
-	var err error
-	for range retry {
-		var tx pgx.Tx
-		tx, err = p.Pool.Begin(ctx)
-		if err == nil {
-			return &Tx{
-				Tx: tx,
-			}, nil
-		}
-		if !strings.Contains(err.Error(), connClosed) {
-			return nil, err
+// WithTx run fn in transaction and commit it.
+// Whole transaction is repeated according to policy if it fails with retryable error,
+// so fn must be safe to run several times.
+func WithTx(ctx context.Context, pool IPool, policy RetryPolicy, fn func(tx pgx.Tx) error) error {
+	return policy.do(ctx, metricTransactions, func(int) error {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("can not start transaction: %w", err)
 		}
-	}
-
-	return nil, err
-}
 
-// Query is a wrapper for database Query.
-func (t *Tx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
-	var err error
-	for range retry {
-		var rows pgx.Rows
-		rows, err = t.Tx.Query(ctx, sql, args...)
+		err = fn(tx)
 		if err == nil {
-			return rows, nil
+			err = tx.Commit(ctx)
+			if err == nil {
+				return nil
+			}
+			err = fmt.Errorf("can not commit transaction: %w", err)
 		}
-		if !strings.Contains(err.Error(), connClosed) {
-			return nil, err
+
+		rollbackErr := tx.Rollback(ctx)
+		if rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			return errors.Join(err, fmt.Errorf("can not rollback transaction: %w", rollbackErr))
 		}
+		return err
+	})
+}
+
+// NewPool create new pool which retries failed operations according to policy.
+func NewPool(ctx context.Context, connString string, policy RetryPolicy) (*Pool, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, fmt.Errorf("can not create new pool for PostgreSQL server: %w", err)
 	}
+	return &Pool{
+		Pool:   pool,
+		policy: policy,
+	}, nil
+}
 
-	return nil, err
+// Scan is a wrapper for database Scan.
+func (r *Row) Scan(dest ...any) error {
+	return r.policy.do(r.ctx, metricQueries, func(attempt int) error {
+		if attempt > 0 {
+			r.Row = r.retry()
+		}
+		return r.Row.Scan(dest...)
+	})
 }
 
-// SendBatch is a wrapper for database SendBatch.
-func (t *Tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
-	batchResults := t.Tx.SendBatch(ctx, b)
-	return &BatchResults{
-		BatchResults: batchResults,
-		retry: func() pgx.BatchResults {
-			return t.Tx.SendBatch(ctx, b)
+// QueryRow is a wrapper for database QueryRow.
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	row := p.Pool.QueryRow(ctx, sql, args...)
+	return &Row{
+		Row: row,
+		ctx: ctx,
+		retry: func() pgx.Row {
+			return p.Pool.QueryRow(ctx, sql, args...)
 		},
+		policy: p.policy,
 	}
 }
 
-// Commit is a wrapper for database Commit.
-func (t *Tx) Commit(ctx context.Context) error {
-	var err error
-	for range retry {
-		err = t.Tx.Commit(ctx)
-		if err == nil {
-			return nil
-		}
-		if !strings.Contains(err.Error(), connClosed) {
-			return err
-		}
+// Query is a wrapper for database Query.
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := p.policy.do(ctx, metricQueries, func(int) error {
+		var err error
+		rows, err = p.Pool.Query(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return err
+	return rows, nil
 }
 
 // Exec is a wrapper for database Exec.
-func (b *BatchResults) Exec() (pgconn.CommandTag, error) {
-	var err error
-	for range retry {
-		var commandTag pgconn.CommandTag
-		commandTag, err = b.BatchResults.Exec()
-		if err == nil {
-			return commandTag, nil
-		}
-		if !strings.Contains(err.Error(), connClosed) {
-			return pgconn.CommandTag{}, err
-		}
-		b.BatchResults = b.retry()
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var commandTag pgconn.CommandTag
+	err := p.policy.do(ctx, metricQueries, func(int) error {
+		var err error
+		commandTag, err = p.Pool.Exec(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return pgconn.CommandTag{}, err
 	}
-
-	return pgconn.CommandTag{}, err
+	return commandTag, nil
 }
 
 // Ping is a wrapper for database Ping.
 func (p *Pool) Ping(ctx context.Context) error {
-	var err error
-	for range retry {
-		err = p.Pool.Ping(ctx)
-		if err == nil {
-			return nil
-		}
-		if !strings.Contains(err.Error(), connClosed) {
-			return err
-		}
-	}
-
-	return err
+	return p.policy.do(ctx, metricQueries, func(int) error {
+		return p.Pool.Ping(ctx)
+	})
 }
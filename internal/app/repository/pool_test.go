@@ -3,9 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
@@ -13,12 +17,26 @@ import (
 
 func TestNewPool(t *testing.T) {
 	t.Run("error new pool", func(t *testing.T) {
-		pool, err := NewPool(context.Background(), "")
+		pool, err := NewPool(context.Background(), "", DefaultRetryPolicy())
 		assert.NoError(t, err)
 		assert.NotEmpty(t, pool)
 	})
 }
 
+// safeToRetryError is an error which occurred before sending any data to the server.
+type safeToRetryError struct{}
+
+func (safeToRetryError) Error() string {
+	return "conn closed"
+}
+
+func (safeToRetryError) SafeToRetry() bool {
+	return true
+}
+
+// testRetryPolicy is a RetryPolicy without delays.
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3}
+
 func TestRowScan(t *testing.T) {
 	mockPool, err := pgxmock.NewPool()
 	assert.NoError(t, err)
@@ -33,9 +51,11 @@ func TestRowScan(t *testing.T) {
 		row := mockPool.QueryRow(ctx, sql)
 		wrappedRow := &Row{
 			Row: row,
+			ctx: ctx,
 			retry: func() pgx.Row {
 				return mockPool.QueryRow(ctx, sql)
 			},
+			policy: testRetryPolicy,
 		}
 
 		var result int
@@ -44,16 +64,18 @@ func TestRowScan(t *testing.T) {
 		assert.Equal(t, 1, result)
 	})
 
-	t.Run("retry on connection closed", func(t *testing.T) {
-		mockPool.ExpectQuery(sql).WillReturnError(errors.New(connClosed))
+	t.Run("retry on safe to retry error", func(t *testing.T) {
+		mockPool.ExpectQuery(sql).WillReturnError(safeToRetryError{})
 		mockPool.ExpectQuery(sql).WillReturnRows(pgxmock.NewRows([]string{"column"}).AddRow(1))
 
 		row := mockPool.QueryRow(ctx, sql)
 		wrappedRow := &Row{
 			Row: row,
+			ctx: ctx,
 			retry: func() pgx.Row {
 				return mockPool.QueryRow(ctx, sql)
 			},
+			policy: testRetryPolicy,
 		}
 
 		var result int
@@ -61,6 +83,26 @@ func TestRowScan(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 1, result)
 	})
+
+	t.Run("no retry on not retryable error", func(t *testing.T) {
+		mockPool.ExpectQuery(sql).WillReturnError(errors.New("syntax error"))
+
+		row := mockPool.QueryRow(ctx, sql)
+		wrappedRow := &Row{
+			Row: row,
+			ctx: ctx,
+			retry: func() pgx.Row {
+				return mockPool.QueryRow(ctx, sql)
+			},
+			policy: testRetryPolicy,
+		}
+
+		var result int
+		err := wrappedRow.Scan(&result)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
 func TestQueryRow(t *testing.T) {
@@ -86,108 +128,149 @@ func TestBegin(t *testing.T) {
 	})
 }
 
-func TestTxQuery(t *testing.T) {
+func TestWithTx(t *testing.T) {
 	mockPool, err := pgxmock.NewPool()
 	assert.NoError(t, err)
 	defer mockPool.Close()
 
 	ctx := context.Background()
-	sql := "SELECT 1"
+	sql := "INSERT INTO test"
 
-	t.Run("successful query", func(t *testing.T) {
+	t.Run("successful transaction", func(t *testing.T) {
 		mockPool.ExpectBegin()
-		mockPool.ExpectQuery(sql).WillReturnRows(pgxmock.NewRows([]string{"column"}).AddRow(1))
-
-		tx, err := mockPool.Begin(ctx)
-		assert.NoError(t, err)
-
-		wrappedTx := &Tx{
-			Tx: tx,
-		}
+		mockPool.ExpectExec(sql).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectCommit()
 
-		rows, err := wrappedTx.Query(ctx, sql)
+		calls := 0
+		err := WithTx(ctx, mockPool, testRetryPolicy, func(tx pgx.Tx) error {
+			calls++
+			_, err := tx.Exec(ctx, sql)
+			return err
+		})
 		assert.NoError(t, err)
-		assert.NotNil(t, rows)
-
-		rows.Close()
+		assert.Equal(t, 1, calls)
 	})
 
-	t.Run("retry on connection closed", func(t *testing.T) {
+	t.Run("retry whole transaction", func(t *testing.T) {
 		mockPool.ExpectBegin()
-		mockPool.ExpectQuery(sql).WillReturnError(errors.New(connClosed))
-		mockPool.ExpectQuery(sql).WillReturnRows(pgxmock.NewRows([]string{"column"}).AddRow(1))
-
-		tx, err := mockPool.Begin(ctx)
-		assert.NoError(t, err)
-
-		wrappedTx := &Tx{
-			Tx: tx,
-		}
+		mockPool.ExpectExec(sql).WillReturnError(&pgconn.PgError{Code: pgerrcode.SerializationFailure})
+		mockPool.ExpectRollback()
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(sql).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectCommit()
 
-		rows, err := wrappedTx.Query(ctx, sql)
+		calls := 0
+		err := WithTx(ctx, mockPool, testRetryPolicy, func(tx pgx.Tx) error {
+			calls++
+			_, err := tx.Exec(ctx, sql)
+			return err
+		})
 		assert.NoError(t, err)
-		assert.NotNil(t, rows)
-
-		rows.Close()
+		assert.Equal(t, 2, calls)
 	})
 
-	t.Run("retry exhausted", func(t *testing.T) {
+	t.Run("retry failed commit", func(t *testing.T) {
+		mockPool.ExpectBegin()
+		mockPool.ExpectCommit().WillReturnError(&pgconn.PgError{Code: pgerrcode.SerializationFailure})
+		mockPool.ExpectRollback()
 		mockPool.ExpectBegin()
-		mockPool.ExpectQuery(sql).WillReturnError(errors.New(connClosed))
+		mockPool.ExpectCommit()
 
-		tx, err := mockPool.Begin(ctx)
+		err := WithTx(ctx, mockPool, testRetryPolicy, func(pgx.Tx) error {
+			return nil
+		})
 		assert.NoError(t, err)
+	})
 
-		wrappedTx := &Tx{
-			Tx: tx,
-		}
-
-		rows, err := wrappedTx.Query(ctx, sql)
+	t.Run("no retry on not retryable error", func(t *testing.T) {
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(sql).WillReturnError(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+		mockPool.ExpectRollback()
+
+		calls := 0
+		err := WithTx(ctx, mockPool, testRetryPolicy, func(tx pgx.Tx) error {
+			calls++
+			_, err := tx.Exec(ctx, sql)
+			return err
+		})
 		assert.Error(t, err)
-		assert.Nil(t, rows)
+		assert.Equal(t, 1, calls)
+	})
 
-		if rows != nil {
-			rows.Close()
+	t.Run("attempts exhausted", func(t *testing.T) {
+		for range testRetryPolicy.MaxAttempts {
+			mockPool.ExpectBegin().WillReturnError(safeToRetryError{})
 		}
+
+		exhausted := retryMetrics.Get(metricExhausted)
+		err := WithTx(ctx, mockPool, testRetryPolicy, func(pgx.Tx) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, safeToRetryError{})
+		assert.NotEqual(t, exhausted, retryMetrics.Get(metricExhausted))
 	})
-}
 
-func TestBatchResultsExec(t *testing.T) {
-	mockPool, err := pgxmock.NewPool()
-	assert.NoError(t, err)
-	defer mockPool.Close()
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
 
-	ctx := context.Background()
-	batch := &pgx.Batch{
-		QueuedQueries: []*pgx.QueuedQuery{{SQL: "INSERT INTO test"}},
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		name     string
+		expected bool
+	}{
+		{name: "safe to retry", err: safeToRetryError{}, expected: true},
+		{name: "serialization failure", err: &pgconn.PgError{Code: pgerrcode.SerializationFailure}, expected: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: pgerrcode.DeadlockDetected}, expected: true},
+		{name: "connection exception", err: &pgconn.PgError{Code: pgerrcode.ConnectionFailure}, expected: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: pgerrcode.UniqueViolation}, expected: false},
+		{name: "no rows", err: pgx.ErrNoRows, expected: false},
+		{name: "canceled", err: errors.Join(safeToRetryError{}, context.Canceled), expected: false},
+		{name: "wrapped", err: fmt.Errorf("can not set link: %w", safeToRetryError{}), expected: true},
 	}
 
-	t.Run("successful exec", func(t *testing.T) {
-		mockPool.ExpectBegin()
-		mockPool.ExpectBatch().ExpectExec("INSERT INTO test").WillReturnResult(pgxmock.NewResult("INSERT", 1))
-		mockPool.ExpectCommit()
-
-		tx, err := mockPool.Begin(ctx)
-		assert.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isRetryable(tt.err))
+		})
+	}
+}
 
-		wrappedTx := &Tx{
-			Tx: tx,
-		}
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for range 100 {
+		assert.LessOrEqual(t, policy.backoff(1), 10*time.Millisecond)
+		assert.LessOrEqual(t, policy.backoff(3), 40*time.Millisecond)
+		assert.LessOrEqual(t, policy.backoff(64), 50*time.Millisecond)
+	}
+	assert.Zero(t, RetryPolicy{}.backoff(1))
 
-		batchResults := wrappedTx.SendBatch(ctx, batch)
-		wrappedBatchResults := &BatchResults{
-			BatchResults: batchResults,
-			retry: func() pgx.BatchResults {
-				return tx.SendBatch(ctx, batch)
-			},
-		}
+	assert.Equal(t, DefaultRetryPolicy(), RetryPolicy{}.withDefaults())
+}
 
-		commandTag, err := wrappedBatchResults.Exec()
-		assert.NoError(t, err)
-		assert.Equal(t, "INSERT 1", commandTag.String())
+func TestRetryPolicyDo(t *testing.T) {
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+
+		calls := 0
+		err := policy.do(ctx, metricQueries, func(int) error {
+			calls++
+			cancel()
+			return safeToRetryError{}
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
 
-		err = wrappedTx.Commit(ctx)
-		assert.NoError(t, err)
+	t.Run("zero policy", func(t *testing.T) {
+		calls := 0
+		err := RetryPolicy{}.do(context.Background(), metricQueries, func(int) error {
+			calls++
+			return safeToRetryError{}
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
 	})
 }
 
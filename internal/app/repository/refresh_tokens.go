@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a refresh token of user. Only hash of token is stored.
+// Token which is issued by rotation of another one shares its family,
+// zero UsedAt means that token is not rotated yet.
+type RefreshToken struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at,omitzero"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+	Hash      string    `json:"hash"`
+	FamilyID  string    `json:"family_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// RefreshTokenRepository is an interface of repositories which store refresh tokens.
+type RefreshTokenRepository interface {
+	// SetRefreshToken store new refresh token and remove expired tokens of its user.
+	SetRefreshToken(ctx context.Context, token RefreshToken) error
+	// UseRefreshToken mark refresh token as used and return its state before marking,
+	// so token which is used again can be detected by non-zero UsedAt.
+	UseRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+	// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+	RevokeRefreshTokens(ctx context.Context, familyID string) error
+}
+
+// memoryRefreshTokens is a storage of refresh tokens in memory.
+type memoryRefreshTokens struct {
+	m      *sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+// newMemoryRefreshTokens create new memoryRefreshTokens.
+func newMemoryRefreshTokens() *memoryRefreshTokens {
+	return &memoryRefreshTokens{
+		m:      &sync.Mutex{},
+		tokens: make(map[string]*RefreshToken),
+	}
+}
+
+// SetRefreshToken store new refresh token and remove expired tokens of its user.
+func (r *memoryRefreshTokens) SetRefreshToken(_ context.Context, token RefreshToken) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.purge(token.UserID, time.Now())
+	r.set(token)
+	return nil
+}
+
+// set store refresh token replacing token with the same hash.
+func (r *memoryRefreshTokens) set(token RefreshToken) {
+	r.tokens[token.Hash] = &token
+}
+
+// purge remove expired tokens of user.
+func (r *memoryRefreshTokens) purge(userID uuid.UUID, now time.Time) {
+	for hash, token := range r.tokens {
+		if token.UserID == userID && !token.ExpiresAt.After(now) {
+			delete(r.tokens, hash)
+		}
+	}
+}
+
+// UseRefreshToken mark refresh token as used and return its state before marking.
+func (r *memoryRefreshTokens) UseRefreshToken(_ context.Context, hash string) (*RefreshToken, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	token, ok := r.tokens[hash]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	result := *token
+	if token.UsedAt.IsZero() {
+		token.UsedAt = time.Now().UTC()
+	}
+	return &result, nil
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+func (r *memoryRefreshTokens) RevokeRefreshTokens(_ context.Context, familyID string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	now := time.Now().UTC()
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt.IsZero() {
+			token.RevokedAt = now
+		}
+	}
+	return nil
+}
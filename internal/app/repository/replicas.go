@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// Default values of DBConfig.
+const (
+	DefaultReplicaHealthCheckInterval = 5 * time.Second
+)
+
+// DBConfig is a set of parameters of database repository.
+// Zero values are replaced with default ones.
+// Reads of user are served by primary during read-your-writes window after
+// the last write of this user, read-your-writes is disabled if window is zero.
+type DBConfig struct {
+	ReplicaDSNs                []string
+	Retry                      RetryPolicy
+	ReplicaHealthCheckInterval time.Duration
+	ReadYourWritesWindow       time.Duration
+}
+
+// replica is a read-only database with its health state.
+type replica struct {
+	pool    IPool
+	healthy *atomic.Bool
+	host    string
+}
+
+// replicaSet routes reads between healthy replicas.
+type replicaSet struct {
+	logger   *zap.Logger
+	next     *atomic.Uint64
+	m        *sync.Mutex
+	writes   map[uuid.UUID]time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+	replicas []*replica
+	interval time.Duration
+	window   time.Duration
+}
+
+// newReplicaSet create new replicaSet and start health checking of replicas.
+func newReplicaSet(ctx context.Context, logger *zap.Logger, cfg DBConfig) (*replicaSet, error) {
+	replicas := make([]*replica, 0, len(cfg.ReplicaDSNs))
+	for _, dsn := range cfg.ReplicaDSNs {
+		pool, err := NewPool(ctx, dsn, cfg.Retry)
+		if err != nil {
+			for _, r := range replicas {
+				r.pool.Close()
+			}
+			return nil, fmt.Errorf("can not create new pool for replica: %w", err)
+		}
+		replicas = append(replicas, &replica{
+			pool:    pool,
+			healthy: &atomic.Bool{},
+			host:    replicaHost(dsn),
+		})
+	}
+
+	set := newReplicaSetOf(logger, replicas, cfg)
+	set.check(ctx)
+	go set.run()
+
+	return set, nil
+}
+
+// newReplicaSetOf create new replicaSet of provided replicas without health checking.
+func newReplicaSetOf(logger *zap.Logger, replicas []*replica, cfg DBConfig) *replicaSet {
+	if cfg.ReplicaHealthCheckInterval <= 0 {
+		cfg.ReplicaHealthCheckInterval = DefaultReplicaHealthCheckInterval
+	}
+
+	return &replicaSet{
+		logger:   logger,
+		next:     &atomic.Uint64{},
+		m:        &sync.Mutex{},
+		writes:   make(map[uuid.UUID]time.Time),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		replicas: replicas,
+		interval: cfg.ReplicaHealthCheckInterval,
+		window:   cfg.ReadYourWritesWindow,
+	}
+}
+
+// replicaHost return host of replica from DSN without credentials for logs.
+func replicaHost(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+	return parsed.Host
+}
+
+// run check health of replicas periodically until replica set is closed.
+func (r *replicaSet) run() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+			r.check(ctx)
+			cancel()
+			r.prune()
+		}
+	}
+}
+
+// check ping all replicas and update their health state.
+func (r *replicaSet) check(ctx context.Context) {
+	for _, replica := range r.replicas {
+		err := replica.pool.Ping(ctx)
+		if err != nil {
+			r.fail(replica, err)
+			continue
+		}
+		if !replica.healthy.Swap(true) {
+			r.logger.Info("Replica is healthy", zap.String("host", replica.host))
+		}
+	}
+}
+
+// fail mark replica as unhealthy until next successful health check.
+func (r *replicaSet) fail(replica *replica, err error) {
+	if replica.healthy.Swap(false) {
+		r.logger.Warn("Replica is unhealthy", zap.String("host", replica.host), zap.Error(err))
+	}
+}
+
+// isReplicaFailure check if error of query means that replica is unavailable.
+// Not found rows, context errors and errors of query which are returned by server
+// do not mark replica as unhealthy, only connection errors and shutdown of server do.
+func isReplicaFailure(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgerrcode.IsConnectionException(pgErr.Code) ||
+			(pgerrcode.IsOperatorIntervention(pgErr.Code) && pgErr.Code != pgerrcode.QueryCanceled)
+	}
+	return true
+}
+
+// pick return healthy replica in round robin order.
+// Return nil if there is no healthy replica or user has written within read-your-writes window.
+func (r *replicaSet) pick(userID *uuid.UUID) *replica {
+	if r == nil || len(r.replicas) == 0 {
+		return nil
+	}
+	if userID != nil && r.recent(*userID) {
+		return nil
+	}
+
+	start := r.next.Add(1) - 1
+	for i := range uint64(len(r.replicas)) {
+		replica := r.replicas[(start+i)%uint64(len(r.replicas))]
+		if replica.healthy.Load() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// wrote remember time of the last write of user for read-your-writes.
+func (r *replicaSet) wrote(userID uuid.UUID) {
+	if r == nil || r.window <= 0 {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.writes[userID] = time.Now()
+}
+
+// recent check if user has written within read-your-writes window.
+func (r *replicaSet) recent(userID uuid.UUID) bool {
+	if r.window <= 0 {
+		return false
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	last, ok := r.writes[userID]
+	return ok && time.Since(last) < r.window
+}
+
+// prune forget writes which are out of read-your-writes window.
+func (r *replicaSet) prune() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for userID, last := range r.writes {
+		if time.Since(last) >= r.window {
+			delete(r.writes, userID)
+		}
+	}
+}
+
+// close stop health checking and close connections with replicas.
+func (r *replicaSet) close() {
+	if r == nil {
+		return
+	}
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.stopped
+
+	for _, replica := range r.replicas {
+		replica.pool.Close()
+	}
+}
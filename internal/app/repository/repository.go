@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/RexArseny/url_shortener/internal/app/models"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -16,6 +18,13 @@ var (
 	ErrOriginalURLUniqueViolation  = errors.New("original url unique violation")
 	ErrReachedMaxGenerationRetries = errors.New("reached max generation retries")
 	ErrURLIsDeleted                = errors.New("url is deleted")
+	ErrURLIsDisabled               = errors.New("url is disabled by administrator")
+	ErrNotFound                    = errors.New("no original url by provided short url")
+	ErrUnavailable                 = errors.New("storage is unavailable")
+	ErrInvalidShortURL             = errors.New("short url is empty")
+	ErrNotSupported                = errors.New("operation is not supported by storage")
+	ErrAPIKeyNotFound              = errors.New("api key not found")
+	ErrRefreshTokenNotFound        = errors.New("refresh token not found")
 )
 
 // Repository is an interface of repositories which store URLs data.
@@ -39,7 +48,7 @@ type Repository interface {
 		batch []models.ShortenBatchRequest,
 		shortURLs [][]string,
 		userID uuid.UUID,
-	) ([]string, error)
+	) ([]LinkResult, error)
 	DeleteURLs(
 		ctx context.Context,
 		urls []string,
@@ -47,6 +56,28 @@ type Repository interface {
 	) error
 	Ping(ctx context.Context) error
 	Stats(ctx context.Context) (*models.Stats, error)
+	APIKeyRepository
+	RefreshTokenRepository
+	UserRepository
+	AdminRepository
+	AuditRepository
+}
+
+// DeletionQueue is an interface of repositories which delete URLs through durable deletion queue.
+type DeletionQueue interface {
+	DeleteURLsInDB(ctx context.Context, limit int) (int, error)
+}
+
+// StatusReporter is an interface of repositories which report their state in readiness check.
+type StatusReporter interface {
+	Status() map[string]any
+}
+
+// LinkResult is a result of storing of link of batch.
+// Status is one of models.BatchStatus values, ShortURL is empty if link is not stored.
+type LinkResult struct {
+	ShortURL string
+	Status   string
 }
 
 // Batch is a model for bates of URLs.
@@ -59,29 +90,69 @@ type Batch struct {
 func NewRepository(
 	ctx context.Context,
 	logger *zap.Logger,
-	fileStoragePath string,
-	databaseDSN string,
+	cfg *config.Config,
 ) (Repository, func() error, error) {
+	if cfg.DatabaseDSN != "" && cfg.AutoMigrate {
+		err := Migrate(cfg.DatabaseDSN)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not migrate database: %w", err)
+		}
+	}
+
 	switch {
-	case databaseDSN != "":
-		dbRepository, err := NewDBRepository(ctx, logger, databaseDSN)
+	case strings.HasPrefix(cfg.DatabaseDSN, SQLiteScheme):
+		sqliteRepository, err := NewSQLiteRepository(ctx, logger, cfg.DatabaseDSN)
 		if err != nil {
-			return nil, nil, fmt.Errorf("can not init db repository: %w", err)
+			return nil, nil, fmt.Errorf("can not init sqlite repository: %w", err)
 		}
-		return dbRepository,
-			func() error {
-				dbRepository.Close()
-				return nil
+		return sqliteRepository, sqliteRepository.Close, nil
+	case cfg.DatabaseDSN != "":
+		dbRepository, err := NewDBRepository(ctx, logger, cfg.DatabaseDSN, DBConfig{
+			ReplicaDSNs:                cfg.DatabaseReplicaDSNs,
+			ReplicaHealthCheckInterval: cfg.ReplicaCheckInterval.Duration,
+			ReadYourWritesWindow:       cfg.ReadYourWritesWindow.Duration,
+			Retry: RetryPolicy{
+				MaxAttempts: cfg.DBRetryAttempts,
+				BaseDelay:   cfg.DBRetryBaseDelay.Duration,
+				MaxDelay:    cfg.DBRetryMaxDelay.Duration,
 			},
-			nil
-	case fileStoragePath != "":
-		linksWithFile, err := NewLinksWithFile(fileStoragePath)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not init db repository: %w", err)
+		}
+		closeDBRepository := func() error {
+			dbRepository.Close()
+			return nil
+		}
+		if cfg.BreakerThreshold > 0 {
+			breaker := NewBreaker(logger.Named("breaker"), dbRepository, BreakerConfig{
+				Threshold:   cfg.BreakerThreshold,
+				OpenTimeout: cfg.BreakerOpenTimeout.Duration,
+				CacheSize:   cfg.BreakerCacheSize,
+			})
+			return breaker, closeDBRepository, nil
+		}
+		return dbRepository, closeDBRepository, nil
+	case cfg.BoltStoragePath != "":
+		boltRepository, err := NewBoltRepository(cfg.BoltStoragePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not init bolt repository: %w", err)
+		}
+		return boltRepository, boltRepository.Close, nil
+	case cfg.FileStoragePath != "":
+		linksWithFile, err := NewLinksWithFile(logger, cfg.FileStoragePath, FileConfig{
+			FsyncPolicy:     cfg.FileFsyncPolicy,
+			FsyncInterval:   cfg.FileFsyncInterval.Duration,
+			CompactInterval: cfg.FileCompactInterval.Duration,
+			Recovery:        cfg.FileRecovery,
+			AuditPath:       cfg.AuditStoragePath,
+		})
 		if err != nil {
 			return nil, nil, fmt.Errorf("can not init file repository: %w", err)
 		}
 		return linksWithFile, linksWithFile.Close, nil
 	default:
-		links := NewLinks()
+		links := NewShardedLinks()
 		return links, nil, nil
 	}
 }
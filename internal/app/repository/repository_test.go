@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -14,14 +16,41 @@ func TestNewRepository(t *testing.T) {
 	logger := zap.NewNop()
 
 	t.Run("database DSN provided", func(t *testing.T) {
-		repository, closer, err := NewRepository(ctx, logger, "", "invalid_dsn")
+		repository, closer, err := NewRepository(ctx, logger, &config.Config{DatabaseDSN: "invalid_dsn"})
 		assert.Error(t, err)
 		assert.Nil(t, repository)
 		assert.Nil(t, closer)
 	})
 
+	t.Run("sqlite DSN provided", func(t *testing.T) {
+		repository, closer, err := NewRepository(ctx, logger, &config.Config{
+			DatabaseDSN: SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db"),
+			AutoMigrate: true,
+		})
+		assert.NoError(t, err)
+		assert.IsType(t, &SQLiteRepository{}, repository)
+		assert.NotNil(t, closer)
+
+		err = closer()
+		assert.NoError(t, err)
+	})
+
+	t.Run("bolt storage path provided", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "shorturls.db")
+		repository, closer, err := NewRepository(ctx, logger, &config.Config{
+			BoltStoragePath: path,
+			FileStoragePath: "valid_path",
+		})
+		assert.NoError(t, err)
+		assert.IsType(t, &BoltRepository{}, repository)
+		assert.NotNil(t, closer)
+
+		err = closer()
+		assert.NoError(t, err)
+	})
+
 	t.Run("file storage path provided", func(t *testing.T) {
-		repository, closer, err := NewRepository(ctx, logger, "valid_path", "")
+		repository, closer, err := NewRepository(ctx, logger, &config.Config{FileStoragePath: "valid_path"})
 		assert.NoError(t, err)
 		assert.NotNil(t, repository)
 		assert.NotNil(t, closer)
@@ -34,7 +63,7 @@ func TestNewRepository(t *testing.T) {
 	})
 
 	t.Run("no database DSN or file storage path provided", func(t *testing.T) {
-		repository, closer, err := NewRepository(ctx, logger, "", "")
+		repository, closer, err := NewRepository(ctx, logger, &config.Config{})
 		assert.NoError(t, err)
 		assert.NotNil(t, repository)
 		assert.Nil(t, closer)
@@ -0,0 +1,576 @@
+package repository
+
+import (
+	"context"
+	"hash/maphash"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+)
+
+// Amount of shards of ShardedLinks indexes.
+const linksShards = 64
+
+// ShardedLinks is a repository which stores data in memory with low lock contention.
+// Redirects read short URLs index without locks, original URLs and users indexes
+// are split into shards which are locked independently.
+type ShardedLinks struct {
+	*memoryAPIKeys
+	*memoryRefreshTokens
+	*memoryIdentities
+	*memoryAudit
+	shortURLs    *sync.Map
+	count        *atomic.Int64
+	originalURLs [linksShards]*originalURLsShard
+	users        [linksShards]*usersShard
+	seed         maphash.Seed
+}
+
+// shardedLink is a link which is stored in ShardedLinks.
+// Link becomes visible for readers only after it is committed.
+type shardedLink struct {
+	createdAt time.Time
+	original  string
+	committed atomic.Bool
+	deleted   atomic.Bool
+	disabled  atomic.Bool
+	userID    uuid.UUID
+}
+
+// originalURLsShard is a shard of short URLs by original URLs.
+type originalURLsShard struct {
+	m     *sync.Mutex
+	links map[string]string
+}
+
+// usersShard is a shard of short URLs by users.
+type usersShard struct {
+	m     *sync.RWMutex
+	links map[uuid.UUID][]string
+}
+
+// NewShardedLinks create new ShardedLinks.
+func NewShardedLinks() *ShardedLinks {
+	links := &ShardedLinks{
+		memoryAPIKeys:       newMemoryAPIKeys(),
+		memoryRefreshTokens: newMemoryRefreshTokens(),
+		memoryIdentities:    newMemoryIdentities(),
+		memoryAudit:         newMemoryAudit(),
+		shortURLs:           &sync.Map{},
+		count:               &atomic.Int64{},
+		seed:                maphash.MakeSeed(),
+	}
+	for i := range linksShards {
+		links.originalURLs[i] = &originalURLsShard{
+			m:     &sync.Mutex{},
+			links: make(map[string]string),
+		}
+		links.users[i] = &usersShard{
+			m:     &sync.RWMutex{},
+			links: make(map[uuid.UUID][]string),
+		}
+	}
+	return links
+}
+
+// GetOriginalURL return original URL by short URL.
+func (l *ShardedLinks) GetOriginalURL(_ context.Context, shortLink string) (*string, error) {
+	link, ok := l.load(shortLink)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if link.deleted.Load() {
+		return nil, ErrURLIsDeleted
+	}
+	if link.disabled.Load() {
+		return nil, ErrURLIsDisabled
+	}
+	return &link.original, nil
+}
+
+// SetLink add short URL if such does not exist already.
+func (l *ShardedLinks) SetLink(
+	_ context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	shard := l.originalURLsShard(originalURL)
+	shard.m.Lock()
+	defer shard.m.Unlock()
+	if shortLink, ok := shard.links[originalURL]; ok {
+		return &shortLink, ErrOriginalURLUniqueViolation
+	}
+
+	for _, shortURL := range shortURLs {
+		link := newShardedLink(originalURL, userID)
+		if !l.reserve(shortURL, link) {
+			continue
+		}
+		shard.links[originalURL] = shortURL
+		l.commit(shortURL, link)
+
+		return &shortURL, nil
+	}
+	return nil, ErrReachedMaxGenerationRetries
+}
+
+// SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
+func (l *ShardedLinks) SetLinks(
+	_ context.Context,
+	batch []models.ShortenBatchRequest,
+	shortURLs [][]string,
+	userID uuid.UUID,
+) ([]LinkResult, error) {
+	result := make([]LinkResult, len(batch))
+	for i := range batch {
+		_, err := url.ParseRequestURI(batch[i].OriginalURL)
+		if err != nil {
+			result[i].Status = models.BatchStatusInvalid
+		}
+	}
+
+	shards := l.lockOriginalURLsShards(batch)
+	defer func() {
+		for _, shard := range shards {
+			shard.m.Unlock()
+		}
+	}()
+
+	reserved := make(map[string]*shardedLink)
+	planned := make(map[string]string)
+	for i := range batch {
+		if result[i].Status == models.BatchStatusInvalid {
+			continue
+		}
+
+		shortLink, ok := l.originalURLsShard(batch[i].OriginalURL).links[batch[i].OriginalURL]
+		if !ok {
+			shortLink, ok = planned[batch[i].OriginalURL]
+		}
+		if ok {
+			result[i] = LinkResult{ShortURL: shortLink, Status: models.BatchStatusExisting}
+			continue
+		}
+
+		var generated bool
+		var shortURL string
+		link := newShardedLink(batch[i].OriginalURL, userID)
+		for _, shortURL = range shortURLs[i] {
+			if l.reserve(shortURL, link) {
+				generated = true
+				break
+			}
+		}
+		if !generated {
+			result[i].Status = models.BatchStatusError
+			continue
+		}
+
+		planned[batch[i].OriginalURL] = shortURL
+		reserved[shortURL] = link
+		result[i] = LinkResult{ShortURL: shortURL, Status: models.BatchStatusCreated}
+	}
+
+	for originalURL, shortURL := range planned {
+		l.originalURLsShard(originalURL).links[originalURL] = shortURL
+	}
+	for i := range result {
+		if result[i].Status != models.BatchStatusCreated {
+			continue
+		}
+		l.commit(result[i].ShortURL, reserved[result[i].ShortURL])
+	}
+
+	return result, nil
+}
+
+// GetShortLinksOfUser return URLs of user if such exist.
+func (l *ShardedLinks) GetShortLinksOfUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.ShortenOfUserResponse, error) {
+	shard := l.usersShard(userID)
+	shard.m.RLock()
+	shortURLs := slices.Clone(shard.links[userID])
+	shard.m.RUnlock()
+
+	var urls []models.ShortenOfUserResponse
+	for _, shortURL := range shortURLs {
+		link, ok := l.load(shortURL)
+		if !ok {
+			continue
+		}
+		urls = append(urls, models.ShortenOfUserResponse{
+			ShortURL:    shortURL,
+			OriginalURL: link.original,
+		})
+	}
+
+	return urls, nil
+}
+
+// DeleteURLs delete URLs.
+// Shard of original URL of every link is locked, so deletion is not lost by concurrent move of link.
+func (l *ShardedLinks) DeleteURLs(_ context.Context, urls []string, userID uuid.UUID) error {
+	for _, shortURL := range urls {
+		l.delete(shortURL, userID)
+	}
+
+	return nil
+}
+
+// delete mark link as deleted if it belongs to user.
+func (l *ShardedLinks) delete(shortURL string, userID uuid.UUID) {
+	link, ok := l.load(shortURL)
+	if !ok {
+		return
+	}
+	shard := l.originalURLsShard(link.original)
+	shard.m.Lock()
+	defer shard.m.Unlock()
+	link, ok = l.load(shortURL)
+	if ok && link.userID == userID {
+		link.deleted.Store(true)
+	}
+}
+
+// MergeUser move links of anonymous user into another user.
+// Links of user with identity are not moved. Links are immutable for readers,
+// so every moved link is replaced by its copy with new user.
+func (l *ShardedLinks) MergeUser(_ context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to || l.memoryIdentities.has(from) {
+		return nil
+	}
+
+	fromShard := l.usersShard(from)
+	fromShard.m.Lock()
+	shortURLs := fromShard.links[from]
+	delete(fromShard.links, from)
+	fromShard.m.Unlock()
+
+	merged := make([]string, 0, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		if _, ok := l.move(shortURL, to); ok {
+			merged = append(merged, shortURL)
+		}
+	}
+
+	toShard := l.usersShard(to)
+	toShard.m.Lock()
+	toShard.links[to] = append(toShard.links[to], merged...)
+	toShard.m.Unlock()
+
+	return nil
+}
+
+// SearchLinks return links which match filter in order of creation.
+func (l *ShardedLinks) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	records, err := exportRecords(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return searchRecords(records, filter), nil
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+// Shard of original URL of link is locked, so flag is not lost by concurrent move of link.
+func (l *ShardedLinks) SetLinkDisabled(_ context.Context, shortURL string, disabled bool) (*Record, error) {
+	link, ok := l.load(shortURL)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	shard := l.originalURLsShard(link.original)
+	shard.m.Lock()
+	defer shard.m.Unlock()
+	link, ok = l.load(shortURL)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := link.record(shortURL)
+	record.Disabled = link.disabled.Swap(disabled)
+	return &record, nil
+}
+
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (l *ShardedLinks) GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error) {
+	records, err := exportRecords(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return usersOfRecords(records, limit, offset), nil
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+// Links are immutable for readers, so every moved link is replaced by its copy with new user.
+func (l *ShardedLinks) TransferLinks(_ context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	var records []Record
+	for _, shortURL := range shortURLs {
+		link, ok := l.move(shortURL, to)
+		if !ok || link.userID == to {
+			continue
+		}
+		records = append(records, link.record(shortURL))
+
+		fromShard := l.usersShard(link.userID)
+		fromShard.m.Lock()
+		fromShard.links[link.userID] = slices.DeleteFunc(fromShard.links[link.userID], func(userShortURL string) bool {
+			return userShortURL == shortURL
+		})
+		if len(fromShard.links[link.userID]) == 0 {
+			delete(fromShard.links, link.userID)
+		}
+		fromShard.m.Unlock()
+
+		toShard := l.usersShard(to)
+		toShard.m.Lock()
+		toShard.links[to] = append(toShard.links[to], shortURL)
+		toShard.m.Unlock()
+	}
+	return records, nil
+}
+
+// move replace committed link by its copy with another user without changing users index.
+// Return replaced link.
+func (l *ShardedLinks) move(shortURL string, to uuid.UUID) (*shardedLink, bool) {
+	link, ok := l.load(shortURL)
+	if !ok {
+		return nil, false
+	}
+	shard := l.originalURLsShard(link.original)
+	shard.m.Lock()
+	defer shard.m.Unlock()
+	link, ok = l.load(shortURL)
+	if !ok {
+		return nil, false
+	}
+	if link.userID == to {
+		return link, true
+	}
+
+	moved := newShardedLink(link.original, to)
+	moved.createdAt = link.createdAt
+	moved.deleted.Store(link.deleted.Load())
+	moved.disabled.Store(link.disabled.Load())
+	moved.committed.Store(true)
+	l.shortURLs.Store(shortURL, moved)
+	return link, true
+}
+
+// Ping return info about connection.
+func (l *ShardedLinks) Ping(_ context.Context) error {
+	return nil
+}
+
+// Stats return statistic of shortened urls and users in service.
+func (l *ShardedLinks) Stats(_ context.Context) (*models.Stats, error) {
+	var users int
+	for _, shard := range l.users {
+		shard.m.RLock()
+		users += len(shard.links)
+		shard.m.RUnlock()
+	}
+
+	return &models.Stats{
+		URLs:  int(l.count.Load()),
+		Users: users,
+	}, nil
+}
+
+// Export call fn for every link in order of creation.
+func (l *ShardedLinks) Export(ctx context.Context, fn func(Record) error) error {
+	var records []Record
+	l.shortURLs.Range(func(key, _ any) bool {
+		shortURL, _ := key.(string)
+		if link, ok := l.load(shortURL); ok {
+			records = append(records, link.record(shortURL))
+		}
+		return true
+	})
+
+	sortRecords(records)
+	for i := range records {
+		if ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck // error of context
+		}
+		err := fn(records[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup return existing links which have short URL or original URL of one of records.
+func (l *ShardedLinks) Lookup(_ context.Context, records []Record) ([]Record, error) {
+	shortURLs, originalURLs := lookupKeys(records)
+	for originalURL := range originalURLs {
+		shard := l.originalURLsShard(originalURL)
+		shard.m.Lock()
+		shortURL, ok := shard.links[originalURL]
+		shard.m.Unlock()
+		if ok {
+			shortURLs[shortURL] = struct{}{}
+		}
+	}
+
+	var result []Record
+	for shortURL := range shortURLs {
+		if link, ok := l.load(shortURL); ok {
+			result = append(result, link.record(shortURL))
+		}
+	}
+	return result, nil
+}
+
+// Import store records as is replacing links with the same short URL or original URL.
+// All shards of original URLs index are locked during import, so other writers wait for it.
+func (l *ShardedLinks) Import(_ context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range l.originalURLs {
+		shard.m.Lock()
+	}
+	defer func() {
+		for _, shard := range l.originalURLs {
+			shard.m.Unlock()
+		}
+	}()
+
+	for i := range records {
+		shard := l.originalURLsShard(records[i].OriginalURL)
+		if shortURL, ok := shard.links[records[i].OriginalURL]; ok {
+			l.drop(shortURL)
+		}
+		l.drop(records[i].ShortURL)
+
+		link := newShardedLink(records[i].OriginalURL, records[i].UserID)
+		link.createdAt = records[i].CreatedAt
+		link.deleted.Store(records[i].Deleted)
+		link.disabled.Store(records[i].Disabled)
+		l.shortURLs.Store(records[i].ShortURL, link)
+		shard.links[records[i].OriginalURL] = records[i].ShortURL
+		l.commit(records[i].ShortURL, link)
+	}
+
+	return nil
+}
+
+// drop delete committed link by short URL from all indexes.
+// Shard of original URL of link has to be locked.
+func (l *ShardedLinks) drop(shortURL string) {
+	link, ok := l.load(shortURL)
+	if !ok {
+		return
+	}
+	l.shortURLs.Delete(shortURL)
+	delete(l.originalURLsShard(link.original).links, link.original)
+
+	shard := l.usersShard(link.userID)
+	shard.m.Lock()
+	shard.links[link.userID] = slices.DeleteFunc(shard.links[link.userID], func(userShortURL string) bool {
+		return userShortURL == shortURL
+	})
+	if len(shard.links[link.userID]) == 0 {
+		delete(shard.links, link.userID)
+	}
+	shard.m.Unlock()
+
+	l.count.Add(-1)
+}
+
+// record return link as record of export.
+func (s *shardedLink) record(shortURL string) Record {
+	return Record{
+		CreatedAt:   s.createdAt,
+		ShortURL:    shortURL,
+		OriginalURL: s.original,
+		UserID:      s.userID,
+		Deleted:     s.deleted.Load(),
+		Disabled:    s.disabled.Load(),
+	}
+}
+
+// newShardedLink create new not committed link.
+func newShardedLink(originalURL string, userID uuid.UUID) *shardedLink {
+	return &shardedLink{
+		createdAt: time.Now().UTC(),
+		original:  originalURL,
+		userID:    userID,
+	}
+}
+
+// load return committed link by short URL.
+func (l *ShardedLinks) load(shortURL string) (*shardedLink, bool) {
+	value, ok := l.shortURLs.Load(shortURL)
+	if !ok {
+		return nil, false
+	}
+	link, ok := value.(*shardedLink)
+	if !ok || !link.committed.Load() {
+		return nil, false
+	}
+	return link, true
+}
+
+// reserve store not committed link by short URL if such short URL is free.
+func (l *ShardedLinks) reserve(shortURL string, link *shardedLink) bool {
+	_, loaded := l.shortURLs.LoadOrStore(shortURL, link)
+	return !loaded
+}
+
+// commit make reserved link visible and add it to users index.
+func (l *ShardedLinks) commit(shortURL string, link *shardedLink) {
+	shard := l.usersShard(link.userID)
+	shard.m.Lock()
+	shard.links[link.userID] = append(shard.links[link.userID], shortURL)
+	shard.m.Unlock()
+
+	l.count.Add(1)
+	link.committed.Store(true)
+}
+
+// originalURLsShard return shard of original URLs index which contains original URL.
+func (l *ShardedLinks) originalURLsShard(originalURL string) *originalURLsShard {
+	return l.originalURLs[maphash.String(l.seed, originalURL)%linksShards]
+}
+
+// usersShard return shard of users index which contains user.
+func (l *ShardedLinks) usersShard(userID uuid.UUID) *usersShard {
+	return l.users[maphash.Bytes(l.seed, userID[:])%linksShards]
+}
+
+// lockOriginalURLsShards lock shards of original URLs of batch in order of their indexes.
+// Return locked shards.
+func (l *ShardedLinks) lockOriginalURLsShards(batch []models.ShortenBatchRequest) []*originalURLsShard {
+	var indexes []uint64
+	for i := range batch {
+		index := maphash.String(l.seed, batch[i].OriginalURL) % linksShards
+		if !slices.Contains(indexes, index) {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.Sort(indexes)
+
+	shards := make([]*originalURLsShard, 0, len(indexes))
+	for _, index := range indexes {
+		l.originalURLs[index].m.Lock()
+		shards = append(shards, l.originalURLs[index])
+	}
+	return shards
+}
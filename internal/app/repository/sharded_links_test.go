@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedLinksSetLinksPerItem(t *testing.T) {
+	links := NewShardedLinks()
+	userID := uuid.New()
+
+	_, err := links.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	batch := []models.ShortenBatchRequest{
+		{OriginalURL: "http://another.com"},
+		{OriginalURL: "http://third.com"},
+	}
+	result, err := links.SetLinks(context.Background(), batch, [][]string{{"def456"}, {"abc123"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "def456", Status: models.BatchStatusCreated},
+		{Status: models.BatchStatusError},
+	}, result)
+
+	_, ok := links.originalURLsShard("http://third.com").links["http://third.com"]
+	assert.False(t, ok)
+	originalURL, err := links.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com", *originalURL)
+
+	result, err = links.SetLinks(context.Background(), batch, [][]string{{"jkl012"}, {"ghi789"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "def456", Status: models.BatchStatusExisting},
+		{ShortURL: "ghi789", Status: models.BatchStatusCreated},
+	}, result)
+}
+
+func TestShardedLinksReservedLinkIsNotVisible(t *testing.T) {
+	links := NewShardedLinks()
+	userID := uuid.New()
+
+	assert.True(t, links.reserve("abc123", newShardedLink("http://example.com", userID)))
+
+	_, err := links.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrNotFound)
+	result, err := links.SetLink(context.Background(), "http://another.com", []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", *result)
+
+	stats, err := links.Stats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Stats{URLs: 1, Users: 1}, stats)
+}
+
+func TestShardedLinksGetShortLinksOfUserOrder(t *testing.T) {
+	links := NewShardedLinks()
+	userID := uuid.New()
+
+	var expected []models.ShortenOfUserResponse
+	for i := range 10 {
+		shortURL := strconv.Itoa(i)
+		originalURL := fmt.Sprintf("http://example.com/%d", i)
+		_, err := links.SetLink(context.Background(), originalURL, []string{shortURL}, userID)
+		assert.NoError(t, err)
+		expected = append(expected, models.ShortenOfUserResponse{ShortURL: shortURL, OriginalURL: originalURL})
+	}
+
+	urls, err := links.GetShortLinksOfUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, urls)
+}
+
+// benchmarkParallel measure throughput of repository under redirects mixed with batch inserts.
+// Every writeEvery operation is a batch insert of ten links, others are redirects.
+// Inserts are disabled if writeEvery is zero.
+func benchmarkParallel(b *testing.B, repo Repository, writeEvery int) {
+	b.Helper()
+	const preloaded = 10000
+	userID := uuid.New()
+	for i := range preloaded {
+		_, err := repo.SetLink(
+			context.Background(),
+			fmt.Sprintf("http://example.com/%d", i),
+			[]string{strconv.Itoa(i)},
+			userID,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	keys := make([]string, preloaded)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	counter := &atomic.Int64{}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			i++
+			if writeEvery == 0 || i%writeEvery != 0 {
+				_, err := repo.GetOriginalURL(context.Background(), keys[i%preloaded])
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				continue
+			}
+
+			batch := make([]models.ShortenBatchRequest, 0, 10)
+			shortURLs := make([][]string, 0, 10)
+			for range 10 {
+				id := strconv.FormatInt(counter.Add(1), 10)
+				batch = append(batch, models.ShortenBatchRequest{OriginalURL: "http://batch.com/" + id})
+				shortURLs = append(shortURLs, []string{"batch" + id})
+			}
+			_, err := repo.SetLinks(context.Background(), batch, shortURLs, userID)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkLinksParallel(b *testing.B) {
+	b.Run("redirects", func(b *testing.B) {
+		benchmarkParallel(b, NewLinks(), 0)
+	})
+	b.Run("mixed", func(b *testing.B) {
+		benchmarkParallel(b, NewLinks(), 10)
+	})
+}
+
+func BenchmarkShardedLinksParallel(b *testing.B) {
+	b.Run("redirects", func(b *testing.B) {
+		benchmarkParallel(b, NewShardedLinks(), 0)
+	})
+	b.Run("mixed", func(b *testing.B) {
+		benchmarkParallel(b, NewShardedLinks(), 10)
+	})
+}
+
+func TestShardedLinksDeleteDuringTransfer(t *testing.T) {
+	links := NewShardedLinks()
+	ctx := context.Background()
+	userID := uuid.New()
+	anotherUserID := uuid.New()
+
+	shortURLs := make([]string, 1000)
+	for i := range shortURLs {
+		shortURLs[i] = "short" + strconv.Itoa(i)
+		_, err := links.SetLink(ctx, fmt.Sprintf("http://example%d.com", i), []string{shortURLs[i]}, userID)
+		assert.NoError(t, err)
+	}
+
+	records := make([]Record, len(shortURLs))
+	wg := &sync.WaitGroup{}
+	for i, shortURL := range shortURLs {
+		start := make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			transferred, err := links.TransferLinks(ctx, []string{shortURL}, anotherUserID)
+			assert.NoError(t, err)
+			if assert.Len(t, transferred, 1) {
+				records[i] = transferred[0]
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			err := links.DeleteURLs(ctx, []string{shortURL}, userID)
+			assert.NoError(t, err)
+		}()
+		close(start)
+	}
+	wg.Wait()
+
+	assert.Len(t, records, len(shortURLs))
+	for _, record := range records {
+		_, err := links.GetOriginalURL(ctx, record.ShortURL)
+		assert.Equal(t, record.Deleted, errors.Is(err, ErrURLIsDeleted), record.ShortURL)
+	}
+}
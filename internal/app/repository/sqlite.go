@@ -0,0 +1,1027 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteScheme is a scheme of database DSN which selects SQLite repository.
+const SQLiteScheme = "sqlite://"
+
+// SQLiteRepository is a repository which stores data in SQLite database.
+// SQLite has single writer, so deletion queue is drained without row locks.
+type SQLiteRepository struct {
+	logger *zap.Logger
+	db     *sql.DB
+}
+
+// NewSQLiteRepository create new SQLiteRepository.
+func NewSQLiteRepository(ctx context.Context, logger *zap.Logger, connString string) (*SQLiteRepository, error) {
+	path, err := sqlitePath(connString)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("can not open SQLite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("can not ping SQLite database: %w", err), db.Close())
+	}
+
+	return &SQLiteRepository{
+		logger: logger,
+		db:     db,
+	}, nil
+}
+
+// sqlitePath return path to database file from DSN without parameters of migrations.
+func sqlitePath(connString string) (string, error) {
+	dsn, err := url.Parse(connString)
+	if err != nil {
+		return "", fmt.Errorf("can not parse SQLite DSN: %w", err)
+	}
+	return strings.Replace(migrate.FilterCustomQuery(dsn).String(), SQLiteScheme, "", 1), nil
+}
+
+// GetOriginalURL return original URL by short URL.
+func (s *SQLiteRepository) GetOriginalURL(ctx context.Context, shortLink string) (*string, error) {
+	var originalURL string
+	var deleted bool
+	var disabled bool
+	err := s.db.QueryRowContext(ctx, `SELECT original_url, deleted, disabled
+									FROM urls WHERE short_url = ?`, shortLink).Scan(&originalURL, &deleted, &disabled)
+	if err != nil {
+		return nil, fmt.Errorf("can not get original url: %w", err)
+	}
+	if deleted {
+		return nil, ErrURLIsDeleted
+	}
+	if disabled {
+		return nil, ErrURLIsDisabled
+	}
+	return &originalURL, nil
+}
+
+// SetLink add short URL if such does not exist already.
+func (s *SQLiteRepository) SetLink(
+	ctx context.Context,
+	originalURL string,
+	shortURLs []string,
+	userID uuid.UUID,
+) (*string, error) {
+	_, err := url.ParseRequestURI(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	for _, shortURL := range shortURLs {
+		var link string
+		err = s.db.QueryRowContext(ctx, `INSERT INTO urls (short_url, original_url, user_id, created_at)
+										VALUES (?, ?, ?, ?)
+										ON CONFLICT (original_url)
+										DO UPDATE SET original_url = excluded.original_url
+										RETURNING short_url`, shortURL, originalURL, userID.String(), time.Now().UTC()).
+			Scan(&link)
+		if err != nil {
+			if isShortURLUniqueViolation(err) {
+				continue
+			}
+			return nil, fmt.Errorf("can not set link: %w", err)
+		}
+		if link != shortURL {
+			return &link, ErrOriginalURLUniqueViolation
+		}
+
+		return &shortURL, nil
+	}
+	return nil, ErrReachedMaxGenerationRetries
+}
+
+// isShortURLUniqueViolation check if error is a violation of short URL uniqueness.
+func isShortURLUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+		strings.Contains(sqliteErr.Error(), "urls.short_url")
+}
+
+// SetLinks add short URLs if such do not exist already.
+// Result of every link is reported by its status, invalid links and links
+// which short URL can not be generated for are skipped.
+func (s *SQLiteRepository) SetLinks(
+	ctx context.Context,
+	batch []models.ShortenBatchRequest,
+	shortURLs [][]string,
+	userID uuid.UUID,
+) ([]LinkResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	result := make([]LinkResult, 0, len(batch))
+	createdAt := time.Now().UTC()
+	for i := range batch {
+		_, err = url.ParseRequestURI(batch[i].OriginalURL)
+		if err != nil {
+			result = append(result, LinkResult{Status: models.BatchStatusInvalid})
+			continue
+		}
+
+		var shortLink string
+		err = tx.QueryRowContext(ctx, "SELECT short_url FROM urls WHERE original_url = ?", batch[i].OriginalURL).
+			Scan(&shortLink)
+		if err == nil {
+			result = append(result, LinkResult{ShortURL: shortLink, Status: models.BatchStatusExisting})
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("can not get original url: %w", err)
+		}
+
+		var generated bool
+		var shortURL string
+		for _, shortURL = range shortURLs[i] {
+			res, err := tx.ExecContext(ctx, `INSERT INTO urls (short_url, original_url, user_id, created_at)
+											VALUES (?, ?, ?, ?)
+											ON CONFLICT (short_url)
+											DO NOTHING`, shortURL, batch[i].OriginalURL, userID.String(), createdAt)
+			if err != nil {
+				return nil, fmt.Errorf("can not set link: %w", err)
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("can not get affected rows: %w", err)
+			}
+			if affected != 0 {
+				generated = true
+				break
+			}
+		}
+
+		if !generated {
+			result = append(result, LinkResult{Status: models.BatchStatusError})
+			continue
+		}
+		result = append(result, LinkResult{ShortURL: shortURL, Status: models.BatchStatusCreated})
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("can not commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetShortLinksOfUser return URLs of user if such exist.
+func (s *SQLiteRepository) GetShortLinksOfUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.ShortenOfUserResponse, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT short_url, original_url FROM urls WHERE user_id = ?", userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("can not get urls of user: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var urls []models.ShortenOfUserResponse
+	for rows.Next() {
+		var shortURL string
+		var originalURL string
+		err = rows.Scan(
+			&shortURL,
+			&originalURL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+
+		urls = append(urls, models.ShortenOfUserResponse{
+			ShortURL:    shortURL,
+			OriginalURL: originalURL,
+		})
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return urls, nil
+}
+
+// DeleteURLs add URLs to deletion queue.
+func (s *SQLiteRepository) DeleteURLs(ctx context.Context, urls []string, userID uuid.UUID) error {
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("can not marshal urls: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO urls_for_delete (urls, user_id) VALUES (?, ?)",
+		string(data), userID.String())
+	if err != nil {
+		return fmt.Errorf("can not add urls for delete: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteURLsInDB get and delete URLs from deletion queue.
+// Up to limit entries are processed in one transaction.
+// Return amount of processed deletion queue entries.
+func (s *SQLiteRepository) DeleteURLsInDB(ctx context.Context, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, urls, user_id FROM urls_for_delete ORDER BY id LIMIT ?", limit)
+	if err != nil {
+		return 0, fmt.Errorf("can not get urls for delete: %w", err)
+	}
+
+	var ids []int
+	var userIDs []string
+	urlsOfUsers := make(map[string][]string)
+	for rows.Next() {
+		var id int
+		var data []byte
+		var userID string
+		err = rows.Scan(&id, &data, &userID)
+		if err != nil {
+			return 0, errors.Join(fmt.Errorf("can not read row: %w", err), rows.Close())
+		}
+
+		var urls []string
+		err = json.Unmarshal(data, &urls)
+		if err != nil {
+			return 0, errors.Join(fmt.Errorf("can not unmarshal urls: %w", err), rows.Close())
+		}
+
+		ids = append(ids, id)
+		if _, ok := urlsOfUsers[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+		urlsOfUsers[userID] = append(urlsOfUsers[userID], urls...)
+	}
+	if rows.Err() != nil {
+		return 0, errors.Join(fmt.Errorf("can not read rows: %w", rows.Err()), rows.Close())
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("can not close rows: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, userID := range userIDs {
+		data, err := json.Marshal(urlsOfUsers[userID])
+		if err != nil {
+			return 0, fmt.Errorf("can not marshal urls: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE urls SET deleted = true
+									WHERE user_id = ? AND short_url IN (SELECT value FROM json_each(?))`, userID, string(data))
+		if err != nil {
+			return 0, fmt.Errorf("can not delete urls: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return 0, fmt.Errorf("can not marshal ids: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM urls_for_delete WHERE id IN (SELECT value FROM json_each(?))", string(data))
+	if err != nil {
+		return 0, fmt.Errorf("can not clear urls for delete: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("can not commit transaction: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// rollback rollback transaction if it has not been committed.
+func (s *SQLiteRepository) rollback(tx *sql.Tx) {
+	err := tx.Rollback()
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		s.logger.Error("Can not rollback transaction", zap.Error(err))
+	}
+}
+
+// SetAPIKey store new API key.
+func (s *SQLiteRepository) SetAPIKey(ctx context.Context, key APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("can not marshal scopes: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO api_keys (id, hash, user_id, name, scopes, created_at)
+								VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Hash, key.UserID.String(), key.Name, string(scopes), key.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("can not set api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey return API key by hash including revoked one.
+func (s *SQLiteRepository) GetAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, hash, user_id, name, scopes, created_at, revoked_at
+										FROM api_keys WHERE hash = ?`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("can not get api key: %w", err)
+	}
+	keys, err := s.scanAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &keys[0], nil
+}
+
+// GetAPIKeysOfUser return API keys of user in order of creation.
+func (s *SQLiteRepository) GetAPIKeysOfUser(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, hash, user_id, name, scopes, created_at, revoked_at
+										FROM api_keys WHERE user_id = ? ORDER BY created_at, id`, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("can not get api keys of user: %w", err)
+	}
+	return s.scanAPIKeys(rows)
+}
+
+// scanAPIKeys read API keys from rows and close them.
+func (s *SQLiteRepository) scanAPIKeys(rows *sql.Rows) ([]APIKey, error) {
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		var userID string
+		var scopes string
+		var revokedAt sql.NullTime
+		err := rows.Scan(&key.ID, &key.Hash, &userID, &key.Name, &scopes, &key.CreatedAt, &revokedAt)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+		key.UserID, err = uuid.Parse(userID)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse user id: %w", err)
+		}
+		err = json.Unmarshal([]byte(scopes), &key.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("can not unmarshal scopes: %w", err)
+		}
+		key.CreatedAt = key.CreatedAt.UTC()
+		if revokedAt.Valid {
+			key.RevokedAt = revokedAt.Time.UTC()
+		}
+		keys = append(keys, key)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey mark API key of user as revoked.
+func (s *SQLiteRepository) RevokeAPIKey(ctx context.Context, id string, userID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?)
+										WHERE id = ? AND user_id = ?`, time.Now().UTC(), id, userID.String())
+	if err != nil {
+		return fmt.Errorf("can not revoke api key: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can not get amount of revoked api keys: %w", err)
+	}
+	if count == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// SetRefreshToken store new refresh token and remove expired tokens of its user.
+func (s *SQLiteRepository) SetRefreshToken(ctx context.Context, token RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ? AND expires_at <= ?",
+		token.UserID.String(), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("can not delete expired refresh tokens: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO refresh_tokens (hash, family_id, user_id, expires_at)
+								VALUES (?, ?, ?, ?)`,
+		token.Hash, token.FamilyID, token.UserID.String(), token.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("can not set refresh token: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseRefreshToken mark refresh token as used and return its state before marking.
+func (s *SQLiteRepository) UseRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	token := RefreshToken{Hash: hash}
+	var userID string
+	var usedAt sql.NullTime
+	var revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT family_id, user_id, expires_at, used_at, revoked_at
+								FROM refresh_tokens WHERE hash = ?`, hash).
+		Scan(&token.FamilyID, &userID, &token.ExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("can not get refresh token: %w", err)
+	}
+	token.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse user id: %w", err)
+	}
+	token.ExpiresAt = token.ExpiresAt.UTC()
+	if usedAt.Valid {
+		token.UsedAt = usedAt.Time.UTC()
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = revokedAt.Time.UTC()
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = COALESCE(used_at, ?) WHERE hash = ?",
+		time.Now().UTC(), hash)
+	if err != nil {
+		return nil, fmt.Errorf("can not use refresh token: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return &token, nil
+}
+
+// RevokeRefreshTokens mark all refresh tokens of family as revoked.
+func (s *SQLiteRepository) RevokeRefreshTokens(ctx context.Context, familyID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ?
+									WHERE family_id = ? AND revoked_at IS NULL`, time.Now().UTC(), familyID)
+	if err != nil {
+		return fmt.Errorf("can not revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// SetIdentity store identity if it is not stored yet and return stored identity.
+func (s *SQLiteRepository) SetIdentity(ctx context.Context, identity Identity) (*Identity, error) {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO users (issuer, subject, user_id, created_at)
+								VALUES (?, ?, ?, ?)
+								ON CONFLICT (issuer, subject) DO NOTHING`,
+		identity.Issuer, identity.Subject, identity.UserID.String(), identity.CreatedAt.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("can not set identity: %w", err)
+	}
+
+	var userID string
+	err = s.db.QueryRowContext(ctx, `SELECT user_id, created_at FROM users WHERE issuer = ? AND subject = ?`,
+		identity.Issuer, identity.Subject).Scan(&userID, &identity.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("can not get identity: %w", err)
+	}
+	identity.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse user id: %w", err)
+	}
+	identity.CreatedAt = identity.CreatedAt.UTC()
+	return &identity, nil
+}
+
+// MergeUser move links and queued deletions of anonymous user into another user.
+// Links of user with identity are not moved.
+func (s *SQLiteRepository) MergeUser(ctx context.Context, from uuid.UUID, to uuid.UUID) error {
+	if from == to {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	for _, table := range []string{"urls", "urls_for_delete"} {
+		_, err = tx.ExecContext(ctx, `UPDATE `+table+` SET user_id = ?
+									WHERE user_id = ? AND NOT EXISTS (SELECT 1 FROM users WHERE user_id = ?)`,
+			to.String(), from.String(), from.String())
+		if err != nil {
+			return fmt.Errorf("can not move %s: %w", table, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SearchLinks return links which match filter in order of creation.
+func (s *SQLiteRepository) SearchLinks(ctx context.Context, filter LinkFilter) ([]Record, error) {
+	var userID string
+	if filter.UserID != uuid.Nil {
+		userID = filter.UserID.String()
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at
+										FROM urls
+										WHERE (? = '' OR user_id = ?)
+										AND (instr(short_url, ?) > 0 OR instr(original_url, ?) > 0)
+										ORDER BY id LIMIT ? OFFSET ?`,
+		userID, userID, filter.Query, filter.Query, sqliteLimit(filter.Limit), max(filter.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("can not search links: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var result []Record
+	for rows.Next() {
+		record, err := scanSQLiteRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return result, nil
+}
+
+// SetLinkDisabled disable or reenable link and return link as it was before change.
+func (s *SQLiteRepository) SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) (*Record, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	records, err := s.recordsInTx(ctx, tx, []string{shortURL})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE urls SET disabled = ? WHERE short_url = ?", disabled, shortURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not set disabling flag: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return &records[0], nil
+}
+
+// GetUsers return users which have links with amount of their links in order of user ID.
+func (s *SQLiteRepository) GetUsers(ctx context.Context, limit int, offset int) ([]UserLinks, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, COUNT(*) FROM urls
+										GROUP BY user_id ORDER BY user_id LIMIT ? OFFSET ?`,
+		sqliteLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("can not get users: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var users []UserLinks
+	for rows.Next() {
+		var user UserLinks
+		var userID string
+		err = rows.Scan(&userID, &user.URLs)
+		if err != nil {
+			return nil, fmt.Errorf("can not read row: %w", err)
+		}
+		user.UserID, err = uuid.Parse(userID)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse user id: %w", err)
+		}
+		users = append(users, user)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return users, nil
+}
+
+// TransferLinks move links to another user and return links as they were before transfer.
+func (s *SQLiteRepository) TransferLinks(ctx context.Context, shortURLs []string, to uuid.UUID) ([]Record, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	records, err := s.recordsInTx(ctx, tx, shortURLs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(shortURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal urls: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE urls SET user_id = ? WHERE short_url IN (SELECT value FROM json_each(?))",
+		to.String(), string(data))
+	if err != nil {
+		return nil, fmt.Errorf("can not transfer links: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return records, nil
+}
+
+// AppendAudit store events in audit log.
+func (s *SQLiteRepository) AppendAudit(ctx context.Context, events []AuditEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	for i := range events {
+		before, err := marshalAuditRecord(events[i].Before)
+		if err != nil {
+			return err
+		}
+		after, err := marshalAuditRecord(events[i].After)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (created_at, action, short_url, user_id, actor_id,
+										auth_method, client_ip, request_id, before, after)
+									VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			events[i].CreatedAt.UTC(), events[i].Action, events[i].ShortURL, events[i].UserID.String(),
+			events[i].ActorID.String(), events[i].AuthMethod, events[i].ClientIP, events[i].RequestID,
+			sqliteText(before), sqliteText(after))
+		if err != nil {
+			return fmt.Errorf("can not insert audit event: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("can not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ExportAudit call fn for every event which matches filter in order of appending.
+// Time range is checked after reading, because times are stored as text.
+func (s *SQLiteRepository) ExportAudit(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	var userID string
+	if filter.UserID != uuid.Nil {
+		userID = filter.UserID.String()
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, created_at, action, short_url, user_id, actor_id,
+										auth_method, client_ip, request_id, before, after
+										FROM audit_log
+										WHERE (? = '' OR short_url = ?) AND (? = '' OR user_id = ? OR actor_id = ?)
+										ORDER BY id`,
+		filter.ShortURL, filter.ShortURL, userID, userID, userID)
+	if err != nil {
+		return fmt.Errorf("can not get audit events: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var count int
+	for rows.Next() {
+		event, err := scanSQLiteAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if !filter.match(event) {
+			continue
+		}
+		err = fn(event)
+		if err != nil {
+			return err
+		}
+		count++
+		if filter.Limit > 0 && count == filter.Limit {
+			return nil
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	return nil
+}
+
+// recordsInTx return links by short URLs in order of creation inside transaction.
+func (s *SQLiteRepository) recordsInTx(ctx context.Context, tx *sql.Tx, shortURLs []string) ([]Record, error) {
+	data, err := json.Marshal(shortURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal urls: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at
+									FROM urls WHERE short_url IN (SELECT value FROM json_each(?)) ORDER BY id`,
+		string(data))
+	if err != nil {
+		return nil, fmt.Errorf("can not get links: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var records []Record
+	for rows.Next() {
+		record, err := scanSQLiteRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+	return records, nil
+}
+
+// sqliteLimit return limit of query, SQLite treats negative limit as no limit.
+func sqliteLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+// Ping check connection with database.
+func (s *SQLiteRepository) Ping(ctx context.Context) error {
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("can not ping SQLite database: %w", err)
+	}
+	return nil
+}
+
+// Stats return statistic of shortened urls and users in service.
+func (s *SQLiteRepository) Stats(ctx context.Context) (*models.Stats, error) {
+	var urls int
+	var users int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT short_url), COUNT(DISTINCT user_id) FROM urls").
+		Scan(&urls, &users)
+	if err != nil {
+		return nil, fmt.Errorf("can not get stats: %w", err)
+	}
+
+	return &models.Stats{
+		URLs:  urls,
+		Users: users,
+	}, nil
+}
+
+// Export call fn for every link in order of creation.
+func (s *SQLiteRepository) Export(ctx context.Context, fn func(Record) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at
+										FROM urls ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("can not get links: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	for rows.Next() {
+		record, err := scanSQLiteRecord(rows)
+		if err != nil {
+			return err
+		}
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return nil
+}
+
+// Lookup return existing links which have short URL or original URL of one of records.
+func (s *SQLiteRepository) Lookup(ctx context.Context, records []Record) ([]Record, error) {
+	shortURLs := make([]string, 0, len(records))
+	originalURLs := make([]string, 0, len(records))
+	for i := range records {
+		shortURLs = append(shortURLs, records[i].ShortURL)
+		originalURLs = append(originalURLs, records[i].OriginalURL)
+	}
+	shortURLsData, err := json.Marshal(shortURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal short urls: %w", err)
+	}
+	originalURLsData, err := json.Marshal(originalURLs)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal original urls: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT short_url, original_url, user_id, deleted, disabled, created_at
+										FROM urls
+										WHERE short_url IN (SELECT value FROM json_each(?))
+										OR original_url IN (SELECT value FROM json_each(?))`,
+		string(shortURLsData), string(originalURLsData))
+	if err != nil {
+		return nil, fmt.Errorf("can not lookup links: %w", err)
+	}
+	defer func() {
+		err = rows.Close()
+		if err != nil {
+			s.logger.Error("Can not close rows", zap.Error(err))
+		}
+	}()
+
+	var result []Record
+	for rows.Next() {
+		record, err := scanSQLiteRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("can not read rows: %w", rows.Err())
+	}
+
+	return result, nil
+}
+
+// scanSQLiteRecord read link from row as record of export.
+func scanSQLiteRecord(rows *sql.Rows) (Record, error) {
+	var record Record
+	var userID string
+	var createdAt sql.NullTime
+	err := rows.Scan(&record.ShortURL, &record.OriginalURL, &userID, &record.Deleted, &record.Disabled, &createdAt)
+	if err != nil {
+		return Record{}, fmt.Errorf("can not read row: %w", err)
+	}
+	record.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		record.UserID = uuid.UUID{}
+	}
+	if createdAt.Valid {
+		record.CreatedAt = createdAt.Time.UTC()
+	}
+	return record, nil
+}
+
+// Import store records as is replacing links with the same short URL or original URL.
+func (s *SQLiteRepository) Import(ctx context.Context, records []Record) error {
+	err := validateRecords(records)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can not start transaction: %w", err)
+	}
+	defer s.rollback(tx)
+
+	for i := range records {
+		_, err = tx.ExecContext(ctx, "DELETE FROM urls WHERE short_url = ? OR original_url = ?",
+			records[i].ShortURL, records[i].OriginalURL)
+		if err != nil {
+			return fmt.Errorf("can not delete conflicting links: %w", err)
+		}
+
+		var createdAt *time.Time
+		if !records[i].CreatedAt.IsZero() {
+			createdAt = &records[i].CreatedAt
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO urls (short_url, original_url, user_id, deleted, disabled, created_at)
+									VALUES (?, ?, ?, ?, ?, ?)`, records[i].ShortURL, records[i].OriginalURL,
+			records[i].UserID.String(), records[i].Deleted, records[i].Disabled, createdAt)
+		if err != nil {
+			return fmt.Errorf("can not import link: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("can not commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Close close connection with database.
+func (s *SQLiteRepository) Close() error {
+	err := s.db.Close()
+	if err != nil {
+		return fmt.Errorf("can not close SQLite database: %w", err)
+	}
+	return nil
+}
+
+// scanSQLiteAuditEvent read audit event from row.
+func scanSQLiteAuditEvent(rows *sql.Rows) (AuditEvent, error) {
+	var event AuditEvent
+	var userID, actorID string
+	var before, after sql.NullString
+	err := rows.Scan(&event.ID, &event.CreatedAt, &event.Action, &event.ShortURL, &userID, &actorID,
+		&event.AuthMethod, &event.ClientIP, &event.RequestID, &before, &after)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("can not read row: %w", err)
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	event.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("can not parse user id: %w", err)
+	}
+	event.ActorID, err = uuid.Parse(actorID)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("can not parse actor id: %w", err)
+	}
+	event.Before, err = unmarshalAuditRecord([]byte(before.String))
+	if err != nil {
+		return AuditEvent{}, err
+	}
+	event.After, err = unmarshalAuditRecord([]byte(after.String))
+	if err != nil {
+		return AuditEvent{}, err
+	}
+	return event, nil
+}
+
+// sqliteText return text of data or NULL for empty data.
+func sqliteText(data []byte) sql.NullString {
+	return sql.NullString{String: string(data), Valid: len(data) != 0}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// newTestSQLiteRepository create SQLiteRepository in temporary directory.
+func newTestSQLiteRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	dsn := SQLiteScheme + filepath.Join(t.TempDir(), "shorturls.db")
+	err := Migrate(dsn)
+	assert.NoError(t, err)
+
+	repo, err := NewSQLiteRepository(context.Background(), zap.NewNop(), dsn)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		err := repo.Close()
+		assert.NoError(t, err)
+	})
+	return repo
+}
+
+func TestNewSQLiteRepository(t *testing.T) {
+	t.Run("successful creation", func(t *testing.T) {
+		repo := newTestSQLiteRepository(t)
+		assert.NotNil(t, repo)
+
+		err := repo.Ping(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		repo, err := NewSQLiteRepository(
+			context.Background(),
+			zap.NewNop(),
+			SQLiteScheme+filepath.Join(t.TempDir(), "nonexistent", "shorturls.db"),
+		)
+		assert.Error(t, err)
+		assert.Nil(t, repo)
+	})
+}
+
+func TestSQLiteRepositorySetLink(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+
+	originalURL := "http://example.com"
+	userID := uuid.New()
+
+	result, err := repo.SetLink(context.Background(), originalURL, []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", *result)
+
+	result, err = repo.SetLink(context.Background(), originalURL, []string{"ghi789"}, userID)
+	assert.ErrorIs(t, err, ErrOriginalURLUniqueViolation)
+	assert.Equal(t, "abc123", *result)
+
+	result, err = repo.SetLink(context.Background(), "http://another.com", []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", *result)
+
+	result, err = repo.SetLink(context.Background(), "http://third.com", []string{"abc123"}, userID)
+	assert.ErrorIs(t, err, ErrReachedMaxGenerationRetries)
+	assert.Nil(t, result)
+
+	result, err = repo.SetLink(context.Background(), "invalid", []string{"jkl012"}, userID)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	assert.Nil(t, result)
+
+	originalURLResult, err := repo.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, originalURL, *originalURLResult)
+
+	_, err = repo.GetOriginalURL(context.Background(), "nonexistent")
+	assert.Error(t, err)
+}
+
+func TestSQLiteRepositorySetLinks(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+
+	batch := []models.ShortenBatchRequest{
+		{OriginalURL: "http://example.com"},
+		{OriginalURL: "http://another.com"},
+	}
+	result, err := repo.SetLinks(context.Background(), batch, [][]string{{"def456"}, {"abc123", "ghi789"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "abc123", Status: models.BatchStatusExisting},
+		{ShortURL: "ghi789", Status: models.BatchStatusCreated},
+	}, result)
+
+	batch = []models.ShortenBatchRequest{
+		{OriginalURL: "http://third.com"},
+		{OriginalURL: "invalid"},
+	}
+	result, err = repo.SetLinks(context.Background(), batch, [][]string{{"jkl012"}, {"mno345"}}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []LinkResult{
+		{ShortURL: "jkl012", Status: models.BatchStatusCreated},
+		{Status: models.BatchStatusInvalid},
+	}, result)
+
+	_, err = repo.GetOriginalURL(context.Background(), "mno345")
+	assert.Error(t, err)
+}
+
+func TestSQLiteRepositoryGetShortLinksOfUser(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, uuid.New())
+	assert.NoError(t, err)
+
+	result, err := repo.GetShortLinksOfUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ShortenOfUserResponse{{
+		ShortURL:    "abc123",
+		OriginalURL: "http://example.com",
+	}}, result)
+}
+
+func TestSQLiteRepositoryDeleteURLs(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID1)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, userID2)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://third.com", []string{"ghi789"}, userID1)
+	assert.NoError(t, err)
+
+	err = repo.DeleteURLs(context.Background(), []string{"abc123", "def456"}, userID1)
+	assert.NoError(t, err)
+	err = repo.DeleteURLs(context.Background(), []string{"def456"}, userID2)
+	assert.NoError(t, err)
+	err = repo.DeleteURLs(context.Background(), []string{"ghi789"}, userID1)
+	assert.NoError(t, err)
+
+	_, err = repo.GetOriginalURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+
+	count, err := repo.DeleteURLsInDB(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = repo.GetOriginalURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+	_, err = repo.GetOriginalURL(context.Background(), "def456")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+	_, err = repo.GetOriginalURL(context.Background(), "ghi789")
+	assert.NoError(t, err)
+
+	count, err = repo.DeleteURLsInDB(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = repo.GetOriginalURL(context.Background(), "ghi789")
+	assert.ErrorIs(t, err, ErrURLIsDeleted)
+
+	count, err = repo.DeleteURLsInDB(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestSQLiteRepositoryStats(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+
+	userID := uuid.New()
+	_, err := repo.SetLink(context.Background(), "http://example.com", []string{"abc123"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://another.com", []string{"def456"}, userID)
+	assert.NoError(t, err)
+	_, err = repo.SetLink(context.Background(), "http://third.com", []string{"ghi789"}, uuid.New())
+	assert.NoError(t, err)
+
+	stats, err := repo.Stats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Stats{URLs: 3, Users: 2}, stats)
+}
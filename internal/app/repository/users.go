@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity is an external identity of user, like subject of OpenID Connect issuer.
+type Identity struct {
+	CreatedAt time.Time `json:"created_at"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// UserRepository is an interface of repositories which store users of external identities.
+type UserRepository interface {
+	// SetIdentity store identity if it is not stored yet and return stored identity,
+	// so the same identity is always mapped onto the same user.
+	SetIdentity(ctx context.Context, identity Identity) (*Identity, error)
+	// MergeUser move links of anonymous user into another user.
+	// Links of user with identity are not moved.
+	MergeUser(ctx context.Context, from uuid.UUID, to uuid.UUID) error
+}
+
+// identityKey is a key of identity in memory.
+type identityKey struct {
+	issuer  string
+	subject string
+}
+
+// memoryIdentities is a storage of identities in memory.
+type memoryIdentities struct {
+	m          *sync.RWMutex
+	identities map[identityKey]*Identity
+	users      map[uuid.UUID]struct{}
+}
+
+// newMemoryIdentities create new memoryIdentities.
+func newMemoryIdentities() *memoryIdentities {
+	return &memoryIdentities{
+		m:          &sync.RWMutex{},
+		identities: make(map[identityKey]*Identity),
+		users:      make(map[uuid.UUID]struct{}),
+	}
+}
+
+// SetIdentity store identity if it is not stored yet and return stored identity.
+func (i *memoryIdentities) SetIdentity(_ context.Context, identity Identity) (*Identity, error) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	if stored, ok := i.get(identity); ok {
+		return &stored, nil
+	}
+	i.set(identity)
+	return &identity, nil
+}
+
+// get return stored identity with the same issuer and subject.
+func (i *memoryIdentities) get(identity Identity) (Identity, bool) {
+	stored, ok := i.identities[identityKey{issuer: identity.Issuer, subject: identity.Subject}]
+	if !ok {
+		return Identity{}, false
+	}
+	return *stored, true
+}
+
+// set store identity.
+func (i *memoryIdentities) set(identity Identity) {
+	i.identities[identityKey{issuer: identity.Issuer, subject: identity.Subject}] = &identity
+	i.users[identity.UserID] = struct{}{}
+}
+
+// has check that user has identity.
+func (i *memoryIdentities) has(userID uuid.UUID) bool {
+	i.m.RLock()
+	defer i.m.RUnlock()
+	_, ok := i.users[userID]
+	return ok
+}
@@ -9,35 +9,78 @@ import (
 	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/RexArseny/url_shortener/internal/app/controllers"
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 // NewRouter creates new router.
+// Idempotency keys are supported by creation endpoints if idempotency is not nil.
+// Creation, redirect and admin endpoints are rate limited if rateLimit is not nil.
 func NewRouter(
 	cfg *config.Config,
 	controller controllers.Controller,
-	middleware *middlewares.Middleware) (*gin.Engine, error) {
+	middleware *middlewares.Middleware,
+	idempotency *middlewares.Idempotency,
+	rateLimit *middlewares.RateLimit) (*gin.Engine, error) {
 	prefix, err := getURLPrefix(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	router := gin.New()
+	router.ContextWithFallback = true
 	router.Use(
+		middleware.ClientIP(),
 		gin.Recovery(),
 		middleware.Logger(),
 		middleware.Compressor(),
-		middleware.Auth(),
 	)
+	router.GET("/.well-known/jwks.json", middleware.JWKS())
+	router.POST("/api/auth/refresh", middleware.Refresh())
+	router.GET("/api/auth/oidc/callback", middleware.OIDCCallback())
 
-	router.POST("/", controller.CreateShortLink)
-	router.POST("/api/shorten", controller.CreateShortLinkJSON)
-	router.POST("/api/shorten/batch", controller.CreateShortLinkJSONBatch)
-	router.GET(fmt.Sprintf("%s/:%s", *prefix, controllers.ID), controller.GetShortLink)
-	router.GET("/api/user/urls", controller.GetShortLinksOfUser)
-	router.DELETE("/api/user/urls", controller.DeleteURLs)
-	router.GET("/ping", controller.PingDB)
-	router.GET("/api/internal/stats", controller.Stats)
+	authorized := router.Group("", middleware.Auth())
+	authorized.POST("/api/auth/token", middleware.DenyAPIKeys(), middleware.Token())
+	authorized.GET("/api/auth/oidc/login", middleware.DenyAPIKeys(), middleware.OIDCLogin())
+	creation := authorized.Group("", middleware.RequireScope(models.ScopeCreate))
+	single := creation.Group("", rateLimit.Handler(ratelimit.ClassCreate))
+	batch := creation.Group("", rateLimit.Handler(ratelimit.ClassBatch))
+	batch.POST("/api/shorten/bulk", middleware.DenyIdempotencyKey(), controller.CreateShortLinkBulk)
+	idempotentSingle := single.Group("")
+	idempotentBatch := batch.Group("")
+	if idempotency != nil {
+		idempotentSingle.Use(idempotency.Handler())
+		idempotentBatch.Use(idempotency.Handler())
+	}
+	idempotentSingle.POST("/", controller.CreateShortLink)
+	idempotentSingle.POST("/api/shorten", controller.CreateShortLinkJSON)
+	idempotentBatch.POST("/api/shorten/batch", controller.CreateShortLinkJSONBatch)
+	authorized.GET(
+		fmt.Sprintf("%s/:%s", *prefix, controllers.ID),
+		rateLimit.Handler(ratelimit.ClassRedirect),
+		controller.GetShortLink,
+	)
+	authorized.GET("/api/user/urls", middleware.RequireScope(models.ScopeRead), controller.GetShortLinksOfUser)
+	authorized.DELETE("/api/user/urls", middleware.RequireScope(models.ScopeDelete), controller.DeleteURLs)
+
+	apiKeys := authorized.Group("/api/user/api-keys", middleware.DenyAPIKeys())
+	apiKeys.POST("", controller.CreateAPIKey)
+	apiKeys.GET("", controller.GetAPIKeys)
+	apiKeys.DELETE(fmt.Sprintf("/:%s", controllers.ID), controller.RevokeAPIKey)
+
+	admin := authorized.Group("/api/admin", middleware.RequireAdmin(), rateLimit.Handler(ratelimit.ClassAdmin))
+	admin.GET("/links", controller.SearchLinks)
+	admin.POST("/links/transfer", controller.TransferLinks)
+	admin.POST(fmt.Sprintf("/links/:%s/disable", controllers.ID), controller.DisableLink)
+	admin.POST(fmt.Sprintf("/links/:%s/enable", controllers.ID), controller.EnableLink)
+	admin.GET("/users", controller.GetUsers)
+	admin.GET(fmt.Sprintf("/users/:%s/links", controllers.ID), controller.GetLinksOfUser)
+	admin.GET("/audit", controller.GetAudit)
+	admin.GET("/audit/export", controller.ExportAudit)
+	authorized.GET("/ping", controller.PingDB)
+	authorized.GET("/ready", controller.Ready)
+	authorized.GET("/api/internal/stats", controller.Stats)
 
 	return router, nil
 }
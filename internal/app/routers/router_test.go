@@ -2,16 +2,22 @@ package routers
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/config"
 	"github.com/RexArseny/url_shortener/internal/app/controllers"
+	"github.com/RexArseny/url_shortener/internal/app/idempotency"
 	"github.com/RexArseny/url_shortener/internal/app/logger"
 	"github.com/RexArseny/url_shortener/internal/app/middlewares"
+	"github.com/RexArseny/url_shortener/internal/app/ratelimit"
 	"github.com/RexArseny/url_shortener/internal/app/repository"
 	"github.com/RexArseny/url_shortener/internal/app/usecases"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestNewRouter(t *testing.T) {
@@ -19,9 +25,9 @@ func TestNewRouter(t *testing.T) {
 	assert.NoError(t, err)
 	testLogger, err := logger.InitLogger()
 	assert.NoError(t, err)
-	file, err := os.CreateTemp("./", "*.test")
+	file, err := os.CreateTemp(t.TempDir(), "*.test")
 	assert.NoError(t, err)
-	urlRepository, err := repository.NewLinksWithFile(file.Name())
+	urlRepository, err := repository.NewLinksWithFile(zap.NewNop(), file.Name(), repository.FileConfig{})
 	assert.NoError(t, err)
 
 	defer func() {
@@ -38,6 +44,7 @@ func TestNewRouter(t *testing.T) {
 		testLogger.Named("interactor"),
 		cfg.BasicPath,
 		urlRepository,
+		usecases.DeleterConfig{},
 	)
 	conntroller := controllers.NewController(testLogger.Named("controller"), interactor, nil)
 
@@ -48,23 +55,41 @@ func TestNewRouter(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	router, err := NewRouter(cfg, conntroller, middleware)
+	idempotencyMiddleware := middlewares.NewIdempotency(
+		testLogger.Named("idempotency"),
+		idempotency.NewMemoryStore(),
+		time.Hour,
+	)
+
+	rateLimitMiddleware := middlewares.NewRateLimit(
+		testLogger.Named("ratelimit"),
+		ratelimit.NewMemoryLimiter(),
+		map[string]ratelimit.Limit{ratelimit.ClassRedirect: {Rate: 0.001, Burst: 1}},
+	)
+
+	router, err := NewRouter(cfg, conntroller, middleware, idempotencyMiddleware, rateLimitMiddleware)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, router)
 
+	for _, statusCode := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc", nil))
+		assert.Equal(t, statusCode, w.Code)
+	}
+
 	cfg.ServerAddress = "abc"
-	router, err = NewRouter(cfg, conntroller, middleware)
+	router, err = NewRouter(cfg, conntroller, middleware, nil, nil)
 	assert.Error(t, err)
 	assert.Empty(t, router)
 
 	cfg.ServerAddress = config.DefaultServerAddress
 	cfg.BasicPath = "abc"
-	router, err = NewRouter(cfg, conntroller, middleware)
+	router, err = NewRouter(cfg, conntroller, middleware, nil, nil)
 	assert.Error(t, err)
 	assert.Empty(t, router)
 
 	cfg.BasicPath = "http://localhost:8081"
-	router, err = NewRouter(cfg, conntroller, middleware)
+	router, err = NewRouter(cfg, conntroller, middleware, nil, nil)
 	assert.Error(t, err)
 	assert.Empty(t, router)
 }
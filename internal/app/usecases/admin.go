@@ -0,0 +1,166 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Limits of pages of admin API.
+const (
+	DefaultAdminPageLimit = 100
+	MaxAdminPageLimit     = 1000
+)
+
+// Errors of admin API.
+var (
+	ErrInvalidPage     = errors.New("invalid limit or offset")
+	ErrInvalidTransfer = errors.New("invalid transfer")
+)
+
+// SearchLinks return links of all users which match filter including deleted and disabled ones.
+// Zero limit is replaced with default one.
+func (i *Interactor) SearchLinks(
+	ctx context.Context,
+	actor uuid.UUID,
+	filter repository.LinkFilter,
+) ([]models.AdminLink, error) {
+	limit, err := adminPageLimit(filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = limit
+
+	records, err := i.urlRepository.SearchLinks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("can not search links: %w", err)
+	}
+
+	i.audit(actor, "search links",
+		zap.String("query", filter.Query),
+		zap.Stringer("user_id", filter.UserID),
+		zap.Int("found", len(records)))
+
+	response := make([]models.AdminLink, 0, len(records))
+	for j := range records {
+		response = append(response, i.adminLink(records[j]))
+	}
+	return response, nil
+}
+
+// SetLinkDisabled disable or reenable link and return link after change.
+func (i *Interactor) SetLinkDisabled(
+	ctx context.Context,
+	actor uuid.UUID,
+	shortURL string,
+	disabled bool,
+) (*models.AdminLink, error) {
+	before, err := i.urlRepository.SetLinkDisabled(ctx, shortURL, disabled)
+	if err != nil {
+		return nil, fmt.Errorf("can not set link disabled: %w", err)
+	}
+
+	after := *before
+	after.Disabled = disabled
+	action := AuditDisable
+	if !disabled {
+		action = AuditEnable
+	}
+	i.record(ctx, actor, []repository.AuditEvent{change(action, before, &after)})
+
+	link := i.adminLink(after)
+	return &link, nil
+}
+
+// GetUsers return users which have links with amount of their links.
+// Zero limit is replaced with default one.
+func (i *Interactor) GetUsers(
+	ctx context.Context,
+	actor uuid.UUID,
+	limit int,
+	offset int,
+) ([]models.AdminUser, error) {
+	limit, err := adminPageLimit(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := i.urlRepository.GetUsers(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("can not get users: %w", err)
+	}
+
+	i.audit(actor, "get users", zap.Int("found", len(users)))
+
+	response := make([]models.AdminUser, 0, len(users))
+	for j := range users {
+		response = append(response, models.AdminUser{
+			UserID: users[j].UserID,
+			URLs:   users[j].URLs,
+		})
+	}
+	return response, nil
+}
+
+// TransferLinks move links to another user and return amount of transferred links.
+// Unknown short URLs are skipped.
+func (i *Interactor) TransferLinks(
+	ctx context.Context,
+	actor uuid.UUID,
+	shortURLs []string,
+	to uuid.UUID,
+) (int, error) {
+	if len(shortURLs) == 0 || to == uuid.Nil {
+		return 0, ErrInvalidTransfer
+	}
+
+	records, err := i.urlRepository.TransferLinks(ctx, shortURLs, to)
+	if err != nil {
+		return 0, fmt.Errorf("can not transfer links: %w", err)
+	}
+
+	events := make([]repository.AuditEvent, 0, len(records))
+	for j := range records {
+		after := records[j]
+		after.UserID = to
+		events = append(events, change(AuditTransfer, &records[j], &after))
+	}
+	i.record(ctx, actor, events)
+
+	return len(records), nil
+}
+
+// audit write reading action of administrator into log, changes are stored by record.
+func (i *Interactor) audit(actor uuid.UUID, action string, fields ...zap.Field) {
+	i.logger.Named("audit").Info("Admin action",
+		append([]zap.Field{zap.String("action", action), zap.Stringer("actor", actor)}, fields...)...)
+}
+
+// adminLink convert record into link of admin response.
+func (i *Interactor) adminLink(record repository.Record) models.AdminLink {
+	return models.AdminLink{
+		CreatedAt:   record.CreatedAt,
+		ID:          record.ShortURL,
+		ShortURL:    i.formatURL(record.ShortURL),
+		OriginalURL: record.OriginalURL,
+		UserID:      record.UserID,
+		Deleted:     record.Deleted,
+		Disabled:    record.Disabled,
+	}
+}
+
+// adminPageLimit validate limit and offset of page and return limit with default applied.
+func adminPageLimit(limit int, offset int) (int, error) {
+	if limit < 0 || limit > MaxAdminPageLimit || offset < 0 {
+		return 0, ErrInvalidPage
+	}
+	if limit == 0 {
+		return DefaultAdminPageLimit, nil
+	}
+	return limit, nil
+}
@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	core, logs := observer.New(zap.InfoLevel)
+	actor := uuid.New()
+	owner := uuid.New()
+	receiver := uuid.New()
+	interactor := NewInteractor(
+		ctx,
+		zap.New(core),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		DeleterConfig{},
+	)
+
+	first, err := interactor.CreateShortLink(ctx, "https://ya.ru", owner)
+	assert.NoError(t, err)
+	second, err := interactor.CreateShortLink(ctx, "https://google.com", owner)
+	assert.NoError(t, err)
+	firstID := path.Base(*first)
+	secondID := path.Base(*second)
+
+	_, err = interactor.SearchLinks(ctx, actor, repository.LinkFilter{Limit: MaxAdminPageLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidPage)
+	_, err = interactor.GetUsers(ctx, actor, 0, -1)
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	links, err := interactor.SearchLinks(ctx, actor, repository.LinkFilter{Query: "ya.ru"})
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, firstID, links[0].ID)
+	assert.Equal(t, *first, links[0].ShortURL)
+	assert.Equal(t, owner, links[0].UserID)
+
+	link, err := interactor.SetLinkDisabled(ctx, actor, firstID, true)
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+	_, err = interactor.GetShortLink(ctx, firstID)
+	assert.ErrorIs(t, err, repository.ErrURLIsDisabled)
+	_, err = interactor.SetLinkDisabled(ctx, actor, "unknown", true)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	link, err = interactor.SetLinkDisabled(ctx, actor, firstID, false)
+	assert.NoError(t, err)
+	assert.False(t, link.Disabled)
+	originalURL, err := interactor.GetShortLink(ctx, firstID)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", *originalURL)
+
+	_, err = interactor.TransferLinks(ctx, actor, nil, receiver)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	_, err = interactor.TransferLinks(ctx, actor, []string{firstID}, uuid.Nil)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	transferred, err := interactor.TransferLinks(ctx, actor, []string{secondID, "unknown"}, receiver)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transferred)
+
+	users, err := interactor.GetUsers(ctx, actor, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	for _, user := range users {
+		assert.Equal(t, 1, user.URLs)
+	}
+
+	links, err = interactor.SearchLinks(ctx, actor, repository.LinkFilter{UserID: receiver})
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, secondID, links[0].ID)
+
+	reads := logs.FilterMessage("Admin action").FilterField(zap.Stringer("actor", actor))
+	assert.Equal(t, 3, reads.Len())
+
+	events, err := interactor.GetAudit(ctx, actor, repository.AuditFilter{UserID: actor})
+	assert.NoError(t, err)
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{AuditDisable, AuditEnable, AuditTransfer}, actions)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, secondID, events[2].LinkID)
+		assert.Equal(t, receiver, events[2].UserID)
+		assert.Equal(t, owner, events[2].Before.UserID)
+		assert.Equal(t, receiver, events[2].After.UserID)
+	}
+}
@@ -0,0 +1,161 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix is a prefix of API keys which distinguishes them from JWT.
+const APIKeyPrefix = "usk_"
+
+// Limits of API keys and refresh tokens.
+const (
+	secretLength        = 32
+	maxAPIKeyNameLength = 255
+)
+
+// Scopes are default scopes of API keys.
+// Admin scope is granted only if it is requested explicitly.
+var Scopes = []string{models.ScopeCreate, models.ScopeRead, models.ScopeDelete}
+
+// Errors of API keys.
+var (
+	ErrInvalidAPIKey     = errors.New("invalid api key")
+	ErrInvalidScope      = errors.New("invalid scope")
+	ErrInvalidAPIKeyName = errors.New("invalid api key name")
+)
+
+// CreateAPIKey create new API key of user with scopes.
+// Key is returned only once, only its hash is stored.
+// Caller has to check that user is allowed to get admin scope.
+func (i *Interactor) CreateAPIKey(
+	ctx context.Context,
+	userID uuid.UUID,
+	name string,
+	scopes []string,
+) (*models.APIKeyResponse, error) {
+	if len(name) > maxAPIKeyNameLength {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if len(scopes) == 0 {
+		scopes = Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) && scope != models.ScopeAdmin {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("can not generate api key: %w", err)
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := repository.APIKey{
+		CreatedAt: time.Now().UTC(),
+		ID:        uuid.NewString(),
+		Hash:      hashSecret(key),
+		Name:      name,
+		Scopes:    scopes,
+		UserID:    userID,
+	}
+	err = i.urlRepository.SetAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("can not set api key: %w", err)
+	}
+
+	response := apiKeyResponse(apiKey)
+	response.Key = key
+	return &response, nil
+}
+
+// GetAPIKeys return all API keys of user including revoked ones.
+func (i *Interactor) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKeyResponse, error) {
+	keys, err := i.urlRepository.GetAPIKeysOfUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("can not get api keys of user: %w", err)
+	}
+
+	response := make([]models.APIKeyResponse, 0, len(keys))
+	for j := range keys {
+		response = append(response, apiKeyResponse(keys[j]))
+	}
+
+	return response, nil
+}
+
+// RevokeAPIKey revoke API key of user.
+func (i *Interactor) RevokeAPIKey(ctx context.Context, id string, userID uuid.UUID) error {
+	err := i.urlRepository.RevokeAPIKey(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("can not revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey return user and scopes of API key.
+// ErrInvalidAPIKey is returned if key is unknown or revoked.
+func (i *Interactor) AuthenticateAPIKey(ctx context.Context, key string) (uuid.UUID, []string, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return uuid.Nil, nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := i.urlRepository.GetAPIKey(ctx, hashSecret(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return uuid.Nil, nil, ErrInvalidAPIKey
+		}
+		return uuid.Nil, nil, fmt.Errorf("can not get api key: %w", err)
+	}
+	if !apiKey.RevokedAt.IsZero() {
+		return uuid.Nil, nil, ErrInvalidAPIKey
+	}
+
+	return apiKey.UserID, apiKey.Scopes, nil
+}
+
+// generateSecret return random secret of API key or refresh token.
+func generateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("can not read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashSecret return hash of API key or refresh token which is stored instead of it.
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// apiKeyResponse convert API key into response without key.
+func apiKeyResponse(key repository.APIKey) models.APIKeyResponse {
+	response := models.APIKeyResponse{
+		CreatedAt: key.CreatedAt,
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+	}
+	if !key.RevokedAt.IsZero() {
+		revokedAt := key.RevokedAt
+		response.RevokedAt = &revokedAt
+	}
+	return response
+}
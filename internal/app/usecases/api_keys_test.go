@@ -0,0 +1,70 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	userID := uuid.New()
+	interactor := NewInteractor(
+		ctx,
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		DeleterConfig{},
+	)
+
+	_, err := interactor.CreateAPIKey(ctx, userID, "ci", []string{"unknown"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, err = interactor.CreateAPIKey(ctx, userID, strings.Repeat("n", maxAPIKeyNameLength+1), nil)
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyName)
+
+	key, err := interactor.CreateAPIKey(ctx, userID, "ci", []string{models.ScopeRead, models.ScopeCreate, models.ScopeRead})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.Key, APIKeyPrefix))
+	assert.Equal(t, []string{models.ScopeCreate, models.ScopeRead}, key.Scopes)
+
+	all, err := interactor.CreateAPIKey(ctx, userID, "", nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, Scopes, all.Scopes)
+
+	admin, err := interactor.CreateAPIKey(ctx, userID, "admin", []string{models.ScopeAdmin})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{models.ScopeAdmin}, admin.Scopes)
+
+	authenticatedUserID, scopes, err := interactor.AuthenticateAPIKey(ctx, key.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, authenticatedUserID)
+	assert.Equal(t, key.Scopes, scopes)
+
+	_, _, err = interactor.AuthenticateAPIKey(ctx, APIKeyPrefix+"unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, _, err = interactor.AuthenticateAPIKey(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	err = interactor.RevokeAPIKey(ctx, key.ID, uuid.New())
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	err = interactor.RevokeAPIKey(ctx, key.ID, userID)
+	assert.NoError(t, err)
+	_, _, err = interactor.AuthenticateAPIKey(ctx, key.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	keys, err := interactor.GetAPIKeys(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+	for _, item := range keys {
+		assert.Empty(t, item.Key)
+		assert.Equal(t, item.ID == key.ID, item.RevokedAt != nil)
+	}
+}
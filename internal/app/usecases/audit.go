@@ -0,0 +1,185 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Actions of audit events.
+// Deletion is recorded when it is requested, because links are deleted later by deleter.
+const (
+	AuditCreate          = "create"
+	AuditDeleteRequested = "delete_requested"
+	AuditDisable         = "disable"
+	AuditEnable          = "enable"
+	AuditTransfer        = "transfer"
+	AuditMerge           = "merge"
+)
+
+// Methods of authentication of actors of audit events.
+const (
+	AuthMethodCookie = "cookie"
+	AuthMethodBearer = "bearer"
+	AuthMethodAPIKey = "api_key"
+	AuthMethodNew    = "new"
+	AuthMethodOIDC   = "oidc"
+)
+
+// Origin is a description of request which is stored in audit events.
+type Origin struct {
+	AuthMethod string
+	ClientIP   string
+	RequestID  string
+}
+
+// originKey is a key of origin in context.
+type originKey struct{}
+
+// WithOrigin return context which carries origin of request.
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFromContext return origin of request from context, it is empty if it is not presented.
+func OriginFromContext(ctx context.Context) Origin {
+	origin, _ := ctx.Value(originKey{}).(Origin)
+	return origin
+}
+
+// GetAudit return events of audit log which match filter.
+// Zero limit is replaced with default one.
+func (i *Interactor) GetAudit(
+	ctx context.Context,
+	actor uuid.UUID,
+	filter repository.AuditFilter,
+) ([]models.AuditEvent, error) {
+	limit, err := adminPageLimit(filter.Limit, 0)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = limit
+
+	response := make([]models.AuditEvent, 0, limit)
+	err = i.urlRepository.ExportAudit(ctx, filter, func(event repository.AuditEvent) error {
+		response = append(response, i.auditEvent(event))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not get audit events: %w", err)
+	}
+
+	i.audit(actor, "get audit",
+		zap.String("short_url", filter.ShortURL),
+		zap.Stringer("user_id", filter.UserID),
+		zap.Int("found", len(response)))
+
+	return response, nil
+}
+
+// ExportAudit write events of audit log which match filter into w as JSON lines.
+// Zero limit means all events.
+func (i *Interactor) ExportAudit(
+	ctx context.Context,
+	actor uuid.UUID,
+	filter repository.AuditFilter,
+	w io.Writer,
+) error {
+	if filter.Limit < 0 {
+		return ErrInvalidPage
+	}
+
+	i.audit(actor, "export audit",
+		zap.String("short_url", filter.ShortURL),
+		zap.Stringer("user_id", filter.UserID))
+
+	encoder := json.NewEncoder(w)
+	err := i.urlRepository.ExportAudit(ctx, filter, func(event repository.AuditEvent) error {
+		err := encoder.Encode(i.auditEvent(event))
+		if err != nil {
+			return fmt.Errorf("can not write audit event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can not export audit events: %w", err)
+	}
+	return nil
+}
+
+// record store events of change of links made by actor in audit log.
+// Operation is already done, so failure of audit log is only logged.
+func (i *Interactor) record(ctx context.Context, actor uuid.UUID, events []repository.AuditEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	origin := OriginFromContext(ctx)
+	now := time.Now().UTC()
+	for j := range events {
+		events[j].CreatedAt = now
+		events[j].ActorID = actor
+		events[j].AuthMethod = origin.AuthMethod
+		events[j].ClientIP = origin.ClientIP
+		events[j].RequestID = origin.RequestID
+	}
+
+	err := i.urlRepository.AppendAudit(ctx, events)
+	if err != nil {
+		i.logger.Error("Can not append audit events",
+			zap.Error(err),
+			zap.String("action", events[0].Action),
+			zap.Stringer("actor", actor),
+			zap.Int("events", len(events)))
+	}
+}
+
+// change return audit event of change of link from before to after state.
+// Owner of event is an owner of link after change.
+func change(action string, before *repository.Record, after *repository.Record) repository.AuditEvent {
+	event := repository.AuditEvent{
+		Before: before,
+		After:  after,
+		Action: action,
+	}
+	switch {
+	case after != nil:
+		event.ShortURL = after.ShortURL
+		event.UserID = after.UserID
+	case before != nil:
+		event.ShortURL = before.ShortURL
+		event.UserID = before.UserID
+	}
+	return event
+}
+
+// auditEvent convert event of audit log into event of admin response.
+func (i *Interactor) auditEvent(event repository.AuditEvent) models.AuditEvent {
+	response := models.AuditEvent{
+		CreatedAt:  event.CreatedAt,
+		Action:     event.Action,
+		LinkID:     event.ShortURL,
+		AuthMethod: event.AuthMethod,
+		ClientIP:   event.ClientIP,
+		RequestID:  event.RequestID,
+		ID:         event.ID,
+		UserID:     event.UserID,
+		ActorID:    event.ActorID,
+	}
+	if event.Before != nil {
+		before := i.adminLink(*event.Before)
+		response.Before = &before
+	}
+	if event.After != nil {
+		after := i.adminLink(*event.After)
+		response.After = &after
+	}
+	return response
+}
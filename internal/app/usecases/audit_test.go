@@ -0,0 +1,145 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/models"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// failingLookupRepository is a repository which fails to look up links.
+type failingLookupRepository struct {
+	*repository.Links
+}
+
+// Lookup return error.
+func (f *failingLookupRepository) Lookup(context.Context, []repository.Record) ([]repository.Record, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAudit(t *testing.T) {
+	ctx := WithOrigin(context.Background(), Origin{
+		AuthMethod: AuthMethodCookie,
+		ClientIP:   "192.0.2.1",
+		RequestID:  "request",
+	})
+
+	interactor := NewInteractor(
+		ctx,
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		DeleterConfig{},
+	)
+
+	admin := uuid.New()
+	anonymousID := uuid.New()
+	link, err := interactor.CreateShortLink(ctx, "https://ya.ru", anonymousID)
+	assert.NoError(t, err)
+	id := path.Base(*link)
+	_, err = interactor.CreateShortLink(ctx, "https://ya.ru", anonymousID)
+	assert.ErrorIs(t, err, repository.ErrOriginalURLUniqueViolation)
+	batch, err := interactor.CreateShortLinks(ctx, []models.ShortenBatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://google.com"},
+		{CorrelationID: "2", OriginalURL: "invalid"},
+	}, anonymousID, true)
+	assert.NoError(t, err)
+	batchID := path.Base(batch[0].ShortURL)
+
+	err = interactor.DeleteURLs(ctx, []string{batchID, "unknown"}, anonymousID)
+	assert.NoError(t, err)
+	err = interactor.DeleteURLs(ctx, []string{id}, uuid.New())
+	assert.NoError(t, err)
+
+	userID, err := interactor.LoginUser(ctx, "https://issuer.com", "subject", anonymousID, true)
+	assert.NoError(t, err)
+
+	events, err := interactor.GetAudit(ctx, admin, repository.AuditFilter{UserID: anonymousID})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, AuditCreate, events[0].Action)
+		assert.Equal(t, id, events[0].LinkID)
+		assert.Nil(t, events[0].Before)
+		assert.Equal(t, "https://ya.ru", events[0].After.OriginalURL)
+		assert.Equal(t, anonymousID, events[0].ActorID)
+		assert.Equal(t, AuthMethodCookie, events[0].AuthMethod)
+		assert.Equal(t, "192.0.2.1", events[0].ClientIP)
+		assert.Equal(t, "request", events[0].RequestID)
+
+		assert.Equal(t, AuditCreate, events[1].Action)
+		assert.Equal(t, batchID, events[1].LinkID)
+
+		assert.Equal(t, AuditDeleteRequested, events[2].Action)
+		assert.Equal(t, batchID, events[2].LinkID)
+		assert.False(t, events[2].Before.Deleted)
+		assert.True(t, events[2].After.Deleted)
+	}
+
+	events, err = interactor.GetAudit(ctx, admin, repository.AuditFilter{UserID: userID})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		for _, event := range events {
+			assert.Equal(t, AuditMerge, event.Action)
+			assert.Equal(t, userID, event.ActorID)
+			assert.Equal(t, anonymousID, event.Before.UserID)
+			assert.Equal(t, userID, event.After.UserID)
+		}
+	}
+
+	_, err = interactor.GetAudit(ctx, admin, repository.AuditFilter{Limit: MaxAdminPageLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidPage)
+	events, err = interactor.GetAudit(ctx, admin, repository.AuditFilter{ShortURL: id, Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	var buffer bytes.Buffer
+	err = interactor.ExportAudit(ctx, admin, repository.AuditFilter{ShortURL: id}, &buffer)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	var event models.AuditEvent
+	err = json.Unmarshal([]byte(lines[1]), &event)
+	assert.NoError(t, err)
+	assert.Equal(t, AuditMerge, event.Action)
+	assert.Equal(t, id, event.LinkID)
+
+	err = interactor.ExportAudit(ctx, admin, repository.AuditFilter{Limit: -1}, &buffer)
+	assert.ErrorIs(t, err, ErrInvalidPage)
+}
+
+func TestAuditDeleteRequestedWithoutLookup(t *testing.T) {
+	interactor := NewInteractor(
+		context.Background(),
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		&failingLookupRepository{Links: repository.NewLinks()},
+		DeleterConfig{},
+	)
+	userID := uuid.New()
+
+	err := interactor.DeleteURLs(context.Background(), []string{"abc123", "def456"}, userID)
+	assert.NoError(t, err)
+
+	events, err := interactor.GetAudit(context.Background(), uuid.New(), repository.AuditFilter{UserID: userID})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		for _, event := range events {
+			assert.Equal(t, AuditDeleteRequested, event.Action)
+			assert.Nil(t, event.Before)
+		}
+		assert.ElementsMatch(t, []string{"abc123", "def456"}, []string{events[0].LinkID, events[1].LinkID})
+	}
+
+	err = interactor.Shutdown(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,279 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Default values of DeleterConfig.
+const (
+	DefaultDeleteBatchSize     = 100
+	DefaultDeleteConcurrency   = 4
+	DefaultDeleteFlushInterval = urlsDeleteTimer * time.Millisecond
+)
+
+// ErrDeleterStopped is used in case if deletion is requested after deleter has been stopped.
+var ErrDeleterStopped = errors.New("deleter is stopped")
+
+// DeleterConfig is a set of parameters of URLs deletion worker.
+// Zero values are replaced with default ones.
+type DeleterConfig struct {
+	FlushInterval time.Duration
+	BatchSize     int
+	Concurrency   int
+}
+
+// deleteRequest is a request of user to delete URLs.
+type deleteRequest struct {
+	urls   []string
+	userID uuid.UUID
+}
+
+// Deleter is a worker which collects URLs deletion requests,
+// coalesces them by user and deletes them in batches.
+// If repository has deletion queue, requests are persisted in it before they are accepted
+// and deleter drains it, otherwise requests are kept in memory until they are flushed.
+type Deleter struct {
+	urlRepository repository.Repository
+	logger        *zap.Logger
+	requests      chan deleteRequest
+	stop          chan struct{}
+	stopped       chan struct{}
+	stopOnce      *sync.Once
+	m             *sync.RWMutex
+	pending       map[uuid.UUID][]string
+	seen          map[uuid.UUID]map[string]struct{}
+	size          int
+	cfg           DeleterConfig
+	closed        bool
+}
+
+// NewDeleter create new Deleter.
+func NewDeleter(logger *zap.Logger, urlRepository repository.Repository, cfg DeleterConfig) *Deleter {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultDeleteFlushInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultDeleteBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultDeleteConcurrency
+	}
+
+	return &Deleter{
+		urlRepository: urlRepository,
+		logger:        logger,
+		requests:      make(chan deleteRequest, cfg.BatchSize),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		stopOnce:      &sync.Once{},
+		m:             &sync.RWMutex{},
+		pending:       make(map[uuid.UUID][]string),
+		seen:          make(map[uuid.UUID]map[string]struct{}),
+		cfg:           cfg,
+	}
+}
+
+// Enqueue add URLs of user to deletion.
+// Requests are accepted only while deleter is not stopped, so every accepted request is flushed by Shutdown.
+func (d *Deleter) Enqueue(ctx context.Context, urls []string, userID uuid.UUID) error {
+	request := deleteRequest{
+		urls:   append([]string(nil), urls...),
+		userID: userID,
+	}
+
+	d.m.RLock()
+	defer d.m.RUnlock()
+	if d.closed {
+		return ErrDeleterStopped
+	}
+
+	if _, ok := d.urlRepository.(repository.DeletionQueue); ok {
+		err := d.urlRepository.DeleteURLs(ctx, request.urls, request.userID)
+		if err != nil {
+			return fmt.Errorf("can not add urls into deletion queue: %w", err)
+		}
+		return nil
+	}
+
+	select {
+	case d.requests <- request:
+		return nil
+	case <-d.stop:
+		return ErrDeleterStopped
+	case <-ctx.Done():
+		return fmt.Errorf("can not enqueue urls for delete: %w", ctx.Err())
+	}
+}
+
+// Run collect deletion requests and flush them by batch size or flush interval
+// until context is done or deleter is stopped.
+func (d *Deleter) Run(ctx context.Context) {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(d.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stop:
+			return
+		case request := <-d.requests:
+			d.add(request)
+			if d.size >= d.cfg.BatchSize {
+				d.logError(d.flush(ctx))
+			}
+		case <-ticker.C:
+			d.logError(d.flush(ctx))
+			d.logError(d.drain(ctx))
+		}
+	}
+}
+
+// Shutdown stop deleter and flush all pending requests.
+// New requests are rejected after senders which are in progress finish,
+// so no request is left in channel. Deletion queue of repository is drained completely.
+func (d *Deleter) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	d.m.Lock()
+	d.closed = true
+	d.m.Unlock()
+
+	select {
+	case <-d.stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("can not stop deleter: %w", ctx.Err())
+	}
+
+	for len(d.requests) > 0 {
+		d.add(<-d.requests)
+	}
+
+	err := d.flush(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.drain(ctx)
+}
+
+// add coalesce request with pending requests of the same user.
+func (d *Deleter) add(request deleteRequest) {
+	seen, ok := d.seen[request.userID]
+	if !ok {
+		seen = make(map[string]struct{})
+		d.seen[request.userID] = seen
+	}
+
+	for _, shortURL := range request.urls {
+		if _, ok := seen[shortURL]; ok {
+			continue
+		}
+		seen[shortURL] = struct{}{}
+		d.pending[request.userID] = append(d.pending[request.userID], shortURL)
+		d.size++
+	}
+}
+
+// flush delete pending URLs in batches with limited concurrency.
+// URLs which can not be deleted stay pending until next flush.
+func (d *Deleter) flush(ctx context.Context) error {
+	if d.size == 0 {
+		return nil
+	}
+
+	jobs := make(chan deleteRequest)
+	m := &sync.Mutex{}
+	var failed []deleteRequest
+	var errs []error
+	wg := &sync.WaitGroup{}
+	for range d.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				err := d.urlRepository.DeleteURLs(ctx, job.urls, job.userID)
+				if err != nil {
+					m.Lock()
+					failed = append(failed, job)
+					errs = append(errs, fmt.Errorf("can not delete urls of user %s: %w", job.userID, err))
+					m.Unlock()
+				}
+			}
+		}()
+	}
+
+	for userID, urls := range d.pending {
+		for start := 0; start < len(urls); start += d.cfg.BatchSize {
+			jobs <- deleteRequest{
+				urls:   urls[start:min(start+d.cfg.BatchSize, len(urls))],
+				userID: userID,
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	d.pending = make(map[uuid.UUID][]string)
+	d.seen = make(map[uuid.UUID]map[string]struct{})
+	d.size = 0
+	for _, job := range failed {
+		d.add(job)
+	}
+
+	return errors.Join(errs...)
+}
+
+// drain process deletion queue of repository if it has such with limited concurrency.
+func (d *Deleter) drain(ctx context.Context) error {
+	queue, ok := d.urlRepository.(repository.DeletionQueue)
+	if !ok {
+		return nil
+	}
+
+	errs := make(chan error, d.cfg.Concurrency)
+	wg := &sync.WaitGroup{}
+	for range d.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				count, err := queue.DeleteURLsInDB(ctx, d.cfg.BatchSize)
+				if err != nil {
+					errs <- fmt.Errorf("can not drain urls deletion queue: %w", err)
+					return
+				}
+				if count < d.cfg.BatchSize {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var drainErrs []error
+	for err := range errs {
+		drainErrs = append(drainErrs, err)
+	}
+
+	return errors.Join(drainErrs...)
+}
+
+// logError log error of background deletion if it is presented.
+func (d *Deleter) logError(err error) {
+	if err != nil {
+		d.logger.Error("Can not delete urls", zap.Error(err))
+	}
+}
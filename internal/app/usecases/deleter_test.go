@@ -0,0 +1,227 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// recordingRepository is a repository which records deletion requests.
+type recordingRepository struct {
+	*repository.Links
+	m      *sync.Mutex
+	calls  map[uuid.UUID][][]string
+	queue  int
+	failed int
+}
+
+// newRecordingRepository create new recordingRepository.
+func newRecordingRepository() *recordingRepository {
+	return &recordingRepository{
+		Links: repository.NewLinks(),
+		m:     &sync.Mutex{},
+		calls: make(map[uuid.UUID][][]string),
+	}
+}
+
+// DeleteURLs record deletion request and fail if failures are requested.
+func (r *recordingRepository) DeleteURLs(_ context.Context, urls []string, userID uuid.UUID) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.failed > 0 {
+		r.failed--
+		return errors.New("test")
+	}
+	r.calls[userID] = append(r.calls[userID], append([]string(nil), urls...))
+	return nil
+}
+
+// amountOfCalls return amount of successful deletion requests.
+func (r *recordingRepository) amountOfCalls() int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var amount int
+	for _, calls := range r.calls {
+		amount += len(calls)
+	}
+	return amount
+}
+
+// queueRepository is a repository with deletion queue.
+type queueRepository struct {
+	*recordingRepository
+}
+
+// DeleteURLsInDB process up to limit entries of deletion queue.
+func (q *queueRepository) DeleteURLsInDB(_ context.Context, limit int) (int, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	count := min(limit, q.queue)
+	q.queue -= count
+	return count, nil
+}
+
+func TestDeleter(t *testing.T) {
+	t.Run("requests of the same user are coalesced", func(t *testing.T) {
+		repo := newRecordingRepository()
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Hour})
+		go deleter.Run(context.Background())
+
+		userID1 := uuid.New()
+		userID2 := uuid.New()
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a", "b"}, userID1))
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"b", "c"}, userID1))
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"d"}, userID2))
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, 2, repo.amountOfCalls())
+		urls := repo.calls[userID1][0]
+		sort.Strings(urls)
+		assert.Equal(t, []string{"a", "b", "c"}, urls)
+		assert.Equal(t, [][]string{{"d"}}, repo.calls[userID2])
+	})
+
+	t.Run("requests are flushed by batch size", func(t *testing.T) {
+		repo := newRecordingRepository()
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{
+			FlushInterval: time.Hour,
+			BatchSize:     2,
+			Concurrency:   1,
+		})
+		go deleter.Run(context.Background())
+
+		userID := uuid.New()
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a", "b", "c"}, userID))
+
+		assert.Eventually(t, func() bool {
+			return repo.amountOfCalls() == 2
+		}, time.Second, time.Millisecond*10)
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("requests are flushed by flush interval", func(t *testing.T) {
+		repo := newRecordingRepository()
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Millisecond * 10})
+		go deleter.Run(context.Background())
+
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a"}, uuid.New()))
+
+		assert.Eventually(t, func() bool {
+			return repo.amountOfCalls() == 1
+		}, time.Second, time.Millisecond*10)
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed requests stay pending", func(t *testing.T) {
+		repo := newRecordingRepository()
+		repo.failed = 1
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Millisecond * 10})
+		go deleter.Run(context.Background())
+
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a"}, uuid.New()))
+
+		assert.Eventually(t, func() bool {
+			return repo.amountOfCalls() == 1
+		}, time.Second, time.Millisecond*10)
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("pending requests are flushed after context is done", func(t *testing.T) {
+		repo := newRecordingRepository()
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Hour})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go deleter.Run(ctx)
+
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a"}, uuid.New()))
+		cancel()
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, repo.amountOfCalls())
+	})
+
+	t.Run("deletion queue is drained", func(t *testing.T) {
+		repo := &queueRepository{recordingRepository: newRecordingRepository()}
+		repo.queue = 25
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{
+			FlushInterval: time.Hour,
+			BatchSize:     10,
+			Concurrency:   2,
+		})
+		go deleter.Run(context.Background())
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, repo.queue)
+	})
+
+	t.Run("requests are persisted in deletion queue", func(t *testing.T) {
+		repo := &queueRepository{recordingRepository: newRecordingRepository()}
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Hour})
+
+		assert.NoError(t, deleter.Enqueue(context.Background(), []string{"a"}, uuid.New()))
+		assert.Equal(t, 1, repo.amountOfCalls())
+
+		repo.failed = 1
+		err := deleter.Enqueue(context.Background(), []string{"b"}, uuid.New())
+		assert.ErrorContains(t, err, "can not add urls into deletion queue")
+		assert.Equal(t, 1, repo.amountOfCalls())
+	})
+
+	t.Run("accepted requests are flushed by concurrent shutdown", func(t *testing.T) {
+		repo := newRecordingRepository()
+		deleter := NewDeleter(zap.NewNop(), repo, DeleterConfig{FlushInterval: time.Hour, BatchSize: 1000})
+		go deleter.Run(context.Background())
+
+		var accepted int
+		m := &sync.Mutex{}
+		wg := &sync.WaitGroup{}
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := deleter.Enqueue(context.Background(), []string{"a"}, uuid.New())
+				if err == nil {
+					m.Lock()
+					accepted++
+					m.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, ErrDeleterStopped)
+			}()
+		}
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+		wg.Wait()
+		assert.Equal(t, accepted, repo.amountOfCalls())
+	})
+
+	t.Run("enqueue after shutdown", func(t *testing.T) {
+		deleter := NewDeleter(zap.NewNop(), newRecordingRepository(), DeleterConfig{})
+		go deleter.Run(context.Background())
+
+		err := deleter.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		err = deleter.Enqueue(context.Background(), []string{"a"}, uuid.New())
+		assert.ErrorIs(t, err, ErrDeleterStopped)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/url"
+	"slices"
 	"time"
 
 	"github.com/RexArseny/url_shortener/internal/app/models"
@@ -24,46 +25,50 @@ const (
 // Letters which are used for new short URL generation.
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 
+// Errors of items of batch by their statuses.
+var batchItemErrors = map[string]error{
+	models.BatchStatusInvalid: repository.ErrInvalidURL,
+	models.BatchStatusError:   repository.ErrReachedMaxGenerationRetries,
+}
+
 // Interactor is responsible for managing the logic of the service.
 type Interactor struct {
 	urlRepository repository.Repository
 	logger        *zap.Logger
+	deleter       *Deleter
 	basicPath     string
 }
 
 // NewInteractor create new Interactor.
+// Deleter is not stopped by cancellation of ctx, it accepts deletions until Shutdown,
+// so requests which are served while servers are drained are not blocked.
 func NewInteractor(
 	ctx context.Context,
 	logger *zap.Logger,
 	basicPath string,
 	urlRepository repository.Repository,
+	deleterConfig DeleterConfig,
 ) Interactor {
 	interactor := Interactor{
 		logger:        logger,
 		urlRepository: urlRepository,
+		deleter:       NewDeleter(logger.Named("deleter"), urlRepository, deleterConfig),
 		basicPath:     basicPath,
 	}
 
-	go interactor.runDeleteFromDB(ctx)
+	go interactor.deleter.Run(context.WithoutCancel(ctx))
 
 	return interactor
 }
 
-// runDeleteFromDB is a runner that execute URLs deletyeion from URL deletion queue.
-func (i *Interactor) runDeleteFromDB(ctx context.Context) {
-	db, ok := i.urlRepository.(*repository.DBRepository)
-	if !ok {
-		return
+// Shutdown stop background runners and flush pending URLs deletions.
+func (i *Interactor) Shutdown(ctx context.Context) error {
+	err := i.deleter.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("can not shutdown deleter: %w", err)
 	}
 
-	ticker := time.NewTicker(urlsDeleteTimer * time.Millisecond)
-	for range ticker.C {
-		err := db.DeleteURLsInDB(ctx)
-		if err != nil {
-			i.logger.Error("Can not delete urls", zap.Error(err))
-			return
-		}
-	}
+	return nil
 }
 
 // CreateShortLink create new short URL from original URL.
@@ -92,17 +97,38 @@ func (i *Interactor) CreateShortLink(
 		return nil, fmt.Errorf("can not set short link: %w", err)
 	}
 
+	i.record(ctx, userID, []repository.AuditEvent{change(AuditCreate, nil, &repository.Record{
+		CreatedAt:   time.Now().UTC(),
+		ShortURL:    *shortURL,
+		OriginalURL: originalURL,
+		UserID:      userID,
+	})})
+
 	path := i.formatURL(*shortURL)
 
 	return &path, nil
 }
 
 // CreateShortLinks create new short URLs from original URLs.
+// In per item mode every item of response carries its own status and error,
+// valid items are stored even if some of items are invalid or can not be stored.
+// Otherwise the whole batch fails on the first invalid item
+// and ErrOriginalURLUniqueViolation is returned if some of original URLs already exist.
 func (i *Interactor) CreateShortLinks(
 	ctx context.Context,
 	batch []models.ShortenBatchRequest,
 	userID uuid.UUID,
+	perItem bool,
 ) ([]models.ShortenBatchResponse, error) {
+	if !perItem {
+		for j := range batch {
+			_, err := url.ParseRequestURI(batch[j].OriginalURL)
+			if err != nil {
+				return nil, repository.ErrInvalidURL
+			}
+		}
+	}
+
 	shortURLs := make([][]string, 0, len(batch))
 	for range batch {
 		urls := make([]string, 0, linkGenerationRetries)
@@ -114,29 +140,39 @@ func (i *Interactor) CreateShortLinks(
 
 	result, err := i.urlRepository.SetLinks(ctx, batch, shortURLs, userID)
 	if err != nil {
-		if errors.Is(err, repository.ErrOriginalURLUniqueViolation) && result != nil {
-			response := make([]models.ShortenBatchResponse, 0, len(result))
-			for j := range result {
-				response = append(response, models.ShortenBatchResponse{
-					CorrelationID: batch[j].CorrelationID,
-					ShortURL:      i.formatURL(result[j]),
-				})
-			}
-
-			return response, repository.ErrOriginalURLUniqueViolation
-		}
 		return nil, fmt.Errorf("can not create short links: %w", err)
 	}
 	if len(result) != len(batch) {
 		return nil, errors.New("amount of results does not match amount of requests")
 	}
+	i.record(ctx, userID, createdEvents(batch, result, userID))
 
 	response := make([]models.ShortenBatchResponse, 0, len(result))
+	var originalURLUniqueViolation bool
 	for j := range result {
-		response = append(response, models.ShortenBatchResponse{
+		item := models.ShortenBatchResponse{
 			CorrelationID: batch[j].CorrelationID,
-			ShortURL:      i.formatURL(result[j]),
-		})
+		}
+		if result[j].ShortURL != "" {
+			item.ShortURL = i.formatURL(result[j].ShortURL)
+		}
+		switch {
+		case result[j].Status == models.BatchStatusExisting:
+			originalURLUniqueViolation = true
+		case result[j].Status == models.BatchStatusError && !perItem:
+			return nil, fmt.Errorf("can not create short links: %w", repository.ErrReachedMaxGenerationRetries)
+		}
+		if perItem {
+			item.Status = result[j].Status
+			if itemErr, ok := batchItemErrors[result[j].Status]; ok {
+				item.Error = itemErr.Error()
+			}
+		}
+		response = append(response, item)
+	}
+
+	if !perItem && originalURLUniqueViolation {
+		return response, repository.ErrOriginalURLUniqueViolation
 	}
 
 	return response, nil
@@ -173,14 +209,16 @@ func (i *Interactor) GetShortLinksOfUser(
 	return response, nil
 }
 
-// DeleteURLs delete short URLs of user if such exist.
+// DeleteURLs add short URLs of user to deletion if such exist.
 func (i *Interactor) DeleteURLs(ctx context.Context, urls []string, userID uuid.UUID) error {
-	go func() {
-		err := i.urlRepository.DeleteURLs(ctx, urls, userID)
-		if err != nil {
-			i.logger.Error("can not get add urls for delete", zap.Error(err))
-		}
-	}()
+	events := i.deleteRequestedEvents(ctx, urls, userID)
+
+	err := i.deleter.Enqueue(ctx, urls, userID)
+	if err != nil {
+		return fmt.Errorf("can not add urls for delete: %w", err)
+	}
+
+	i.record(ctx, userID, events)
 
 	return nil
 }
@@ -195,6 +233,22 @@ func (i *Interactor) PingDB(ctx context.Context) error {
 	return nil
 }
 
+// Ready check readiness of service and collect state of its components.
+func (i *Interactor) Ready(ctx context.Context) (*models.Readiness, error) {
+	readiness := &models.Readiness{Status: models.ReadinessReady}
+	if reporter, ok := i.urlRepository.(repository.StatusReporter); ok {
+		readiness.Components = reporter.Status()
+	}
+
+	err := i.urlRepository.Ping(ctx)
+	if err != nil {
+		readiness.Status = models.ReadinessNotReady
+		return readiness, fmt.Errorf("can not ping repository: %w", err)
+	}
+
+	return readiness, nil
+}
+
 // Stats return statistic of shortened urls and users in service.
 func (i *Interactor) Stats(ctx context.Context) (*models.Stats, error) {
 	stats, err := i.urlRepository.Stats(ctx)
@@ -205,6 +259,72 @@ func (i *Interactor) Stats(ctx context.Context) (*models.Stats, error) {
 	return stats, nil
 }
 
+// createdEvents return audit events of links of batch which are created.
+func createdEvents(
+	batch []models.ShortenBatchRequest,
+	result []repository.LinkResult,
+	userID uuid.UUID,
+) []repository.AuditEvent {
+	now := time.Now().UTC()
+	var events []repository.AuditEvent
+	for j := range result {
+		if result[j].Status != models.BatchStatusCreated {
+			continue
+		}
+		events = append(events, change(AuditCreate, nil, &repository.Record{
+			CreatedAt:   now,
+			ShortURL:    result[j].ShortURL,
+			OriginalURL: batch[j].OriginalURL,
+			UserID:      userID,
+		}))
+	}
+	return events
+}
+
+// deleteRequestedEvents return audit events of requested deletion of links of user.
+// Links are looked up before deletion if repository supports it,
+// otherwise or if lookup fails events carry only short URLs without state of links.
+func (i *Interactor) deleteRequestedEvents(
+	ctx context.Context,
+	urls []string,
+	userID uuid.UUID,
+) []repository.AuditEvent {
+	dumper, ok := i.urlRepository.(repository.Dumper)
+	if !ok {
+		return shortURLEvents(AuditDeleteRequested, urls, userID)
+	}
+
+	keys := make([]repository.Record, 0, len(urls))
+	for j := range urls {
+		keys = append(keys, repository.Record{ShortURL: urls[j]})
+	}
+	records, err := dumper.Lookup(ctx, keys)
+	if err != nil {
+		i.logger.Error("Can not look up links for audit", zap.Error(err))
+		return shortURLEvents(AuditDeleteRequested, urls, userID)
+	}
+
+	var events []repository.AuditEvent
+	for j := range records {
+		if records[j].UserID != userID || records[j].Deleted || !slices.Contains(urls, records[j].ShortURL) {
+			continue
+		}
+		after := records[j]
+		after.Deleted = true
+		events = append(events, change(AuditDeleteRequested, &records[j], &after))
+	}
+	return events
+}
+
+// shortURLEvents return audit events of action with links of user which carry only short URLs.
+func shortURLEvents(action string, urls []string, userID uuid.UUID) []repository.AuditEvent {
+	events := make([]repository.AuditEvent, 0, len(urls))
+	for j := range urls {
+		events = append(events, repository.AuditEvent{Action: action, ShortURL: urls[j], UserID: userID})
+	}
+	return events
+}
+
 // generatePath create new short URL.
 func (i *Interactor) generatePath() string {
 	path := make([]rune, shortLinkPathLength)
@@ -2,7 +2,10 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
+	"os"
 	"path"
 	"testing"
 	"time"
@@ -27,6 +30,7 @@ func TestCreateShortLink(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	result1, err := interactor.CreateShortLink(context.Background(), "", userID)
@@ -61,39 +65,55 @@ func TestCreateShortLinks(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
-	result1, err := interactor.CreateShortLinks(context.Background(), nil, userID)
+	result1, err := interactor.CreateShortLinks(context.Background(), nil, userID, false)
 	assert.NoError(t, err)
 	assert.Empty(t, result1)
 
-	result2, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{}, userID)
+	result2, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{}, userID, false)
 	assert.NoError(t, err)
 	assert.Empty(t, result2)
 
 	result3, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{{
 		CorrelationID: "1",
 		OriginalURL:   "abc",
-	}}, userID)
+	}}, userID, false)
 	assert.Error(t, err)
 	assert.Nil(t, result3)
 
 	result4, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{{
 		CorrelationID: "2",
 		OriginalURL:   "https://ya.ru",
-	}}, userID)
+	}}, userID, false)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, result4)
 	parsedURL, err := url.ParseRequestURI(result4[0].ShortURL)
 	assert.NoError(t, err)
 	assert.NotNil(t, parsedURL)
+	assert.Empty(t, result4[0].Status)
 
 	result5, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{{
 		CorrelationID: "2",
 		OriginalURL:   "https://ya.ru",
-	}}, userID)
-	assert.Error(t, err)
+	}}, userID, false)
+	assert.ErrorIs(t, err, repository.ErrOriginalURLUniqueViolation)
 	assert.NotNil(t, result5)
+	assert.Equal(t, result4[0].ShortURL, result5[0].ShortURL)
+
+	result6, err := interactor.CreateShortLinks(context.Background(), []models.ShortenBatchRequest{
+		{CorrelationID: "3", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "4", OriginalURL: "abc"},
+		{CorrelationID: "5", OriginalURL: "https://google.com"},
+	}, userID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ShortenBatchResponse{
+		{CorrelationID: "3", ShortURL: result4[0].ShortURL, Status: models.BatchStatusExisting},
+		{CorrelationID: "4", Status: models.BatchStatusInvalid, Error: repository.ErrInvalidURL.Error()},
+		{CorrelationID: "5", ShortURL: result6[2].ShortURL, Status: models.BatchStatusCreated},
+	}, result6)
+	assert.NotEmpty(t, result6[2].ShortURL)
 }
 
 func TestGetShortLink(t *testing.T) {
@@ -108,6 +128,7 @@ func TestGetShortLink(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
@@ -144,6 +165,7 @@ func TestGetShortLinksOfUser(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
@@ -172,6 +194,7 @@ func TestDeleteURLs(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
@@ -192,6 +215,110 @@ func TestDeleteURLs(t *testing.T) {
 	assert.Nil(t, result1)
 }
 
+// slowRepository is a repository which deletes URLs with delay.
+type slowRepository struct {
+	*repository.Links
+}
+
+// DeleteURLs delete URLs with delay.
+func (s *slowRepository) DeleteURLs(ctx context.Context, urls []string, userID uuid.UUID) error {
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.Links.DeleteURLs(ctx, urls, userID)
+}
+
+func TestShutdown(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+
+	t.Run("pending deletions are flushed", func(t *testing.T) {
+		userID := uuid.New()
+		interactor := NewInteractor(
+			context.Background(),
+			testLogger.Named("interactor"),
+			config.DefaultBasicPath,
+			&slowRepository{Links: repository.NewLinks()},
+			DeleterConfig{},
+		)
+
+		link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, link)
+
+		parsedURL, err := url.ParseRequestURI(*link)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err = interactor.DeleteURLs(ctx, []string{path.Base(parsedURL.Path)}, userID)
+		assert.NoError(t, err)
+		cancel()
+
+		err = interactor.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		result, err := interactor.GetShortLink(context.Background(), path.Base(parsedURL.Path))
+		assert.ErrorIs(t, err, repository.ErrURLIsDeleted)
+		assert.Nil(t, result)
+	})
+
+	t.Run("deletions are accepted after context is canceled", func(t *testing.T) {
+		userID := uuid.New()
+		ctx, cancel := context.WithCancel(context.Background())
+		interactor := NewInteractor(
+			ctx,
+			testLogger.Named("interactor"),
+			config.DefaultBasicPath,
+			repository.NewLinks(),
+			DeleterConfig{FlushInterval: time.Hour, BatchSize: 2},
+		)
+
+		var shortURLs []string
+		for i := range 10 {
+			link, err := interactor.CreateShortLink(context.Background(), fmt.Sprintf("https://ya%d.ru", i), userID)
+			assert.NoError(t, err)
+			shortURLs = append(shortURLs, path.Base(*link))
+		}
+		cancel()
+
+		for _, shortURL := range shortURLs {
+			deleteCtx, deleteCancel := context.WithTimeout(context.Background(), time.Second)
+			err := interactor.DeleteURLs(deleteCtx, []string{shortURL}, userID)
+			deleteCancel()
+			assert.NoError(t, err)
+		}
+
+		err := interactor.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		for _, shortURL := range shortURLs {
+			_, err = interactor.GetShortLink(context.Background(), shortURL)
+			assert.ErrorIs(t, err, repository.ErrURLIsDeleted)
+		}
+	})
+
+	t.Run("shutdown timeout", func(t *testing.T) {
+		interactor := NewInteractor(
+			context.Background(),
+			testLogger.Named("interactor"),
+			config.DefaultBasicPath,
+			&slowRepository{Links: repository.NewLinks()},
+			DeleterConfig{},
+		)
+
+		err := interactor.DeleteURLs(context.Background(), []string{"abc"}, uuid.New())
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		err = interactor.Shutdown(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
 func TestPingDB(t *testing.T) {
 	ctx := context.Background()
 
@@ -203,12 +330,74 @@ func TestPingDB(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	err = interactor.PingDB(context.Background())
 	assert.NoError(t, err)
 }
 
+// unavailableRepository is a repository which can not be pinged.
+type unavailableRepository struct {
+	*repository.Links
+}
+
+// Ping return error of unavailable repository.
+func (u *unavailableRepository) Ping(_ context.Context) error {
+	return errors.New("unavailable")
+}
+
+func TestReady(t *testing.T) {
+	testLogger, err := logger.InitLogger()
+	assert.NoError(t, err)
+
+	t.Run("ready with components", func(t *testing.T) {
+		file, err := os.CreateTemp(t.TempDir(), "testfile")
+		assert.NoError(t, err)
+		err = file.Close()
+		assert.NoError(t, err)
+		defer func() {
+			err = os.Remove(file.Name())
+			assert.NoError(t, err)
+		}()
+
+		linksWithFile, err := repository.NewLinksWithFile(testLogger, file.Name(), repository.FileConfig{})
+		assert.NoError(t, err)
+		defer func() {
+			err = linksWithFile.Close()
+			assert.NoError(t, err)
+		}()
+
+		interactor := NewInteractor(
+			context.Background(),
+			testLogger.Named("interactor"),
+			config.DefaultBasicPath,
+			linksWithFile,
+			DeleterConfig{},
+		)
+
+		readiness, err := interactor.Ready(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReadinessReady, readiness.Status)
+		assert.Equal(t, repository.LoadReport{}, readiness.Components["file"])
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		interactor := NewInteractor(
+			context.Background(),
+			testLogger.Named("interactor"),
+			config.DefaultBasicPath,
+			&unavailableRepository{Links: repository.NewLinks()},
+			DeleterConfig{},
+		)
+
+		readiness, err := interactor.Ready(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, models.ReadinessNotReady, readiness.Status)
+		assert.Empty(t, readiness.Components)
+	})
+}
+
 func TestStats(t *testing.T) {
 	ctx := context.Background()
 
@@ -221,6 +410,7 @@ func TestStats(t *testing.T) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
@@ -244,6 +434,7 @@ func BenchmarkCreateShortLink(b *testing.B) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	for range b.N {
@@ -265,6 +456,7 @@ func BenchmarkGetShortLink(b *testing.B) {
 		testLogger.Named("interactor"),
 		config.DefaultBasicPath,
 		repository.NewLinks(),
+		DeleterConfig{},
 	)
 
 	link, err := interactor.CreateShortLink(context.Background(), "https://ya.ru", userID)
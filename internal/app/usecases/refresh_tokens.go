@@ -0,0 +1,81 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+)
+
+// Errors of refresh tokens.
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token is reused")
+)
+
+// IssueRefreshToken issue refresh token of user which starts new family of tokens.
+func (i *Interactor) IssueRefreshToken(ctx context.Context, userID uuid.UUID, ttl time.Duration) (string, error) {
+	return i.issueRefreshToken(ctx, userID, uuid.NewString(), ttl)
+}
+
+// RotateRefreshToken exchange refresh token for new one of the same family and return user of token.
+// Reuse of already rotated token revokes the whole family, so stolen token is useless after
+// either thief or user rotates it. Such reuse is reported by error wrapping ErrRefreshTokenReused.
+func (i *Interactor) RotateRefreshToken(
+	ctx context.Context,
+	token string,
+	ttl time.Duration,
+) (uuid.UUID, string, error) {
+	stored, err := i.urlRepository.UseRefreshToken(ctx, hashSecret(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return uuid.Nil, "", ErrInvalidRefreshToken
+		}
+		return uuid.Nil, "", fmt.Errorf("can not use refresh token: %w", err)
+	}
+	if !stored.RevokedAt.IsZero() || !stored.ExpiresAt.After(time.Now()) {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+	if !stored.UsedAt.IsZero() {
+		err = i.urlRepository.RevokeRefreshTokens(ctx, stored.FamilyID)
+		if err != nil {
+			return uuid.Nil, "", fmt.Errorf("can not revoke refresh tokens: %w", err)
+		}
+		return uuid.Nil, "", fmt.Errorf("%w: %w", ErrInvalidRefreshToken, ErrRefreshTokenReused)
+	}
+
+	rotated, err := i.issueRefreshToken(ctx, stored.UserID, stored.FamilyID, ttl)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	return stored.UserID, rotated, nil
+}
+
+// issueRefreshToken issue refresh token of user in family.
+func (i *Interactor) issueRefreshToken(
+	ctx context.Context,
+	userID uuid.UUID,
+	familyID string,
+	ttl time.Duration,
+) (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", fmt.Errorf("can not generate refresh token: %w", err)
+	}
+
+	err = i.urlRepository.SetRefreshToken(ctx, repository.RefreshToken{
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+		Hash:      hashSecret(token),
+		FamilyID:  familyID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can not set refresh token: %w", err)
+	}
+
+	return token, nil
+}
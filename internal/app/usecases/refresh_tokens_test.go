@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+
+	userID := uuid.New()
+	interactor := NewInteractor(
+		ctx,
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		DeleterConfig{},
+	)
+
+	first, err := interactor.IssueRefreshToken(ctx, userID, time.Hour)
+	assert.NoError(t, err)
+
+	rotatedUserID, second, err := interactor.RotateRefreshToken(ctx, first, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, rotatedUserID)
+	assert.NotEqual(t, first, second)
+
+	_, _, err = interactor.RotateRefreshToken(ctx, first, time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, _, err = interactor.RotateRefreshToken(ctx, second, time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, _, err = interactor.RotateRefreshToken(ctx, "unknown", time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	expired, err := interactor.IssueRefreshToken(ctx, userID, -time.Second)
+	assert.NoError(t, err)
+	_, _, err = interactor.RotateRefreshToken(ctx, expired, time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
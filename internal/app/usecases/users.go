@@ -0,0 +1,81 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// LoginUser return user of external identity and create such user on first login.
+// If merge is set, links of anonymous user are moved into logged-in user,
+// links of user which has identity itself are never moved.
+func (i *Interactor) LoginUser(
+	ctx context.Context,
+	issuer string,
+	subject string,
+	anonymousID uuid.UUID,
+	merge bool,
+) (uuid.UUID, error) {
+	identity, err := i.urlRepository.SetIdentity(ctx, repository.Identity{
+		CreatedAt: time.Now().UTC(),
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    uuid.New(),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("can not set identity: %w", err)
+	}
+
+	if merge && anonymousID != uuid.Nil {
+		err = i.mergeUser(ctx, anonymousID, identity.UserID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return identity.UserID, nil
+}
+
+// mergeUser move links of anonymous user into logged-in user and record moved links in audit log.
+// Links which are still owned by anonymous user after merge are not moved.
+func (i *Interactor) mergeUser(ctx context.Context, from uuid.UUID, to uuid.UUID) error {
+	before, err := i.urlRepository.SearchLinks(ctx, repository.LinkFilter{UserID: from})
+	if err != nil {
+		return fmt.Errorf("can not get links of user: %w", err)
+	}
+
+	err = i.urlRepository.MergeUser(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("can not merge user: %w", err)
+	}
+	if len(before) == 0 {
+		return nil
+	}
+
+	left, err := i.urlRepository.SearchLinks(ctx, repository.LinkFilter{UserID: from})
+	if err != nil {
+		i.logger.Error("Can not get links of user for audit", zap.Error(err))
+		return nil
+	}
+	kept := make(map[string]struct{}, len(left))
+	for j := range left {
+		kept[left[j].ShortURL] = struct{}{}
+	}
+
+	events := make([]repository.AuditEvent, 0, len(before))
+	for j := range before {
+		if _, ok := kept[before[j].ShortURL]; ok {
+			continue
+		}
+		after := before[j]
+		after.UserID = to
+		events = append(events, change(AuditMerge, &before[j], &after))
+	}
+	i.record(ctx, to, events)
+
+	return nil
+}
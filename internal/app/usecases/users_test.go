@@ -0,0 +1,49 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RexArseny/url_shortener/internal/app/config"
+	"github.com/RexArseny/url_shortener/internal/app/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLoginUser(t *testing.T) {
+	ctx := context.Background()
+
+	interactor := NewInteractor(
+		ctx,
+		zap.NewNop(),
+		config.DefaultBasicPath,
+		repository.NewLinks(),
+		DeleterConfig{},
+	)
+
+	anonymousID := uuid.New()
+	_, err := interactor.CreateShortLink(ctx, "https://example.com", anonymousID)
+	assert.NoError(t, err)
+
+	userID, err := interactor.LoginUser(ctx, "https://issuer.com", "subject", anonymousID, false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, anonymousID, userID)
+	result, err := interactor.GetShortLinksOfUser(ctx, userID)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	sameUserID, err := interactor.LoginUser(ctx, "https://issuer.com", "subject", anonymousID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, sameUserID)
+	result, err = interactor.GetShortLinksOfUser(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+
+	anotherUserID, err := interactor.LoginUser(ctx, "https://issuer.com", "another", userID, true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, userID, anotherUserID)
+	result, err = interactor.GetShortLinksOfUser(ctx, anotherUserID)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}